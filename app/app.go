@@ -25,8 +25,10 @@ package app
 import (
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/sirupsen/logrus"
@@ -34,10 +36,13 @@ import (
 	"github.com/haakenlabs/arc/core"
 	"github.com/haakenlabs/arc/system/asset"
 	"github.com/haakenlabs/arc/system/asset/font"
+	"github.com/haakenlabs/arc/system/asset/material"
 	"github.com/haakenlabs/arc/system/asset/mesh"
+	"github.com/haakenlabs/arc/system/asset/prefab"
 	"github.com/haakenlabs/arc/system/asset/shader"
 	"github.com/haakenlabs/arc/system/asset/skybox"
 	"github.com/haakenlabs/arc/system/asset/texture"
+	"github.com/haakenlabs/arc/system/console"
 )
 
 const (
@@ -62,6 +67,29 @@ type App struct {
 	// Company is the name of the company responsible for this app.
 	Company string
 
+	// Headless runs the app without a window or an OpenGL context. Scenes are
+	// still updated, but never displayed. This can also be enabled with the
+	// app.headless configuration option.
+	Headless bool
+
+	// MaxFrames stops the app after the given number of frames. If zero, the
+	// app runs until Quit is called. This can also be set with the app.frames
+	// configuration option.
+	MaxFrames int
+
+	// Clock is the time source used by the time system. If nil, a real-time
+	// clock is used.
+	Clock core.Clock
+
+	// Args holds the command line arguments which did not override a setting.
+	// Settings are overridden with arguments of the form --key=value.
+	Args []string
+
+	// CrashFunc is an optional callback invoked with the crash report when the
+	// app recovers from a panic during Setup or Run, before teardown. It may be
+	// used to upload the report or show it to the user.
+	CrashFunc func(*CrashReport)
+
 	// PreSetupFunc is a callback invoked prior to app setup.
 	PreSetupFunc func() error
 
@@ -74,23 +102,67 @@ type App struct {
 	// PostTeardownFunc is a callback invoked after app teardown.
 	PostTeardownFunc func()
 
-	systems []core.System
-	running bool
+	systems      []core.System
+	logs         *core.LogRing
+	setupCount   int
+	tornDown     bool
+	preUpdaters  []phaseFunc
+	updaters     []phaseFunc
+	fixedUpdates []phaseFunc
+	postRenders  []phaseFunc
+	running      bool
+}
+
+// phaseFunc is a per-frame callback of a system.
+type phaseFunc struct {
+	name string
+	call func()
 }
 
-// Setup sets up the App.
-func (a *App) Setup() error {
+// Setup sets up the App. If a panic occurs, the systems which have been set up
+// are torn down, a crash report is written and an ErrPanic is returned.
+func (a *App) Setup() (err error) {
 	if appInst != nil {
 		return errors.New("app already created")
 	}
 	setApp(a)
 
-	core.LoadGlobalConfig()
+	a.logs = core.NewLogRing(core.DefaultLogLines)
+	logrus.AddHook(a.logs)
+
+	defer a.recoverPanic(&err)
+
+	if err := core.LoadGlobalConfig(); err != nil {
+		return err
+	}
+
+	settings := core.GlobalSettings()
 
-	a.RegisterSystem(core.NewWindowSystem(a.Name))
+	args, err := settings.ApplyArgs(os.Args[1:])
+	if err != nil {
+		return err
+	}
+	a.Args = args
+
+	if settings.Bool("app.headless") {
+		a.Headless = true
+	}
+	if a.MaxFrames == 0 {
+		a.MaxFrames = settings.Int("app.frames")
+	}
+
+	a.RegisterSystem(core.NewEventSystem())
+	a.RegisterSystem(core.NewJobSystem(settings.Int("job.workers")))
+	a.RegisterSystem(a.newProfilerSystem())
+	a.RegisterSystem(core.NewConsoleSystem())
+	if a.Headless {
+		a.RegisterSystem(core.NewHeadlessWindowSystem(a.Name))
+	} else {
+		a.RegisterSystem(core.NewWindowSystem(a.Name))
+	}
 	a.RegisterSystem(core.NewInstanceSystem())
 	a.RegisterSystem(core.NewAssetSystem())
-	a.RegisterSystem(core.NewTimeSystem())
+	a.RegisterSystem(a.newTimeSystem())
 	a.RegisterSystem(core.NewSceneSystem())
 
 	if a.PreSetupFunc != nil {
@@ -99,23 +171,56 @@ func (a *App) Setup() error {
 		}
 	}
 
+	systems, err := core.SortSystems(a.systems)
+	if err != nil {
+		return err
+	}
+	a.systems = systems
+
 	for i := range a.systems {
 		logrus.Debug("Setting up system: ", a.systems[i].Name())
 
 		if err := a.systems[i].Setup(); err != nil {
 			return err
 		}
+		a.setupCount = i + 1
 	}
 
 	asset.RegisterHandler(texture.NewHandler())
 	asset.RegisterHandler(shader.NewHandler())
 	asset.RegisterHandler(mesh.NewHandler())
+	asset.RegisterHandler(material.NewHandler())
+	asset.RegisterHandler(prefab.NewHandler())
 	asset.RegisterHandler(font.NewHandler())
 	asset.RegisterHandler(skybox.NewHandler())
 
-	if err := asset.LoadManifest(builtinAssets); err != nil {
+	verifier, err := packageVerifier()
+	if err != nil {
 		return err
 	}
+	asset.SetPackageVerifier(verifier)
+
+	if settings.Bool("asset.hot_reload") {
+		if err := asset.EnableHotReload(reloadInterval(), settings.String("asset.builtin_dir")); err != nil {
+			return err
+		}
+	}
+
+	// Builtin assets require an OpenGL context.
+	if !a.Headless {
+		if err := asset.LoadManifest(builtinAssets); err != nil {
+			return err
+		}
+		if err := mesh.AddBuiltins(); err != nil {
+			return err
+		}
+	}
+
+	if script := settings.String("console.exec"); script != "" {
+		if err := console.ExecScript(script); err != nil {
+			return err
+		}
+	}
 
 	if a.PostSetupFunc != nil {
 		if err := a.PostSetupFunc(); err != nil {
@@ -126,13 +231,19 @@ func (a *App) Setup() error {
 	return nil
 }
 
-// Teardown tears down the app.
+// Teardown tears down the app. Only the systems which have been set up are
+// torn down, and calling Teardown more than once has no effect.
 func (a *App) Teardown() {
+	if a.tornDown {
+		return
+	}
+	a.tornDown = true
+
 	if a.PreTeardownFunc != nil {
 		a.PreTeardownFunc()
 	}
 
-	for i := len(a.systems) - 1; i >= 0; i-- {
+	for i := a.setupCount - 1; i >= 0; i-- {
 		logrus.Debug("Tearing down system: ", a.systems[i].Name())
 
 		a.systems[i].Teardown()
@@ -143,7 +254,12 @@ func (a *App) Teardown() {
 	}
 }
 
-func (a *App) Run() error {
+// Run runs the main loop until Quit is called or the window is closed. If a
+// panic occurs, the app is torn down, a crash report is written and an
+// ErrPanic is returned.
+func (a *App) Run() (err error) {
+	defer a.recoverPanic(&err)
+
 	a.running = true
 
 	a.setupSignalHandler()
@@ -154,29 +270,63 @@ func (a *App) Run() error {
 	time := a.MustSystem(core.SysNameTime).(*core.TimeSystem)
 	window := a.MustSystem(core.SysNameWindow).(*core.WindowSystem)
 	scene := a.MustSystem(core.SysNameScene).(*core.SceneSystem)
+	events := a.MustSystem(core.SysNameEvent).(*core.EventSystem)
+	jobs := a.MustSystem(core.SysNameJob).(*core.JobSystem)
+	profiler := a.MustSystem(core.SysNameProfiler).(*core.ProfilerSystem)
+
+	budget := mainBudget()
+
+	a.collectPhases()
 
 	for a.running {
 		a.running = !window.ShouldClose()
 
 		time.FrameStart()
+		profiler.FrameStart()
 
 		frame++
 
-		scene.OnUpdate()
+		profiler.Begin("Events")
+		events.DispatchQueued()
+		profiler.End()
+
+		profiler.Begin("Jobs")
+		jobs.DrainMain(budget)
+		profiler.End()
+
+		runPhase(profiler, "PreUpdate", a.preUpdaters)
+		runPhase(profiler, "Update", a.updaters)
 
 		loops = 0
 		for time.LogicUpdate() && loops < maxFrameSkip {
 			time.LogicTick()
-			scene.OnFixedUpdate()
+			runPhase(profiler, "FixedUpdate", a.fixedUpdates)
 			loops++
 		}
 
-		window.ClearBuffers()
-		scene.OnDisplay()
-		window.SwapBuffers()
+		if !a.Headless {
+			profiler.Begin("Display")
+			window.ClearBuffers()
+			scene.OnDisplay()
+			profiler.End()
+		}
+		runPhase(profiler, "PostRender", a.postRenders)
+		if !a.Headless {
+			profiler.Begin("SwapBuffers")
+			window.SwapBuffers()
+			profiler.End()
+		}
 
+		profiler.Begin("HandleEvents")
 		window.HandleEvents()
+		profiler.End()
+
+		profiler.FrameEnd()
 		time.FrameEnd()
+
+		if a.MaxFrames > 0 && frame >= a.MaxFrames {
+			a.running = false
+		}
 	}
 
 	return nil
@@ -189,7 +339,9 @@ func (a *App) Quit() {
 
 // RegisterSystem registers a system with the App. A system can only be added
 // once, it is an error to add a system more than once. Systems are initialized
-// in the order they are added and torn down in the reverse order.
+// after their dependencies, otherwise in the order they are added, and torn
+// down in the reverse order. Per-frame callbacks are invoked in the same order
+// as initialization.
 func (a *App) RegisterSystem(s core.System) {
 	// Check for existing system.
 	if a.SystemRegistered(s.Name()) {
@@ -235,6 +387,122 @@ func (a *App) MustSystem(name string) core.System {
 	return s
 }
 
+// collectPhases caches the systems implementing each of the per-frame
+// callbacks, in the order the systems were set up.
+func (a *App) collectPhases() {
+	a.preUpdaters = a.preUpdaters[:0]
+	a.updaters = a.updaters[:0]
+	a.fixedUpdates = a.fixedUpdates[:0]
+	a.postRenders = a.postRenders[:0]
+
+	for i := range a.systems {
+		name := a.systems[i].Name()
+
+		if s, ok := a.systems[i].(core.SystemPreUpdater); ok {
+			a.preUpdaters = append(a.preUpdaters, phaseFunc{name, s.PreUpdate})
+		}
+		if s, ok := a.systems[i].(core.SystemUpdater); ok {
+			a.updaters = append(a.updaters, phaseFunc{name, s.Update})
+		}
+		if s, ok := a.systems[i].(core.SystemFixedUpdater); ok {
+			a.fixedUpdates = append(a.fixedUpdates, phaseFunc{name, s.FixedUpdate})
+		}
+		if s, ok := a.systems[i].(core.SystemPostRenderer); ok {
+			a.postRenders = append(a.postRenders, phaseFunc{name, s.PostRender})
+		}
+	}
+}
+
+// runPhase calls each of the given callbacks within a profiler scope for the
+// phase, and a nested scope for each system.
+func runPhase(profiler *core.ProfilerSystem, name string, phase []phaseFunc) {
+	if len(phase) == 0 {
+		return
+	}
+
+	profiler.Begin(name)
+	for i := range phase {
+		profiler.Begin(phase[i].name)
+		phase[i].call()
+		profiler.End()
+	}
+	profiler.End()
+}
+
+// newProfilerSystem creates the profiler system from the profile
+// configuration options.
+func (a *App) newProfilerSystem() *core.ProfilerSystem {
+	settings := core.GlobalSettings()
+
+	p := core.NewProfilerSystem(settings.Int("profile.frames"))
+
+	p.SetEnabled(settings.Bool("profile.enabled"))
+	p.SetDetailed(settings.Bool("profile.detailed"))
+	p.SetOutput(settings.String("profile.output"))
+
+	return p
+}
+
+// newTimeSystem creates the time system using the configured clock and fixed
+// time step.
+func (a *App) newTimeSystem() *core.TimeSystem {
+	var t *core.TimeSystem
+
+	switch {
+	case a.Clock != nil:
+		t = core.NewTimeSystemWithClock(a.Clock)
+	case a.Headless:
+		t = core.NewHeadlessTimeSystem()
+	default:
+		t = core.NewTimeSystem()
+	}
+
+	if err := t.SetFixedTime(core.GlobalSettings().Float("time.fixed")); err != nil {
+		logrus.Warnf("Ignoring time.fixed setting: %v", err)
+	}
+
+	return t
+}
+
+// mainBudget returns the time per frame spent running main thread jobs, from
+// the job.budget configuration option in milliseconds.
+func mainBudget() time.Duration {
+	return time.Duration(core.GlobalSettings().Float("job.budget") * float64(time.Millisecond))
+}
+
+// reloadInterval returns the time between checks for changed asset files,
+// from the asset.reload_interval configuration option in milliseconds.
+func reloadInterval() time.Duration {
+	return time.Duration(core.GlobalSettings().Float("asset.reload_interval") * float64(time.Millisecond))
+}
+
+// packageVerifier returns how packages are verified, from the
+// asset.package_verify and asset.trusted_keys configuration options.
+func packageVerifier() (*core.PackageVerifier, error) {
+	settings := core.GlobalSettings()
+
+	policy, err := core.ParsePackagePolicy(settings.String("asset.package_verify"))
+	if err != nil {
+		return nil, err
+	}
+
+	v := &core.PackageVerifier{Policy: policy}
+
+	for _, s := range strings.Split(settings.String("asset.trusted_keys"), ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+
+		key, err := core.ParsePublicKey(s)
+		if err != nil {
+			return nil, errors.Annotate(err, "asset.trusted_keys")
+		}
+		v.Keys = append(v.Keys, key)
+	}
+
+	return v, nil
+}
+
 func (a *App) setupSignalHandler() {
 	s := make(chan os.Signal)
 	signal.Notify(s, os.Interrupt, syscall.SIGTERM)
@@ -246,7 +514,7 @@ func handleSignal(s chan os.Signal, a *App) {
 	a.Quit()
 }
 
-/// / NewApp creates a new application.
+// NewApp creates a new application.
 func NewApp() *App {
 	a := &App{}
 
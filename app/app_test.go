@@ -0,0 +1,133 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package app
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/haakenlabs/arc/core"
+	"github.com/haakenlabs/arc/scene"
+	"github.com/haakenlabs/arc/system/asset"
+	"github.com/haakenlabs/arc/system/instance"
+	scenesys "github.com/haakenlabs/arc/system/scene"
+)
+
+// counter counts the frames it is updated.
+type counter struct {
+	scene.BaseScriptComponent
+
+	updates int
+}
+
+func (c *counter) Update() {
+	c.updates++
+}
+
+func TestApp_Headless(t *testing.T) {
+	a := NewApp()
+	a.Name = "arc-test"
+	a.Headless = true
+	a.MaxFrames = 3
+
+	c := &counter{}
+	c.SetName("Counter")
+
+	unloads := 0
+
+	s := scene.NewScene("headless")
+	s.Manifests = []string{"test:headless.json"}
+	s.UnloadFunc = func() {
+		unloads++
+	}
+	s.LoadFunc = func() error {
+		instance.MustAssign(c)
+
+		// Cameras and materials must not need OpenGL when headless.
+		camera := scene.NewGameObject("Camera")
+		camera.AddComponent(scene.NewCamera(scene.RenderPathDeferred, true))
+		camera.AddComponent(c)
+
+		if m := scene.NewMaterialPBR(); m.Shader() != nil {
+			t.Errorf("got: %v want: nil shader", m.Shader())
+		}
+
+		return s.AddObject(camera, nil)
+	}
+
+	a.PostSetupFunc = func() error {
+		if err := asset.Mount("test", "mem", fstest.MapFS{
+			"headless.json": {Data: []byte(`{"name": "headless"}`)},
+		}, 0); err != nil {
+			return err
+		}
+		if err := scenesys.Register(s); err != nil {
+			return err
+		}
+
+		return scenesys.Push("headless")
+	}
+
+	if err := a.Setup(); err != nil {
+		t.Fatalf("got: %v want: nil", err)
+	}
+	defer a.Teardown()
+
+	if err := a.Run(); err != nil {
+		t.Fatalf("got: %v want: nil", err)
+	}
+
+	if c.updates != a.MaxFrames {
+		t.Errorf("got: %d updates want: %d", c.updates, a.MaxFrames)
+	}
+	if s.Environment() == nil {
+		t.Errorf("got: nil environment want: environment")
+	}
+
+	// Popping the scene releases its manifests and unloads its objects.
+	h := instance.HandleOf(c)
+	scenesys.Pop()
+
+	if unloads != 1 {
+		t.Errorf("got: %d unloads want: 1", unloads)
+	}
+	if s.Loaded() || s.Environment() != nil {
+		t.Errorf("got: loaded %v want: unloaded", s.Loaded())
+	}
+	if instance.Valid(h) {
+		t.Errorf("got: valid counter want: released")
+	}
+	if core.GetAssetSystem().ManifestLoaded("test:headless.json") {
+		t.Errorf("got: manifest loaded want: unloaded")
+	}
+
+	// The scene can be loaded again once it has been unloaded.
+	c = &counter{}
+	c.SetName("Counter")
+	if err := scenesys.Push("headless"); err != nil {
+		t.Fatalf("got: %v want: nil", err)
+	}
+	if !s.Loaded() || !instance.Valid(instance.HandleOf(c)) {
+		t.Errorf("got: loaded %v want: reloaded", s.Loaded())
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/haakenlabs/arc/core"
+)
+
+const defaultCrashDir = "crash"
+
+// ErrPanic reports that the app recovered from a panic.
+type ErrPanic struct {
+	// Value is the value passed to panic.
+	Value interface{}
+
+	// Report is the crash report directory, or empty if the report could not
+	// be written.
+	Report string
+}
+
+func (e ErrPanic) Error() string {
+	if e.Report == "" {
+		return fmt.Sprintf("panic: %v", e.Value)
+	}
+
+	return fmt.Sprintf("panic: %v (crash report: %s)", e.Value, e.Report)
+}
+
+// CrashReport describes the state of the app when it panicked.
+type CrashReport struct {
+	// Time is the time of the crash.
+	Time time.Time
+
+	// Dir is the directory the report was written to. It is empty if the
+	// report could not be written.
+	Dir string
+
+	// Panic is the value passed to panic.
+	Panic interface{}
+
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+
+	// Log holds the most recent log lines.
+	Log []string
+
+	// Scenes holds the names of the active scenes, top of the stack last.
+	Scenes []string
+
+	// Hierarchy is the scene graph of the active scene.
+	Hierarchy string
+
+	// Instances is the number of objects of each type.
+	Instances map[string]int
+
+	// Config holds all configuration values.
+	Config map[string]interface{}
+}
+
+// recoverPanic recovers a panic, writes a crash report, calls the crash hook
+// and tears down the app. The panic is returned in err as an ErrPanic. It
+// must be deferred directly.
+func (a *App) recoverPanic(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	report := a.newCrashReport(r, debug.Stack())
+
+	logrus.Errorf("Recovered from panic: %v", r)
+
+	if dir, werr := report.Write(crashDir()); werr != nil {
+		logrus.Error("Unable to write crash report: ", werr)
+	} else {
+		report.Dir = dir
+		logrus.Error("Wrote crash report to ", dir)
+	}
+
+	if a.CrashFunc != nil {
+		a.safeCall(func() { a.CrashFunc(report) })
+	}
+
+	a.safeCall(a.Teardown)
+
+	*err = ErrPanic{Value: r, Report: report.Dir}
+}
+
+// crashDir returns the directory crash reports are written to. The settings
+// may not have been loaded if the panic happened early in setup.
+func crashDir() string {
+	if settings := core.GlobalSettings(); settings.Registered("app.crash_dir") {
+		return settings.String("app.crash_dir")
+	}
+
+	return defaultCrashDir
+}
+
+// safeCall calls fn, logging rather than propagating any panic.
+func (a *App) safeCall(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("Panic while handling crash: %v", r)
+		}
+	}()
+
+	fn()
+}
+
+// newCrashReport collects the state of the app. Each part is collected
+// separately, so a failure in one does not prevent the others.
+func (a *App) newCrashReport(value interface{}, stack []byte) *CrashReport {
+	report := &CrashReport{
+		Time:  time.Now(),
+		Panic: value,
+		Stack: stack,
+	}
+
+	if a.logs != nil {
+		report.Log = a.logs.Lines()
+	}
+
+	a.safeCall(func() {
+		if s := core.GetSceneSystem(); s != nil {
+			report.Scenes = s.Stack()
+
+			if w, ok := s.Active().(core.SceneHierarchyWriter); ok {
+				var buf bytes.Buffer
+				w.WriteHierarchy(&buf)
+				report.Hierarchy = buf.String()
+			}
+		}
+	})
+	a.safeCall(func() {
+		if s := core.GetInstanceSystem(); s != nil {
+			report.Instances = s.CountByType()
+		}
+	})
+	a.safeCall(func() {
+		report.Config = viper.AllSettings()
+	})
+
+	return report
+}
+
+// Write writes the report to a new directory within dir, named after the
+// time of the crash, and returns the path of the new directory.
+func (r *CrashReport) Write(dir string) (string, error) {
+	dir = filepath.Join(dir, r.Time.Format("20060102-150405.000"))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	var summary bytes.Buffer
+	fmt.Fprintf(&summary, "panic: %v\n\n", r.Panic)
+	fmt.Fprintf(&summary, "time: %s\n", r.Time.Format(time.RFC3339))
+	fmt.Fprintf(&summary, "go: %s %s/%s\n\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	summary.Write(r.Stack)
+
+	var scenes bytes.Buffer
+	fmt.Fprintf(&scenes, "stack: %s\n\n", strings.Join(r.Scenes, " > "))
+	scenes.WriteString(r.Hierarchy)
+
+	names := make([]string, 0, len(r.Instances))
+	for name := range r.Instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var instances bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&instances, "%6d %s\n", r.Instances[name], name)
+	}
+
+	config, err := json.MarshalIndent(r.Config, "", "  ")
+	if err != nil {
+		config = []byte(err.Error())
+	}
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"crash.txt", summary.Bytes()},
+		{"log.txt", []byte(strings.Join(r.Log, "\n") + "\n")},
+		{"scenes.txt", scenes.Bytes()},
+		{"instances.txt", instances.Bytes()},
+		{"config.json", config},
+	}
+
+	for _, f := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, f.name), f.data, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package app
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCrashReport_Write(t *testing.T) {
+	report := &CrashReport{
+		Time:      time.Date(2018, 3, 4, 5, 6, 7, 890000000, time.UTC),
+		Panic:     "boom",
+		Stack:     []byte("goroutine 1 [running]:\n"),
+		Log:       []string{"first", "second"},
+		Scenes:    []string{"menu", "level"},
+		Hierarchy: "Camera [00000001] active=true {}\n",
+		Instances: map[string]int{"*scene.GameObject": 2, "*scene.Camera": 1},
+		Config:    map[string]interface{}{"app": map[string]interface{}{"headless": true}},
+	}
+
+	root := t.TempDir()
+
+	dir, err := report.Write(root)
+	if err != nil {
+		t.Fatalf("got: %v want: nil", err)
+	}
+	if want := filepath.Join(root, "20180304-050607.890"); dir != want {
+		t.Errorf("got: %s want: %s", dir, want)
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("got: %v want: nil", err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+
+	wantNames := []string{"config.json", "crash.txt", "instances.txt", "log.txt", "scenes.txt"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("got: %v want: %v", names, wantNames)
+	}
+
+	tests := []struct {
+		file     string
+		contains []string
+	}{
+		{file: "crash.txt", contains: []string{"panic: boom\n", "time: 2018-03-04T05:06:07Z\n", "goroutine 1 [running]:"}},
+		{file: "log.txt", contains: []string{"first\nsecond\n"}},
+		{file: "scenes.txt", contains: []string{"stack: menu > level\n", "Camera [00000001]"}},
+		{file: "instances.txt", contains: []string{"     1 *scene.Camera\n     2 *scene.GameObject\n"}},
+		{file: "config.json", contains: []string{`"headless": true`}},
+	}
+
+	for i, v := range tests {
+		data, err := ioutil.ReadFile(filepath.Join(dir, v.file))
+		if err != nil {
+			t.Errorf("Test %d: got: %v want: nil", i, err)
+			continue
+		}
+
+		for _, s := range v.contains {
+			if !strings.Contains(string(data), s) {
+				t.Errorf("Test %d: got: %q want: containing %q", i, data, s)
+			}
+		}
+	}
+}
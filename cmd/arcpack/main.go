@@ -0,0 +1,409 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Command arcpack builds, inspects and verifies asset packages.
+//
+// Usage:
+//
+//	arcpack build [-o file.pkg] [-k key] [-m manifest.json]... dir
+//	arcpack list [-l] file.pkg
+//	arcpack extract [-o dir] file.pkg [file]...
+//	arcpack verify [-k key.pub]... file.pkg...
+//	arcpack keygen [-o name]
+//	arcpack meta file...
+//
+// Packages are built from every file in a directory. Each manifest given with
+// -m is checked so that the assets it references are in the package, and the
+// package includes a content hash index which is checked by verify. Given a
+// private key with -k, the index is signed, and verify checks the signature
+// against the public keys given with -k. Keys are created by keygen. The meta
+// command creates the .meta sidecar files of assets which have none, each
+// with a new GUID.
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/haakenlabs/arc/core"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(fset *flag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{"build", "build [-o file.pkg] [-k key] [-m manifest.json]... dir", build},
+	{"list", "list [-l] file.pkg", list},
+	{"extract", "extract [-o dir] file.pkg [file]...", extract},
+	{"verify", "verify [-k key.pub]... file.pkg...", verify},
+	{"keygen", "keygen [-o name]", keygen},
+	{"meta", "meta file...", meta},
+}
+
+// stringsFlag is a flag which may be given more than once.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	logrus.SetLevel(logrus.WarnLevel)
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(newFlagSet(c), os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, "arcpack:", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	usage()
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage:")
+	for _, c := range commands {
+		fmt.Fprintln(os.Stderr, "\tarcpack", c.usage)
+	}
+	os.Exit(2)
+}
+
+// newFlagSet creates the flag set of a command.
+func newFlagSet(c command) *flag.FlagSet {
+	fset := flag.NewFlagSet(c.name, flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: arcpack", c.usage)
+		fset.PrintDefaults()
+	}
+
+	return fset
+}
+
+func build(fset *flag.FlagSet, args []string) error {
+	var manifests stringsFlag
+
+	out := fset.String("o", "", "package file to write (default dir.pkg)")
+	keyFile := fset.String("k", "", "private key file to sign the package with")
+	fset.Var(&manifests, "m", "manifest to check, relative to dir")
+	fset.Parse(args)
+
+	if fset.NArg() != 1 {
+		fset.Usage()
+		os.Exit(2)
+	}
+
+	var key ed25519.PrivateKey
+	if *keyFile != "" {
+		data, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return err
+		}
+		if key, err = core.ParsePrivateKey(string(data)); err != nil {
+			return fmt.Errorf("%s: %v", *keyFile, err)
+		}
+	}
+
+	dir := fset.Arg(0)
+	if *out == "" {
+		*out = filepath.Clean(dir) + ".pkg"
+	}
+
+	// Write to a temporary file so that a failed build does not leave a
+	// partial package behind.
+	f, err := os.CreateTemp(filepath.Dir(*out), ".arcpack-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	index, err := core.BuildSignedPackage(f, os.DirFS(dir), key, manifests...)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(f.Name(), *out); err != nil {
+		return err
+	}
+
+	var size int64
+	for _, e := range index.Files {
+		size += e.Size
+	}
+
+	fmt.Printf("%s: %d files, %d bytes\n", *out, len(index.Files), size)
+
+	return nil
+}
+
+func list(fset *flag.FlagSet, args []string) error {
+	long := fset.Bool("l", false, "show the size and content hash of each file")
+	fset.Parse(args)
+
+	if fset.NArg() != 1 {
+		fset.Usage()
+		os.Exit(2)
+	}
+
+	p, err := mountPackage(fset.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer p.Unmount()
+
+	index, err := p.Index()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	for _, name := range p.Files() {
+		if !*long {
+			fmt.Println(name)
+			continue
+		}
+
+		info, err := p.Stat(name)
+		if err != nil {
+			return err
+		}
+
+		hash := "-"
+		if index != nil {
+			if e, ok := index.Files[name]; ok {
+				hash = e.SHA256
+			}
+		}
+
+		fmt.Printf("%10d  %-64s  %s\n", info.Size(), hash, name)
+	}
+
+	return nil
+}
+
+func extract(fset *flag.FlagSet, args []string) error {
+	out := fset.String("o", ".", "directory to extract to")
+	fset.Parse(args)
+
+	if fset.NArg() < 1 {
+		fset.Usage()
+		os.Exit(2)
+	}
+
+	p, err := mountPackage(fset.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer p.Unmount()
+
+	files := fset.Args()[1:]
+	if len(files) == 0 {
+		files = p.Files()
+	}
+
+	for _, name := range files {
+		data, err := p.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		dst := filepath.Join(*out, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func verify(fset *flag.FlagSet, args []string) error {
+	var keyFiles stringsFlag
+
+	fset.Var(&keyFiles, "k", "public key file the packages must be signed with")
+	fset.Parse(args)
+
+	if fset.NArg() < 1 {
+		fset.Usage()
+		os.Exit(2)
+	}
+
+	var keys []ed25519.PublicKey
+	for _, file := range keyFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		key, err := core.ParsePublicKey(string(data))
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		keys = append(keys, key)
+	}
+
+	failed := 0
+
+	for _, file := range fset.Args() {
+		p, err := mountPackage(file)
+		if err == nil {
+			err = p.Verify()
+			if err == nil && len(keys) != 0 {
+				_, err = p.VerifySignature(keys...)
+			}
+			p.Unmount()
+		}
+
+		if err != nil {
+			fmt.Printf("%s: %v\n", file, err)
+			failed++
+			continue
+		}
+
+		fmt.Printf("%s: ok\n", file)
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("%d of %d packages failed verification", failed, fset.NArg())
+	}
+
+	return nil
+}
+
+func keygen(fset *flag.FlagSet, args []string) error {
+	out := fset.String("o", "arcpack", "name of the key files to write")
+	fset.Parse(args)
+
+	if fset.NArg() != 0 {
+		fset.Usage()
+		os.Exit(2)
+	}
+
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	// The private key is written first, and never overwritten, so that a key
+	// which signed released packages is not lost.
+	f, err := os.OpenFile(*out+".key", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, core.EncodeKey(key)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(*out+".pub", []byte(core.EncodeKey(pub)+"\n"), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s.key: private key\n%s.pub: %s\n", *out, *out, core.EncodeKey(pub))
+
+	return nil
+}
+
+func meta(fset *flag.FlagSet, args []string) error {
+	fset.Parse(args)
+
+	if fset.NArg() < 1 {
+		fset.Usage()
+		os.Exit(2)
+	}
+
+	for _, file := range fset.Args() {
+		if _, err := os.Stat(file); err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(core.AssetMeta{GUID: core.NewGUID()}, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(file+core.AssetMetaExtension, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			fmt.Printf("%s%s: exists\n", file, core.AssetMetaExtension)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = f.Write(append(data, '\n'))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s%s: created\n", file, core.AssetMetaExtension)
+	}
+
+	return nil
+}
+
+// mountPackage mounts a package file, named after the file.
+func mountPackage(file string) (*core.Package, error) {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+
+	p := core.NewPackageFile(name, file)
+	if err := p.Mount(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -23,16 +23,11 @@ SOFTWARE.
 package core
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
-	"os"
-	"path"
+	"io/fs"
+	"sort"
 	"sync"
 
 	"github.com/sirupsen/logrus"
-
-	"github.com/haakenlabs/arc/internal/builtin"
 )
 
 var assetInst *AssetSystem
@@ -60,6 +55,21 @@ func (e ErrAssetType) Error() string {
 	return "asset: type assertion error for asset: " + string(e)
 }
 
+// ErrAssetNotAcquired reports that an asset was released more times than it
+// was acquired.
+type ErrAssetNotAcquired string
+
+func (e ErrAssetNotAcquired) Error() string {
+	return "asset: asset not acquired: " + string(e)
+}
+
+// ErrManifestNotLoaded reports that a manifest is not loaded.
+type ErrManifestNotLoaded string
+
+func (e ErrManifestNotLoaded) Error() string {
+	return "asset: manifest not loaded: " + string(e)
+}
+
 // ErrAssetNotFound reports that the handler is not registered.
 type ErrHandlerNotFound string
 
@@ -69,7 +79,9 @@ func (e ErrHandlerNotFound) Error() string {
 
 type AssetHandler interface {
 	// Load will allocate the asset. If OpenGL is required for allocation, this
-	// function should not be called from a goroutine.
+	// function should not be called from a goroutine. Handlers may decode on
+	// the job system's workers and allocate from a Future's Then callback,
+	// which runs on the main thread.
 	Load(*Resource) error
 
 	// GetAsset gets an asset by name.
@@ -83,20 +95,71 @@ type AssetHandler interface {
 
 	// Count returns the number of assets tracked by this handler.
 	Count() int
+
+	// Names returns the names of the assets tracked by this handler.
+	Names() []string
+
+	// Acquire gets an asset by name and adds a reference to it.
+	Acquire(string) (Object, error)
+
+	// Release removes a reference to an asset, and removes the asset once it
+	// has no references left. It reports whether the asset was removed.
+	Release(string) (bool, error)
+
+	// Refs returns the number of references to an asset.
+	Refs(string) int
+
+	// Remove removes an asset and deallocates it, regardless of its
+	// references.
+	Remove(string) error
+
+	// Unload removes and deallocates all assets tracked by this handler.
+	Unload()
 }
 
 var _ System = &AssetSystem{}
+var _ SystemDependent = &AssetSystem{}
 
 type AssetSystem struct {
-	handlers map[string]AssetHandler
-	packages map[string]*Package
-	mu       *sync.RWMutex
-}
-
+	handlers   map[string]AssetHandler
+	packages   map[string]mountedPackage
+	manifests  map[string]*loadedManifest
+	fs         *FS
+	watcher    *FileWatcher
+	sources    map[string][]assetSource
+	builtinDir string
+	verifier   *PackageVerifier
+	graph      *assetGraph
+	mu         *sync.RWMutex
+}
+
+// loadedManifest records the assets loaded by a manifest and the manifests it
+// includes, so that they can be released when the manifest is unloaded.
+type loadedManifest struct {
+	name     string
+	location string
+	refs     int
+	assets   []AssetRef
+	includes []string
+}
+
+// mountedPackage is a package and the mount point it is mounted at.
+type mountedPackage struct {
+	pkg   *Package
+	point string
+}
+
+// AssetManifest lists assets by kind. Included manifests are loaded before
+// the assets of the manifest, and Depends maps the file of an asset to the
+// files of the assets it depends on, which are loaded before it. Files are
+// relative to the manifest, unless given by their full path in a mounted
+// filesystem or the builtin assets.
 type AssetManifest struct {
 	Name        string              `json:"name"`
 	Description string              `json:"description"`
+	Includes    []string            `json:"includes"`
 	Assets      map[string][]string `json:"assets,required"`
+	Depends     map[string][]string `json:"depends"`
 }
 
 type AssetMetadata struct {
@@ -108,6 +171,8 @@ type AssetMetadata struct {
 type BaseAssetHandler struct {
 	Items map[string]int32
 	Mu    *sync.RWMutex
+
+	refs map[string]int
 }
 
 // Setup sets up the System.
@@ -122,6 +187,7 @@ func (a *AssetSystem) Setup() error {
 
 // Teardown tears down the System.
 func (a *AssetSystem) Teardown() {
+	a.DisableHotReload()
 	a.ReleaseAll()
 	a.UnmountAllPackages()
 }
@@ -131,52 +197,126 @@ func (a *AssetSystem) Name() string {
 	return SysNameAsset
 }
 
-// MountPackage mounts a new package by name.
+// Dependencies returns the names of the systems this System depends on.
+func (a *AssetSystem) Dependencies() []string {
+	return []string{SysNameWindow, SysNameInstance}
+}
+
+// MountPackage mounts a new package by name, from the assets directory. The
+// package is mounted at a mount point of the same name.
 func (a *AssetSystem) MountPackage(name string) error {
+	return a.MountPackageAt(NewPackage(name), name, 0)
+}
+
+// MountPackageFile mounts the package file at the given path by name, at a
+// mount point with the given priority.
+func (a *AssetSystem) MountPackageFile(name, file, point string, priority int) error {
+	return a.MountPackageAt(NewPackageFile(name, file), point, priority)
+}
+
+// MountPackageAt mounts a package at a mount point with the given priority.
+// Several packages may be mounted at the same mount point, where packages
+// with a higher priority, or mounted later with the same priority, override
+// the files of the others. Packages without a verifier are verified with the
+// package verifier of the asset system.
+func (a *AssetSystem) MountPackageAt(p *Package, point string, priority int) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	name := p.Name()
 	if _, dup := a.packages[name]; dup {
 		return ErrPackageMounted(name)
 	}
 
-	p := NewPackage(name)
+	if a.verifier != nil {
+		p.useVerifier(a.verifier)
+	}
+
 	if err := p.Mount(); err != nil {
 		return err
 	}
 
-	a.packages[name] = p
+	if err := a.fs.Mount(point, name, p, priority); err != nil {
+		p.Unmount()
+		return err
+	}
+
+	a.packages[name] = mountedPackage{pkg: p, point: point}
+
+	enqueueEvent(EventPackageMount{Name: name})
 
 	return nil
 }
 
+// SetPackageVerifier sets how packages mounted from now on are verified.
+func (a *AssetSystem) SetPackageVerifier(v *PackageVerifier) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.verifier = v
+}
+
 // UnmountPackage unmounts a mounted package given by name.
 func (a *AssetSystem) UnmountPackage(name string) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	if _, ok := a.packages[name]; !ok {
+	m, ok := a.packages[name]
+	if !ok {
 		return ErrPackageNotMounted(name)
 	}
 
-	if err := a.packages[name].Unmount(); err != nil {
+	if err := a.fs.Unmount(m.point, name); err != nil {
 		return err
 	}
 
 	delete(a.packages, name)
 
+	if err := m.pkg.Unmount(); err != nil {
+		return err
+	}
+
+	enqueueEvent(EventPackageUnmount{Name: name})
+
 	return nil
 }
 
 // UnmountAllPackages unmounts all mounted packages.
 func (a *AssetSystem) UnmountAllPackages() {
-	for p := range a.packages {
+	for _, p := range a.Packages() {
 		if err := a.UnmountPackage(p); err != nil {
 			logrus.Error(err)
 		}
 	}
 }
 
+// Packages returns the names of the mounted packages, sorted.
+func (a *AssetSystem) Packages() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	names := make([]string, 0, len(a.packages))
+	for name := range a.packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Package gets a mounted package by name.
+func (a *AssetSystem) Package(name string) (*Package, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	m, ok := a.packages[name]
+	if !ok {
+		return nil, ErrPackageNotMounted(name)
+	}
+
+	return m.pkg, nil
+}
+
 // Get gets an asset by name from a handler by kind.
 func (a *AssetSystem) Get(kind, name string) (Object, error) {
 	return a.GetAsset(kind, name)
@@ -191,96 +331,258 @@ func (a *AssetSystem) MustGet(kind, name string) Object {
 	}
 }
 
-// LoadManifest loads a manifest of assets.
+// LoadManifest loads manifests of assets and the manifests they include.
+// Assets are loaded after the assets they depend on, and an asset which
+// depends on another holds a reference to it. Each asset loaded by a manifest
+// is acquired, and an asset which has already been loaded is shared rather
+// than loaded again. Loading a manifest which is already loaded only adds a
+// reference to it, which must be released by UnloadManifest. If an asset fails
+// to load, the manifests are unloaded again and the error is returned.
 func (a *AssetSystem) LoadManifest(files ...string) error {
-	for _, v := range files {
-		m := NewAssetManifest()
+	plan, err := a.planLoad(files)
+	if err != nil {
+		return err
+	}
+
+	for _, err := range plan.errs {
+		logrus.Error("Error reading manifest: ", err)
+	}
+
+	events := a.commitPlan(plan)
 
-		r, err := NewResource(v)
+	if err := a.loadPlanned(plan); err != nil {
+		// The manifests were committed before their assets loaded, so undo
+		// them to let a retry start over.
+		if uerr := a.UnloadManifest(files...); uerr != nil {
+			logrus.Error("Error unloading manifest: ", uerr)
+		}
+		return err
+	}
+
+	for _, e := range events {
+		publishEvent(e)
+	}
+
+	return nil
+}
+
+// loadPlanned loads the assets of a committed plan.
+func (a *AssetSystem) loadPlanned(plan *loadPlan) error {
+	for _, pa := range plan.assets {
+		h, err := a.GetHandler(pa.kind)
 		if err != nil {
-			logrus.Error("Error reading manifest: ", err)
+			logrus.Error(err)
 			continue
 		}
-		if err := a.ReadResource(r); err != nil {
-			logrus.Error("Error reading manifest: ", err)
-			continue
+
+		ar, err := NewResource(pa.file)
+		if err != nil {
+			return err
 		}
 
-		if err := json.Unmarshal(r.Bytes(), m); err != nil {
+		if err := a.ReadResource(ar); err != nil {
 			return err
 		}
 
-		// Load assets.
-		for t := range m.Assets {
-			h, err := a.GetHandler(t)
-			if err != nil {
-				logrus.Error(err)
-				continue
-			}
+		logrus.Debug("Read asset: ", pa.file)
+
+		names, err := loadAsset(h, a.locatedNames(pa.file, pa.kind), func() error {
+			return h.Load(ar)
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, m := range pa.manifests {
+			a.retainAssets(m, h, names)
+		}
+		a.linkAsset(pa, h, names, ar)
+		a.watchAssets(h, names, ar)
 
-			logrus.Debug(">>>>>>>> Loading asset type: ", t)
+		logrus.Debug("Loaded asset: ", pa.file)
 
-			// Read and load assets.
-			for n := range m.Assets[t] {
-				ar, err := NewResource(path.Join(r.DirPrefix(), m.Assets[t][n]))
-				if err != nil {
-					return err
-				}
+		publishEvent(EventAssetLoad{Kind: pa.kind, Location: ar.Location()})
+	}
 
-				if err := a.ReadResource(ar); err != nil {
-					return err
-				}
+	return nil
+}
 
-				logrus.Debug("Read asset: ", m.Assets[t][n])
+// UnloadManifest reverses a LoadManifest. Once a manifest has been unloaded as
+// many times as it was loaded, the references to its assets are released, the
+// manifests it includes are unloaded, and assets which are no longer
+// referenced are deallocated.
+func (a *AssetSystem) UnloadManifest(files ...string) error {
+	var err error
 
-				if err := h.Load(ar); err != nil {
-					return err
-				}
+	for _, v := range files {
+		v = manifestKey(v)
+
+		a.mu.Lock()
+		m, ok := a.manifests[v]
+		release := false
+		if ok {
+			m.refs--
+			if m.refs <= 0 {
+				delete(a.manifests, v)
+				release = true
+			}
+		}
+		a.mu.Unlock()
+
+		if !ok {
+			if err == nil {
+				err = ErrManifestNotLoaded(v)
+			}
+			continue
+		}
+		if !release {
+			continue
+		}
 
-				logrus.Debug("Loaded asset: ", m.Assets[t][n])
+		for i := len(m.assets) - 1; i >= 0; i-- {
+			if rerr := a.Release(m.assets[i].Kind, m.assets[i].Name); rerr != nil {
+				logrus.Error("Error unloading manifest: ", rerr)
+			}
+		}
+		for i := len(m.includes) - 1; i >= 0; i-- {
+			if rerr := a.UnloadManifest(m.includes[i]); rerr != nil {
+				logrus.Error("Error unloading manifest: ", rerr)
 			}
 		}
+
+		publishEvent(EventManifestUnload{Name: m.name, Location: m.location})
 	}
 
-	return nil
+	return err
 }
 
-func (a *AssetSystem) ReadResource(r *Resource) error {
-	if r == nil {
-		return nil
+// ManifestLoaded reports if a manifest is loaded.
+func (a *AssetSystem) ManifestLoaded(file string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	_, ok := a.manifests[manifestKey(file)]
+
+	return ok
+}
+
+// retainManifest adds a reference to a manifest if it is already loaded.
+func (a *AssetSystem) retainManifest(file string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	m, ok := a.manifests[file]
+	if ok {
+		m.refs++
 	}
 
-	switch r.resType {
-	case ResourceFile:
-		f, err := os.Open(r.location)
-		if err != nil {
-			return err
+	return ok
+}
+
+// addManifest starts recording the assets loaded by a manifest.
+func (a *AssetSystem) addManifest(file string, m *AssetManifest, r *Resource, includes []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.manifests[file] = &loadedManifest{
+		name:     m.Name,
+		location: r.Location(),
+		refs:     1,
+		includes: includes,
+	}
+}
+
+// retainAssets acquires assets loaded by a manifest, and records them so they
+// can be released when the manifest is unloaded. Assets loaded after their
+// manifest was unloaded are not acquired.
+func (a *AssetSystem) retainAssets(file string, h AssetHandler, names []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	m, ok := a.manifests[file]
+	if !ok {
+		return
+	}
+
+	for _, name := range names {
+		if _, err := h.Acquire(name); err != nil {
+			logrus.Error(err)
+			continue
 		}
-		defer f.Close()
 
-		_, err = io.Copy(r.buffer, f)
+		m.assets = append(m.assets, AssetRef{Kind: h.Name(), Name: name})
+	}
+}
 
-		return err
-	case ResourcePackage:
-		p, ok := a.packages[r.container]
-		if !ok {
-			return ErrPackageNotMounted(r.container)
+// loadAsset calls load and returns the names of the assets it added to the
+// handler. If the asset already exists, the names of the assets located from
+// the same file, or else its name, are returned so that they can be shared.
+func loadAsset(h AssetHandler, located []string, load func() error) ([]string, error) {
+	before := make(map[string]bool)
+	for _, name := range h.Names() {
+		before[name] = true
+	}
+
+	if err := load(); err != nil {
+		if name, ok := err.(ErrAssetExists); ok {
+			if len(located) != 0 {
+				return located, nil
+			}
+			return []string{string(name)}, nil
 		}
 
-		return p.Read(r.location, r.buffer)
-	case ResourceBindata:
-		data, err := builtin.Asset(r.location)
-		if err != nil {
-			panic(err)
-			return err
+		return nil, err
+	}
+
+	var added []string
+	for _, name := range h.Names() {
+		if !before[name] {
+			added = append(added, name)
 		}
+	}
+
+	return added, nil
+}
 
-		_, err = r.buffer.Write(data)
+// ReadResource reads the contents of a resource, and its sidecar file if it
+// has one, from the virtual filesystem.
+func (a *AssetSystem) ReadResource(r *Resource) error {
+	if r == nil {
+		return nil
+	}
+
+	data, err := a.fs.ReadFile(r.FSPath())
+	if err != nil {
+		return err
+	}
 
+	meta, err := a.readMeta(r)
+	if err != nil {
 		return err
-	default:
-		return fmt.Errorf("resource: unknown resource type for resource: %d", int(r.resType))
 	}
+	r.meta = meta
+
+	_, err = r.buffer.Write(data)
+
+	return err
+}
+
+// FS returns the virtual filesystem which resources are read from.
+func (a *AssetSystem) FS() *FS {
+	return a.fs
+}
+
+// Mount mounts a filesystem as a layer named name at a mount point of the
+// virtual filesystem. Layers with a higher priority shadow the files of lower
+// layers.
+func (a *AssetSystem) Mount(point, name string, fsys fs.FS, priority int) error {
+	return a.fs.Mount(point, name, fsys, priority)
+}
+
+// Unmount removes the layer named name from a mount point of the virtual
+// filesystem.
+func (a *AssetSystem) Unmount(point, name string) error {
+	return a.fs.Unmount(point, name)
 }
 
 // Register registers an asset handler.
@@ -351,9 +653,67 @@ func (a *AssetSystem) MustGetAsset(kind, name string) Object {
 	return asset
 }
 
-// ReleaseAll releases all builtin managed by this asset store.
+// Acquire gets an asset by name from a handler by kind, and adds a reference
+// to it. Each call should be paired with a call to Release.
+func (a *AssetSystem) Acquire(kind, name string) (Object, error) {
+	h, err := a.GetHandler(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.Acquire(name)
+}
+
+// Release removes a reference to an asset, and deallocates it if it is no
+// longer referenced. Deallocating an asset releases the assets it depends on.
+func (a *AssetSystem) Release(kind, name string) error {
+	h, err := a.GetHandler(kind)
+	if err != nil {
+		return err
+	}
+
+	removed, err := h.Release(name)
+	if err != nil {
+		return err
+	}
+
+	if removed {
+		a.mu.Lock()
+		a.unwatchAsset(kind, name)
+		deps := a.graph.remove(AssetRef{Kind: kind, Name: name})
+		a.mu.Unlock()
+
+		publishEvent(EventAssetUnload{Kind: kind, Name: name})
+
+		for _, dep := range deps {
+			if err := a.Release(dep.Kind, dep.Name); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ReleaseAll releases all assets managed by this asset store, regardless of
+// their references, and forgets all loaded manifests.
 func (a *AssetSystem) ReleaseAll() {
+	a.mu.Lock()
+	handlers := make([]AssetHandler, 0, len(a.handlers))
+	for _, h := range a.handlers {
+		handlers = append(handlers, h)
+	}
+	a.manifests = make(map[string]*loadedManifest)
+	a.graph = newAssetGraph()
+	for file := range a.sources {
+		delete(a.sources, file)
+		a.watcher.Remove(file)
+	}
+	a.mu.Unlock()
 
+	for _, h := range handlers {
+		h.Unload()
+	}
 }
 
 // Count reports the total number of assets managed by this asset store.
@@ -420,14 +780,131 @@ func (h *BaseAssetHandler) Count() int {
 	return len(h.Items)
 }
 
+// Names returns the names of the assets tracked by this handler, sorted.
+func (h *BaseAssetHandler) Names() []string {
+	h.Mu.RLock()
+	defer h.Mu.RUnlock()
+
+	names := make([]string, 0, len(h.Items))
+	for name := range h.Items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Acquire gets an asset by name and adds a reference to it.
+func (h *BaseAssetHandler) Acquire(name string) (Object, error) {
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+
+	id, ok := h.Items[name]
+	if !ok {
+		return nil, ErrAssetNotFound(name)
+	}
+
+	obj, err := GetInstanceSystem().Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.refs == nil {
+		h.refs = make(map[string]int)
+	}
+	h.refs[name]++
+
+	return obj, nil
+}
+
+// Release removes a reference to an asset, and removes the asset once it has
+// no references left. It reports whether the asset was removed.
+func (h *BaseAssetHandler) Release(name string) (bool, error) {
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+
+	if _, ok := h.Items[name]; !ok {
+		return false, ErrAssetNotFound(name)
+	}
+	if h.refs[name] <= 0 {
+		return false, ErrAssetNotAcquired(name)
+	}
+
+	h.refs[name]--
+	if h.refs[name] > 0 {
+		return false, nil
+	}
+
+	h.remove(name)
+
+	return true, nil
+}
+
+// Refs returns the number of references to an asset.
+func (h *BaseAssetHandler) Refs(name string) int {
+	h.Mu.RLock()
+	defer h.Mu.RUnlock()
+
+	return h.refs[name]
+}
+
+// Remove removes an asset and deallocates it through the instance system,
+// regardless of its references.
+func (h *BaseAssetHandler) Remove(name string) error {
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+
+	if _, ok := h.Items[name]; !ok {
+		return ErrAssetNotFound(name)
+	}
+
+	h.remove(name)
+
+	return nil
+}
+
+// Unload removes and deallocates all assets tracked by this handler.
+func (h *BaseAssetHandler) Unload() {
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+
+	for name := range h.Items {
+		h.remove(name)
+	}
+}
+
+// remove forgets an asset and releases its instance. The lock must be held.
+func (h *BaseAssetHandler) remove(name string) {
+	id := h.Items[name]
+
+	delete(h.Items, name)
+	delete(h.refs, name)
+
+	if i := GetInstanceSystem(); i != nil {
+		i.Release(id)
+	}
+}
+
 func NewAssetSystem() *AssetSystem {
 	return &AssetSystem{
-		handlers: make(map[string]AssetHandler),
-		packages: make(map[string]*Package),
-		mu:       &sync.RWMutex{},
+		handlers:  make(map[string]AssetHandler),
+		packages:  make(map[string]mountedPackage),
+		manifests: make(map[string]*loadedManifest),
+		fs:        newAssetFS(),
+		graph:     newAssetGraph(),
+		mu:        &sync.RWMutex{},
 	}
 }
 
+// newAssetFS creates the virtual filesystem with the host filesystem and the
+// builtin assets mounted.
+func newAssetFS() *FS {
+	f := NewFS()
+	f.Mount(MountBuiltin, "builtin", BuiltinFS{}, 0)
+
+	return f
+}
+
 // GetAsset gets the asset system from the current app.
 func GetAssetSystem() *AssetSystem {
 	return assetInst
@@ -0,0 +1,558 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AssetRef identifies a loaded asset by kind and name.
+type AssetRef struct {
+	Kind string
+	Name string
+}
+
+func (r AssetRef) String() string {
+	return r.Kind + ":" + r.Name
+}
+
+// ErrManifestCycle reports that manifests include each other. It lists the
+// manifests of the cycle, starting and ending with the same manifest.
+type ErrManifestCycle []string
+
+func (e ErrManifestCycle) Error() string {
+	return "asset: manifest include cycle: " + strings.Join(e, " -> ")
+}
+
+// ErrAssetCycle reports that assets depend on each other. It lists the assets
+// of the cycle, starting and ending with the same asset.
+type ErrAssetCycle []string
+
+func (e ErrAssetCycle) Error() string {
+	return "asset: dependency cycle: " + strings.Join(e, " -> ")
+}
+
+// ErrAssetDependency reports that a dependency of an asset is neither loaded
+// nor listed in the manifests being loaded.
+type ErrAssetDependency struct {
+	Asset      string
+	Dependency string
+}
+
+func (e ErrAssetDependency) Error() string {
+	return fmt.Sprintf("asset: %s depends on %s, which is not in a manifest", e.Asset, e.Dependency)
+}
+
+// assetGraph records the files assets were loaded from, their GUIDs, and the
+// dependencies between loaded assets. Each dependency holds a reference to
+// the asset it depends on, which is released when the dependent asset is
+// removed.
+type assetGraph struct {
+	located    map[string][]AssetRef
+	origin     map[AssetRef]string
+	guids      map[string]AssetRef
+	depends    map[AssetRef][]AssetRef
+	dependents map[AssetRef][]AssetRef
+}
+
+func newAssetGraph() *assetGraph {
+	return &assetGraph{
+		located:    make(map[string][]AssetRef),
+		origin:     make(map[AssetRef]string),
+		guids:      make(map[string]AssetRef),
+		depends:    make(map[AssetRef][]AssetRef),
+		dependents: make(map[AssetRef][]AssetRef),
+	}
+}
+
+// locate records that an asset was loaded from file, with the GUID of its
+// sidecar file if it has one.
+func (g *assetGraph) locate(file string, ref AssetRef, guid string) {
+	if _, ok := g.origin[ref]; ok {
+		return
+	}
+
+	g.origin[ref] = file
+	g.located[file] = append(g.located[file], ref)
+
+	if guid == "" {
+		return
+	}
+	if other, dup := g.guids[guid]; dup && other != ref {
+		logrus.Warnf("asset: %s has the same guid as %s: %s", ref, other, guid)
+		return
+	}
+	g.guids[guid] = ref
+}
+
+// link records that an asset depends on another, and reports if the
+// dependency is new.
+func (g *assetGraph) link(ref, dep AssetRef) bool {
+	for _, v := range g.depends[ref] {
+		if v == dep {
+			return false
+		}
+	}
+
+	g.depends[ref] = append(g.depends[ref], dep)
+	g.dependents[dep] = append(g.dependents[dep], ref)
+
+	return true
+}
+
+// remove forgets an asset, and returns the assets it depended on.
+func (g *assetGraph) remove(ref AssetRef) []AssetRef {
+	deps := g.depends[ref]
+	delete(g.depends, ref)
+
+	for _, dep := range deps {
+		g.dependents[dep] = withoutRef(g.dependents[dep], ref)
+		if len(g.dependents[dep]) == 0 {
+			delete(g.dependents, dep)
+		}
+	}
+	for _, v := range g.dependents[ref] {
+		g.depends[v] = withoutRef(g.depends[v], ref)
+	}
+	delete(g.dependents, ref)
+
+	for guid, v := range g.guids {
+		if v == ref {
+			delete(g.guids, guid)
+		}
+	}
+
+	if file, ok := g.origin[ref]; ok {
+		delete(g.origin, ref)
+
+		g.located[file] = withoutRef(g.located[file], ref)
+		if len(g.located[file]) == 0 {
+			delete(g.located, file)
+		}
+	}
+
+	return deps
+}
+
+// withoutRef returns refs without ref.
+func withoutRef(refs []AssetRef, ref AssetRef) []AssetRef {
+	out := refs[:0]
+	for _, v := range refs {
+		if v != ref {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
+
+// sortedRefs returns a sorted copy of refs.
+func sortedRefs(refs []AssetRef) []AssetRef {
+	out := append([]AssetRef(nil), refs...)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Name < out[j].Name
+	})
+
+	return out
+}
+
+// AssetDependencies returns the loaded assets which an asset depends on.
+func (a *AssetSystem) AssetDependencies(kind, name string) []AssetRef {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return sortedRefs(a.graph.depends[AssetRef{kind, name}])
+}
+
+// AssetDependents returns the loaded assets which depend on an asset. An
+// asset is not deallocated while assets depend on it.
+func (a *AssetSystem) AssetDependents(kind, name string) []AssetRef {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return sortedRefs(a.graph.dependents[AssetRef{kind, name}])
+}
+
+// LoadOrder returns the files of the assets which LoadManifest would load for
+// the given manifests, in the order they would be loaded.
+func (a *AssetSystem) LoadOrder(files ...string) ([]string, error) {
+	plan, err := a.planLoad(files)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make([]string, len(plan.assets))
+	for i := range plan.assets {
+		order[i] = plan.assets[i].file
+	}
+
+	return order, nil
+}
+
+// loadPlan is the manifests and assets of a load, with the assets in
+// dependency order.
+type loadPlan struct {
+	manifests []*plannedManifest
+	assets    []*plannedAsset
+	errs      []error
+}
+
+// plannedManifest is a manifest of a load. A manifest which is already loaded
+// is only retained, once for each time it is included.
+type plannedManifest struct {
+	file     string
+	manifest *AssetManifest
+	resource *Resource
+	includes []string
+	refs     int
+	loaded   bool
+}
+
+// plannedAsset is an asset of a load, the manifests which list it, and the
+// files of the assets it depends on.
+type plannedAsset struct {
+	kind      string
+	file      string
+	manifests []string
+	depends   []string
+	level     int
+}
+
+// waves groups the assets of a load so that the assets of each wave only
+// depend on assets of earlier waves.
+func (p *loadPlan) waves() [][]*plannedAsset {
+	var waves [][]*plannedAsset
+
+	for _, v := range p.assets {
+		for len(waves) <= v.level {
+			waves = append(waves, nil)
+		}
+		waves[v.level] = append(waves[v.level], v)
+	}
+
+	return waves
+}
+
+// planLoad reads manifests and the manifests they include, and orders their
+// assets so that each asset is loaded after the assets it depends on. Assets
+// are otherwise loaded in the order they are listed, by kind, with the assets
+// of included manifests first. Manifests which cannot be read are skipped and
+// reported by the plan, while include cycles, dependency cycles and missing
+// dependencies fail the plan.
+func (a *AssetSystem) planLoad(files []string) (*loadPlan, error) {
+	plan := &loadPlan{}
+	planned := make(map[string]*plannedManifest)
+	visiting := make(map[string]bool)
+
+	var stack []string
+	var visit func(file string) error
+	visit = func(file string) error {
+		if m, ok := planned[file]; ok {
+			m.refs++
+			return nil
+		}
+		if visiting[file] {
+			cycle := []string{file}
+			for i := len(stack) - 1; i >= 0 && stack[i] != file; i-- {
+				cycle = append([]string{stack[i]}, cycle...)
+			}
+			return ErrManifestCycle(append([]string{file}, cycle...))
+		}
+
+		m := &plannedManifest{file: file, refs: 1, loaded: a.ManifestLoaded(file)}
+		if !m.loaded {
+			if err := a.readManifest(m); err != nil {
+				plan.errs = append(plan.errs, err)
+				return nil
+			}
+		}
+
+		visiting[file] = true
+		stack = append(stack, file)
+		for _, inc := range m.includes {
+			if err := visit(inc); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		delete(visiting, file)
+
+		planned[file] = m
+		plan.manifests = append(plan.manifests, m)
+
+		return nil
+	}
+
+	for _, v := range files {
+		if err := visit(manifestKey(v)); err != nil {
+			return nil, err
+		}
+	}
+
+	assets := make(map[string]*plannedAsset)
+	var listed []*plannedAsset
+
+	for _, m := range plan.manifests {
+		if m.loaded {
+			continue
+		}
+
+		kinds := make([]string, 0, len(m.manifest.Assets))
+		for kind := range m.manifest.Assets {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		for _, kind := range kinds {
+			for _, v := range m.manifest.Assets[kind] {
+				file := resolveAssetPath(m.resource, v)
+
+				pa, ok := assets[file]
+				if !ok {
+					pa = &plannedAsset{kind: kind, file: file}
+					assets[file] = pa
+					listed = append(listed, pa)
+				}
+				pa.manifests = append(pa.manifests, m.file)
+			}
+		}
+
+		deps := make([]string, 0, len(m.manifest.Depends))
+		for v := range m.manifest.Depends {
+			deps = append(deps, v)
+		}
+		sort.Strings(deps)
+
+		for _, v := range deps {
+			file := resolveAssetPath(m.resource, v)
+
+			pa, ok := assets[file]
+			if !ok {
+				err := fmt.Errorf("dependencies given for %s, which is not an asset of a manifest", file)
+				return nil, ErrAssetLoad{Kind: "manifest", Location: m.file, Err: err}
+			}
+			for _, dep := range m.manifest.Depends[v] {
+				pa.depends = append(pa.depends, resolveAssetPath(m.resource, dep))
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int)
+	var trail []string
+
+	var order func(pa *plannedAsset) error
+	order = func(pa *plannedAsset) error {
+		switch state[pa.file] {
+		case done:
+			return nil
+		case inProgress:
+			cycle := []string{pa.file}
+			for i := len(trail) - 1; i >= 0 && trail[i] != pa.file; i-- {
+				cycle = append([]string{trail[i]}, cycle...)
+			}
+			return ErrAssetCycle(append([]string{pa.file}, cycle...))
+		}
+
+		state[pa.file] = inProgress
+		trail = append(trail, pa.file)
+
+		for _, file := range pa.depends {
+			dep, ok := assets[file]
+			if !ok {
+				if !a.assetLocated(file) {
+					return ErrAssetDependency{Asset: pa.file, Dependency: file}
+				}
+				continue
+			}
+
+			if err := order(dep); err != nil {
+				return err
+			}
+			if dep.level >= pa.level {
+				pa.level = dep.level + 1
+			}
+		}
+
+		trail = trail[:len(trail)-1]
+		state[pa.file] = done
+		plan.assets = append(plan.assets, pa)
+
+		return nil
+	}
+
+	for _, pa := range listed {
+		if err := order(pa); err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// readManifest reads a manifest of a load, and resolves its includes.
+func (a *AssetSystem) readManifest(m *plannedManifest) error {
+	r, err := NewResource(m.file)
+	if err == nil {
+		err = a.ReadResource(r)
+	}
+
+	manifest := NewAssetManifest()
+	if err == nil {
+		err = json.Unmarshal(r.Bytes(), manifest)
+	}
+	if err != nil {
+		return ErrAssetLoad{Kind: "manifest", Location: m.file, Err: err}
+	}
+
+	m.manifest = manifest
+	m.resource = r
+	for _, v := range manifest.Includes {
+		m.includes = append(m.includes, resolveAssetPath(r, v))
+	}
+
+	return nil
+}
+
+// commitPlan records the manifests of a load as loaded, and returns the
+// events of the manifests which were not already loaded.
+func (a *AssetSystem) commitPlan(plan *loadPlan) []EventManifestLoad {
+	var events []EventManifestLoad
+
+	for _, m := range plan.manifests {
+		refs := m.refs
+
+		if !m.loaded || !a.retainManifest(m.file) {
+			if m.manifest == nil {
+				logrus.Error(ErrManifestNotLoaded(m.file))
+				continue
+			}
+
+			a.addManifest(m.file, m.manifest, m.resource, m.includes)
+			events = append(events, EventManifestLoad{Name: m.manifest.Name, Location: m.resource.Location()})
+		}
+
+		for i := 1; i < refs; i++ {
+			a.retainManifest(m.file)
+		}
+	}
+
+	return events
+}
+
+// linkAsset records the assets loaded from the resource of a planned asset,
+// and the dependencies of the assets. Each new dependency acquires the asset
+// it depends on.
+func (a *AssetSystem) linkAsset(pa *plannedAsset, h AssetHandler, names []string, r *Resource) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var guid string
+	if meta := r.Meta(); meta != nil {
+		guid = meta.GUID
+	}
+
+	for i, name := range names {
+		ref := AssetRef{Kind: h.Name(), Name: name}
+		if i == 0 {
+			a.graph.locate(pa.file, ref, guid)
+		} else {
+			a.graph.locate(pa.file, ref, "")
+		}
+
+		for _, file := range pa.depends {
+			for _, dep := range a.graph.located[file] {
+				if !a.graph.link(ref, dep) {
+					continue
+				}
+
+				dh, ok := a.handlers[dep.Kind]
+				if !ok {
+					continue
+				}
+				if _, err := dh.Acquire(dep.Name); err != nil {
+					logrus.Error(err)
+				}
+			}
+		}
+	}
+}
+
+// assetLocated reports if an asset has been loaded from file.
+func (a *AssetSystem) assetLocated(file string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return len(a.graph.located[file]) != 0
+}
+
+// locatedNames returns the names of the assets of a kind which have been
+// loaded from file.
+func (a *AssetSystem) locatedNames(file, kind string) []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var names []string
+	for _, ref := range a.graph.located[file] {
+		if ref.Kind == kind {
+			names = append(names, ref.Name)
+		}
+	}
+
+	return names
+}
+
+// resolveAssetPath resolves a file referenced by the manifest read from r.
+// Files in a mounted filesystem or the builtin assets are given by their full
+// path, and other files are relative to the manifest.
+func resolveAssetPath(r *Resource, file string) string {
+	if !strings.HasPrefix(file, bindataPrefix) && !IsPackagePath(file) {
+		file = path.Join(r.DirPrefix(), file)
+	}
+
+	return manifestKey(file)
+}
+
+// manifestKey returns the path a file is loaded by, so that the same file is
+// always given by the same path.
+func manifestKey(file string) string {
+	r, err := NewResource(file)
+	if err != nil {
+		return file
+	}
+
+	return r.FSPath()
+}
@@ -0,0 +1,117 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAssetSystem_LoadOrder(t *testing.T) {
+	a := NewAssetSystem()
+
+	a.Mount("test", "mem", fstest.MapFS{
+		"base.json":  {Data: []byte(`{"assets": {"test": ["tex", "shader"]}}`)},
+		"mat.json":   {Data: []byte(`{"includes": ["base.json"], "assets": {"test": ["mat", "ui"]}, "depends": {"mat": ["tex", "shader"]}}`)},
+		"order.json": {Data: []byte(`{"assets": {"test": ["c", "b", "a"]}, "depends": {"c": ["b"], "b": ["a"]}}`)},
+		"cycle.json": {Data: []byte(`{"assets": {"test": ["a", "b"]}, "depends": {"a": ["b"], "b": ["a"]}}`)},
+		"inc1.json":  {Data: []byte(`{"includes": ["inc2.json"]}`)},
+		"inc2.json":  {Data: []byte(`{"includes": ["inc1.json"]}`)},
+		"dep.json":   {Data: []byte(`{"assets": {"test": ["a"]}, "depends": {"a": ["missing"]}}`)},
+		"font.json":  {Data: []byte(`{"assets": {"test": ["ui"]}, "depends": {"ui": ["<builtin>:fonts/font.ttf"]}}`)},
+	}, 0)
+
+	tests := []struct {
+		files []string
+		order []string
+		err   error
+	}{
+		{[]string{"test:mat.json"}, []string{"test:tex", "test:shader", "test:mat", "test:ui"}, nil},
+		{[]string{"test:order.json"}, []string{"test:a", "test:b", "test:c"}, nil},
+		{[]string{"test:mat.json", "test:base.json"}, []string{"test:tex", "test:shader", "test:mat", "test:ui"}, nil},
+		{[]string{"test:cycle.json"}, nil, ErrAssetCycle{"test:a", "test:b", "test:a"}},
+		{[]string{"test:inc1.json"}, nil, ErrManifestCycle{"test:inc1.json", "test:inc2.json", "test:inc1.json"}},
+		{[]string{"test:dep.json"}, nil, ErrAssetDependency{Asset: "test:a", Dependency: "test:missing"}},
+		{[]string{"test:font.json"}, nil, ErrAssetDependency{Asset: "test:ui", Dependency: "<builtin>:fonts/font.ttf"}},
+	}
+
+	for i, v := range tests {
+		order, err := a.LoadOrder(v.files...)
+		if !reflect.DeepEqual(order, v.order) || !reflect.DeepEqual(err, v.err) {
+			t.Errorf("Test %d: got: %v, %v want: %v, %v", i, order, err, v.order, v.err)
+		}
+	}
+}
+
+func TestAssetSystem_Dependents(t *testing.T) {
+	prev := instanceInst
+	instanceInst = NewInstanceSystem()
+	defer func() { instanceInst = prev }()
+
+	a := NewAssetSystem()
+	h := newTestAssetHandler()
+	a.RegisterHandler(h)
+
+	a.Mount("test", "mem", fstest.MapFS{
+		"base.json": {Data: []byte(`{"assets": {"test": ["tex"]}}`)},
+		"mat.json":  {Data: []byte(`{"includes": ["base.json"], "assets": {"test": ["mat"]}, "depends": {"mat": ["tex"]}}`)},
+		"tex":       {Data: []byte("tex")},
+		"mat":       {Data: []byte("mat")},
+	}, 0)
+
+	if err := a.LoadManifest("test:mat.json"); err != nil {
+		t.Fatal(err)
+	}
+	if !a.ManifestLoaded("test:base.json") {
+		t.Errorf("ManifestLoaded(base.json) got: false want: true")
+	}
+
+	mat := AssetRef{Kind: "test", Name: "mat"}
+	tex := AssetRef{Kind: "test", Name: "tex"}
+
+	if got := a.AssetDependents("test", "tex"); !reflect.DeepEqual(got, []AssetRef{mat}) {
+		t.Errorf("AssetDependents() got: %v want: %v", got, []AssetRef{mat})
+	}
+	if got := a.AssetDependencies("test", "mat"); !reflect.DeepEqual(got, []AssetRef{tex}) {
+		t.Errorf("AssetDependencies() got: %v want: %v", got, []AssetRef{tex})
+	}
+
+	// The texture is referenced by its manifest and by the material.
+	if n := h.Refs("tex"); n != 2 {
+		t.Errorf("Refs(tex) got: %d want: 2", n)
+	}
+
+	if err := a.UnloadManifest("test:mat.json"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(h.Names()); n != 0 {
+		t.Errorf("Names() got: %d want: 0", n)
+	}
+	if a.ManifestLoaded("test:base.json") {
+		t.Errorf("ManifestLoaded(base.json) got: true want: false")
+	}
+	if got := a.AssetDependents("test", "tex"); len(got) != 0 {
+		t.Errorf("AssetDependents() got: %v want: none", got)
+	}
+}
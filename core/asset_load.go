@@ -0,0 +1,349 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrLoadCancelled reports that an asynchronous load was cancelled.
+var ErrLoadCancelled = errors.New("asset: load cancelled")
+
+// AssetDecoder is implemented by asset handlers which can split loading into
+// a decode step, which may run on any goroutine, and an upload step, which
+// runs on the main thread. Handlers which do not implement it are loaded
+// entirely on the main thread by asynchronous loads, though their resources
+// are still read by workers.
+type AssetDecoder interface {
+	// Decode reads the resource and prepares the asset without using OpenGL.
+	// It may be called from any goroutine.
+	Decode(*Resource) (interface{}, error)
+
+	// Upload allocates an asset prepared by Decode. It is only called from
+	// the main thread.
+	Upload(*Resource, interface{}) error
+}
+
+// ErrAssetLoad reports that an asset could not be loaded.
+type ErrAssetLoad struct {
+	Kind     string
+	Location string
+	Err      error
+}
+
+func (e ErrAssetLoad) Error() string {
+	return "asset: loading " + e.Kind + " " + e.Location + ": " + e.Err.Error()
+}
+
+// ManifestLoad tracks the progress of an asynchronous manifest load. Its
+// methods are safe to call from any goroutine.
+type ManifestLoad struct {
+	total     int32
+	done      int32
+	bytes     int64
+	ready     int32
+	cancelled int32
+	errs      []error
+	manifests []EventManifestLoad
+	finished  chan struct{}
+	mu        *sync.Mutex
+}
+
+// decodedAsset is the result of reading and decoding an asset on a worker.
+type decodedAsset struct {
+	resource *Resource
+	handler  AssetHandler
+	value    interface{}
+}
+
+// LoadManifestAsync loads manifests of assets without blocking. Resources are
+// read and decoded by the job system's workers, and allocated on the main
+// thread while the main thread queue is drained. Assets are loaded in waves,
+// so that each asset is allocated after the assets it depends on, and assets
+// of the same wave are loaded in no particular order. Errors do not stop the
+// load, and are reported by the returned ManifestLoad. As with LoadManifest,
+// the loaded assets are acquired and can be released with UnloadManifest.
+func (a *AssetSystem) LoadManifestAsync(files ...string) *ManifestLoad {
+	l := &ManifestLoad{
+		finished: make(chan struct{}),
+		mu:       &sync.Mutex{},
+	}
+
+	jobs := GetJobSystem()
+	if jobs == nil {
+		l.addError(ErrSystemNotFound(SysNameJob))
+		atomic.StoreInt32(&l.ready, 1)
+		close(l.finished)
+		return l
+	}
+
+	// Producing jobs may block when the job queue is full, and waits for each
+	// wave to finish, so it must not be done by a worker or the main thread.
+	go a.produceLoad(l, jobs, files)
+
+	return l
+}
+
+// produceLoad plans the load and submits a job for each asset, one wave at a
+// time.
+func (a *AssetSystem) produceLoad(l *ManifestLoad, jobs *JobSystem, files []string) {
+	plan, err := a.planLoad(files)
+	if err != nil {
+		l.addError(err)
+		atomic.StoreInt32(&l.ready, 1)
+		jobs.queueMain(l.finish, l.abandon)
+		return
+	}
+
+	for _, err := range plan.errs {
+		l.addError(err)
+	}
+
+	events := a.commitPlan(plan)
+
+	l.mu.Lock()
+	l.manifests = events
+	l.mu.Unlock()
+
+	atomic.StoreInt32(&l.total, int32(len(plan.assets)))
+	atomic.StoreInt32(&l.ready, 1)
+
+	if len(plan.assets) == 0 {
+		jobs.queueMain(l.finish, l.abandon)
+		return
+	}
+
+	for _, wave := range plan.waves() {
+		wg := &sync.WaitGroup{}
+		wg.Add(len(wave))
+
+		for i := range wave {
+			pa := wave[i]
+
+			jobs.Go(func() (interface{}, error) {
+				if l.Cancelled() {
+					return nil, ErrLoadCancelled
+				}
+
+				h, err := a.GetHandler(pa.kind)
+				if err != nil {
+					return nil, err
+				}
+
+				r, err := NewResource(pa.file)
+				if err != nil {
+					return nil, err
+				}
+				if err := a.ReadResource(r); err != nil {
+					return nil, err
+				}
+				atomic.AddInt64(&l.bytes, int64(r.Size()))
+
+				d := decodedAsset{resource: r, handler: h}
+				if decoder, ok := h.(AssetDecoder); ok {
+					if d.value, err = decoder.Decode(r); err != nil {
+						return nil, err
+					}
+				}
+
+				return d, nil
+			}).Then(func(v interface{}, err error) {
+				defer l.itemDone()
+				defer wg.Done()
+
+				if err == ErrJobSystemClosed {
+					// The app is shutting down, so the rest of the load is
+					// abandoned.
+					l.Cancel()
+				}
+				if err == nil && l.Cancelled() {
+					err = ErrLoadCancelled
+				}
+				if err == ErrLoadCancelled || err == ErrJobSystemClosed {
+					return
+				}
+				var names []string
+				var d decodedAsset
+				if err == nil {
+					d = v.(decodedAsset)
+					names, err = loadAsset(d.handler, a.locatedNames(pa.file, pa.kind), func() error {
+						if decoder, ok := d.handler.(AssetDecoder); ok {
+							return decoder.Upload(d.resource, d.value)
+						}
+
+						return d.handler.Load(d.resource)
+					})
+				}
+				if err != nil {
+					l.addError(ErrAssetLoad{Kind: pa.kind, Location: pa.file, Err: err})
+					return
+				}
+
+				for _, m := range pa.manifests {
+					a.retainAssets(m, d.handler, names)
+				}
+				a.linkAsset(pa, d.handler, names, d.resource)
+				a.watchAssets(d.handler, names, d.resource)
+
+				logrus.Debug("Loaded asset: ", pa.file)
+
+				publishEvent(EventAssetLoad{Kind: pa.kind, Location: d.resource.Location()})
+			})
+		}
+
+		wg.Wait()
+	}
+}
+
+// Progress returns the number of assets which have finished loading, and the
+// total number of assets. The total is zero until the manifests have been
+// read.
+func (l *ManifestLoad) Progress() (done, total int) {
+	return int(atomic.LoadInt32(&l.done)), int(atomic.LoadInt32(&l.total))
+}
+
+// Fraction returns the progress as a value between 0 and 1.
+func (l *ManifestLoad) Fraction() float64 {
+	if l.Finished() {
+		return 1
+	}
+
+	done, total := l.Progress()
+	if total == 0 {
+		return 0
+	}
+
+	return float64(done) / float64(total)
+}
+
+// Bytes returns the number of bytes of asset data read so far.
+func (l *ManifestLoad) Bytes() int64 {
+	return atomic.LoadInt64(&l.bytes)
+}
+
+// Errors returns the errors of the assets which could not be loaded.
+func (l *ManifestLoad) Errors() []error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]error(nil), l.errs...)
+}
+
+// Err returns the first error, ErrLoadCancelled if the load was cancelled, or
+// nil.
+func (l *ManifestLoad) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.errs) > 0 {
+		return l.errs[0]
+	}
+	if atomic.LoadInt32(&l.cancelled) != 0 {
+		return ErrLoadCancelled
+	}
+
+	return nil
+}
+
+// Cancel stops loading assets which have not yet been allocated. Assets which
+// have already been allocated remain loaded. The load still finishes once
+// outstanding jobs have been discarded.
+func (l *ManifestLoad) Cancel() {
+	atomic.StoreInt32(&l.cancelled, 1)
+}
+
+// Cancelled reports if Cancel has been called.
+func (l *ManifestLoad) Cancelled() bool {
+	return atomic.LoadInt32(&l.cancelled) != 0
+}
+
+// Done returns a channel which is closed when the load has finished.
+func (l *ManifestLoad) Done() <-chan struct{} {
+	return l.finished
+}
+
+// Finished reports if the load has finished.
+func (l *ManifestLoad) Finished() bool {
+	select {
+	case <-l.finished:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait blocks until the load has finished and returns Err. Assets are
+// allocated on the main thread, so calling Wait from the main thread
+// deadlocks.
+func (l *ManifestLoad) Wait() error {
+	<-l.finished
+
+	return l.Err()
+}
+
+func (l *ManifestLoad) addError(err error) {
+	logrus.Error(err)
+
+	l.mu.Lock()
+	l.errs = append(l.errs, err)
+	l.mu.Unlock()
+}
+
+// itemDone counts a finished asset, and finishes the load after the last one.
+// It is called on the main thread, or on any goroutine once the job system
+// has been torn down.
+func (l *ManifestLoad) itemDone() {
+	done := atomic.AddInt32(&l.done, 1)
+
+	if atomic.LoadInt32(&l.ready) != 0 && done == atomic.LoadInt32(&l.total) {
+		l.finish()
+	}
+}
+
+// abandon cancels and finishes the load. It is called when the job system is
+// torn down before the load has finished.
+func (l *ManifestLoad) abandon() {
+	l.Cancel()
+	l.finish()
+}
+
+// finish publishes the manifest events and marks the load as finished. It is
+// called on the main thread, or on any goroutine once the job system has been
+// torn down, in which case the load has been cancelled.
+func (l *ManifestLoad) finish() {
+	if !l.Cancelled() {
+		l.mu.Lock()
+		manifests := l.manifests
+		l.mu.Unlock()
+
+		for _, m := range manifests {
+			publishEvent(m)
+		}
+	}
+
+	close(l.finished)
+}
@@ -0,0 +1,202 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// testDecodeHandler is a testAssetHandler which decodes its resources on the
+// job system's workers.
+type testDecodeHandler struct {
+	*testAssetHandler
+	uploads map[string]interface{}
+}
+
+func (h *testDecodeHandler) Decode(r *Resource) (interface{}, error) {
+	if string(r.Bytes()) == "bad" {
+		return nil, errors.New("bad data")
+	}
+
+	return "decoded " + string(r.Bytes()), nil
+}
+
+func (h *testDecodeHandler) Upload(r *Resource, v interface{}) error {
+	h.uploads[r.Base()] = v
+
+	return h.testAssetHandler.Load(r)
+}
+
+func (h *testDecodeHandler) Name() string {
+	return "decode"
+}
+
+// setupAsyncLoad sets up an asset system with both test handlers and a job
+// system whose main thread queue is drained by the test. It returns a
+// function which restores the previous systems.
+func setupAsyncLoad(t *testing.T) (*AssetSystem, *testAssetHandler, *testDecodeHandler, *JobSystem, func()) {
+	prevInstance, prevJob := instanceInst, jobInst
+	instanceInst = NewInstanceSystem()
+
+	jobs := NewJobSystem(2)
+	jobs.start()
+	jobInst = jobs
+
+	a := NewAssetSystem()
+	h := newTestAssetHandler()
+	d := &testDecodeHandler{testAssetHandler: newTestAssetHandler(), uploads: make(map[string]interface{})}
+	a.RegisterHandler(h)
+	a.RegisterHandler(d)
+
+	a.Mount("test", "mem", fstest.MapFS{
+		"one.json": {Data: []byte(`{"name": "one", "assets": {"test": ["a", "b"], "decode": ["c", "bad", "missing"]}}`)},
+		"a":        {Data: []byte("a")},
+		"b":        {Data: []byte("bb")},
+		"c":        {Data: []byte("c")},
+		"bad":      {Data: []byte("bad")},
+	}, 0)
+
+	return a, h, d, jobs, func() {
+		jobs.Teardown()
+		instanceInst, jobInst = prevInstance, prevJob
+	}
+}
+
+// drain runs main thread jobs until the load has finished.
+func drain(t *testing.T, jobs *JobSystem, l *ManifestLoad) {
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !l.Finished() {
+		if time.Now().After(deadline) {
+			t.Fatal("load did not finish")
+		}
+
+		jobs.DrainMain(0)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAssetSystem_LoadManifestAsync(t *testing.T) {
+	a, h, d, jobs, teardown := setupAsyncLoad(t)
+	defer teardown()
+
+	l := a.LoadManifestAsync("test:one.json")
+	drain(t, jobs, l)
+
+	if done, total := l.Progress(); done != 5 || total != 5 {
+		t.Errorf("Progress() got: %d, %d want: 5, 5", done, total)
+	}
+	if f := l.Fraction(); f != 1 {
+		t.Errorf("Fraction() got: %v want: 1", f)
+	}
+	if b := l.Bytes(); b != 7 {
+		t.Errorf("Bytes() got: %d want: 7", b)
+	}
+
+	errs := l.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("Errors() got: %v want: 2 errors", errs)
+	}
+	for i, err := range errs {
+		if e, ok := err.(ErrAssetLoad); !ok || e.Kind != "decode" {
+			t.Errorf("Test %d: got: %v want: ErrAssetLoad of decode", i, err)
+		}
+	}
+	if l.Err() != errs[0] {
+		t.Errorf("Err() got: %v want: %v", l.Err(), errs[0])
+	}
+
+	if n := len(h.Names()); n != 2 {
+		t.Errorf("got: %d test assets want: 2", n)
+	}
+	if v := d.uploads["c"]; v != "decoded c" || len(d.uploads) != 1 {
+		t.Errorf("got: %v want: map[c:decoded c]", d.uploads)
+	}
+
+	// The loaded assets are acquired by the manifest.
+	if err := a.UnloadManifest("test:one.json"); err != nil {
+		t.Errorf("UnloadManifest() got: %v want: nil", err)
+	}
+	if n := len(h.Names()) + len(d.Names()); n != 0 {
+		t.Errorf("got: %d assets want: 0", n)
+	}
+}
+
+func TestAssetSystem_LoadManifestAsyncCancel(t *testing.T) {
+	a, h, d, jobs, teardown := setupAsyncLoad(t)
+	defer teardown()
+
+	// Nothing is allocated before the main thread queue is drained.
+	l := a.LoadManifestAsync("test:one.json")
+	l.Cancel()
+	drain(t, jobs, l)
+
+	if err := l.Wait(); err != ErrLoadCancelled {
+		t.Errorf("Wait() got: %v want: %v", err, ErrLoadCancelled)
+	}
+	if n := len(h.Names()) + len(d.Names()); n != 0 {
+		t.Errorf("got: %d assets want: 0", n)
+	}
+	if done, total := l.Progress(); done != total {
+		t.Errorf("Progress() got: %d, %d want: equal", done, total)
+	}
+}
+
+func TestAssetSystem_LoadManifestAsyncTeardown(t *testing.T) {
+	a, h, _, jobs, teardown := setupAsyncLoad(t)
+	defer teardown()
+
+	l := a.LoadManifestAsync("test:one.json")
+
+	// Tearing down the job system before the main thread queue is drained
+	// abandons the load rather than leaving it waiting forever.
+	jobs.Teardown()
+
+	select {
+	case <-l.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("load did not finish")
+	}
+
+	if err := l.Err(); err != ErrLoadCancelled {
+		t.Errorf("Err() got: %v want: %v", err, ErrLoadCancelled)
+	}
+	if n := len(h.Names()); n != 0 {
+		t.Errorf("got: %d assets want: 0", n)
+	}
+}
+
+func TestAssetSystem_LoadManifestAsyncNoJobs(t *testing.T) {
+	prev := jobInst
+	jobInst = nil
+	defer func() { jobInst = prev }()
+
+	l := NewAssetSystem().LoadManifestAsync("test:one.json")
+
+	if !l.Finished() || l.Err() != ErrSystemNotFound(SysNameJob) {
+		t.Errorf("got: %v, %v want: true, %v", l.Finished(), l.Err(), ErrSystemNotFound(SysNameJob))
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+)
+
+// AssetMetaExtension is the extension of the sidecar file which holds the
+// GUID and import settings of an asset. The sidecar of "textures/stone.png"
+// is "textures/stone.png.meta".
+const AssetMetaExtension = ".meta"
+
+var guidRe = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// AssetMeta is the sidecar file of an asset. The GUID identifies the asset
+// independently of its file name, and the import settings are decoded by the
+// handler which loads the asset.
+type AssetMeta struct {
+	GUID   string          `json:"guid"`
+	Import json.RawMessage `json:"import,omitempty"`
+
+	file string
+}
+
+// ErrAssetMeta reports that the sidecar file of an asset is invalid.
+type ErrAssetMeta struct {
+	File string
+	Err  error
+}
+
+func (e ErrAssetMeta) Error() string {
+	return fmt.Sprintf("asset: invalid meta file '%s': %v", e.File, e.Err)
+}
+
+// Unwrap returns the reason the sidecar file is invalid.
+func (e ErrAssetMeta) Unwrap() error {
+	return e.Err
+}
+
+// ParseAssetMeta parses the sidecar file of an asset. Unknown keys and
+// invalid GUIDs are rejected.
+func ParseAssetMeta(file string, data []byte) (*AssetMeta, error) {
+	m := &AssetMeta{file: file}
+
+	if err := decodeStrict(data, m); err != nil {
+		return nil, ErrAssetMeta{File: file, Err: err}
+	}
+	if !ValidGUID(m.GUID) {
+		return nil, ErrAssetMeta{File: file, Err: fmt.Errorf("invalid guid: %q", m.GUID)}
+	}
+
+	return m, nil
+}
+
+// File returns the name of the sidecar file.
+func (m *AssetMeta) File() string {
+	if m == nil {
+		return ""
+	}
+
+	return m.file
+}
+
+// Decode decodes the import settings into v, which should hold the default
+// settings. Unknown keys are rejected. A nil meta, or one without import
+// settings, leaves v unchanged.
+func (m *AssetMeta) Decode(v interface{}) error {
+	if m == nil || len(m.Import) == 0 {
+		return nil
+	}
+
+	if err := decodeStrict(m.Import, v); err != nil {
+		return ErrAssetMeta{File: m.file, Err: err}
+	}
+
+	return nil
+}
+
+// Invalid returns an error reporting that the import settings of the sidecar
+// file are invalid.
+func (m *AssetMeta) Invalid(format string, args ...interface{}) error {
+	return ErrAssetMeta{File: m.File(), Err: fmt.Errorf(format, args...)}
+}
+
+// NewGUID returns a new random GUID.
+func NewGUID() string {
+	var b [16]byte
+	if _, err := io.ReadFull(rand.Reader, b[:]); err != nil {
+		panic(err)
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// ValidGUID reports if s is a GUID in lower case hexadecimal, as returned by
+// NewGUID.
+func ValidGUID(s string) bool {
+	return guidRe.MatchString(s)
+}
+
+// decodeStrict decodes a single JSON value into v, rejecting unknown keys.
+func decodeStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after settings")
+	}
+
+	return nil
+}
+
+// readMeta reads the sidecar file of a resource, if it has one.
+func (a *AssetSystem) readMeta(r *Resource) (*AssetMeta, error) {
+	file := r.FSPath() + AssetMetaExtension
+
+	data, err := a.fs.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseAssetMeta(file, data)
+}
+
+// AssetByGUID returns the loaded asset with the GUID given by its sidecar
+// file.
+func (a *AssetSystem) AssetByGUID(guid string) (AssetRef, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	ref, ok := a.graph.guids[guid]
+
+	return ref, ok
+}
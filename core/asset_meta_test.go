@@ -0,0 +1,159 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+const testGUID = "0f8fad5b-d9cb-469f-a165-70867728950e"
+
+func TestParseAssetMeta(t *testing.T) {
+	tests := []struct {
+		data string
+		err  bool
+	}{
+		{`{"guid": "` + testGUID + `"}`, false},
+		{`{"guid": "` + testGUID + `", "import": {"filter": "nearest"}}`, false},
+		{`{"guid": "` + testGUID + `", "settings": {}}`, true},
+		{`{"guid": "0F8FAD5B-D9CB-469F-A165-70867728950E"}`, true},
+		{`{"import": {}}`, true},
+		{`{"guid": "` + testGUID + `"} {}`, true},
+		{`not json`, true},
+	}
+
+	for i, v := range tests {
+		_, err := ParseAssetMeta("a.png.meta", []byte(v.data))
+		if (err != nil) != v.err {
+			t.Errorf("Test %d: got: %v want error: %v", i, err, v.err)
+		}
+
+		var metaErr ErrAssetMeta
+		if err != nil && !errors.As(err, &metaErr) {
+			t.Errorf("Test %d: got: %T want: ErrAssetMeta", i, err)
+		}
+	}
+
+	if guid := NewGUID(); !ValidGUID(guid) {
+		t.Errorf("NewGUID() got: %s, which is not valid", guid)
+	}
+}
+
+func TestAssetMeta_Decode(t *testing.T) {
+	type settings struct {
+		Filter  string `json:"filter"`
+		Mipmaps bool   `json:"mipmaps"`
+	}
+
+	tests := []struct {
+		data string
+		want settings
+		err  bool
+	}{
+		{`{"guid": "` + testGUID + `"}`, settings{Filter: "linear"}, false},
+		{`{"guid": "` + testGUID + `", "import": {"mipmaps": true}}`, settings{Filter: "linear", Mipmaps: true}, false},
+		{`{"guid": "` + testGUID + `", "import": {"filter": "nearest"}}`, settings{Filter: "nearest"}, false},
+		{`{"guid": "` + testGUID + `", "import": {"mipmap": true}}`, settings{Filter: "linear"}, true},
+		{`{"guid": "` + testGUID + `", "import": {"mipmaps": "yes"}}`, settings{Filter: "linear"}, true},
+	}
+
+	for i, v := range tests {
+		m, err := ParseAssetMeta("a.png.meta", []byte(v.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := settings{Filter: "linear"}
+		err = m.Decode(&got)
+		if (err != nil) != v.err || got != v.want {
+			t.Errorf("Test %d: got: %v, %v want: %v, error: %v", i, got, err, v.want, v.err)
+		}
+	}
+
+	var m *AssetMeta
+	if err := m.Decode(&settings{}); err != nil {
+		t.Errorf("nil Decode() got: %v want: nil", err)
+	}
+}
+
+func TestAssetSystem_ReadResourceMeta(t *testing.T) {
+	prev := instanceInst
+	instanceInst = NewInstanceSystem()
+	defer func() { instanceInst = prev }()
+
+	a := NewAssetSystem()
+	a.RegisterHandler(newTestAssetHandler())
+
+	a.Mount("test", "mem", fstest.MapFS{
+		"assets.json": {Data: []byte(`{"assets": {"test": ["a.png", "b.png"]}}`)},
+		"a.png":       {Data: []byte("a")},
+		"a.png.meta":  {Data: []byte(`{"guid": "` + testGUID + `"}`)},
+		"b.png":       {Data: []byte("b")},
+		"c.png":       {Data: []byte("c")},
+		"c.png.meta":  {Data: []byte(`{"guid": "` + testGUID + `", "unknown": 1}`)},
+	}, 0)
+
+	tests := []struct {
+		file string
+		guid string
+		err  bool
+	}{
+		{"test:a.png", testGUID, false},
+		{"test:b.png", "", false},
+		{"test:c.png", "", true},
+	}
+
+	for i, v := range tests {
+		r, _ := NewResource(v.file)
+		err := a.ReadResource(r)
+		if (err != nil) != v.err {
+			t.Errorf("Test %d: got: %v want error: %v", i, err, v.err)
+		}
+
+		var guid string
+		if r.Meta() != nil {
+			guid = r.Meta().GUID
+		}
+		if guid != v.guid {
+			t.Errorf("Test %d: got: %q want: %q", i, guid, v.guid)
+		}
+	}
+
+	if err := a.LoadManifest("test:assets.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := AssetRef{Kind: "test", Name: "a.png"}
+	if ref, ok := a.AssetByGUID(testGUID); !ok || ref != want {
+		t.Errorf("AssetByGUID() got: %v, %v want: %v, true", ref, ok, want)
+	}
+
+	if err := a.UnloadManifest("test:assets.json"); err != nil {
+		t.Fatal(err)
+	}
+	if ref, ok := a.AssetByGUID(testGUID); ok {
+		t.Errorf("AssetByGUID() got: %v want: none", ref)
+	}
+}
@@ -0,0 +1,284 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultReloadInterval is the default time between checks for changed asset
+// files.
+const DefaultReloadInterval = 500 * time.Millisecond
+
+// AssetReloader is implemented by asset handlers which can reload an asset in
+// place, so that existing references to the asset stay valid.
+type AssetReloader interface {
+	// Reload loads the resource again and replaces the data of the named
+	// asset with it. If loading fails, the asset must keep its previous data.
+	// It is only called from the main thread.
+	Reload(name string, r *Resource) error
+}
+
+// AssetSourcer is implemented by asset handlers whose assets are loaded from
+// more than one file, so that changes to any of them reload the asset.
+type AssetSourcer interface {
+	// Sources returns the locations of the files read to load an asset, other
+	// than the resource given to Load.
+	Sources(name string) []string
+}
+
+// assetSource is an asset which is reloaded from file when a watched file
+// changes.
+type assetSource struct {
+	kind string
+	name string
+	file string
+}
+
+// EnableHotReload watches the files of assets loaded from now on, and reloads
+// the assets when their files change. Only assets loaded from files, and from
+// the builtin assets when builtinDir is the directory they were generated
+// from, are watched. Reloads are run on the main thread by the job system.
+func (a *AssetSystem) EnableHotReload(interval time.Duration, builtinDir string) error {
+	jobs := GetJobSystem()
+	if jobs == nil {
+		return ErrSystemNotFound(SysNameJob)
+	}
+
+	a.mu.Lock()
+	if a.watcher == nil {
+		a.watcher = NewFileWatcher()
+		a.sources = make(map[string][]assetSource)
+	}
+	a.builtinDir = builtinDir
+	watcher := a.watcher
+	a.mu.Unlock()
+
+	watcher.Start(interval, func(file string) {
+		jobs.RunOnMain(func() {
+			a.reloadFile(file)
+		})
+	})
+
+	logrus.Info("Asset hot reload enabled")
+
+	return nil
+}
+
+// DisableHotReload stops watching asset files.
+func (a *AssetSystem) DisableHotReload() {
+	a.mu.Lock()
+	watcher := a.watcher
+	a.watcher = nil
+	a.sources = nil
+	a.mu.Unlock()
+
+	if watcher != nil {
+		watcher.Stop()
+	}
+}
+
+// HotReloading reports if asset files are being watched.
+func (a *AssetSystem) HotReloading() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.watcher != nil
+}
+
+// WatchedFiles returns the asset files being watched, sorted.
+func (a *AssetSystem) WatchedFiles() []string {
+	a.mu.RLock()
+	watcher := a.watcher
+	a.mu.RUnlock()
+
+	if watcher == nil {
+		return nil
+	}
+
+	return watcher.Files()
+}
+
+// Reload reloads an asset from its files.
+func (a *AssetSystem) Reload(kind, name string) error {
+	a.mu.RLock()
+	var src *assetSource
+	for file := range a.sources {
+		for i, v := range a.sources[file] {
+			if v.kind == kind && v.name == name && v.file == file {
+				src = &a.sources[file][i]
+			}
+		}
+	}
+	a.mu.RUnlock()
+
+	if src == nil {
+		return ErrAssetNotFound(name)
+	}
+
+	return a.reloadAsset(*src)
+}
+
+// watchAssets watches the files of assets loaded from a resource.
+func (a *AssetSystem) watchAssets(h AssetHandler, names []string, r *Resource) {
+	if _, ok := h.(AssetReloader); !ok {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.watcher == nil {
+		return
+	}
+
+	file := a.sourceFile(r)
+	if file == "" {
+		return
+	}
+
+	for _, name := range names {
+		src := assetSource{kind: h.Name(), name: name, file: file}
+
+		files := []string{file}
+		if r.Meta() != nil {
+			files = append(files, file+AssetMetaExtension)
+		}
+		if sourcer, ok := h.(AssetSourcer); ok {
+			for _, location := range sourcer.Sources(name) {
+				if rs, err := NewResource(location); err == nil {
+					if f := a.sourceFile(rs); f != "" {
+						files = append(files, f)
+					}
+				}
+			}
+		}
+
+		for _, f := range files {
+			if !containsSource(a.sources[f], src) {
+				a.sources[f] = append(a.sources[f], src)
+			}
+			a.watcher.Add(f)
+		}
+	}
+}
+
+// unwatchAsset stops watching the files of an asset. The lock must be held.
+func (a *AssetSystem) unwatchAsset(kind, name string) {
+	if a.watcher == nil {
+		return
+	}
+
+	for file, sources := range a.sources {
+		kept := sources[:0]
+		for _, v := range sources {
+			if v.kind != kind || v.name != name {
+				kept = append(kept, v)
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(a.sources, file)
+			a.watcher.Remove(file)
+		} else {
+			a.sources[file] = kept
+		}
+	}
+}
+
+// sourceFile returns the file on disk a resource was read from, or an empty
+// string if it was not read from a file. The lock must be held.
+func (a *AssetSystem) sourceFile(r *Resource) string {
+	switch r.Type() {
+	case ResourceFile:
+		return r.Location()
+	case ResourceBindata:
+		if a.builtinDir != "" {
+			return filepath.Join(a.builtinDir, filepath.FromSlash(r.Location()))
+		}
+	}
+
+	return ""
+}
+
+// reloadFile reloads the assets which were loaded from a file. It is called
+// on the main thread.
+func (a *AssetSystem) reloadFile(file string) {
+	a.mu.RLock()
+	sources := append([]assetSource(nil), a.sources[file]...)
+	a.mu.RUnlock()
+
+	for _, src := range sources {
+		if err := a.reloadAsset(src); err != nil {
+			logrus.Error(ErrAssetLoad{Kind: src.kind, Location: src.file, Err: err})
+			continue
+		}
+
+		logrus.Infof("Reloaded %s: %s", src.kind, src.name)
+	}
+}
+
+// reloadAsset reloads an asset from the file it was loaded from.
+func (a *AssetSystem) reloadAsset(src assetSource) error {
+	h, err := a.GetHandler(src.kind)
+	if err != nil {
+		return err
+	}
+
+	reloader, ok := h.(AssetReloader)
+	if !ok {
+		return ErrHandlerNotFound(src.kind)
+	}
+
+	r, err := NewResource(src.file)
+	if err != nil {
+		return err
+	}
+	if err := a.ReadResource(r); err != nil {
+		return err
+	}
+
+	if err := reloader.Reload(src.name, r); err != nil {
+		return err
+	}
+
+	// The asset may read different files after reloading.
+	a.watchAssets(h, []string{src.name}, r)
+
+	publishEvent(EventAssetReload{Kind: src.kind, Name: src.name, Location: src.file})
+
+	return nil
+}
+
+func containsSource(sources []assetSource, src assetSource) bool {
+	for _, v := range sources {
+		if v == src {
+			return true
+		}
+	}
+
+	return false
+}
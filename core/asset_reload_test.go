@@ -0,0 +1,201 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// testReloadHandler is a test asset handler whose assets can be reloaded, and
+// which reads an extra source file for each asset.
+type testReloadHandler struct {
+	*testAssetHandler
+
+	data    map[string]string
+	sources map[string][]string
+	fail    bool
+}
+
+func (h *testReloadHandler) Name() string {
+	return "reload"
+}
+
+func (h *testReloadHandler) Reload(name string, r *Resource) error {
+	if h.fail {
+		return errors.New("reload failed")
+	}
+
+	h.data[name] = string(r.Bytes())
+
+	return nil
+}
+
+func (h *testReloadHandler) Sources(name string) []string {
+	return h.sources[name]
+}
+
+func TestAssetSystem_ReloadFile(t *testing.T) {
+	prev := instanceInst
+	instanceInst = NewInstanceSystem()
+	defer func() { instanceInst = prev }()
+
+	dir, err := ioutil.TempDir("", "arc-reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileA := filepath.Join(dir, "a")
+	fileB := filepath.Join(dir, "b")
+	shared := filepath.Join(dir, "shared")
+	manifest := filepath.Join(dir, "one.json")
+
+	files := map[string]string{
+		fileA:    "a1",
+		fileB:    "b1",
+		shared:   "s",
+		manifest: fmt.Sprintf(`{"name": "one", "assets": {"reload": ["a", "b"]}}`),
+	}
+	for file, data := range files {
+		if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := NewAssetSystem()
+	h := &testReloadHandler{
+		testAssetHandler: newTestAssetHandler(),
+		data:             make(map[string]string),
+		sources:          map[string][]string{"a": {shared}},
+	}
+	a.RegisterHandler(h)
+
+	// Watch without polling, so that reloads are only run by the test.
+	a.watcher = NewFileWatcher()
+	a.sources = make(map[string][]assetSource)
+
+	if err := a.LoadManifest(manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	watched := []string{fileA, fileB, shared}
+	sort.Strings(watched)
+	if got := a.WatchedFiles(); !reflect.DeepEqual(got, watched) {
+		t.Fatalf("WatchedFiles() got: %v want: %v", got, watched)
+	}
+
+	tests := []struct {
+		file  string
+		write string
+		fail  bool
+		want  map[string]string
+	}{
+		{fileA, "a2", false, map[string]string{"a": "a2"}},
+		{shared, "", false, map[string]string{"a": "a2"}},
+		{fileB, "b2", false, map[string]string{"a": "a2", "b": "b2"}},
+		{fileA, "a3", true, map[string]string{"a": "a2", "b": "b2"}},
+		{manifest, "", false, map[string]string{"a": "a2", "b": "b2"}},
+	}
+
+	for i, v := range tests {
+		if v.write != "" {
+			if err := ioutil.WriteFile(v.file, []byte(v.write), 0644); err != nil {
+				t.Fatalf("Test %d: %v", i, err)
+			}
+		}
+
+		h.fail = v.fail
+		a.reloadFile(v.file)
+
+		if !reflect.DeepEqual(h.data, v.want) {
+			t.Errorf("Test %d: got: %v want: %v", i, h.data, v.want)
+		}
+	}
+
+	h.fail = false
+	if err := a.Reload("reload", "a"); err != nil {
+		t.Errorf("Reload() got: %v want: nil", err)
+	}
+	if h.data["a"] != "a3" {
+		t.Errorf("Reload() got: %s want: a3", h.data["a"])
+	}
+	if err := a.Reload("reload", "c"); err != ErrAssetNotFound("c") {
+		t.Errorf("Reload() got: %v want: %v", err, ErrAssetNotFound("c"))
+	}
+
+	// Releasing an asset stops watching the files only it was loaded from.
+	if err := a.UnloadManifest(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.WatchedFiles(); len(got) != 0 {
+		t.Errorf("WatchedFiles() got: %v want: none", got)
+	}
+	if n := len(a.sources); n != 0 {
+		t.Errorf("sources got: %d want: 0", n)
+	}
+}
+
+func TestAssetSystem_UnwatchAsset(t *testing.T) {
+	a := NewAssetSystem()
+	a.watcher = NewFileWatcher()
+	a.sources = make(map[string][]assetSource)
+
+	srcA := assetSource{kind: "reload", name: "a", file: "a"}
+	srcB := assetSource{kind: "reload", name: "b", file: "b"}
+	a.sources["a"] = []assetSource{srcA}
+	a.sources["b"] = []assetSource{srcB}
+	a.sources["shared"] = []assetSource{srcA, srcB}
+	for file := range a.sources {
+		a.watcher.Add(file)
+	}
+
+	tests := []struct {
+		kind    string
+		name    string
+		watched []string
+	}{
+		{"other", "a", []string{"a", "b", "shared"}},
+		{"reload", "a", []string{"b", "shared"}},
+		{"reload", "a", []string{"b", "shared"}},
+		{"reload", "b", []string{}},
+	}
+
+	for i, v := range tests {
+		a.unwatchAsset(v.kind, v.name)
+
+		if got := a.WatchedFiles(); !reflect.DeepEqual(got, v.watched) {
+			t.Errorf("Test %d: got: %v want: %v", i, got, v.watched)
+		}
+	}
+
+	if got := a.sources["shared"]; len(got) != 0 {
+		t.Errorf("sources got: %v want: none", got)
+	}
+}
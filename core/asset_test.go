@@ -0,0 +1,276 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+type testAssetHandler struct {
+	BaseAssetHandler
+	objects map[string]*testObject
+}
+
+func (h *testAssetHandler) Load(r *Resource) error {
+	name := r.Base()
+
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+
+	if _, dup := h.Items[name]; dup {
+		return ErrAssetExists(name)
+	}
+
+	o := newTestObject(name)
+	if err := GetInstanceSystem().Assign(o); err != nil {
+		return err
+	}
+
+	h.Items[name] = o.ID()
+	h.objects[name] = o
+
+	return nil
+}
+
+func (h *testAssetHandler) Name() string {
+	return "test"
+}
+
+func newTestAssetHandler() *testAssetHandler {
+	h := &testAssetHandler{objects: make(map[string]*testObject)}
+	h.Items = make(map[string]int32)
+	h.Mu = &sync.RWMutex{}
+
+	return h
+}
+
+func TestBaseAssetHandler_Release(t *testing.T) {
+	prev := instanceInst
+	instanceInst = NewInstanceSystem()
+	defer func() { instanceInst = prev }()
+
+	h := newTestAssetHandler()
+	if err := h.Load(&Resource{location: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Acquire("a")
+	h.Acquire("a")
+
+	tests := []struct {
+		removed bool
+		err     error
+	}{
+		{false, nil},
+		{true, nil},
+		{false, ErrAssetNotFound("a")},
+	}
+
+	for i, v := range tests {
+		removed, err := h.Release("a")
+		if removed != v.removed || err != v.err {
+			t.Errorf("Test %d: got: %v, %v want: %v, %v", i, removed, err, v.removed, v.err)
+		}
+	}
+
+	if o := h.objects["a"]; o.deallocs != 1 || o.ID() != 0 {
+		t.Errorf("released asset got: deallocs %d ID %d want: 1, 0", o.deallocs, o.ID())
+	}
+
+	if err := h.Load(&Resource{location: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Release("b"); err != ErrAssetNotAcquired("b") {
+		t.Errorf("Release() got: %v want: %v", err, ErrAssetNotAcquired("b"))
+	}
+}
+
+func TestAssetSystem_UnloadManifest(t *testing.T) {
+	prev := instanceInst
+	instanceInst = NewInstanceSystem()
+	defer func() { instanceInst = prev }()
+
+	a := NewAssetSystem()
+	h := newTestAssetHandler()
+	a.RegisterHandler(h)
+
+	a.Mount("test", "mem", fstest.MapFS{
+		"one.json": {Data: []byte(`{"name": "one", "assets": {"test": ["a", "b"]}}`)},
+		"two.json": {Data: []byte(`{"name": "two", "assets": {"test": ["a"]}}`)},
+		"a":        {Data: []byte("a")},
+		"b":        {Data: []byte("b")},
+	}, 0)
+
+	if err := a.LoadManifest("test:one.json", "test:two.json", "test:two.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		unload string
+		err    error
+		names  int
+	}{
+		{"", nil, 2},
+		{"test:one.json", nil, 1},
+		{"test:two.json", nil, 1},
+		{"test:two.json", nil, 0},
+		{"test:two.json", ErrManifestNotLoaded("test:two.json"), 0},
+	}
+
+	for i, v := range tests {
+		if v.unload != "" {
+			if err := a.UnloadManifest(v.unload); err != v.err {
+				t.Errorf("Test %d: got: %v want: %v", i, err, v.err)
+			}
+		}
+		if n := len(h.Names()); n != v.names {
+			t.Errorf("Test %d: got: %d want: %d", i, n, v.names)
+		}
+	}
+
+	if n := instanceInst.Count(); n != 0 {
+		t.Errorf("Count() got: %d want: 0", n)
+	}
+}
+
+func TestAssetSystem_LoadManifestRetry(t *testing.T) {
+	prev := instanceInst
+	instanceInst = NewInstanceSystem()
+	defer func() { instanceInst = prev }()
+
+	a := NewAssetSystem()
+	h := newTestAssetHandler()
+	a.RegisterHandler(h)
+
+	files := fstest.MapFS{
+		"one.json": {Data: []byte(`{"name": "one", "assets": {"test": ["a", "c"]}}`)},
+		"a":        {Data: []byte("a")},
+	}
+	a.Mount("test", "mem", files, 0)
+
+	if err := a.LoadManifest("test:one.json"); err == nil {
+		t.Fatal("LoadManifest() got: nil want: error")
+	}
+	if a.ManifestLoaded("test:one.json") {
+		t.Error("ManifestLoaded() got: true want: false")
+	}
+	if n := len(h.Names()); n != 0 {
+		t.Errorf("Names() got: %d want: 0", n)
+	}
+	if n := instanceInst.Count(); n != 0 {
+		t.Errorf("Count() got: %d want: 0", n)
+	}
+
+	files["c"] = &fstest.MapFile{Data: []byte("c")}
+
+	if err := a.LoadManifest("test:one.json"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(h.Names()); n != 2 {
+		t.Errorf("Names() got: %d want: 2", n)
+	}
+
+	if err := a.UnloadManifest("test:one.json"); err != nil {
+		t.Fatal(err)
+	}
+	if n := instanceInst.Count(); n != 0 {
+		t.Errorf("Count() got: %d want: 0", n)
+	}
+}
+
+// testLibraryHandler is a test asset handler which loads two assets from each
+// resource.
+type testLibraryHandler struct {
+	*testAssetHandler
+}
+
+func (h *testLibraryHandler) Load(r *Resource) error {
+	names := []string{r.Base() + "/1", r.Base() + "/2"}
+
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+
+	if _, dup := h.Items[names[0]]; dup {
+		return ErrAssetExists(names[0])
+	}
+
+	for _, name := range names {
+		o := newTestObject(name)
+		if err := GetInstanceSystem().Assign(o); err != nil {
+			return err
+		}
+
+		h.Items[name] = o.ID()
+		h.objects[name] = o
+	}
+
+	return nil
+}
+
+func (h *testLibraryHandler) Name() string {
+	return "library"
+}
+
+func TestAssetSystem_LoadManifestShared(t *testing.T) {
+	prev := instanceInst
+	instanceInst = NewInstanceSystem()
+	defer func() { instanceInst = prev }()
+
+	a := NewAssetSystem()
+	h := &testLibraryHandler{newTestAssetHandler()}
+	a.RegisterHandler(h)
+
+	a.Mount("test", "mem", fstest.MapFS{
+		"one.json": {Data: []byte(`{"name": "one", "assets": {"library": ["lib"]}}`)},
+		"two.json": {Data: []byte(`{"name": "two", "assets": {"library": ["lib"]}}`)},
+		"lib":      {Data: []byte("lib")},
+	}, 0)
+
+	if err := a.LoadManifest("test:one.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.LoadManifest("test:two.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each asset loaded from the shared file is kept until both manifests
+	// are unloaded.
+	tests := []struct {
+		unload string
+		names  int
+	}{
+		{"test:one.json", 2},
+		{"test:two.json", 0},
+	}
+
+	for i, v := range tests {
+		if err := a.UnloadManifest(v.unload); err != nil {
+			t.Errorf("Test %d: got: %v want: nil", i, err)
+		}
+		if n := len(h.Names()); n != v.names {
+			t.Errorf("Test %d: got: %d want: %d", i, n, v.names)
+		}
+	}
+}
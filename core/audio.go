@@ -88,7 +88,7 @@ func (s *AudioSystem) SetMute(mute bool) {
 }
 
 func (s *AudioSystem) PlaySound(sound *Sound) {
-	speaker.Play(sound.streamer)
+	speaker.Play(sound.Streamer())
 }
 
 func NewAudioSystem(rate beep.SampleRate) *AudioSystem {
@@ -0,0 +1,262 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// maxStackDepth is the number of frames recorded for each object.
+	maxStackDepth = 32
+
+	// maxCensusStacks is the number of distinct creation stacks kept for each
+	// census entry.
+	maxCensusStacks = 8
+)
+
+// CensusEntry counts the live objects of one type and name.
+type CensusEntry struct {
+	// Type is the name of the concrete type of the objects.
+	Type string
+
+	// Name is the name of the objects.
+	Name string
+
+	// Count is the number of objects.
+	Count int
+
+	// Stacks holds distinct stack traces of where the objects were assigned.
+	// It is only populated when built with the arcdebug tag.
+	Stacks []string
+}
+
+// Census is a snapshot of the objects registered with the instance system.
+type Census struct {
+	// Time is the time the census was taken.
+	Time time.Time
+
+	// Total is the number of objects.
+	Total int
+
+	// Entries are sorted by type, then name.
+	Entries []CensusEntry
+}
+
+// CensusDelta is the change in the number of objects of one type and name
+// between two censuses.
+type CensusDelta struct {
+	Type   string
+	Name   string
+	Before int
+	After  int
+
+	// Stacks holds creation stacks of the objects in the later census.
+	Stacks []string
+}
+
+// CensusDiff lists the changes between two censuses.
+type CensusDiff []CensusDelta
+
+// Census takes a snapshot of the live objects, grouped by concrete type and
+// name.
+func (s *InstanceSystem) Census() Census {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type key struct {
+		t    string
+		name string
+	}
+
+	entries := make(map[key]*CensusEntry)
+	stacks := make(map[key]map[string]bool)
+
+	for i := range s.slots {
+		object := s.slots[i].object
+		if object == nil {
+			continue
+		}
+
+		k := key{reflect.TypeOf(object).String(), object.Name()}
+
+		e, ok := entries[k]
+		if !ok {
+			e = &CensusEntry{Type: k.t, Name: k.name}
+			entries[k] = e
+			stacks[k] = make(map[string]bool)
+		}
+		e.Count++
+
+		if pcs := s.slots[i].stack; len(pcs) > 0 && len(e.Stacks) < maxCensusStacks {
+			stack := formatStack(pcs)
+			if !stacks[k][stack] {
+				stacks[k][stack] = true
+				e.Stacks = append(e.Stacks, stack)
+			}
+		}
+	}
+
+	c := Census{
+		Time:    time.Now(),
+		Total:   s.count,
+		Entries: make([]CensusEntry, 0, len(entries)),
+	}
+
+	for _, e := range entries {
+		c.Entries = append(c.Entries, *e)
+	}
+
+	sort.Slice(c.Entries, func(i, j int) bool {
+		if c.Entries[i].Type != c.Entries[j].Type {
+			return c.Entries[i].Type < c.Entries[j].Type
+		}
+		return c.Entries[i].Name < c.Entries[j].Name
+	})
+
+	return c
+}
+
+// Count returns the number of objects of the given type. The type is named
+// as in CensusEntry, such as "*graphics.Texture2D".
+func (c Census) Count(typeName string) int {
+	var n int
+
+	for i := range c.Entries {
+		if c.Entries[i].Type == typeName {
+			n += c.Entries[i].Count
+		}
+	}
+
+	return n
+}
+
+// Diff returns the changes from an earlier census to this one. It is empty if
+// the number of objects of every type and name is unchanged.
+func (c Census) Diff(before Census) CensusDiff {
+	var diff CensusDiff
+
+	i, j := 0, 0
+	for i < len(before.Entries) || j < len(c.Entries) {
+		switch {
+		case j == len(c.Entries) || (i < len(before.Entries) && censusLess(before.Entries[i], c.Entries[j])):
+			b := before.Entries[i]
+			diff = append(diff, CensusDelta{Type: b.Type, Name: b.Name, Before: b.Count})
+			i++
+		case i == len(before.Entries) || censusLess(c.Entries[j], before.Entries[i]):
+			a := c.Entries[j]
+			diff = append(diff, CensusDelta{Type: a.Type, Name: a.Name, After: a.Count, Stacks: a.Stacks})
+			j++
+		default:
+			b, a := before.Entries[i], c.Entries[j]
+			if b.Count != a.Count {
+				diff = append(diff, CensusDelta{Type: a.Type, Name: a.Name, Before: b.Count, After: a.Count, Stacks: a.Stacks})
+			}
+			i++
+			j++
+		}
+	}
+
+	return diff
+}
+
+// String formats the census as a table for logs.
+func (c Census) String() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "%d objects at %s\n", c.Total, c.Time.Format(time.RFC3339))
+	for _, e := range c.Entries {
+		fmt.Fprintf(&buf, "%6d  %-32s %s\n", e.Count, e.Type, e.Name)
+		writeStacks(&buf, e.Stacks)
+	}
+
+	return buf.String()
+}
+
+// Empty reports if there are no changes.
+func (d CensusDiff) Empty() bool {
+	return len(d) == 0
+}
+
+// String formats the changes as a table for logs.
+func (d CensusDiff) String() string {
+	var buf bytes.Buffer
+
+	for _, e := range d {
+		fmt.Fprintf(&buf, "%+6d  %-32s %s (%d -> %d)\n", e.After-e.Before, e.Type, e.Name, e.Before, e.After)
+		writeStacks(&buf, e.Stacks)
+	}
+
+	return buf.String()
+}
+
+func censusLess(a, b CensusEntry) bool {
+	if a.Type != b.Type {
+		return a.Type < b.Type
+	}
+
+	return a.Name < b.Name
+}
+
+func writeStacks(buf *bytes.Buffer, stacks []string) {
+	for _, stack := range stacks {
+		buf.WriteString("        created at:\n")
+		for _, line := range strings.Split(strings.TrimSuffix(stack, "\n"), "\n") {
+			buf.WriteString("          " + line + "\n")
+		}
+	}
+}
+
+// callerStack records the stack of the caller of the function calling it, if
+// stack tracking is enabled.
+func callerStack() []uintptr {
+	if !trackStacks {
+		return nil
+	}
+
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(3, pcs)
+
+	return pcs[:n]
+}
+
+func formatStack(pcs []uintptr) string {
+	var buf bytes.Buffer
+
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&buf, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,73 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+type otherTestObject struct {
+	BaseObject
+}
+
+func TestInstanceSystem_Census(t *testing.T) {
+	s := NewInstanceSystem()
+
+	s.MustAssign(newTestObject("a"))
+	baseline := s.Census()
+
+	// Simulate loading a scene which leaks one of its objects.
+	b := newTestObject("b")
+	leak := &otherTestObject{}
+	leak.SetName("leak")
+	s.MustAssign(b)
+	s.MustAssign(leak)
+	s.MustAssign(newTestObject("a"))
+
+	loaded := s.Census()
+	if loaded.Total != 4 || loaded.Count("*core.testObject") != 3 {
+		t.Errorf("got: total %d testObjects %d want: 4, 3", loaded.Total, loaded.Count("*core.testObject"))
+	}
+
+	s.Release(b.ID(), 4)
+
+	diff := s.Census().Diff(baseline)
+	if len(diff) != 1 {
+		t.Fatalf("Diff() got: %v want: 1 change", diff)
+	}
+	if d := diff[0]; d.Type != "*core.otherTestObject" || d.Name != "leak" || d.Before != 0 || d.After != 1 {
+		t.Errorf("got: %+v", d)
+	}
+	if trackStacks && (len(diff[0].Stacks) == 0 || !strings.Contains(diff[0].Stacks[0], "TestInstanceSystem_Census")) {
+		t.Errorf("got: stacks %v want: assignment stack", diff[0].Stacks)
+	}
+
+	s.Release(leak.ID())
+	if diff := s.Census().Diff(baseline); !diff.Empty() {
+		t.Errorf("got: %v want: empty diff", diff)
+	}
+	if diff := baseline.Diff(loaded); len(diff) != 3 {
+		t.Errorf("got: %v want: 3 changes", diff)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// Clock is a source of time for the TimeSystem.
+type Clock interface {
+	// Now returns the current time in seconds.
+	Now() float64
+}
+
+// FrameClock is a Clock which is notified at the end of every frame. Clocks
+// that advance in discrete steps, or record their samples, implement this.
+type FrameClock interface {
+	Clock
+
+	// OnFrame is called by the TimeSystem at the end of every frame.
+	OnFrame()
+}
+
+var _ Clock = &GLFWClock{}
+var _ Clock = &WallClock{}
+var _ FrameClock = &ManualClock{}
+var _ FrameClock = &RecordedClock{}
+
+// GLFWClock is a real-time clock backed by the GLFW timer. GLFW must be
+// initialized before it is used.
+type GLFWClock struct{}
+
+// Now returns the current time in seconds.
+func (c *GLFWClock) Now() float64 {
+	return glfw.GetTime()
+}
+
+// WallClock is a real-time clock which does not depend on GLFW. Time is
+// measured from the moment the clock was created.
+type WallClock struct {
+	start time.Time
+}
+
+// Now returns the current time in seconds.
+func (c *WallClock) Now() float64 {
+	return time.Since(c.start).Seconds()
+}
+
+// ManualClock is a clock which only advances when told to. If step is
+// non-zero, the clock also advances by step at the end of every frame, which
+// makes it suitable for deterministic tests.
+type ManualClock struct {
+	now  float64
+	step float64
+	mu   sync.Mutex
+}
+
+// Now returns the current time in seconds.
+func (c *ManualClock) Now() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// OnFrame advances the clock by its step.
+func (c *ManualClock) OnFrame() {
+	c.Advance(c.Step())
+}
+
+// Advance moves the clock forward by d seconds.
+func (c *ManualClock) Advance(d float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now += d
+}
+
+// Set sets the current time of the clock.
+func (c *ManualClock) Set(now float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// Step returns the amount the clock advances each frame.
+func (c *ManualClock) Step() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.step
+}
+
+// SetStep sets the amount the clock advances each frame.
+func (c *ManualClock) SetStep(step float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.step = step
+}
+
+// RecordedClock replays a sequence of frame deltas, such as those captured
+// with TimeSystem.StartRecording, advancing by one delta per frame. Once all
+// deltas have been replayed, the clock stops advancing.
+type RecordedClock struct {
+	deltas []float64
+	now    float64
+	index  int
+}
+
+// Now returns the current time in seconds.
+func (c *RecordedClock) Now() float64 {
+	return c.now
+}
+
+// OnFrame advances the clock by the next recorded delta.
+func (c *RecordedClock) OnFrame() {
+	if c.index < len(c.deltas) {
+		c.now += c.deltas[c.index]
+		c.index++
+	}
+}
+
+// Done reports if all deltas have been replayed.
+func (c *RecordedClock) Done() bool {
+	return c.index >= len(c.deltas)
+}
+
+// NewGLFWClock creates a new clock backed by the GLFW timer.
+func NewGLFWClock() *GLFWClock {
+	return &GLFWClock{}
+}
+
+// NewWallClock creates a new clock backed by the system's monotonic clock.
+func NewWallClock() *WallClock {
+	return &WallClock{
+		start: time.Now(),
+	}
+}
+
+// NewManualClock creates a new manual clock which advances by step at the end
+// of every frame. A step of zero creates a clock which only advances through
+// Advance and Set.
+func NewManualClock(step float64) *ManualClock {
+	return &ManualClock{
+		step: step,
+	}
+}
+
+// NewRecordedClock creates a new clock which replays the given frame deltas.
+func NewRecordedClock(deltas []float64) *RecordedClock {
+	return &RecordedClock{
+		deltas: deltas,
+	}
+}
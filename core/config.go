@@ -23,6 +23,9 @@ SOFTWARE.
 package core
 
 import (
+	"strings"
+	"sync"
+
 	"github.com/spf13/viper"
 
 	"github.com/haakenlabs/arc/pkg/math"
@@ -33,10 +36,17 @@ const (
 	cfgPrefix   = "arc"
 )
 
-// LoadGlobalConfig sets up viper and reads in the main configuration.
+// defaultSettingsOnce ensures the engine settings are only registered once.
+var defaultSettingsOnce sync.Once
+
+// LoadGlobalConfig sets up viper and reads in the main configuration. Values
+// may be overridden with environment variables, such as ARC_GRAPHICS_VSYNC
+// for graphics.vsync. An ErrSettingsInvalid is returned if any setting has an
+// invalid value.
 func LoadGlobalConfig() error {
 	viper.AutomaticEnv()
 	viper.SetEnvPrefix(cfgPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.SetConfigFile(cfgFilename)
 	//viper.AddConfigPath(AppDir)
 	viper.SetConfigType("json")
@@ -48,15 +58,158 @@ func LoadGlobalConfig() error {
 		}
 	}
 
-	loadDefaultSettings()
+	defaultSettingsOnce.Do(loadDefaultSettings)
 
-	return nil
+	return globalSettings.Validate()
 }
 
-// loadDefaultSettings sets default settings.
+// loadDefaultSettings registers the engine settings.
 func loadDefaultSettings() {
+	s := globalSettings
+
+	// App Options
+	s.MustRegister(Setting{
+		Key:         "app.headless",
+		Description: "Run without a window or an OpenGL context.",
+		Type:        SettingBool,
+		Default:     false,
+	})
+	s.MustRegister(Setting{
+		Key:         "app.frames",
+		Description: "Stop after this many frames. Zero runs until quit.",
+		Type:        SettingInt,
+		Default:     0,
+		Min:         0,
+		Max:         1<<31 - 1,
+	})
+
+	s.MustRegister(Setting{
+		Key:         "app.crash_dir",
+		Description: "Directory crash reports are written to.",
+		Type:        SettingString,
+		Default:     "crash",
+	})
+
+	// Time Options
+	s.MustRegister(Setting{
+		Key:         "time.fixed",
+		Description: "Fixed update time step, in seconds.",
+		Type:        SettingFloat,
+		Default:     DefaultFixedTime,
+		Min:         0.001,
+		Max:         1,
+	})
+
+	// Job Options
+	s.MustRegister(Setting{
+		Key:         "job.workers",
+		Description: "Number of worker goroutines. Zero uses one per CPU.",
+		Type:        SettingInt,
+		Default:     0,
+		Min:         0,
+		Max:         256,
+	})
+	s.MustRegister(Setting{
+		Key:         "job.budget",
+		Description: "Time per frame spent running main thread jobs, in milliseconds.",
+		Type:        SettingFloat,
+		Default:     DefaultMainBudget.Seconds() * 1000,
+		Min:         0,
+		Max:         1000,
+	})
+
+	// Profiler Options
+	s.MustRegister(Setting{
+		Key:         "profile.enabled",
+		Description: "Record frame timings.",
+		Type:        SettingBool,
+		Default:     false,
+	})
+	s.MustRegister(Setting{
+		Key:         "profile.detailed",
+		Description: "Record timings of individual components.",
+		Type:        SettingBool,
+		Default:     false,
+	})
+	s.MustRegister(Setting{
+		Key:         "profile.frames",
+		Description: "Number of frames kept by the profiler.",
+		Type:        SettingInt,
+		Default:     DefaultProfileFrames,
+		Min:         1,
+		Max:         100000,
+	})
+	s.MustRegister(Setting{
+		Key:         "profile.output",
+		Description: "File to write a Chrome trace to on exit.",
+		Type:        SettingString,
+		Default:     "",
+	})
+
+	// Asset Options
+	s.MustRegister(Setting{
+		Key:         "asset.hot_reload",
+		Description: "Reload assets when their files change.",
+		Type:        SettingBool,
+		Default:     false,
+	})
+	s.MustRegister(Setting{
+		Key:         "asset.reload_interval",
+		Description: "Time between checks for changed asset files, in milliseconds.",
+		Type:        SettingFloat,
+		Default:     DefaultReloadInterval.Seconds() * 1000,
+		Min:         10,
+		Max:         60000,
+	})
+	s.MustRegister(Setting{
+		Key:         "asset.builtin_dir",
+		Description: "Directory the builtin assets were generated from, watched by hot reload.",
+		Type:        SettingString,
+		Default:     "",
+	})
+	s.MustRegister(Setting{
+		Key:         "asset.package_verify",
+		Description: "How packages are verified when mounted: off, warn or enforce.",
+		Type:        SettingString,
+		Default:     PackageVerifyOff.String(),
+		Options:     packagePolicyNames,
+	})
+	s.MustRegister(Setting{
+		Key:         "asset.trusted_keys",
+		Description: "Comma separated base64 ed25519 public keys trusted to sign packages.",
+		Type:        SettingString,
+		Default:     "",
+	})
+
+	// Console Options
+	s.MustRegister(Setting{
+		Key:         "console.exec",
+		Description: "Console script to run after setup.",
+		Type:        SettingString,
+		Default:     "",
+	})
+
 	// Graphics Options
-	viper.SetDefault("graphics.resolution", math.IVec2{1280, 720})
-	viper.SetDefault("graphics.mode", 0)
-	viper.SetDefault("graphics.vsync", true)
+	s.MustRegister(Setting{
+		Key:         "graphics.resolution",
+		Description: "Window resolution, in pixels.",
+		Type:        SettingIVec2,
+		Default:     math.IVec2{1280, 720},
+		Min:         1,
+		Max:         16384,
+	})
+	s.MustRegister(Setting{
+		Key:         "graphics.mode",
+		Description: "Display mode: 0 windowed, 1 windowed fullscreen, 2 fullscreen.",
+		Type:        SettingInt,
+		Default:     int(DisplayModeWindow),
+		Min:         float64(DisplayModeWindow),
+		Max:         float64(DisplayModeFullscreen),
+	})
+	s.MustRegister(Setting{
+		Key:         "graphics.vsync",
+		Description: "Synchronize buffer swaps with the display refresh rate.",
+		Type:        SettingBool,
+		Default:     true,
+	})
 }
@@ -0,0 +1,814 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/haakenlabs/arc/pkg/math"
+)
+
+var _ System = &ConsoleSystem{}
+
+var consoleInst *ConsoleSystem
+
+// cvarRegistrars register the cvars of other packages with each new console.
+var cvarRegistrars []func(c *ConsoleSystem)
+
+const SysNameConsole = "console"
+
+const (
+	// DefaultConsoleHistory is the number of lines kept in the console
+	// history.
+	DefaultConsoleHistory = 100
+
+	// maxExecDepth limits how deeply scripts may exec other scripts.
+	maxExecDepth = 16
+)
+
+// ErrCommandNotFound reports that no command or cvar has the given name.
+type ErrCommandNotFound string
+
+func (e ErrCommandNotFound) Error() string {
+	return "console: unknown command or cvar " + string(e)
+}
+
+// ErrCommandExists reports that a command or cvar with the given name has
+// already been registered.
+type ErrCommandExists string
+
+func (e ErrCommandExists) Error() string {
+	return "console: " + string(e) + " already exists"
+}
+
+// ErrCommandUsage reports that a command was called with the wrong number of
+// arguments.
+type ErrCommandUsage struct {
+	Command string
+	Usage   string
+}
+
+func (e ErrCommandUsage) Error() string {
+	return "console: usage: " + e.Command + " " + e.Usage
+}
+
+// ErrConsoleSyntax reports that a line could not be parsed.
+type ErrConsoleSyntax string
+
+func (e ErrConsoleSyntax) Error() string {
+	return "console: syntax error: " + string(e)
+}
+
+// ErrScript reports an error on a line of a script.
+type ErrScript struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e ErrScript) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+// CommandFunc runs a console command with the given arguments.
+type CommandFunc func(c *ConsoleSystem, args []string) error
+
+// Command describes a console command.
+type Command struct {
+	// Name is the name used to call the command.
+	Name string
+
+	// Help is a short description of the command.
+	Help string
+
+	// Usage describes the arguments of the command, such as "<name> [value]".
+	Usage string
+
+	// MinArgs and MaxArgs bound the number of arguments. If MaxArgs is less
+	// than MinArgs, any number of arguments above MinArgs is accepted. If
+	// both are zero, the arguments are not checked.
+	MinArgs int
+	MaxArgs int
+
+	// Func runs the command.
+	Func CommandFunc
+
+	// Complete returns completions for the last of the given arguments. It
+	// may be nil.
+	Complete func(args []string) []string
+}
+
+// Cvar is a typed console variable. Its type, default, range and options are
+// described by a Setting.
+type Cvar struct {
+	setting   Setting
+	value     interface{}
+	listeners []SettingListener
+}
+
+// ConsoleSystem is a registry of console variables and commands, which are
+// run from lines of text entered by the user or read from scripts.
+//
+// Lines may contain several statements separated by semicolons. Words are
+// separated by whitespace, and may be grouped with double quotes. A word
+// starting with // or # begins a comment.
+//
+// Lines may be queued from any goroutine with Enqueue, and are run at the
+// start of the next frame. All other methods must only be called from the
+// main thread.
+type ConsoleSystem struct {
+	commands map[string]*Command
+	cvars    map[string]*Cvar
+	history  []string
+	queue    []string
+	output   io.Writer
+	depth    int
+	mu       *sync.Mutex
+}
+
+// Setup sets up the System.
+func (c *ConsoleSystem) Setup() error {
+	if consoleInst != nil {
+		return ErrSystemInit(SysNameConsole)
+	}
+	consoleInst = c
+
+	return nil
+}
+
+// Teardown tears down the System.
+func (c *ConsoleSystem) Teardown() {}
+
+// Name returns the name of the System.
+func (c *ConsoleSystem) Name() string {
+	return SysNameConsole
+}
+
+// PreUpdate runs the lines queued with Enqueue.
+func (c *ConsoleSystem) PreUpdate() {
+	c.mu.Lock()
+	queue := c.queue
+	c.queue = nil
+	c.mu.Unlock()
+
+	for _, line := range queue {
+		if err := c.Exec(line); err != nil {
+			c.Println(err)
+		}
+	}
+}
+
+// Enqueue queues a line to be run at the start of the next frame. It is safe
+// to call from any goroutine.
+func (c *ConsoleSystem) Enqueue(line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.queue = append(c.queue, line)
+}
+
+// SetOutput sets the writer command output is written to.
+func (c *ConsoleSystem) SetOutput(w io.Writer) {
+	c.output = w
+}
+
+// Printf writes formatted command output.
+func (c *ConsoleSystem) Printf(format string, a ...interface{}) {
+	fmt.Fprintf(c.output, format, a...)
+}
+
+// Println writes a line of command output.
+func (c *ConsoleSystem) Println(a ...interface{}) {
+	fmt.Fprintln(c.output, a...)
+}
+
+// RegisterCommand adds a command.
+func (c *ConsoleSystem) RegisterCommand(cmd Command) error {
+	if c.nameTaken(cmd.Name) {
+		return ErrCommandExists(cmd.Name)
+	}
+
+	c.commands[cmd.Name] = &cmd
+
+	return nil
+}
+
+// MustRegisterCommand is like RegisterCommand, but panics if an error occurs.
+func (c *ConsoleSystem) MustRegisterCommand(cmd Command) {
+	if err := c.RegisterCommand(cmd); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterCvar adds a console variable described by s. The key of the
+// setting is the name of the variable.
+func (c *ConsoleSystem) RegisterCvar(s Setting) (*Cvar, error) {
+	if c.nameTaken(s.Key) {
+		return nil, ErrCommandExists(s.Key)
+	}
+
+	value, err := s.Convert(s.Default)
+	if err != nil {
+		return nil, err
+	}
+	s.Default = value
+
+	cvar := &Cvar{setting: s, value: value}
+	c.cvars[s.Key] = cvar
+
+	return cvar, nil
+}
+
+// MustRegisterCvar is like RegisterCvar, but panics if an error occurs.
+func (c *ConsoleSystem) MustRegisterCvar(s Setting) *Cvar {
+	cvar, err := c.RegisterCvar(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return cvar
+}
+
+// Cvar returns the console variable with the given name.
+func (c *ConsoleSystem) Cvar(name string) (*Cvar, error) {
+	cvar, ok := c.cvars[name]
+	if !ok {
+		return nil, ErrCommandNotFound(name)
+	}
+
+	return cvar, nil
+}
+
+// Command returns the command with the given name.
+func (c *ConsoleSystem) Command(name string) (*Command, error) {
+	cmd, ok := c.commands[name]
+	if !ok {
+		return nil, ErrCommandNotFound(name)
+	}
+
+	return cmd, nil
+}
+
+// Names returns the names of all commands and cvars, sorted.
+func (c *ConsoleSystem) Names() []string {
+	names := make([]string, 0, len(c.commands)+len(c.cvars))
+
+	for name := range c.commands {
+		names = append(names, name)
+	}
+	for name := range c.cvars {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Exec runs a line and adds it to the history.
+func (c *ConsoleSystem) Exec(line string) error {
+	c.addHistory(line)
+
+	return c.run(line)
+}
+
+// ExecReader runs each line read from r, stopping at the first error. The
+// name is used to identify the script in errors.
+func (c *ConsoleSystem) ExecReader(r io.Reader, name string) error {
+	if c.depth >= maxExecDepth {
+		return ErrScript{File: name, Err: ErrConsoleSyntax("exec nested too deeply")}
+	}
+
+	c.depth++
+	defer func() { c.depth-- }()
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		if err := c.run(scanner.Text()); err != nil {
+			if e, ok := err.(ErrScript); ok {
+				return e
+			}
+			return ErrScript{File: name, Line: line, Err: err}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// ExecScript runs a script, such as autoexec.cfg. The script is read with the
+// asset system, so it may be a file, a package path or a builtin resource.
+func (c *ConsoleSystem) ExecScript(filename string) error {
+	a := GetAssetSystem()
+	if a == nil {
+		return ErrSystemNotFound(SysNameAsset)
+	}
+
+	r, err := NewResource(filename)
+	if err != nil {
+		return err
+	}
+	if err := a.ReadResource(r); err != nil {
+		return err
+	}
+
+	return c.ExecReader(r.Reader(), r.Location())
+}
+
+// History returns the lines run with Exec, oldest first.
+func (c *ConsoleSystem) History() []string {
+	return append([]string(nil), c.history...)
+}
+
+// Complete returns the possible completions of the last word of a partial
+// line. The first word completes to command and cvar names. Later words are
+// completed by the command, or from the options of a cvar.
+func (c *ConsoleSystem) Complete(line string) []string {
+	stmts, err := parseConsoleLine(line)
+	if err != nil {
+		return nil
+	}
+
+	var words []string
+	if len(stmts) > 0 {
+		words = stmts[len(stmts)-1]
+	}
+
+	// A trailing space starts a new, empty word.
+	if len(words) == 0 || strings.HasSuffix(line, " ") {
+		words = append(words, "")
+	}
+
+	if len(words) == 1 {
+		return filterPrefix(c.Names(), words[0])
+	}
+
+	if cmd, ok := c.commands[words[0]]; ok {
+		if cmd.Complete == nil {
+			return nil
+		}
+		return cmd.Complete(words[1:])
+	}
+
+	if cvar, ok := c.cvars[words[0]]; ok && len(words) == 2 {
+		switch cvar.setting.Type {
+		case SettingBool:
+			return filterPrefix([]string{"false", "true"}, words[1])
+		case SettingString:
+			return filterPrefix(cvar.setting.Options, words[1])
+		}
+	}
+
+	return nil
+}
+
+// completeNames completes the first argument to a cvar name.
+func (c *ConsoleSystem) completeNames(args []string) []string {
+	if len(args) != 1 {
+		return nil
+	}
+
+	names := make([]string, 0, len(c.cvars))
+	for name := range c.cvars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return filterPrefix(names, args[0])
+}
+
+func (c *ConsoleSystem) run(line string) error {
+	stmts, err := parseConsoleLine(line)
+	if err != nil {
+		return err
+	}
+
+	for _, words := range stmts {
+		if err := c.runStatement(words); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *ConsoleSystem) runStatement(words []string) error {
+	name, args := words[0], words[1:]
+
+	if cmd, ok := c.commands[name]; ok {
+		if !cmd.acceptsArgs(len(args)) {
+			return ErrCommandUsage{Command: cmd.Name, Usage: cmd.Usage}
+		}
+		return cmd.Func(c, args)
+	}
+
+	if cvar, ok := c.cvars[name]; ok {
+		if len(args) == 0 {
+			c.printCvar(cvar)
+			return nil
+		}
+		return cvar.Set(strings.Join(args, " "))
+	}
+
+	return ErrCommandNotFound(name)
+}
+
+func (c *ConsoleSystem) printCvar(cvar *Cvar) {
+	c.Printf("%s = %s (default %s)", cvar.Name(), cvar.String(), formatCvarValue(cvar.setting.Default))
+	if cvar.Help() != "" {
+		c.Printf(" - %s", cvar.Help())
+	}
+	c.Println()
+}
+
+func (c *ConsoleSystem) addHistory(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	if n := len(c.history); n > 0 && c.history[n-1] == line {
+		return
+	}
+
+	c.history = append(c.history, line)
+	if len(c.history) > DefaultConsoleHistory {
+		c.history = c.history[len(c.history)-DefaultConsoleHistory:]
+	}
+}
+
+func (c *ConsoleSystem) nameTaken(name string) bool {
+	_, cmd := c.commands[name]
+	_, cvar := c.cvars[name]
+
+	return cmd || cvar
+}
+
+// registerBuiltins adds the builtin commands.
+func (c *ConsoleSystem) registerBuiltins() {
+	c.MustRegisterCommand(Command{
+		Name:    "help",
+		Help:    "Show help for a command or cvar, or list all commands.",
+		Usage:   "[name]",
+		MinArgs: 0,
+		MaxArgs: 1,
+		Func: func(c *ConsoleSystem, args []string) error {
+			if len(args) == 0 {
+				for _, name := range c.Names() {
+					if cmd, ok := c.commands[name]; ok {
+						c.Printf("%s %s - %s\n", cmd.Name, cmd.Usage, cmd.Help)
+					}
+				}
+				return nil
+			}
+			if cmd, ok := c.commands[args[0]]; ok {
+				c.Printf("%s %s - %s\n", cmd.Name, cmd.Usage, cmd.Help)
+				return nil
+			}
+			if cvar, ok := c.cvars[args[0]]; ok {
+				c.printCvar(cvar)
+				return nil
+			}
+			return ErrCommandNotFound(args[0])
+		},
+		Complete: func(args []string) []string {
+			if len(args) != 1 {
+				return nil
+			}
+			return filterPrefix(c.Names(), args[0])
+		},
+	})
+	c.MustRegisterCommand(Command{
+		Name:    "list",
+		Help:    "List cvars and their values.",
+		Usage:   "[prefix]",
+		MinArgs: 0,
+		MaxArgs: 1,
+		Func: func(c *ConsoleSystem, args []string) error {
+			prefix := ""
+			if len(args) == 1 {
+				prefix = args[0]
+			}
+			for _, name := range c.completeNames([]string{prefix}) {
+				c.printCvar(c.cvars[name])
+			}
+			return nil
+		},
+		Complete: c.completeNames,
+	})
+	c.MustRegisterCommand(Command{
+		Name:    "set",
+		Help:    "Set the value of a cvar.",
+		Usage:   "<name> <value>",
+		MinArgs: 2,
+		MaxArgs: 1,
+		Func: func(c *ConsoleSystem, args []string) error {
+			cvar, err := c.Cvar(args[0])
+			if err != nil {
+				return err
+			}
+			return cvar.Set(strings.Join(args[1:], " "))
+		},
+		Complete: c.completeNames,
+	})
+	c.MustRegisterCommand(Command{
+		Name:    "reset",
+		Help:    "Set a cvar back to its default value.",
+		Usage:   "<name>",
+		MinArgs: 1,
+		MaxArgs: 1,
+		Func: func(c *ConsoleSystem, args []string) error {
+			cvar, err := c.Cvar(args[0])
+			if err != nil {
+				return err
+			}
+			return cvar.Reset()
+		},
+		Complete: c.completeNames,
+	})
+	c.MustRegisterCommand(Command{
+		Name:    "toggle",
+		Help:    "Invert the value of a bool cvar.",
+		Usage:   "<name>",
+		MinArgs: 1,
+		MaxArgs: 1,
+		Func: func(c *ConsoleSystem, args []string) error {
+			cvar, err := c.Cvar(args[0])
+			if err != nil {
+				return err
+			}
+			if cvar.setting.Type != SettingBool {
+				return ErrSettingInvalid{Key: cvar.Name(), Value: cvar.Value(), Reason: "not a bool"}
+			}
+			return cvar.Set(!cvar.Bool())
+		},
+		Complete: c.completeNames,
+	})
+	c.MustRegisterCommand(Command{
+		Name:    "echo",
+		Help:    "Print the arguments.",
+		Usage:   "[text...]",
+		MinArgs: 0,
+		MaxArgs: -1,
+		Func: func(c *ConsoleSystem, args []string) error {
+			c.Println(strings.Join(args, " "))
+			return nil
+		},
+	})
+	c.MustRegisterCommand(Command{
+		Name:    "exec",
+		Help:    "Run a script.",
+		Usage:   "<file>",
+		MinArgs: 1,
+		MaxArgs: 1,
+		Func: func(c *ConsoleSystem, args []string) error {
+			return c.ExecScript(args[0])
+		},
+	})
+	c.MustRegisterCommand(Command{
+		Name:    "history",
+		Help:    "Show previously entered lines.",
+		MinArgs: 0,
+		MaxArgs: 0,
+		Func: func(c *ConsoleSystem, args []string) error {
+			for i, line := range c.history {
+				c.Printf("%d: %s\n", i+1, line)
+			}
+			return nil
+		},
+	})
+}
+
+func (cmd *Command) acceptsArgs(n int) bool {
+	if cmd.MinArgs == 0 && cmd.MaxArgs == 0 {
+		return true
+	}
+	if n < cmd.MinArgs {
+		return false
+	}
+
+	return cmd.MaxArgs < cmd.MinArgs || n <= cmd.MaxArgs
+}
+
+// Name returns the name of the cvar.
+func (v *Cvar) Name() string {
+	return v.setting.Key
+}
+
+// Help returns the description of the cvar.
+func (v *Cvar) Help() string {
+	return v.setting.Description
+}
+
+// Setting returns the setting describing the cvar.
+func (v *Cvar) Setting() Setting {
+	return v.setting
+}
+
+// Value returns the value of the cvar.
+func (v *Cvar) Value() interface{} {
+	return v.value
+}
+
+// String returns the value of the cvar formatted as it would be entered.
+func (v *Cvar) String() string {
+	return formatCvarValue(v.value)
+}
+
+// Bool returns the value of a bool cvar.
+func (v *Cvar) Bool() bool {
+	return v.value.(bool)
+}
+
+// Int returns the value of an int cvar.
+func (v *Cvar) Int() int {
+	return v.value.(int)
+}
+
+// Float returns the value of a float cvar.
+func (v *Cvar) Float() float64 {
+	return v.value.(float64)
+}
+
+// IVec2 returns the value of an ivec2 cvar.
+func (v *Cvar) IVec2() math.IVec2 {
+	return v.value.(math.IVec2)
+}
+
+// Set changes the value of the cvar. Listeners are notified if the value
+// changes.
+func (v *Cvar) Set(value interface{}) error {
+	converted, err := v.setting.Convert(value)
+	if err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(v.value, converted) {
+		return nil
+	}
+	v.value = converted
+
+	for _, fn := range v.listeners {
+		fn(v.setting.Key, converted)
+	}
+
+	return nil
+}
+
+// Reset changes the cvar back to its default value.
+func (v *Cvar) Reset() error {
+	return v.Set(v.setting.Default)
+}
+
+// Watch registers fn to be called whenever the value of the cvar changes. It
+// returns the cvar to allow chaining.
+func (v *Cvar) Watch(fn SettingListener) *Cvar {
+	v.listeners = append(v.listeners, fn)
+
+	return v
+}
+
+func formatCvarValue(value interface{}) string {
+	switch v := value.(type) {
+	case math.IVec2:
+		return fmt.Sprintf("%dx%d", v[0], v[1])
+	case string:
+		return `"` + v + `"`
+	}
+
+	return fmt.Sprint(value)
+}
+
+// parseConsoleLine splits a line into statements, and each statement into
+// words. Empty statements are omitted.
+func parseConsoleLine(line string) ([][]string, error) {
+	var stmts [][]string
+	var words []string
+	var word strings.Builder
+
+	inWord := false
+	inQuote := false
+
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endStatement := func() {
+		endWord()
+		if len(words) > 0 {
+			stmts = append(stmts, words)
+			words = nil
+		}
+	}
+
+scan:
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+
+		switch {
+		case inQuote:
+			if ch == '\\' && i+1 < len(line) {
+				i++
+				word.WriteByte(line[i])
+			} else if ch == '"' {
+				inQuote = false
+			} else {
+				word.WriteByte(ch)
+			}
+		case ch == '"':
+			inQuote = true
+			inWord = true
+		case !inWord && (ch == '#' || strings.HasPrefix(line[i:], "//")):
+			break scan
+		case ch == ';':
+			endStatement()
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n':
+			endWord()
+		default:
+			word.WriteByte(ch)
+			inWord = true
+		}
+	}
+
+	if inQuote {
+		return nil, ErrConsoleSyntax("unterminated quote")
+	}
+
+	endStatement()
+
+	return stmts, nil
+}
+
+func filterPrefix(names []string, prefix string) []string {
+	var matches []string
+
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+
+	return matches
+}
+
+// NewConsoleSystem creates a new console system with the builtin commands
+// registered. Output is written to stdout.
+func NewConsoleSystem() *ConsoleSystem {
+	c := &ConsoleSystem{
+		commands: make(map[string]*Command),
+		cvars:    make(map[string]*Cvar),
+		output:   os.Stdout,
+		mu:       &sync.Mutex{},
+	}
+
+	c.registerBuiltins()
+	for _, fn := range cvarRegistrars {
+		fn(c)
+	}
+
+	return c
+}
+
+// RegisterCvars adds a function which registers cvars with each console
+// created afterwards. Packages call it from init, so that their cvars are
+// listed by help and completion before the package is used.
+func RegisterCvars(fn func(c *ConsoleSystem)) {
+	cvarRegistrars = append(cvarRegistrars, fn)
+}
+
+// GetConsoleSystem gets the console system from the current app.
+func GetConsoleSystem() *ConsoleSystem {
+	return consoleInst
+}
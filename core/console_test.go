@@ -0,0 +1,180 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestConsole() (*ConsoleSystem, *bytes.Buffer) {
+	var out bytes.Buffer
+
+	c := NewConsoleSystem()
+	c.SetOutput(&out)
+
+	c.MustRegisterCvar(Setting{Key: "particle.rate", Type: SettingFloat, Default: 100.0, Min: 0, Max: 10000})
+	c.MustRegisterCvar(Setting{Key: "particle.attractors", Type: SettingBool, Default: true})
+	c.MustRegisterCvar(Setting{Key: "camera.mode", Type: SettingString, Default: "orbit", Options: []string{"fly", "orbit"}})
+
+	return c, &out
+}
+
+func TestParseConsoleLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want [][]string
+	}{
+		{"", nil},
+		{"echo a  b", [][]string{{"echo", "a", "b"}}},
+		{`echo "a b" c`, [][]string{{"echo", "a b", "c"}}},
+		{`echo "say \"hi\""`, [][]string{{"echo", `say "hi"`}}},
+		{"set a 1; set b 2;;", [][]string{{"set", "a", "1"}, {"set", "b", "2"}}},
+		{"set a 1 // comment", [][]string{{"set", "a", "1"}}},
+		{"# comment", nil},
+		{`echo "a;b"`, [][]string{{"echo", "a;b"}}},
+	}
+
+	for i, v := range tests {
+		got, err := parseConsoleLine(v.line)
+		if err != nil {
+			t.Errorf("Test %d: got: %v want: nil", i, err)
+		}
+		if !reflect.DeepEqual(got, v.want) {
+			t.Errorf("Test %d: got: %q want: %q", i, got, v.want)
+		}
+	}
+
+	if _, err := parseConsoleLine(`echo "open`); err == nil {
+		t.Error("unterminated quote got: nil want: error")
+	}
+}
+
+func TestConsoleSystem_Exec(t *testing.T) {
+	c, out := newTestConsole()
+
+	var changed []interface{}
+	rate, _ := c.Cvar("particle.rate")
+	rate.Watch(func(key string, value interface{}) {
+		changed = append(changed, value)
+	})
+
+	tests := []struct {
+		line  string
+		valid bool
+	}{
+		{"particle.rate 500", true},
+		{"set particle.rate 250; toggle particle.attractors", true},
+		{"particle.rate -1", false},
+		{"camera.mode fly", true},
+		{"camera.mode walk", false},
+		{"toggle camera.mode", false},
+		{"reset", false},
+		{"missing 1", false},
+	}
+
+	for i, v := range tests {
+		if err := c.Exec(v.line); (err == nil) != v.valid {
+			t.Errorf("Test %d: got: %v want valid: %v", i, err, v.valid)
+		}
+	}
+
+	if want := []interface{}{500.0, 250.0}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("got: %v want: %v", changed, want)
+	}
+	if a, _ := c.Cvar("particle.attractors"); a.Bool() {
+		t.Error("toggle got: true want: false")
+	}
+
+	out.Reset()
+	c.Exec("particle.rate")
+	if got := out.String(); got != "particle.rate = 250 (default 100)\n" {
+		t.Errorf("got: %q", got)
+	}
+
+	if n := len(c.History()); n != len(tests)+1 {
+		t.Errorf("History() got: %d want: %d", n, len(tests)+1)
+	}
+}
+
+func TestConsoleSystem_ExecReader(t *testing.T) {
+	c, out := newTestConsole()
+
+	script := "// tuning\nparticle.rate 10\n\necho done\n"
+	if err := c.ExecReader(strings.NewReader(script), "tune.cfg"); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "done\n" {
+		t.Errorf("got: %q want: %q", got, "done\n")
+	}
+	if len(c.History()) != 0 {
+		t.Error("script lines added to history")
+	}
+
+	err := c.ExecReader(strings.NewReader("echo ok\nbogus\n"), "bad.cfg")
+	if e, ok := err.(ErrScript); !ok || e.File != "bad.cfg" || e.Line != 2 {
+		t.Errorf("got: %v want: bad.cfg:2 error", err)
+	}
+}
+
+func TestConsoleSystem_Complete(t *testing.T) {
+	c, _ := newTestConsole()
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"part", []string{"particle.attractors", "particle.rate"}},
+		{"he", []string{"help"}},
+		{"camera.mode ", []string{"fly", "orbit"}},
+		{"particle.attractors t", []string{"true"}},
+		{"set cam", []string{"camera.mode"}},
+		{"echo x; rese", []string{"reset"}},
+	}
+
+	for i, v := range tests {
+		if got := c.Complete(v.line); !reflect.DeepEqual(got, v.want) {
+			t.Errorf("Test %d: got: %v want: %v", i, got, v.want)
+		}
+	}
+}
+
+func TestRegisterCvars(t *testing.T) {
+	prev := cvarRegistrars
+	defer func() { cvarRegistrars = prev }()
+
+	RegisterCvars(func(c *ConsoleSystem) {
+		c.MustRegisterCvar(Setting{Key: "fog.density", Type: SettingFloat, Default: 0.5, Min: 0, Max: 1})
+	})
+
+	c := NewConsoleSystem()
+
+	if _, err := c.Cvar("fog.density"); err != nil {
+		t.Errorf("got: %v want: nil", err)
+	}
+	if got := c.Complete("fog."); !reflect.DeepEqual(got, []string{"fog.density"}) {
+		t.Errorf("got: %v want: %v", got, []string{"fog.density"})
+	}
+}
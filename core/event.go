@@ -0,0 +1,348 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"sync"
+)
+
+var _ System = &EventSystem{}
+
+var eventInst *EventSystem
+
+const SysNameEvent = "event"
+
+// EventType identifies a kind of event. Event types are namespaced with a
+// dot, such as "window.resize".
+type EventType string
+
+// Builtin event types.
+const (
+	EventTypeWindowResize   EventType = "window.resize"
+	EventTypeWindowClose    EventType = "window.close"
+	EventTypeFileDrop       EventType = "window.drop"
+	EventTypeKey            EventType = "input.key"
+	EventTypeChar           EventType = "input.char"
+	EventTypeMouseButton    EventType = "input.mouse_button"
+	EventTypeCursorMove     EventType = "input.cursor_move"
+	EventTypeCursorEnter    EventType = "input.cursor_enter"
+	EventTypeScroll         EventType = "input.scroll"
+	EventTypeJoystick       EventType = "input.joystick"
+	EventTypeScenePush      EventType = "scene.push"
+	EventTypeScenePop       EventType = "scene.pop"
+	EventTypeAssetLoad      EventType = "asset.load"
+	EventTypeManifestLoad   EventType = "asset.manifest_load"
+	EventTypeAssetUnload    EventType = "asset.unload"
+	EventTypeManifestUnload EventType = "asset.manifest_unload"
+	EventTypeAssetReload    EventType = "asset.reload"
+	EventTypePackageMount   EventType = "asset.package_mount"
+	EventTypePackageUnmount EventType = "asset.package_unmount"
+	EventTypeSettingChanged EventType = "settings.changed"
+)
+
+// Event is a message which can be sent through the EventSystem.
+type Event interface {
+	// EventType returns the type of this event.
+	EventType() EventType
+}
+
+// EventHandler is a function which receives events.
+type EventHandler func(Event)
+
+// EventHandle identifies a subscription. It is used to unsubscribe.
+type EventHandle uint64
+
+type eventSubscriber struct {
+	handle  EventHandle
+	handler EventHandler
+}
+
+// EventSystem implements an event bus. Events may be published synchronously,
+// in which case the subscribers are called immediately, or queued, in which
+// case the subscribers are called when the queue is dispatched. The app
+// dispatches the queue at the start of every frame, before any updates.
+type EventSystem struct {
+	subscribers map[EventType][]eventSubscriber
+	types       map[EventHandle]EventType
+	queue       []Event
+	next        EventHandle
+	mu          *sync.RWMutex
+	queueMu     *sync.Mutex
+}
+
+// EventScenePush is sent when a scene has been pushed onto the active stack.
+type EventScenePush struct {
+	Name string
+}
+
+// EventScenePop is sent when a scene has been popped off the active stack.
+type EventScenePop struct {
+	Name string
+}
+
+// EventAssetLoad is sent when an asset has been loaded by a handler.
+type EventAssetLoad struct {
+	Kind     string
+	Location string
+}
+
+// EventManifestLoad is sent when all assets of a manifest have been loaded.
+type EventManifestLoad struct {
+	Name     string
+	Location string
+}
+
+// EventAssetUnload is sent when an asset has been removed from a handler.
+type EventAssetUnload struct {
+	Kind string
+	Name string
+}
+
+// EventManifestUnload is sent when the assets of a manifest have been
+// released.
+type EventManifestUnload struct {
+	Name     string
+	Location string
+}
+
+// EventAssetReload is sent when an asset has been reloaded in place because
+// one of its files changed.
+type EventAssetReload struct {
+	Kind     string
+	Name     string
+	Location string
+}
+
+// EventPackageMount is sent when a package has been mounted.
+type EventPackageMount struct {
+	Name string
+}
+
+// EventPackageUnmount is sent when a package has been unmounted.
+type EventPackageUnmount struct {
+	Name string
+}
+
+// Setup sets up the System.
+func (s *EventSystem) Setup() error {
+	if eventInst != nil {
+		return ErrSystemInit(SysNameEvent)
+	}
+	eventInst = s
+
+	return nil
+}
+
+// Teardown tears down the System.
+func (s *EventSystem) Teardown() {
+	s.UnsubscribeAll()
+	s.ClearQueue()
+}
+
+// Name returns the name of the System.
+func (s *EventSystem) Name() string {
+	return SysNameEvent
+}
+
+// Subscribe registers a handler for events of the given type. Handlers are
+// called in the order they were subscribed.
+func (s *EventSystem) Subscribe(t EventType, handler EventHandler) EventHandle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.next++
+	h := s.next
+
+	s.subscribers[t] = append(s.subscribers[t], eventSubscriber{handle: h, handler: handler})
+	s.types[h] = t
+
+	return h
+}
+
+// Unsubscribe removes the subscription with the given handle. It reports
+// whether the subscription existed.
+func (s *EventSystem) Unsubscribe(h EventHandle) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.types[h]
+	if !ok {
+		return false
+	}
+
+	subscribers := s.subscribers[t]
+	for i := range subscribers {
+		if subscribers[i].handle == h {
+			s.subscribers[t] = append(subscribers[:i:i], subscribers[i+1:]...)
+			break
+		}
+	}
+
+	if len(s.subscribers[t]) == 0 {
+		delete(s.subscribers, t)
+	}
+	delete(s.types, h)
+
+	return true
+}
+
+// UnsubscribeAll removes all subscriptions.
+func (s *EventSystem) UnsubscribeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscribers = make(map[EventType][]eventSubscriber)
+	s.types = make(map[EventHandle]EventType)
+}
+
+// Subscribers returns the number of subscribers for the given event type.
+func (s *EventSystem) Subscribers(t EventType) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.subscribers[t])
+}
+
+// Publish sends an event to all of its subscribers immediately.
+func (s *EventSystem) Publish(e Event) {
+	s.mu.RLock()
+	subscribers := s.subscribers[e.EventType()]
+	s.mu.RUnlock()
+
+	// The slice is never modified in place, so subscribers may safely
+	// subscribe and unsubscribe from within a handler.
+	for i := range subscribers {
+		subscribers[i].handler(e)
+	}
+}
+
+// Enqueue adds an event to the queue. It is safe to call from any goroutine.
+func (s *EventSystem) Enqueue(e Event) {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	s.queue = append(s.queue, e)
+}
+
+// Pending returns the number of queued events.
+func (s *EventSystem) Pending() int {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	return len(s.queue)
+}
+
+// DispatchQueued publishes all queued events in the order they were queued.
+// Events queued while dispatching are kept for the next dispatch.
+func (s *EventSystem) DispatchQueued() {
+	s.queueMu.Lock()
+	queue := s.queue
+	s.queue = nil
+	s.queueMu.Unlock()
+
+	for i := range queue {
+		s.Publish(queue[i])
+	}
+}
+
+// ClearQueue discards all queued events.
+func (s *EventSystem) ClearQueue() {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	s.queue = nil
+}
+
+// EventType returns the type of this event.
+func (e EventScenePush) EventType() EventType {
+	return EventTypeScenePush
+}
+
+// EventType returns the type of this event.
+func (e EventScenePop) EventType() EventType {
+	return EventTypeScenePop
+}
+
+// EventType returns the type of this event.
+func (e EventAssetLoad) EventType() EventType {
+	return EventTypeAssetLoad
+}
+
+// EventType returns the type of this event.
+func (e EventManifestLoad) EventType() EventType {
+	return EventTypeManifestLoad
+}
+
+// EventType returns the type of this event.
+func (e EventAssetUnload) EventType() EventType {
+	return EventTypeAssetUnload
+}
+
+// EventType returns the type of this event.
+func (e EventManifestUnload) EventType() EventType {
+	return EventTypeManifestUnload
+}
+
+// EventType returns the type of this event.
+func (e EventAssetReload) EventType() EventType {
+	return EventTypeAssetReload
+}
+
+// EventType returns the type of this event.
+func (e EventPackageMount) EventType() EventType {
+	return EventTypePackageMount
+}
+
+// EventType returns the type of this event.
+func (e EventPackageUnmount) EventType() EventType {
+	return EventTypePackageUnmount
+}
+
+// NewEventSystem creates a new event system.
+func NewEventSystem() *EventSystem {
+	return &EventSystem{
+		subscribers: make(map[EventType][]eventSubscriber),
+		types:       make(map[EventHandle]EventType),
+		mu:          &sync.RWMutex{},
+		queueMu:     &sync.Mutex{},
+	}
+}
+
+// GetEventSystem gets the event system from the current app.
+func GetEventSystem() *EventSystem {
+	return eventInst
+}
+
+// publishEvent publishes an event if the event system has been set up.
+func publishEvent(e Event) {
+	if eventInst != nil {
+		eventInst.Publish(e)
+	}
+}
+
+// enqueueEvent queues an event if the event system has been set up.
+func enqueueEvent(e Event) {
+	if eventInst != nil {
+		eventInst.Enqueue(e)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+type dummyEvent struct {
+	value int
+}
+
+func (e dummyEvent) EventType() EventType { return "test.dummy" }
+
+func TestEventSystem_Publish(t *testing.T) {
+	s := NewEventSystem()
+
+	var got []int
+	h0 := s.Subscribe("test.dummy", func(e Event) {
+		got = append(got, e.(dummyEvent).value)
+	})
+	s.Subscribe("test.dummy", func(e Event) {
+		got = append(got, e.(dummyEvent).value*10)
+	})
+	s.Subscribe("test.other", func(e Event) {
+		t.Error("handler called for wrong event type")
+	})
+
+	s.Publish(dummyEvent{1})
+	if !s.Unsubscribe(h0) {
+		t.Error("Unsubscribe() got: false want: true")
+	}
+	if s.Unsubscribe(h0) {
+		t.Error("Unsubscribe() of removed handle got: true want: false")
+	}
+	s.Publish(dummyEvent{2})
+
+	want := []int{1, 10, 20}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v want: %v", got, want)
+	}
+	if n := s.Subscribers("test.dummy"); n != 1 {
+		t.Errorf("Subscribers() got: %d want: 1", n)
+	}
+}
+
+func TestEventSystem_Queue(t *testing.T) {
+	s := NewEventSystem()
+
+	var got []int
+	s.Subscribe("test.dummy", func(e Event) {
+		v := e.(dummyEvent).value
+		got = append(got, v)
+
+		// Events queued during dispatch are deferred to the next dispatch.
+		if v < 10 {
+			s.Enqueue(dummyEvent{v * 10})
+		}
+	})
+
+	s.Enqueue(dummyEvent{1})
+	s.Enqueue(dummyEvent{2})
+
+	if len(got) != 0 {
+		t.Errorf("queued events dispatched early: %v", got)
+	}
+
+	s.DispatchQueued()
+	if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first dispatch got: %v want: %v", got, want)
+	}
+	if n := s.Pending(); n != 2 {
+		t.Errorf("Pending() got: %d want: 2", n)
+	}
+
+	s.DispatchQueued()
+	if want := []int{1, 2, 10, 20}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second dispatch got: %v want: %v", got, want)
+	}
+}
+
+func TestEventSystem_UnsubscribeDuringPublish(t *testing.T) {
+	s := NewEventSystem()
+
+	var calls int
+	var h EventHandle
+	h = s.Subscribe("test.dummy", func(e Event) {
+		calls++
+		s.Unsubscribe(h)
+	})
+	s.Subscribe("test.dummy", func(e Event) {
+		calls++
+	})
+
+	s.Publish(dummyEvent{})
+	s.Publish(dummyEvent{})
+
+	if calls != 3 {
+		t.Errorf("calls got: %d want: 3", calls)
+	}
+}
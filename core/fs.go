@@ -0,0 +1,487 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	MountHost    = ""          // MountHost is the mount point of the host filesystem.
+	MountBuiltin = "<builtin>" // MountBuiltin is the mount point of the builtin assets.
+)
+
+// fsPathRe matches paths with a mount point. Mount points are at least two
+// characters long, so that Windows drive letters are not mount points.
+var fsPathRe = regexp.MustCompile(`^([\w-]{2,}):(.*)$`)
+
+// ErrMountNotFound reports that nothing is mounted at a mount point.
+type ErrMountNotFound string
+
+func (e ErrMountNotFound) Error() string {
+	return "fs: nothing mounted at: " + string(e)
+}
+
+// ErrLayerMounted reports that a layer with the same name is already mounted
+// at a mount point.
+type ErrLayerMounted struct {
+	Point string
+	Name  string
+}
+
+func (e ErrLayerMounted) Error() string {
+	return "fs: " + e.Name + " already mounted at: " + e.Point
+}
+
+// ErrLayerNotMounted reports that a layer is not mounted at a mount point.
+type ErrLayerNotMounted struct {
+	Point string
+	Name  string
+}
+
+func (e ErrLayerNotMounted) Error() string {
+	return "fs: " + e.Name + " not mounted at: " + e.Point
+}
+
+// FS is a virtual filesystem of named mount points. Paths have the form
+// "point:name", where name is a slash separated path within the mount point.
+// Paths without a mount point refer to the host filesystem.
+//
+// Each mount point holds one or more layers, which are io/fs filesystems.
+// Layers overlay each other: a file is read from the layer with the highest
+// priority which contains it, so that mods or patches can shadow the files of
+// a base package. Layers with equal priority are searched from the most
+// recently mounted. The methods of FS are safe to call from any goroutine.
+type FS struct {
+	mounts map[string][]fsLayer
+	order  int
+	mu     *sync.RWMutex
+}
+
+type fsLayer struct {
+	name     string
+	fsys     fs.FS
+	priority int
+	order    int
+}
+
+// Mount mounts a filesystem as a layer named name at a mount point.
+func (f *FS) Mount(point, name string, fsys fs.FS, priority int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, l := range f.mounts[point] {
+		if l.name == name {
+			return ErrLayerMounted{Point: point, Name: name}
+		}
+	}
+
+	f.order++
+
+	// Readers may hold the current layers, so they are copied rather than
+	// appended to and sorted in place.
+	layers := f.mounts[point]
+	layers = append(layers[:len(layers):len(layers)], fsLayer{name: name, fsys: fsys, priority: priority, order: f.order})
+	sort.SliceStable(layers, func(i, j int) bool {
+		if layers[i].priority != layers[j].priority {
+			return layers[i].priority > layers[j].priority
+		}
+
+		return layers[i].order > layers[j].order
+	})
+
+	f.mounts[point] = layers
+
+	return nil
+}
+
+// Unmount removes the layer named name from a mount point.
+func (f *FS) Unmount(point, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	layers := f.mounts[point]
+	for i := range layers {
+		if layers[i].name == name {
+			layers = append(layers[:i:i], layers[i+1:]...)
+			if len(layers) == 0 {
+				delete(f.mounts, point)
+			} else {
+				f.mounts[point] = layers
+			}
+
+			return nil
+		}
+	}
+
+	return ErrLayerNotMounted{Point: point, Name: name}
+}
+
+// Mounted reports if anything is mounted at a mount point.
+func (f *FS) Mounted(point string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return len(f.mounts[point]) != 0
+}
+
+// Mounts returns the mount points, sorted.
+func (f *FS) Mounts() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	points := make([]string, 0, len(f.mounts))
+	for point := range f.mounts {
+		points = append(points, point)
+	}
+	sort.Strings(points)
+
+	return points
+}
+
+// Layers returns the names of the layers at a mount point, in the order they
+// are searched.
+func (f *FS) Layers(point string) []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	names := make([]string, len(f.mounts[point]))
+	for i, l := range f.mounts[point] {
+		names[i] = l.name
+	}
+
+	return names
+}
+
+// Sub returns the overlay of the layers at a mount point as an io/fs
+// filesystem. Layers mounted or unmounted later are seen by the filesystem.
+func (f *FS) Sub(point string) fs.FS {
+	return overlayFS{fs: f, point: point}
+}
+
+// Open opens the named file.
+func (f *FS) Open(p string) (fs.File, error) {
+	o, name := f.resolve(p)
+
+	return o.Open(name)
+}
+
+// ReadFile reads the named file.
+func (f *FS) ReadFile(p string) ([]byte, error) {
+	o, name := f.resolve(p)
+
+	return o.ReadFile(name)
+}
+
+// Stat returns information about the named file.
+func (f *FS) Stat(p string) (fs.FileInfo, error) {
+	o, name := f.resolve(p)
+
+	return o.Stat(name)
+}
+
+// Exists reports if the named file exists.
+func (f *FS) Exists(p string) bool {
+	_, err := f.Stat(p)
+
+	return err == nil
+}
+
+// ReadDir reads the named directory of every layer, and returns the entries
+// sorted by name. Entries of higher layers shadow those of lower layers.
+func (f *FS) ReadDir(p string) ([]fs.DirEntry, error) {
+	o, name := f.resolve(p)
+
+	return o.ReadDir(name)
+}
+
+// Glob returns the paths of the files matching pattern, sorted. Patterns use
+// the syntax of path.Match, and the returned paths include the mount point.
+func (f *FS) Glob(pattern string) ([]string, error) {
+	o, name := f.resolve(pattern)
+
+	matches, err := o.Glob(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.point != MountHost {
+		for i := range matches {
+			matches[i] = o.point + ":" + matches[i]
+		}
+	}
+
+	return matches, nil
+}
+
+// resolve returns the overlay of the mount point of a path, and the cleaned
+// name within the mount point. Names outside of the host filesystem may use
+// backslashes and relative elements, and cannot refer above the mount point.
+func (f *FS) resolve(p string) (overlayFS, string) {
+	point, name := SplitFSPath(p)
+
+	if point != MountHost {
+		name = strings.Replace(name, "\\", "/", -1)
+		name = strings.TrimPrefix(path.Clean("/"+name), "/")
+		if name == "" {
+			name = "."
+		}
+	}
+
+	return overlayFS{fs: f, point: point}, name
+}
+
+// layers returns the layers at a mount point.
+func (f *FS) layers(point string) ([]fsLayer, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	layers := f.mounts[point]
+	if len(layers) == 0 {
+		return nil, ErrMountNotFound(point)
+	}
+
+	return layers, nil
+}
+
+// overlayFS is the overlay of the layers at a mount point. Except for the host
+// filesystem, names must be valid io/fs paths.
+type overlayFS struct {
+	fs    *FS
+	point string
+}
+
+// find calls fn with each layer until it returns an error which does not
+// report that the file does not exist.
+func (o overlayFS) find(op, name string, fn func(fs.FS, string) error) error {
+	if err := o.check(op, name); err != nil {
+		return err
+	}
+
+	layers, err := o.fs.layers(o.point)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range layers {
+		if err := fn(l.fsys, name); !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// check returns an error if a name is not valid for the mount point.
+func (o overlayFS) check(op, name string) error {
+	if o.point != MountHost && !fs.ValidPath(name) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	return nil
+}
+
+// Open opens the named file. Directories are opened with the entries of
+// every layer.
+func (o overlayFS) Open(name string) (file fs.File, err error) {
+	err = o.find("open", name, func(fsys fs.FS, name string) (err error) {
+		file, err = fsys.Open(name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil || !info.IsDir() {
+		return file, err
+	}
+	file.Close()
+
+	entries, err := o.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &memDir{info: info, entries: entries}, nil
+}
+
+func (o overlayFS) ReadFile(name string) (data []byte, err error) {
+	err = o.find("read", name, func(fsys fs.FS, name string) (err error) {
+		data, err = fs.ReadFile(fsys, name)
+		return err
+	})
+
+	return data, err
+}
+
+func (o overlayFS) Stat(name string) (info fs.FileInfo, err error) {
+	err = o.find("stat", name, func(fsys fs.FS, name string) (err error) {
+		info, err = fs.Stat(fsys, name)
+		return err
+	})
+
+	return info, err
+}
+
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := o.check("readdir", name); err != nil {
+		return nil, err
+	}
+
+	layers, err := o.fs.layers(o.point)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var entries []fs.DirEntry
+	found := false
+
+	for _, l := range layers {
+		list, err := fs.ReadDir(l.fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		found = true
+		for _, e := range list {
+			if !seen[e.Name()] {
+				seen[e.Name()] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+func (o overlayFS) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	layers, err := o.fs.layers(o.point)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var matches []string
+
+	for _, l := range layers {
+		list, err := fs.Glob(l.fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range list {
+			if !seen[m] {
+				seen[m] = true
+				matches = append(matches, m)
+			}
+		}
+	}
+
+	sort.Strings(matches)
+
+	return matches, nil
+}
+
+// SplitFSPath splits a path into its mount point and the name within the mount
+// point. Paths without a mount point are in the host filesystem.
+func SplitFSPath(p string) (point, name string) {
+	if strings.HasPrefix(p, bindataPrefix) {
+		return MountBuiltin, strings.TrimPrefix(p, bindataPrefix)
+	}
+	if m := fsPathRe.FindStringSubmatch(p); m != nil {
+		return m[1], m[2]
+	}
+
+	return MountHost, p
+}
+
+// HostFS is the host filesystem. Unlike os.DirFS, names are host paths, which
+// may be absolute or relative to the working directory.
+type HostFS struct{}
+
+// Open opens the named file.
+func (HostFS) Open(name string) (fs.File, error) {
+	return os.Open(filepath.FromSlash(name))
+}
+
+// ReadFile reads the named file.
+func (HostFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.FromSlash(name))
+}
+
+// Stat returns information about the named file.
+func (HostFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(filepath.FromSlash(name))
+}
+
+// ReadDir reads the named directory.
+func (HostFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(filepath.FromSlash(name))
+}
+
+// Glob returns the names of the files matching pattern.
+func (HostFS) Glob(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.FromSlash(pattern))
+	for i := range matches {
+		matches[i] = filepath.ToSlash(matches[i])
+	}
+
+	return matches, err
+}
+
+// NewFS creates a new virtual filesystem, with the host filesystem mounted.
+func NewFS() *FS {
+	f := &FS{
+		mounts: make(map[string][]fsLayer),
+		mu:     &sync.RWMutex{},
+	}
+
+	f.Mount(MountHost, "host", HostFS{}, 0)
+
+	return f
+}
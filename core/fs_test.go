@@ -0,0 +1,183 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestFS() *FS {
+	f := NewFS()
+
+	f.Mount("game", "base", fstest.MapFS{
+		"manifest.json":      {Data: []byte("base")},
+		"shaders/a.glsl":     {Data: []byte("base a")},
+		"shaders/b.glsl":     {Data: []byte("base b")},
+		"textures/stone.png": {Data: []byte("base stone")},
+	}, 0)
+	f.Mount("game", "patch", fstest.MapFS{
+		"shaders/b.glsl": {Data: []byte("patch b")},
+	}, 0)
+	f.Mount("game", "mod", fstest.MapFS{
+		"shaders/b.glsl": {Data: []byte("mod b")},
+		"shaders/c.glsl": {Data: []byte("mod c")},
+	}, 10)
+
+	return f
+}
+
+func TestFS_ReadFile(t *testing.T) {
+	f := newTestFS()
+
+	tests := []struct {
+		path string
+		want string
+		err  bool
+	}{
+		{"game:manifest.json", "base", false},
+		{"game:shaders/a.glsl", "base a", false},
+		{"game:shaders/b.glsl", "mod b", false},
+		{"game:./shaders/../shaders/c.glsl", "mod c", false},
+		{"game:shaders/d.glsl", "", true},
+		{"missing:shaders/a.glsl", "", true},
+	}
+
+	for i, v := range tests {
+		data, err := f.ReadFile(v.path)
+		if (err != nil) != v.err {
+			t.Errorf("Test %d: got: %v want error: %v", i, err, v.err)
+			continue
+		}
+		if string(data) != v.want {
+			t.Errorf("Test %d: got: %s want: %s", i, data, v.want)
+		}
+	}
+
+	if err := f.Unmount("game", "mod"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := f.ReadFile("game:shaders/b.glsl"); string(data) != "patch b" {
+		t.Errorf("ReadFile() after Unmount got: %s want: patch b", data)
+	}
+	if got := f.Layers("game"); !reflect.DeepEqual(got, []string{"patch", "base"}) {
+		t.Errorf("Layers() got: %v want: %v", got, []string{"patch", "base"})
+	}
+}
+
+func TestFS_ReadDir(t *testing.T) {
+	f := newTestFS()
+
+	entries, err := f.ReadDir("game:shaders")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+
+	want := []string{"a.glsl", "b.glsl", "c.glsl"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDir() got: %v want: %v", names, want)
+	}
+
+	matches, err := f.Glob("game:*/[bc].glsl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = []string{"game:shaders/b.glsl", "game:shaders/c.glsl"}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("Glob() got: %v want: %v", matches, want)
+	}
+}
+
+func TestFS_Sub(t *testing.T) {
+	f := newTestFS()
+
+	err := fstest.TestFS(f.Sub("game"), "manifest.json", "shaders/a.glsl", "shaders/b.glsl", "shaders/c.glsl", "textures/stone.png")
+	if err != nil {
+		t.Error(err)
+	}
+
+	data, err := fs.ReadFile(f.Sub("game"), "shaders/b.glsl")
+	if err != nil || string(data) != "mod b" {
+		t.Errorf("ReadFile() got: %s, %v want: mod b, nil", data, err)
+	}
+}
+
+func TestFS_Mount(t *testing.T) {
+	f := newTestFS()
+
+	layerNames := func(layers []fsLayer) []string {
+		var names []string
+		for _, l := range layers {
+			names = append(names, l.name)
+		}
+		return names
+	}
+
+	held, err := f.layers("game")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := layerNames(held)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			f.ReadFile("game:shaders/b.glsl")
+		}
+	}()
+
+	tests := []struct {
+		name     string
+		priority int
+	}{
+		{"top", 20},
+		{"bottom", -10},
+		{"middle", 5},
+	}
+
+	for i, v := range tests {
+		if err := f.Mount("game", v.name, fstest.MapFS{}, v.priority); err != nil {
+			t.Errorf("Test %d: got: %v want: nil", i, err)
+		}
+	}
+	<-done
+
+	if got := layerNames(held); !reflect.DeepEqual(got, want) {
+		t.Errorf("held layers got: %v want: %v", got, want)
+	}
+
+	layers, _ := f.layers("game")
+	mounted := []string{"top", "mod", "middle", "patch", "base", "bottom"}
+	if got := layerNames(layers); !reflect.DeepEqual(got, mounted) {
+		t.Errorf("layers got: %v want: %v", got, mounted)
+	}
+}
@@ -25,6 +25,7 @@ package core
 import (
 	"fmt"
 	"math"
+	"reflect"
 	"sync"
 
 	"github.com/juju/errors"
@@ -37,6 +38,15 @@ var instanceInst *InstanceSystem
 
 const SysNameInstance = "instance"
 
+// noFreeSlot marks the end of the free list.
+const noFreeSlot = -1
+
+// DefaultReuseDelay is the number of released IDs which are kept out of use
+// before the oldest is reused. Code which still holds the ID of a released
+// object therefore cannot reach a new object with it until many more have
+// been released.
+const DefaultReuseDelay = 1024
+
 var (
 	ErrMaxIDsExceeded        = errors.New("exceeded maximum number of instance IDs")
 	ErrAssignNilObject       = errors.New("cannot assign nil object")
@@ -47,18 +57,98 @@ type ErrIDAlreadyAssigned int32
 type ErrIDNotFound int32
 
 func (e ErrIDAlreadyAssigned) Error() string {
-	return fmt.Sprintf("object with ID %08X already assigned", e)
+	return fmt.Sprintf("object with ID %08X already assigned", int32(e))
 }
 
 func (e ErrIDNotFound) Error() string {
-	return fmt.Sprintf("object with ID %08X not found", e)
+	return fmt.Sprintf("object with ID %08X not found", int32(e))
+}
+
+// Handle is a reference to an object which, unlike its ID, is never reused.
+// A handle combines the ID of the object with the generation of its slot,
+// which changes every time an object is released. The zero Handle never
+// refers to an object.
+type Handle uint64
+
+// ID returns the instance ID of the object the handle refers to.
+func (h Handle) ID() int32 {
+	return int32(uint32(h))
+}
+
+// Generation returns the generation of the handle.
+func (h Handle) Generation() uint32 {
+	return uint32(h >> 32)
+}
+
+func (h Handle) String() string {
+	return fmt.Sprintf("%08X:%d", h.ID(), h.Generation())
+}
+
+func newHandle(id int32, generation uint32) Handle {
+	return Handle(uint64(generation)<<32 | uint64(uint32(id)))
+}
+
+// WeakRef is a reference to an object which does not prevent it from being
+// released. Once the object has been released, Get returns nil.
+type WeakRef struct {
+	handle Handle
+}
+
+// NewWeakRef creates a weak reference to an assigned object. If the object
+// has not been assigned, the reference is always nil.
+func NewWeakRef(object Object) WeakRef {
+	if instanceInst == nil {
+		return WeakRef{}
+	}
+
+	return WeakRef{handle: instanceInst.HandleOf(object)}
+}
+
+// Get returns the object, or nil if it has been released.
+func (r WeakRef) Get() Object {
+	if instanceInst == nil {
+		return nil
+	}
+
+	object, _ := instanceInst.Resolve(r.handle)
+
+	return object
+}
+
+// Valid reports if the object has not been released.
+func (r WeakRef) Valid() bool {
+	return instanceInst != nil && instanceInst.Valid(r.handle)
+}
+
+// Handle returns the handle of the object.
+func (r WeakRef) Handle() Handle {
+	return r.handle
+}
+
+// instanceSlot holds an object, or links to the next free slot.
+type instanceSlot struct {
+	object     Object
+	stack      []uintptr
+	generation uint32
+	nextFree   int32
 }
 
 // InstanceSystem implements a resource tracking system.
+//
+// Objects are stored in slots, and the ID of an object is the index of its
+// slot plus one. Released slots are queued in a free list and reused oldest
+// first, once more than DefaultReuseDelay are free, so assigning an ID takes
+// constant time. Every release increments the generation of the slot, so a
+// Handle to a released object is never valid again, even when its ID has been
+// reused.
 type InstanceSystem struct {
-	objects map[int32]Object
-	next    int32
-	mu      *sync.RWMutex
+	slots      []instanceSlot
+	freeHead   int32
+	freeTail   int32
+	freeCount  int
+	reuseDelay int
+	count      int
+	mu         *sync.RWMutex
 }
 
 // Setup sets up the System.
@@ -97,7 +187,9 @@ func (s *InstanceSystem) Assign(object Object) error {
 		return err
 	}
 
-	s.objects[id] = object
+	s.slots[id-1].object = object
+	s.slots[id-1].stack = callerStack()
+	s.count++
 	object.SetID(id)
 
 	logrus.Debugf("Assigned ID %08X to %s", id, object.Name())
@@ -120,21 +212,12 @@ func (s *InstanceSystem) Release(ids ...int32) {
 			continue
 		}
 
-		if _, ok := s.objects[v]; !ok {
+		if s.slot(v) == nil {
 			logrus.Error(ErrIDNotFound(v))
 			continue
 		}
 
-		if s.objects[v] == nil {
-			logrus.Warnf("Attempted to release nil object %08X", v)
-		} else {
-			s.objects[v].Dealloc()
-			s.objects[v].Release()
-		}
-
-		delete(s.objects, v)
-
-		logrus.Debugf("Released ID %08X", v)
+		s.release(v)
 	}
 }
 
@@ -142,61 +225,155 @@ func (s *InstanceSystem) ReleaseAll() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for v := range s.objects {
-		if _, ok := s.objects[v]; !ok {
-			logrus.Error(ErrIDNotFound(v))
-			continue
-		}
-
-		if s.objects[v] == nil {
-			logrus.Warnf("Attempted to release nil object %08X", v)
-		} else {
-			s.objects[v].Dealloc()
-			s.objects[v].Release()
+	for i := range s.slots {
+		if s.slots[i].object != nil {
+			s.release(int32(i + 1))
 		}
+	}
+}
 
-		delete(s.objects, v)
-
-		logrus.Debugf("Released ID %08X", v)
+// release deallocates an object and adds its slot to the end of the free
+// list. The lock must be held.
+func (s *InstanceSystem) release(id int32) {
+	slot := &s.slots[id-1]
+
+	object := slot.object
+	object.Dealloc()
+	object.Release()
+
+	slot.object = nil
+	slot.stack = nil
+	slot.generation++
+	slot.nextFree = noFreeSlot
+	if s.freeTail == noFreeSlot {
+		s.freeHead = id - 1
+	} else {
+		s.slots[s.freeTail].nextFree = id - 1
 	}
+	s.freeTail = id - 1
+	s.freeCount++
+	s.count--
+
+	logrus.Debugf("Released ID %08X", id)
 }
 
+// nextID takes the oldest slot from the free list if more than the reuse
+// delay are free, or adds a new slot otherwise, and returns its ID. The lock
+// must be held.
 func (s *InstanceSystem) nextID() (int32, error) {
+	if s.freeCount > s.reuseDelay {
+		i := s.freeHead
+		s.freeHead = s.slots[i].nextFree
+		if s.freeHead == noFreeSlot {
+			s.freeTail = noFreeSlot
+		}
+		s.slots[i].nextFree = noFreeSlot
+		s.freeCount--
 
-	if len(s.objects) >= math.MaxInt32 {
+		return i + 1, nil
+	}
+
+	if len(s.slots) >= math.MaxInt32-1 {
 		return 0, ErrMaxIDsExceeded
 	}
 
-	id := s.next + 1
-	_, ok := s.objects[id]
+	s.slots = append(s.slots, instanceSlot{nextFree: noFreeSlot})
 
-	for ok {
-		id := s.next + 1
-		_, ok = s.objects[id]
-	}
+	return int32(len(s.slots)), nil
+}
 
-	s.next = id
+// slot returns the slot holding the object with the given ID, or nil if
+// there is no such object. The lock must be held.
+func (s *InstanceSystem) slot(id int32) *instanceSlot {
+	if id <= 0 || int(id) > len(s.slots) || s.slots[id-1].object == nil {
+		return nil
+	}
 
-	return s.next, nil
+	return &s.slots[id-1]
 }
 
 func (s *InstanceSystem) Get(id int32) (Object, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	object, ok := s.objects[id]
-	if !ok {
+	slot := s.slot(id)
+	if slot == nil {
 		return nil, ErrIDNotFound(id)
 	}
 
-	return object, nil
+	return slot.object, nil
+}
+
+// HandleOf returns a handle to an assigned object. The zero Handle is
+// returned if the object has not been assigned.
+func (s *InstanceSystem) HandleOf(object Object) Handle {
+	if object == nil {
+		return 0
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	slot := s.slot(object.ID())
+	if slot == nil || slot.object != object {
+		return 0
+	}
+
+	return newHandle(object.ID(), slot.generation)
+}
+
+// Resolve returns the object a handle refers to. It returns false if the
+// object has been released.
+func (s *InstanceSystem) Resolve(h Handle) (Object, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	slot := s.slot(h.ID())
+	if slot == nil || slot.generation != h.Generation() {
+		return nil, false
+	}
+
+	return slot.object, true
+}
+
+// Valid reports if a handle refers to an object which has not been released.
+func (s *InstanceSystem) Valid(h Handle) bool {
+	_, ok := s.Resolve(h)
+
+	return ok
+}
+
+// Count returns the number of objects.
+func (s *InstanceSystem) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.count
+}
+
+// CountByType returns the number of objects of each type, keyed by the name
+// of the type.
+func (s *InstanceSystem) CountByType() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for i := range s.slots {
+		if s.slots[i].object != nil {
+			counts[reflect.TypeOf(s.slots[i].object).String()]++
+		}
+	}
+
+	return counts
 }
 
 // NewInstance creates a new instance system.
 func NewInstanceSystem() *InstanceSystem {
 	s := &InstanceSystem{
-		objects: make(map[int32]Object),
-		mu:      &sync.RWMutex{},
+		freeHead:   noFreeSlot,
+		freeTail:   noFreeSlot,
+		reuseDelay: DefaultReuseDelay,
+		mu:         &sync.RWMutex{},
 	}
 
 	return s
@@ -0,0 +1,150 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"testing"
+)
+
+type testObject struct {
+	BaseObject
+	deallocs int
+}
+
+func (o *testObject) Dealloc() {
+	o.deallocs++
+}
+
+func newTestObject(name string) *testObject {
+	o := &testObject{}
+	o.SetName(name)
+
+	return o
+}
+
+func TestInstanceSystem_Reuse(t *testing.T) {
+	s := NewInstanceSystem()
+	s.reuseDelay = 2
+
+	objects := make([]*testObject, 4)
+	for i := range objects {
+		objects[i] = newTestObject("test")
+		s.MustAssign(objects[i])
+	}
+
+	tests := []struct {
+		release []int32
+		want    int32
+	}{
+		{[]int32{2}, 5},
+		{[]int32{1, 3}, 2},
+		{[]int32{5}, 1},
+		{nil, 6},
+	}
+
+	for i, v := range tests {
+		s.Release(v.release...)
+
+		o := newTestObject("test")
+		if err := s.Assign(o); err != nil {
+			t.Fatalf("Test %d: got: %v want: nil", i, err)
+		}
+		if o.ID() != v.want {
+			t.Errorf("Test %d: got: %d want: %d", i, o.ID(), v.want)
+		}
+	}
+
+	if objects[1].deallocs != 1 || objects[1].ID() != 0 {
+		t.Errorf("released object got: deallocs %d ID %d want: 1, 0", objects[1].deallocs, objects[1].ID())
+	}
+	if n := s.Count(); n != 4 {
+		t.Errorf("Count() got: %d want: 4", n)
+	}
+
+	// By default, a released ID is not reused by the next objects.
+	s = NewInstanceSystem()
+	a := newTestObject("a")
+	s.MustAssign(a)
+	s.Release(a.ID())
+
+	b := newTestObject("b")
+	s.MustAssign(b)
+	if b.ID() == 1 {
+		t.Errorf("got: ID %d want: a new ID", b.ID())
+	}
+}
+
+func TestInstanceSystem_Handle(t *testing.T) {
+	s := NewInstanceSystem()
+	s.reuseDelay = 0
+
+	a := newTestObject("a")
+	s.MustAssign(a)
+	h := s.HandleOf(a)
+
+	if o, ok := s.Resolve(h); !ok || o != a {
+		t.Errorf("Resolve() got: %v, %v want: a, true", o, ok)
+	}
+
+	s.Release(a.ID())
+
+	// The ID is reused, but the stale handle must not alias the new object.
+	b := newTestObject("b")
+	s.MustAssign(b)
+
+	if b.ID() != h.ID() {
+		t.Fatalf("got: ID %d want: %d", b.ID(), h.ID())
+	}
+	if s.Valid(h) {
+		t.Error("stale handle got: valid want: invalid")
+	}
+	if hb := s.HandleOf(b); hb == h || !s.Valid(hb) {
+		t.Errorf("got: %v want: valid handle different from %v", hb, h)
+	}
+	if s.Valid(0) || s.HandleOf(newTestObject("c")) != 0 {
+		t.Error("zero handle got: valid want: invalid")
+	}
+}
+
+func TestWeakRef(t *testing.T) {
+	prev := instanceInst
+	instanceInst = NewInstanceSystem()
+	defer func() { instanceInst = prev }()
+
+	o := newTestObject("weak")
+	instanceInst.MustAssign(o)
+
+	r := NewWeakRef(o)
+	if r.Get() != o || !r.Valid() {
+		t.Errorf("got: %v want: %v", r.Get(), o)
+	}
+
+	instanceInst.Release(o.ID())
+
+	if r.Get() != nil || r.Valid() {
+		t.Errorf("released got: %v want: nil", r.Get())
+	}
+	if (WeakRef{}).Get() != nil {
+		t.Error("zero WeakRef got: object want: nil")
+	}
+}
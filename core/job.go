@@ -0,0 +1,381 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var _ System = &JobSystem{}
+
+var jobInst *JobSystem
+
+const SysNameJob = "job"
+
+const (
+	// DefaultJobQueueSize is the number of jobs which may be waiting for a
+	// worker before Go blocks.
+	DefaultJobQueueSize = 256
+
+	// DefaultMainBudget is the default amount of time per frame spent running
+	// main thread jobs.
+	DefaultMainBudget = 4 * time.Millisecond
+)
+
+// ErrJobSystemClosed reports that a job was submitted after teardown.
+var ErrJobSystemClosed = errors.New("job: job system closed")
+
+// ErrJobPanic reports that a job panicked.
+type ErrJobPanic struct {
+	Value interface{}
+}
+
+func (e ErrJobPanic) Error() string {
+	return fmt.Sprintf("job: panic: %v", e.Value)
+}
+
+// JobFunc is a unit of work which produces a value or an error.
+type JobFunc func() (interface{}, error)
+
+// JobSystem runs work on a bounded pool of worker goroutines, and provides a
+// queue for work which must run on the main thread, such as OpenGL calls.
+//
+// The main thread queue is drained by the app every frame, within a time
+// budget. A typical loader decodes data with Go and uploads the result from a
+// Then callback, which always runs on the main thread.
+type JobSystem struct {
+	queue      chan func()
+	quit       chan struct{}
+	main       []mainJob
+	workers    int
+	closed     bool
+	mainClosed bool
+	wg         *sync.WaitGroup
+	sending    *sync.WaitGroup
+	mu         *sync.RWMutex
+	mainMu     *sync.Mutex
+}
+
+// mainJob is a job queued for the main thread. If the job system is torn
+// down before it runs, cancel is called instead, if it is not nil.
+type mainJob struct {
+	run    func()
+	cancel func()
+}
+
+// Future holds the result of a job which may not have completed yet.
+type Future struct {
+	jobs      *JobSystem
+	done      chan struct{}
+	value     interface{}
+	err       error
+	callbacks []func(interface{}, error)
+	mu        *sync.Mutex
+}
+
+// Setup sets up the System.
+func (s *JobSystem) Setup() error {
+	if jobInst != nil {
+		return ErrSystemInit(SysNameJob)
+	}
+	jobInst = s
+
+	s.start()
+
+	return nil
+}
+
+// Teardown tears down the System. Jobs which have already been submitted are
+// allowed to finish, but queued main thread jobs are not run: futures from
+// Main complete with ErrJobSystemClosed, Then callbacks are called with
+// ErrJobSystemClosed, and functions from RunOnMain are discarded. Teardown
+// must be called from the main thread.
+func (s *JobSystem) Teardown() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.quit)
+	s.mu.Unlock()
+
+	// Calls to Go blocked on a full queue give up once quit is closed, so
+	// the queue can be closed after they return.
+	s.sending.Wait()
+	close(s.queue)
+
+	s.wg.Wait()
+
+	s.mainMu.Lock()
+	pending := s.main
+	s.main = nil
+	s.mainClosed = true
+	s.mainMu.Unlock()
+
+	for i := range pending {
+		if pending[i].cancel != nil {
+			pending[i].cancel()
+		}
+	}
+}
+
+// Name returns the name of the System.
+func (s *JobSystem) Name() string {
+	return SysNameJob
+}
+
+// Workers returns the number of worker goroutines.
+func (s *JobSystem) Workers() int {
+	return s.workers
+}
+
+// Go runs fn on a worker goroutine. If all workers are busy and the queue is
+// full, Go blocks until there is room, or until the job system is torn down.
+// Jobs submitted after teardown complete with ErrJobSystemClosed.
+func (s *JobSystem) Go(fn JobFunc) *Future {
+	f := s.newFuture()
+
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		f.complete(nil, ErrJobSystemClosed)
+		return f
+	}
+	s.sending.Add(1)
+	s.mu.RUnlock()
+
+	defer s.sending.Done()
+
+	select {
+	case s.queue <- func() { f.complete(runJob(fn)) }:
+	case <-s.quit:
+		f.complete(nil, ErrJobSystemClosed)
+	}
+
+	return f
+}
+
+// Main runs fn on the main thread during the next drain of the main thread
+// queue.
+func (s *JobSystem) Main(fn JobFunc) *Future {
+	f := s.newFuture()
+
+	s.queueMain(func() {
+		f.complete(runJob(fn))
+	}, func() {
+		f.complete(nil, ErrJobSystemClosed)
+	})
+
+	return f
+}
+
+// RunOnMain queues fn to run on the main thread. It is safe to call from any
+// goroutine. After teardown, fn is discarded.
+func (s *JobSystem) RunOnMain(fn func()) {
+	s.queueMain(fn, nil)
+}
+
+// queueMain queues run to run on the main thread. If the job system has been
+// torn down, cancel is called immediately instead.
+func (s *JobSystem) queueMain(run, cancel func()) {
+	s.mainMu.Lock()
+	if s.mainClosed {
+		s.mainMu.Unlock()
+		if cancel != nil {
+			cancel()
+		}
+		return
+	}
+	s.main = append(s.main, mainJob{run: run, cancel: cancel})
+	s.mainMu.Unlock()
+}
+
+// MainPending returns the number of jobs waiting to run on the main thread.
+func (s *JobSystem) MainPending() int {
+	s.mainMu.Lock()
+	defer s.mainMu.Unlock()
+
+	return len(s.main)
+}
+
+// DrainMain runs queued main thread jobs in the order they were queued until
+// the queue is empty or budget has elapsed. At least one job is run if any
+// are queued, so progress is always made. If budget is zero or negative, the
+// queue is drained completely, including jobs queued while draining. It
+// returns the number of jobs run. DrainMain must only be called from the main
+// thread.
+func (s *JobSystem) DrainMain(budget time.Duration) int {
+	var count int
+
+	deadline := time.Now().Add(budget)
+
+	for {
+		s.mainMu.Lock()
+		if len(s.main) == 0 {
+			s.mainMu.Unlock()
+			return count
+		}
+		job := s.main[0]
+		s.main[0] = mainJob{}
+		s.main = s.main[1:]
+		s.mainMu.Unlock()
+
+		job.run()
+		count++
+
+		if budget > 0 && time.Now().After(deadline) {
+			return count
+		}
+	}
+}
+
+func (s *JobSystem) start() {
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+
+	logrus.Debugf("[Job] Started %d workers", s.workers)
+}
+
+func (s *JobSystem) worker() {
+	defer s.wg.Done()
+
+	for job := range s.queue {
+		job()
+	}
+}
+
+func (s *JobSystem) newFuture() *Future {
+	return &Future{
+		jobs: s,
+		done: make(chan struct{}),
+		mu:   &sync.Mutex{},
+	}
+}
+
+// Done returns a channel which is closed when the job has completed.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Ready reports if the job has completed.
+func (f *Future) Ready() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait blocks until the job has completed and returns its result. Waiting on
+// the main thread for a job which itself needs the main thread deadlocks.
+func (f *Future) Wait() (interface{}, error) {
+	<-f.done
+
+	return f.value, f.err
+}
+
+// Then registers fn to be called on the main thread with the result of the
+// job once it has completed. If the job system is torn down first, fn is
+// called with ErrJobSystemClosed instead. It returns the Future to allow
+// chaining.
+func (f *Future) Then(fn func(interface{}, error)) *Future {
+	f.mu.Lock()
+	if !f.Ready() {
+		f.callbacks = append(f.callbacks, fn)
+		f.mu.Unlock()
+		return f
+	}
+	value, err := f.value, f.err
+	f.mu.Unlock()
+
+	f.jobs.callback(fn, value, err)
+
+	return f
+}
+
+func (f *Future) complete(value interface{}, err error) {
+	f.mu.Lock()
+	f.value = value
+	f.err = err
+	close(f.done)
+	callbacks := f.callbacks
+	f.callbacks = nil
+	f.mu.Unlock()
+
+	for i := range callbacks {
+		f.jobs.callback(callbacks[i], value, err)
+	}
+}
+
+// callback queues a call of fn with the result of a job on the main thread.
+func (s *JobSystem) callback(fn func(interface{}, error), value interface{}, err error) {
+	s.queueMain(func() {
+		fn(value, err)
+	}, func() {
+		fn(nil, ErrJobSystemClosed)
+	})
+}
+
+func runJob(fn JobFunc) (value interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			value = nil
+			err = ErrJobPanic{Value: r}
+		}
+	}()
+
+	return fn()
+}
+
+// NewJobSystem creates a new job system with the given number of workers. If
+// workers is zero or negative, one worker per CPU is used.
+func NewJobSystem(workers int) *JobSystem {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	return &JobSystem{
+		queue:   make(chan func(), DefaultJobQueueSize),
+		quit:    make(chan struct{}),
+		workers: workers,
+		wg:      &sync.WaitGroup{},
+		sending: &sync.WaitGroup{},
+		mu:      &sync.RWMutex{},
+		mainMu:  &sync.Mutex{},
+	}
+}
+
+// GetJobSystem gets the job system from the current app.
+func GetJobSystem() *JobSystem {
+	return jobInst
+}
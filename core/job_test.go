@@ -0,0 +1,200 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJobSystem_Go(t *testing.T) {
+	s := NewJobSystem(4)
+	s.start()
+	defer s.Teardown()
+
+	futures := make([]*Future, 16)
+	for i := range futures {
+		v := i
+		futures[i] = s.Go(func() (interface{}, error) {
+			return v * v, nil
+		})
+	}
+
+	for i := range futures {
+		v, err := futures[i].Wait()
+		if err != nil {
+			t.Errorf("Test %d: got: %v want: nil", i, err)
+		}
+		if v.(int) != i*i {
+			t.Errorf("Test %d: got: %v want: %v", i, v, i*i)
+		}
+	}
+}
+
+func TestJobSystem_Then(t *testing.T) {
+	s := NewJobSystem(2)
+	s.start()
+	defer s.Teardown()
+
+	errFail := errors.New("fail")
+
+	var got []interface{}
+	var gotErr error
+
+	ok := s.Go(func() (interface{}, error) {
+		return "decoded", nil
+	}).Then(func(v interface{}, err error) {
+		got = append(got, v)
+	})
+	fail := s.Go(func() (interface{}, error) {
+		return nil, errFail
+	}).Then(func(v interface{}, err error) {
+		gotErr = err
+	})
+	panics := s.Go(func() (interface{}, error) {
+		panic("boom")
+	})
+
+	ok.Wait()
+	fail.Wait()
+	if _, err := panics.Wait(); err == nil {
+		t.Error("panicking job got: nil want: ErrJobPanic")
+	}
+
+	// Callbacks only run when the main thread queue is drained.
+	if len(got) != 0 || gotErr != nil {
+		t.Error("callback ran before DrainMain")
+	}
+	if n := s.DrainMain(0); n != 2 {
+		t.Errorf("DrainMain() got: %d want: 2", n)
+	}
+	if len(got) != 1 || got[0] != "decoded" {
+		t.Errorf("got: %v want: [decoded]", got)
+	}
+	if gotErr != errFail {
+		t.Errorf("got: %v want: %v", gotErr, errFail)
+	}
+
+	// Then on a completed future still defers to the main thread.
+	ok.Then(func(v interface{}, err error) {
+		got = append(got, v)
+	})
+	s.DrainMain(0)
+	if len(got) != 2 {
+		t.Errorf("got: %d callbacks want: 2", len(got))
+	}
+}
+
+func TestJobSystem_DrainMainBudget(t *testing.T) {
+	s := NewJobSystem(1)
+
+	var count int
+	for i := 0; i < 4; i++ {
+		s.RunOnMain(func() {
+			count++
+			time.Sleep(2 * time.Millisecond)
+		})
+	}
+
+	// A budget shorter than one job still runs a single job.
+	if n := s.DrainMain(time.Nanosecond); n != 1 {
+		t.Errorf("DrainMain() got: %d want: 1", n)
+	}
+	if n := s.MainPending(); n != 3 {
+		t.Errorf("MainPending() got: %d want: 3", n)
+	}
+	s.DrainMain(0)
+	if count != 4 {
+		t.Errorf("got: %d want: 4", count)
+	}
+}
+
+func TestJobSystem_Closed(t *testing.T) {
+	s := NewJobSystem(1)
+	s.start()
+	s.Teardown()
+
+	if _, err := s.Go(func() (interface{}, error) { return nil, nil }).Wait(); err != ErrJobSystemClosed {
+		t.Errorf("got: %v want: %v", err, ErrJobSystemClosed)
+	}
+}
+
+func TestJobSystem_TeardownPending(t *testing.T) {
+	s := NewJobSystem(1)
+	s.start()
+
+	var thenErr error
+	done := s.Go(func() (interface{}, error) { return nil, nil })
+	done.Wait()
+	done.Then(func(v interface{}, err error) {
+		thenErr = err
+	})
+
+	main := s.Main(func() (interface{}, error) { return "main", nil })
+
+	// Fill the queue from the only worker, so its last Go blocks until
+	// teardown.
+	var last *Future
+	blocked := s.Go(func() (interface{}, error) {
+		for i := 0; i < DefaultJobQueueSize; i++ {
+			s.Go(func() (interface{}, error) { return nil, nil })
+		}
+		last = s.Go(func() (interface{}, error) { return nil, nil })
+
+		return nil, nil
+	})
+
+	finished := make(chan struct{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.Teardown()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Teardown did not return")
+	}
+
+	blocked.Wait()
+	if _, err := last.Wait(); err != ErrJobSystemClosed {
+		t.Errorf("blocked Go got: %v want: %v", err, ErrJobSystemClosed)
+	}
+	if _, err := main.Wait(); err != ErrJobSystemClosed {
+		t.Errorf("Main got: %v want: %v", err, ErrJobSystemClosed)
+	}
+	if thenErr != ErrJobSystemClosed {
+		t.Errorf("Then got: %v want: %v", thenErr, ErrJobSystemClosed)
+	}
+
+	// Callbacks registered after teardown are called immediately.
+	thenErr = nil
+	main.Then(func(v interface{}, err error) {
+		thenErr = err
+	})
+	if thenErr != ErrJobSystemClosed {
+		t.Errorf("late Then got: %v want: %v", thenErr, ErrJobSystemClosed)
+	}
+}
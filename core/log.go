@@ -0,0 +1,98 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+var _ logrus.Hook = &LogRing{}
+
+// DefaultLogLines is the default number of lines kept by a LogRing.
+const DefaultLogLines = 200
+
+// LogRing is a logrus hook which keeps the most recent log lines, such as for
+// inclusion in a crash report.
+type LogRing struct {
+	lines     []string
+	head      int
+	count     int
+	formatter logrus.Formatter
+	mu        *sync.Mutex
+}
+
+// Levels returns the log levels recorded by the hook.
+func (r *LogRing) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire records a log entry.
+func (r *LogRing) Fire(entry *logrus.Entry) error {
+	data, err := r.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lines[r.head] = strings.TrimSuffix(string(data), "\n")
+	r.head = (r.head + 1) % len(r.lines)
+	if r.count < len(r.lines) {
+		r.count++
+	}
+
+	return nil
+}
+
+// Lines returns the recorded log lines, oldest first.
+func (r *LogRing) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lines := make([]string, 0, r.count)
+	start := (r.head - r.count + len(r.lines)) % len(r.lines)
+
+	for i := 0; i < r.count; i++ {
+		lines = append(lines, r.lines[(start+i)%len(r.lines)])
+	}
+
+	return lines
+}
+
+// NewLogRing creates a new LogRing which keeps the given number of lines. If
+// size is zero or negative, DefaultLogLines is used.
+func NewLogRing(size int) *LogRing {
+	if size <= 0 {
+		size = DefaultLogLines
+	}
+
+	return &LogRing{
+		lines:     make([]string, size),
+		formatter: &logrus.TextFormatter{DisableColors: true, FullTimestamp: true},
+		mu:        &sync.Mutex{},
+	}
+}
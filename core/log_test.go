@@ -0,0 +1,77 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogRing(t *testing.T) {
+	tests := []struct {
+		size  int
+		count int
+		want  []string
+	}{
+		{size: 3, count: 0, want: []string{}},
+		{size: 3, count: 2, want: []string{"line 0", "line 1"}},
+		{size: 3, count: 3, want: []string{"line 0", "line 1", "line 2"}},
+		{size: 3, count: 7, want: []string{"line 4", "line 5", "line 6"}},
+		{size: 1, count: 2, want: []string{"line 1"}},
+	}
+
+	for i, v := range tests {
+		r := NewLogRing(v.size)
+		r.formatter = &logrus.TextFormatter{DisableColors: true, DisableTimestamp: true}
+
+		for j := 0; j < v.count; j++ {
+			entry := logrus.NewEntry(logrus.StandardLogger())
+			entry.Level = logrus.InfoLevel
+			entry.Message = "line " + string(rune('0'+j))
+
+			if err := r.Fire(entry); err != nil {
+				t.Fatalf("Test %d: got: %v want: nil", i, err)
+			}
+		}
+
+		got := r.Lines()
+		msgs := make([]string, len(got))
+		for j := range got {
+			if strings.HasSuffix(got[j], "\n") {
+				t.Errorf("Test %d: got: %q want: no trailing newline", i, got[j])
+			}
+			msgs[j] = strings.TrimSuffix(strings.TrimPrefix(got[j], `level=info msg="`), `"`)
+		}
+
+		if !reflect.DeepEqual(msgs, v.want) {
+			t.Errorf("Test %d: got: %v want: %v", i, msgs, v.want)
+		}
+	}
+
+	if r := NewLogRing(0); len(r.lines) != DefaultLogLines {
+		t.Errorf("got: %d lines want: %d", len(r.lines), DefaultLogLines)
+	}
+}
@@ -1,5 +1,5 @@
 /*
-Copyright (c) 2017 HaakenLabs
+Copyright (c) 2018 HaakenLabs
 
 Permission is hereby granted, free of charge, to any person obtaining a copy
 of this software and associated documentation files (the "Software"), to deal
@@ -24,11 +24,19 @@ package core
 
 import (
 	"archive/zip"
+	"crypto/ed25519"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"io/fs"
+	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
@@ -39,10 +47,28 @@ var (
 	pkgRoot      = "assets"
 )
 
+var _ fs.ReadDirFS = &Package{}
+var _ fs.ReadFileFS = &Package{}
+var _ fs.StatFS = &Package{}
+
+// Package is a zip archive of assets. Mounting a package indexes its entries,
+// so reads do not scan the archive. A mounted package is an io/fs filesystem,
+// and its methods are safe to call from any goroutine.
 type Package struct {
 	name   string
 	path   string
-	reader *zip.ReadCloser
+	src    io.ReaderAt
+	size   int64
+	closer io.Closer
+	reader *zip.Reader
+	index  map[string]*zip.File
+	dirs   map[string][]fs.DirEntry
+	mu     *sync.RWMutex
+
+	verifier *PackageVerifier
+	policy   PackagePolicy
+	hashes   *PackageIndex
+	signer   ed25519.PublicKey
 }
 
 // ErrPackageNotFound reports that package was not found/mounted.
@@ -68,47 +94,162 @@ func (e ErrPackageFileNotFound) Error() string {
 	return fmt.Sprintf("fs: file '%s' in package '%s' not found", e.file, e.pkg)
 }
 
-func NewPackage(name string) *Package {
-	p := &Package{
-		name: name,
-	}
+// Is reports that the file does not exist, so that the error matches
+// fs.ErrNotExist.
+func (e ErrPackageFileNotFound) Is(target error) bool {
+	return target == fs.ErrNotExist
+}
 
-	pkgPath := filepath.Join(pkgRoot, p.name)
+// ErrPackageInvalid reports that a package could not be opened as an archive.
+type ErrPackageInvalid struct {
+	Package string
+	Err     error
+}
+
+func (e ErrPackageInvalid) Error() string {
+	return fmt.Sprintf("fs: package '%s' is invalid: %v", e.Package, e.Err)
+}
+
+// ErrPackageCorrupt reports that an entry of a package does not match its
+// recorded size or checksum.
+type ErrPackageCorrupt struct {
+	Package string
+	File    string
+	Err     error
+}
+
+func (e ErrPackageCorrupt) Error() string {
+	return fmt.Sprintf("fs: file '%s' in package '%s' is corrupt: %v", e.File, e.Package, e.Err)
+}
+
+// Unwrap returns the reason the file is corrupt.
+func (e ErrPackageCorrupt) Unwrap() error {
+	return e.Err
+}
+
+// NewPackage creates a package by name, which is read from the assets
+// directory of the working directory.
+func NewPackage(name string) *Package {
+	pkgPath := filepath.Join(pkgRoot, name)
 	if !strings.HasSuffix(pkgPath, pkgExtension) {
 		pkgPath = fmt.Sprintf("%s%s", pkgPath, pkgExtension)
 	}
 
-	p.path = pkgPath
+	return NewPackageFile(name, pkgPath)
+}
 
-	return p
+// NewPackageFile creates a package by name, which is read from the file at
+// the given path.
+func NewPackageFile(name, file string) *Package {
+	return &Package{
+		name: name,
+		path: file,
+		mu:   &sync.RWMutex{},
+	}
 }
 
+// NewPackageReader creates a package by name, which is read from r. The
+// package does not close r when it is unmounted.
+func NewPackageReader(name string, r io.ReaderAt, size int64) *Package {
+	return &Package{
+		name: name,
+		src:  r,
+		size: size,
+		mu:   &sync.RWMutex{},
+	}
+}
+
+// Mount opens the package and indexes its entries. If the package has a
+// verifier, its signature is checked according to the verifier's policy.
 func (p *Package) Mount() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if p.reader != nil {
 		return ErrPackageMounted(p.name)
 	}
 
-	reader, err := zip.OpenReader(p.path)
+	src, size := p.src, p.size
+	var closer io.Closer
+
+	if src == nil {
+		f, err := os.Open(p.path)
+		if err != nil {
+			return err
+		}
+
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		src, size, closer = f, info.Size(), f
+	}
+
+	reader, err := zip.NewReader(src, size)
 	if err != nil {
-		panic(err)
+		if closer != nil {
+			closer.Close()
+		}
+
+		return ErrPackageInvalid{Package: p.name, Err: err}
+	}
+
+	p.index, p.dirs = indexPackage(reader)
+
+	if err := p.verifyMount(); err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		p.index, p.dirs = nil, nil
+
+		return err
 	}
 
 	p.reader = reader
+	p.closer = closer
 
 	logrus.Info("Mounted package: ", p.name)
 
 	return nil
 }
 
+// Unmount closes the package.
 func (p *Package) Unmount() error {
-	err := p.reader.Close()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.reader == nil {
+		return ErrPackageNotMounted(p.name)
+	}
+
+	var err error
+	if p.closer != nil {
+		err = p.closer.Close()
+	}
+
 	p.reader = nil
+	p.closer = nil
+	p.index = nil
+	p.dirs = nil
+	p.policy = PackageVerifyOff
+	p.hashes = nil
+	p.signer = nil
 
 	logrus.Info("Unmounted package: ", p.name)
 
 	return err
 }
 
+// Mounted reports if the package is mounted.
+func (p *Package) Mounted() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.reader != nil
+}
+
 func (p *Package) Name() string {
 	return p.name
 }
@@ -117,37 +258,299 @@ func (p *Package) Path() string {
 	return p.path
 }
 
-func (p *Package) Read(filename string, w io.Writer) error {
+// Files returns the names of the files in the package, sorted.
+func (p *Package) Files() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	files := make([]string, 0, len(p.index))
+	for name := range p.index {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+// Open opens the named file in the package. If the package was mounted with
+// verification, the file is checked against the content hash index of the
+// package once it has been read to its indexed size, and a read which goes
+// past that size fails. Data read before then has not been checked yet.
+func (p *Package) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	if p.reader == nil {
-		return ErrPackageNotMounted(p.name)
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrPackageNotMounted(p.name)}
 	}
 
-	var file *zip.File
+	if f, ok := p.index[name]; ok {
+		check, err := p.hashCheck(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
 
-	for _, f := range p.reader.File {
-		if f.Name != filename {
-			continue
+		rc, err := f.Open()
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
 		}
 
-		file = f
-		break
+		return &pkgFile{ReadCloser: rc, info: f.FileInfo(), pkg: p.name, name: name, check: check}, nil
+	}
+
+	if entries, ok := p.dirs[name]; ok {
+		return &memDir{info: dirInfo(name), entries: append([]fs.DirEntry(nil), entries...)}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: ErrPackageFileNotFound{p.name, name}}
+}
+
+// ReadFile reads the named file in the package, and checks its size and
+// checksum against those recorded in the package. If the package was mounted
+// with verification, the file is also checked against the content hash index
+// of the package.
+func (p *Package) ReadFile(name string) ([]byte, error) {
+	file, err := p.Open(name)
+	if err != nil {
+		return nil, err
 	}
+	defer file.Close()
 
-	if file == nil {
-		return ErrPackageFileNotFound{p.name, filename}
+	f, ok := file.(*pkgFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
 	}
 
-	fReader, err := file.Open()
+	data, err := io.ReadAll(f)
 	if err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	entry := p.index[name]
+	p.mu.RUnlock()
+
+	if entry == nil {
+		return nil, ErrPackageNotMounted(p.name)
+	}
+	if uint64(len(data)) != entry.UncompressedSize64 {
+		return nil, ErrPackageCorrupt{Package: p.name, File: name, Err: zip.ErrFormat}
+	}
+	if crc32.ChecksumIEEE(data) != entry.CRC32 {
+		return nil, ErrPackageCorrupt{Package: p.name, File: name, Err: zip.ErrChecksum}
+	}
+
+	return data, nil
+}
+
+// Stat returns information about the named file in the package.
+func (p *Package) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if f, ok := p.index[name]; ok {
+		return f.FileInfo(), nil
+	}
+	if _, ok := p.dirs[name]; ok {
+		return dirInfo(name), nil
+	}
+
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: ErrPackageFileNotFound{p.name, name}}
+}
+
+// ReadDir reads the named directory in the package, and returns its entries
+// sorted by name.
+func (p *Package) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	entries, ok := p.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: ErrPackageFileNotFound{p.name, name}}
+	}
+
+	return append([]fs.DirEntry(nil), entries...), nil
+}
+
+// Verify reads every file in the package, and returns an error for the first
+// file which does not match its recorded size or checksum, or its entry in the
+// content hash index of the package.
+func (p *Package) Verify() error {
+	index, err := p.Index()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return err
 	}
-	defer fReader.Close()
 
-	_, err = io.Copy(w, fReader)
+	for _, name := range p.Files() {
+		data, err := p.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		if index != nil && !isPackageMeta(name) {
+			if err := index.check(name, data); err != nil {
+				return ErrPackageCorrupt{Package: p.name, File: name, Err: err}
+			}
+		}
+	}
+
+	if index != nil {
+		for name := range index.Files {
+			if _, err := p.Stat(name); err != nil {
+				return ErrPackageCorrupt{Package: p.name, File: name, Err: err}
+			}
+		}
+	}
 
 	return nil
 }
 
+func (p *Package) Read(filename string, w io.Writer) error {
+	data, err := p.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+
+	return err
+}
+
+// hashCheck returns the check of the named file against the content hash
+// index of the package, or nil if the file is not checked. The caller must
+// hold the lock of the package.
+func (p *Package) hashCheck(name string) (*hashCheck, error) {
+	if p.hashes == nil || isPackageMeta(name) {
+		return nil, nil
+	}
+
+	entry, ok := p.hashes.Files[name]
+	if ok {
+		return newHashCheck(entry, p.policy == PackageVerifyEnforce), nil
+	}
+
+	err := ErrPackageCorrupt{Package: p.name, File: name, Err: ErrPackageIndex}
+	if p.policy == PackageVerifyEnforce {
+		return nil, err
+	}
+	logrus.Warn(err)
+
+	return nil, nil
+}
+
+// pkgFile is an open file in a package.
+type pkgFile struct {
+	io.ReadCloser
+	info  fs.FileInfo
+	pkg   string
+	name  string
+	check *hashCheck
+}
+
+func (f *pkgFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *pkgFile) Read(b []byte) (int, error) {
+	n, err := f.ReadCloser.Read(b)
+	if err == zip.ErrChecksum || err == zip.ErrFormat {
+		err = ErrPackageCorrupt{Package: f.pkg, File: f.name, Err: err}
+	}
+
+	if f.check != nil {
+		f.check.write(b[:n])
+
+		// The file is checked as soon as its indexed size has been read, so
+		// that readers which stop without reaching EOF are checked too.
+		if err == io.EOF || f.check.size >= f.check.entry.Size {
+			if !f.check.match() {
+				corrupt := ErrPackageCorrupt{Package: f.pkg, File: f.name, Err: ErrPackageHash}
+				if f.check.enforce {
+					err = corrupt
+				} else {
+					logrus.Warn(corrupt)
+				}
+				f.check = nil
+			} else if err == io.EOF {
+				f.check = nil
+			}
+		}
+	}
+
+	return n, err
+}
+
+// indexPackage maps the names of the files in an archive to their entries, and
+// the names of its directories to their entries sorted by name. Directories
+// are implied by the names of files, and entries with invalid names are
+// skipped.
+func indexPackage(r *zip.Reader) (map[string]*zip.File, map[string][]fs.DirEntry) {
+	index := make(map[string]*zip.File)
+	children := map[string]map[string]fs.DirEntry{".": {}}
+
+	var addDir func(name string)
+	addDir = func(name string) {
+		if _, ok := children[name]; ok {
+			return
+		}
+		children[name] = make(map[string]fs.DirEntry)
+
+		parent := path.Dir(name)
+		addDir(parent)
+		children[parent][path.Base(name)] = fs.FileInfoToDirEntry(dirInfo(name))
+	}
+
+	for _, f := range r.File {
+		name := strings.TrimSuffix(f.Name, "/")
+		if name == "" || !fs.ValidPath(name) {
+			continue
+		}
+
+		if strings.HasSuffix(f.Name, "/") {
+			addDir(name)
+			continue
+		}
+
+		parent := path.Dir(name)
+		addDir(parent)
+
+		index[name] = f
+		children[parent][path.Base(name)] = fs.FileInfoToDirEntry(f.FileInfo())
+	}
+
+	dirs := make(map[string][]fs.DirEntry, len(children))
+	for name, entries := range children {
+		list := make([]fs.DirEntry, 0, len(entries))
+		for _, e := range entries {
+			list = append(list, e)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Name() < list[j].Name()
+		})
+
+		dirs[name] = list
+	}
+
+	return index, dirs
+}
+
+// dirInfo returns information about a directory held in memory.
+func dirInfo(name string) fs.FileInfo {
+	return memInfo{name: path.Base(name), mode: fs.ModeDir | 0555}
+}
+
 func IsPackagePath(filename string) bool {
 	return pkgRe.MatchString(filename)
 }
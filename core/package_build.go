@@ -0,0 +1,303 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// PackageIndexFile is the name of the content hash index of a package.
+	PackageIndexFile = "arcpack.json"
+
+	// PackageIndexVersion is the version of the index written by BuildPackage.
+	PackageIndexVersion = 1
+)
+
+// ErrPackageHash reports that a file does not match the content hash index
+// of its package.
+var ErrPackageHash = errors.New("content hash mismatch")
+
+// ErrPackageIndex reports that the files of a package do not match its
+// content hash index.
+var ErrPackageIndex = errors.New("file not in index")
+
+// ErrPackageBuild reports the problems found while building a package.
+type ErrPackageBuild []string
+
+func (e ErrPackageBuild) Error() string {
+	return "arcpack: cannot build package:\n\t" + strings.Join(e, "\n\t")
+}
+
+// PackageIndex is the content hash index of a package.
+type PackageIndex struct {
+	Version   int                          `json:"version"`
+	Manifests []string                     `json:"manifests"`
+	Files     map[string]PackageIndexEntry `json:"files"`
+}
+
+// PackageIndexEntry describes a file in a package.
+type PackageIndexEntry struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BuildPackage writes a package of every file in src to w. Each manifest is
+// checked so that every asset it references is in the package, file names are
+// checked so that they can be read as package resources, and sidecar files are
+// checked so that they can be parsed. Hidden files are skipped. The package
+// includes a content hash index, which is returned.
+func BuildPackage(w io.Writer, src fs.FS, manifests ...string) (*PackageIndex, error) {
+	return BuildSignedPackage(w, src, nil, manifests...)
+}
+
+// BuildSignedPackage writes a package as BuildPackage does, and signs its
+// content hash index with key. If key is nil, the package is not signed.
+func BuildSignedPackage(w io.Writer, src fs.FS, key ed25519.PrivateKey, manifests ...string) (*PackageIndex, error) {
+	var files []string
+	var problems []string
+
+	err := fs.WalkDir(src, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		switch {
+		case isPackageMeta(name):
+			problems = append(problems, fmt.Sprintf("%s: reserved for the package index", name))
+		case !IsPackagePath("pkg:" + name):
+			problems = append(problems, fmt.Sprintf("%s: name cannot be used in a package path", name))
+		default:
+			files = append(files, name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists := make(map[string]bool, len(files))
+	for _, name := range files {
+		exists[name] = true
+	}
+
+	index := &PackageIndex{
+		Version:   PackageIndexVersion,
+		Manifests: make([]string, 0, len(manifests)),
+		Files:     make(map[string]PackageIndexEntry, len(files)),
+	}
+
+	for _, name := range files {
+		if strings.HasSuffix(name, AssetMetaExtension) {
+			problems = append(problems, checkMeta(src, name)...)
+		}
+	}
+
+	for _, v := range manifests {
+		name := cleanPackagePath(v)
+		index.Manifests = append(index.Manifests, name)
+
+		problems = append(problems, checkManifest(src, name, exists)...)
+	}
+
+	if len(problems) != 0 {
+		return nil, ErrPackageBuild(problems)
+	}
+
+	sort.Strings(files)
+
+	zw := zip.NewWriter(w)
+
+	for _, name := range files {
+		data, err := fs.ReadFile(src, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := writePackageFile(zw, name, data); err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(data)
+		index.Files[name] = PackageIndexEntry{Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writePackageFile(zw, PackageIndexFile, data); err != nil {
+		return nil, err
+	}
+
+	if key != nil {
+		sig, err := signPackageIndex(data, key)
+		if err != nil {
+			return nil, err
+		}
+		if err := writePackageFile(zw, PackageSignatureFile, sig); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// checkManifest returns the problems with the assets referenced by a manifest.
+func checkManifest(src fs.FS, name string, exists map[string]bool) []string {
+	if !exists[name] {
+		return []string{fmt.Sprintf("%s: manifest not found", name)}
+	}
+
+	data, err := fs.ReadFile(src, name)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %v", name, err)}
+	}
+
+	m := NewAssetManifest()
+	if err := json.Unmarshal(data, m); err != nil {
+		return []string{fmt.Sprintf("%s: %v", name, err)}
+	}
+
+	var problems []string
+
+	// check adds a problem if a file referenced by the manifest is not in the
+	// package. Files given by their full path are outside of the package.
+	check := func(what, ref string) {
+		if strings.HasPrefix(ref, bindataPrefix) || IsPackagePath(ref) {
+			return
+		}
+
+		file := cleanPackagePath(path.Join(path.Dir(name), ref))
+		if !exists[file] {
+			problems = append(problems, fmt.Sprintf("%s: %s %s not found", name, what, file))
+		}
+	}
+
+	for kind, assets := range m.Assets {
+		for _, asset := range assets {
+			check(kind+" asset", asset)
+		}
+	}
+	for _, inc := range m.Includes {
+		check("included manifest", inc)
+	}
+	for asset, deps := range m.Depends {
+		check("asset", asset)
+		for _, dep := range deps {
+			check("dependency", dep)
+		}
+	}
+
+	sort.Strings(problems)
+
+	return problems
+}
+
+// checkMeta returns the problems with a sidecar file.
+func checkMeta(src fs.FS, name string) []string {
+	data, err := fs.ReadFile(src, name)
+	if err == nil {
+		_, err = ParseAssetMeta(name, data)
+	}
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %v", name, err)}
+	}
+
+	return nil
+}
+
+// writePackageFile adds a file to a package archive.
+func writePackageFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+
+	return err
+}
+
+// cleanPackagePath converts a name to the form package resources are read
+// with, as by Resource.Path.
+func cleanPackagePath(name string) string {
+	r := &Resource{resType: ResourcePackage}
+
+	return path.Clean(r.Path(name))
+}
+
+// Index reads the content hash index of the package.
+func (p *Package) Index() (*PackageIndex, error) {
+	data, err := p.ReadFile(PackageIndexFile)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &PackageIndex{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, ErrPackageCorrupt{Package: p.name, File: PackageIndexFile, Err: err}
+	}
+
+	return index, nil
+}
+
+// check returns an error if a file does not match its entry in the index.
+func (i *PackageIndex) check(name string, data []byte) error {
+	entry, ok := i.Files[name]
+	if !ok {
+		return ErrPackageIndex
+	}
+
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != entry.Size || hex.EncodeToString(sum[:]) != entry.SHA256 {
+		return ErrPackageHash
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBuildPackage(t *testing.T) {
+	src := fstest.MapFS{
+		"assets.json":            {Data: []byte(`{"name": "test", "assets": {"shader": ["./shaders/a.json"], "texture": ["textures\\stone.png"]}}`)},
+		"shaders/a.json":         {Data: []byte(`{"name": "a", "files": ["a.glsl"]}`)},
+		"shaders/a.glsl":         {Data: []byte("void main() {}")},
+		"textures/stone.png":     {Data: []byte("png")},
+		"textures/.stone.png.sw": {Data: []byte("swap")},
+	}
+
+	buf := &bytes.Buffer{}
+	index, err := BuildPackage(buf, src, "assets.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPackageReader("test", bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err := p.Mount(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Verify(); err != nil {
+		t.Errorf("Verify() got: %v want: nil", err)
+	}
+
+	want := []string{"arcpack.json", "assets.json", "shaders/a.glsl", "shaders/a.json", "textures/stone.png"}
+	if got := p.Files(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Files() got: %v want: %v", got, want)
+	}
+
+	read, err := p.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, index) {
+		t.Errorf("Index() got: %v want: %v", read, index)
+	}
+}
+
+func TestBuildPackage_Problems(t *testing.T) {
+	src := fstest.MapFS{
+		"assets.json":      {Data: []byte(`{"name": "test", "assets": {"texture": ["stone.png", "missing.png"]}}`)},
+		"stone.png":        {Data: []byte("png")},
+		"bad name.png":     {Data: []byte("png")},
+		PackageIndexFile:   {Data: []byte("{}")},
+		"other/empty.json": {Data: []byte("{}")},
+	}
+
+	_, err := BuildPackage(&bytes.Buffer{}, src, "assets.json", "missing.json")
+
+	want := ErrPackageBuild{
+		"arcpack.json: reserved for the package index",
+		"bad name.png: name cannot be used in a package path",
+		"assets.json: texture asset missing.png not found",
+		"missing.json: manifest not found",
+	}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("BuildPackage() got: %v want: %v", err, want)
+	}
+}
+
+func TestPackage_VerifyIndex(t *testing.T) {
+	tests := []struct {
+		index string
+		err   error
+	}{
+		{`{"files": {"a.txt": {"size": 1, "sha256": "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"}}}`, nil},
+		{`{"files": {"a.txt": {"size": 1, "sha256": "00"}}}`, ErrPackageHash},
+		{`{"files": {}}`, ErrPackageIndex},
+	}
+
+	for i, v := range tests {
+		p, _ := newTestPackage(t, "test", map[string]string{"a.txt": "a", PackageIndexFile: v.index})
+		if err := p.Mount(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := p.Verify(); !errors.Is(err, v.err) {
+			t.Errorf("Test %d: got: %v want: %v", i, err, v.err)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PackageSignatureFile is the name of the signature of the content hash index
+// of a package.
+const PackageSignatureFile = "arcpack.sig"
+
+// PackagePolicy is how strictly packages are verified when mounted.
+type PackagePolicy int
+
+const (
+	// PackageVerifyOff mounts packages without verifying them.
+	PackageVerifyOff PackagePolicy = iota
+
+	// PackageVerifyWarn logs packages which are unsigned or not signed by a
+	// trusted key, and files which do not match the content hash index.
+	PackageVerifyWarn
+
+	// PackageVerifyEnforce refuses to mount packages which are unsigned or
+	// not signed by a trusted key, and to read files which do not match the
+	// content hash index.
+	PackageVerifyEnforce
+)
+
+var packagePolicyNames = []string{"off", "warn", "enforce"}
+
+func (p PackagePolicy) String() string {
+	if p < 0 || int(p) >= len(packagePolicyNames) {
+		return fmt.Sprintf("PackagePolicy(%d)", int(p))
+	}
+
+	return packagePolicyNames[p]
+}
+
+// ParsePackagePolicy returns the policy with the given name.
+func ParsePackagePolicy(name string) (PackagePolicy, error) {
+	for i := range packagePolicyNames {
+		if packagePolicyNames[i] == name {
+			return PackagePolicy(i), nil
+		}
+	}
+
+	return PackageVerifyOff, fmt.Errorf("arcpack: unknown verification policy: %s", name)
+}
+
+// PackageVerifier is the policy packages are verified with when mounted, and
+// the public keys trusted to sign them.
+type PackageVerifier struct {
+	Policy PackagePolicy
+	Keys   []ed25519.PublicKey
+}
+
+// PackageSignature is the signature of the content hash index of a package.
+type PackageSignature struct {
+	Key       string `json:"key"`
+	Signature string `json:"signature"`
+}
+
+// ErrPackageKeyUntrusted reports that a package is signed by a key which is
+// not trusted.
+var ErrPackageKeyUntrusted = errors.New("signing key not trusted")
+
+// ErrPackageBadSignature reports that the signature of a package does not
+// match its content hash index.
+var ErrPackageBadSignature = errors.New("signature does not match index")
+
+// ErrPackageUnsigned reports that a package has no signed content hash index.
+type ErrPackageUnsigned string
+
+func (e ErrPackageUnsigned) Error() string {
+	return "fs: package not signed: " + string(e)
+}
+
+// ErrPackageSignature reports that the signature of a package could not be
+// verified.
+type ErrPackageSignature struct {
+	Package string
+	Err     error
+}
+
+func (e ErrPackageSignature) Error() string {
+	return fmt.Sprintf("fs: package '%s' signature invalid: %v", e.Package, e.Err)
+}
+
+// Unwrap returns the reason the signature is invalid.
+func (e ErrPackageSignature) Unwrap() error {
+	return e.Err
+}
+
+// ParsePublicKey decodes a base64 encoded ed25519 public key.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("arcpack: public key is %d bytes, want %d", len(data), ed25519.PublicKeySize)
+	}
+
+	return ed25519.PublicKey(data), nil
+}
+
+// ParsePrivateKey decodes a base64 encoded ed25519 private key, or the seed
+// of one.
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(data) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(data), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(data), nil
+	}
+
+	return nil, fmt.Errorf("arcpack: private key is %d bytes, want %d", len(data), ed25519.PrivateKeySize)
+}
+
+// EncodeKey returns the base64 encoding of a key, as read by ParsePublicKey
+// and ParsePrivateKey.
+func EncodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// signPackageIndex returns the signature file of a content hash index.
+func signPackageIndex(index []byte, key ed25519.PrivateKey) ([]byte, error) {
+	sig := PackageSignature{
+		Key:       EncodeKey(key.Public().(ed25519.PublicKey)),
+		Signature: EncodeKey(ed25519.Sign(key, index)),
+	}
+
+	return json.MarshalIndent(sig, "", "  ")
+}
+
+// verifyPackageIndex checks the signature file of a content hash index, and
+// returns the key it was signed with.
+func verifyPackageIndex(index, data []byte, keys []ed25519.PublicKey) (ed25519.PublicKey, error) {
+	sig := PackageSignature{}
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return nil, err
+	}
+
+	key, err := ParsePublicKey(sig.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	trusted := false
+	for i := range keys {
+		if bytes.Equal(keys[i], key) {
+			trusted = true
+			break
+		}
+	}
+	if !trusted {
+		return nil, ErrPackageKeyUntrusted
+	}
+
+	if !ed25519.Verify(key, index, signature) {
+		return nil, ErrPackageBadSignature
+	}
+
+	return key, nil
+}
+
+// SetVerifier sets how the package is verified when it is next mounted. A nil
+// verifier, the default, does not verify the package.
+func (p *Package) SetVerifier(v *PackageVerifier) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.verifier = v
+}
+
+// useVerifier sets the verifier of the package, unless it already has one.
+func (p *Package) useVerifier(v *PackageVerifier) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.verifier == nil {
+		p.verifier = v
+	}
+}
+
+// Signed reports if the package was mounted with a signature by a trusted
+// key.
+func (p *Package) Signed() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.signer != nil
+}
+
+// VerifySignature checks that the content hash index of the package is signed
+// by one of the given keys, and returns the key it was signed with.
+func (p *Package) VerifySignature(keys ...ed25519.PublicKey) (ed25519.PublicKey, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.reader == nil {
+		return nil, ErrPackageNotMounted(p.name)
+	}
+
+	_, key, err := p.signedIndex(keys)
+
+	return key, err
+}
+
+// verifyMount verifies the package as it is mounted, according to the policy
+// of its verifier. Unless verification is off, files are checked against the
+// content hash index as they are read.
+func (p *Package) verifyMount() error {
+	p.policy = PackageVerifyOff
+	if p.verifier == nil || p.verifier.Policy == PackageVerifyOff {
+		return nil
+	}
+
+	index, key, err := p.signedIndex(p.verifier.Keys)
+	if err != nil && p.verifier.Policy == PackageVerifyEnforce {
+		return err
+	}
+	if err != nil {
+		logrus.Warn(err)
+	}
+
+	p.policy = p.verifier.Policy
+	p.hashes = index
+	p.signer = key
+
+	return nil
+}
+
+// signedIndex reads the content hash index of the package and checks its
+// signature. The index is returned if it could be read, even when the
+// signature is invalid. The caller must hold the lock of the package.
+func (p *Package) signedIndex(keys []ed25519.PublicKey) (*PackageIndex, ed25519.PublicKey, error) {
+	f, ok := p.index[PackageIndexFile]
+	if !ok {
+		return nil, nil, ErrPackageUnsigned(p.name)
+	}
+
+	data, err := readPackageEntry(f)
+	if err != nil {
+		return nil, nil, ErrPackageCorrupt{Package: p.name, File: PackageIndexFile, Err: err}
+	}
+
+	index := &PackageIndex{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, nil, ErrPackageCorrupt{Package: p.name, File: PackageIndexFile, Err: err}
+	}
+
+	f, ok = p.index[PackageSignatureFile]
+	if !ok {
+		return index, nil, ErrPackageUnsigned(p.name)
+	}
+
+	sig, err := readPackageEntry(f)
+	if err != nil {
+		return index, nil, ErrPackageCorrupt{Package: p.name, File: PackageSignatureFile, Err: err}
+	}
+
+	key, err := verifyPackageIndex(data, sig, keys)
+	if err != nil {
+		return index, nil, ErrPackageSignature{Package: p.name, Err: err}
+	}
+
+	return index, key, nil
+}
+
+// readPackageEntry reads a file in an archive.
+func readPackageEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+// isPackageMeta reports if a file of a package is its content hash index or
+// the signature of the index, which are not listed in the index.
+func isPackageMeta(name string) bool {
+	return name == PackageIndexFile || name == PackageSignatureFile
+}
+
+// hashCheck checks the content of a file against its entry in the content
+// hash index of a package as the file is read.
+type hashCheck struct {
+	entry   PackageIndexEntry
+	hash    hash.Hash
+	size    int64
+	enforce bool
+}
+
+func newHashCheck(entry PackageIndexEntry, enforce bool) *hashCheck {
+	return &hashCheck{entry: entry, hash: sha256.New(), enforce: enforce}
+}
+
+func (c *hashCheck) write(b []byte) {
+	c.hash.Write(b)
+	c.size += int64(len(b))
+}
+
+func (c *hashCheck) match() bool {
+	return c.size == c.entry.Size && hex.EncodeToString(c.hash.Sum(nil)) == c.entry.SHA256
+}
@@ -0,0 +1,210 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"testing"
+)
+
+const testIndex = `{"files": {"a.txt": {"size": 1, "sha256": "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"}}}`
+
+func newTestKey(t *testing.T, seed byte) ed25519.PrivateKey {
+	s := make([]byte, ed25519.SeedSize)
+	s[0] = seed
+
+	return ed25519.NewKeyFromSeed(s)
+}
+
+func newTestSignature(t *testing.T, index string, key ed25519.PrivateKey) string {
+	sig, err := signPackageIndex([]byte(index), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(sig)
+}
+
+func TestPackage_MountVerify(t *testing.T) {
+	trusted := newTestKey(t, 1)
+	other := newTestKey(t, 2)
+	keys := []ed25519.PublicKey{trusted.Public().(ed25519.PublicKey)}
+
+	signed := map[string]string{"a.txt": "a", PackageIndexFile: testIndex, PackageSignatureFile: newTestSignature(t, testIndex, trusted)}
+	untrusted := map[string]string{"a.txt": "a", PackageIndexFile: testIndex, PackageSignatureFile: newTestSignature(t, testIndex, other)}
+	tampered := map[string]string{"a.txt": "a", PackageIndexFile: testIndex, PackageSignatureFile: newTestSignature(t, "{}", trusted)}
+	unsigned := map[string]string{"a.txt": "a", PackageIndexFile: testIndex}
+
+	tests := []struct {
+		files  map[string]string
+		policy PackagePolicy
+		err    error
+		signed bool
+	}{
+		{signed, PackageVerifyEnforce, nil, true},
+		{signed, PackageVerifyOff, nil, false},
+		{untrusted, PackageVerifyEnforce, ErrPackageKeyUntrusted, false},
+		{untrusted, PackageVerifyWarn, nil, false},
+		{tampered, PackageVerifyEnforce, ErrPackageBadSignature, false},
+		{unsigned, PackageVerifyEnforce, ErrPackageUnsigned("test"), false},
+		{unsigned, PackageVerifyWarn, nil, false},
+		{map[string]string{"a.txt": "a"}, PackageVerifyEnforce, ErrPackageUnsigned("test"), false},
+	}
+
+	for i, v := range tests {
+		p, _ := newTestPackage(t, "test", v.files)
+		p.SetVerifier(&PackageVerifier{Policy: v.policy, Keys: keys})
+
+		if err := p.Mount(); !errors.Is(err, v.err) {
+			t.Errorf("Test %d: got: %v want: %v", i, err, v.err)
+		}
+		if p.Mounted() != (v.err == nil) {
+			t.Errorf("Test %d: Mounted() got: %v want: %v", i, p.Mounted(), v.err == nil)
+		}
+		if p.Signed() != v.signed {
+			t.Errorf("Test %d: Signed() got: %v want: %v", i, p.Signed(), v.signed)
+		}
+	}
+}
+
+func TestPackage_ReadVerify(t *testing.T) {
+	key := newTestKey(t, 1)
+	keys := []ed25519.PublicKey{key.Public().(ed25519.PublicKey)}
+
+	files := map[string]string{
+		"a.txt":              "b",
+		"extra.txt":          "extra",
+		PackageIndexFile:     testIndex,
+		PackageSignatureFile: newTestSignature(t, testIndex, key),
+	}
+
+	tests := []struct {
+		policy PackagePolicy
+		name   string
+		err    error
+	}{
+		{PackageVerifyEnforce, "a.txt", ErrPackageHash},
+		{PackageVerifyEnforce, "extra.txt", ErrPackageIndex},
+		{PackageVerifyEnforce, PackageIndexFile, nil},
+		{PackageVerifyWarn, "a.txt", nil},
+		{PackageVerifyWarn, "extra.txt", nil},
+		{PackageVerifyOff, "a.txt", nil},
+	}
+
+	for i, v := range tests {
+		p, _ := newTestPackage(t, "test", files)
+		p.SetVerifier(&PackageVerifier{Policy: v.policy, Keys: keys})
+		if err := p.Mount(); err != nil {
+			t.Fatal(err)
+		}
+
+		err := p.Read(v.name, io.Discard)
+		if !errors.Is(err, v.err) {
+			t.Errorf("Test %d: got: %v want: %v", i, err, v.err)
+		}
+
+		var corrupt ErrPackageCorrupt
+		if v.err != nil && !errors.As(err, &corrupt) {
+			t.Errorf("Test %d: got: %T want: ErrPackageCorrupt", i, err)
+		}
+	}
+}
+
+func TestPackage_OpenVerify(t *testing.T) {
+	const index = `{"files": {"a.txt": {"size": 4, "sha256": "88d4266fd4e6338d13b845fcf289579d209c897823b9217da3e161936f031589"}}}`
+
+	key := newTestKey(t, 1)
+	keys := []ed25519.PublicKey{key.Public().(ed25519.PublicKey)}
+
+	// Each read is of two bytes, and reads after the indexed size fail on
+	// the first byte too many.
+	tests := []struct {
+		data string
+		errs []error
+	}{
+		{"abcd", []error{nil, nil, io.EOF}},
+		{"abce", []error{nil, ErrPackageHash}},
+		{"abcdef", []error{nil, nil, ErrPackageHash}},
+		{"abc", []error{nil, ErrPackageHash}},
+	}
+
+	for i, v := range tests {
+		p, _ := newTestPackage(t, "test", map[string]string{
+			"a.txt":              v.data,
+			PackageIndexFile:     index,
+			PackageSignatureFile: newTestSignature(t, index, key),
+		})
+		p.SetVerifier(&PackageVerifier{Policy: PackageVerifyEnforce, Keys: keys})
+		if err := p.Mount(); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := p.Open("a.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for j, want := range v.errs {
+			// io.ReadFull drops errors returned with the last bytes, so the
+			// file is read until two bytes are read or an error occurs.
+			var err error
+			for n, b := 0, make([]byte, 2); n < len(b) && err == nil; {
+				var m int
+				m, err = f.Read(b[n:])
+				n += m
+			}
+			if !errors.Is(err, want) {
+				t.Errorf("Test %d.%d: got: %v want: %v", i, j, err, want)
+			}
+		}
+
+		f.Close()
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key := newTestKey(t, 1)
+
+	tests := []struct {
+		in  string
+		err bool
+	}{
+		{EncodeKey(key), false},
+		{EncodeKey(key.Seed()), false},
+		{EncodeKey(key.Seed()) + "\n", false},
+		{"AAAA", true},
+		{"not base64", true},
+	}
+
+	for i, v := range tests {
+		got, err := ParsePrivateKey(v.in)
+		if (err != nil) != v.err {
+			t.Errorf("Test %d: got: %v want error: %v", i, err, v.err)
+		}
+		if err == nil && !got.Equal(key) {
+			t.Errorf("Test %d: got: %v want: %v", i, got, key)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// newTestPackage creates a package from an archive of the given files, which
+// are stored without compression.
+func newTestPackage(t *testing.T, name string, files map[string]string) (*Package, []byte) {
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+
+	for file, data := range files {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: file, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+
+	return NewPackageReader(name, bytes.NewReader(data), int64(len(data))), data
+}
+
+func TestPackage_ReadFile(t *testing.T) {
+	p, _ := newTestPackage(t, "test", map[string]string{
+		"manifest.json":  "manifest",
+		"shaders/a.glsl": "a",
+		"shaders/b.glsl": "b",
+		"textures/":      "",
+	})
+
+	if _, err := p.ReadFile("manifest.json"); err == nil {
+		t.Errorf("ReadFile() before Mount got: nil want: error")
+	}
+	if err := p.Mount(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+		err  error
+	}{
+		{"manifest.json", "manifest", nil},
+		{"shaders/b.glsl", "b", nil},
+		{"shaders/c.glsl", "", fs.ErrNotExist},
+		{"/manifest.json", "", fs.ErrInvalid},
+	}
+
+	for i, v := range tests {
+		data, err := p.ReadFile(v.name)
+		if !errors.Is(err, v.err) {
+			t.Errorf("Test %d: got: %v want: %v", i, err, v.err)
+		}
+		if string(data) != v.want {
+			t.Errorf("Test %d: got: %s want: %s", i, data, v.want)
+		}
+	}
+
+	if err := fstest.TestFS(p, "manifest.json", "shaders/a.glsl", "shaders/b.glsl", "textures"); err != nil {
+		t.Error(err)
+	}
+
+	if err := p.Unmount(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Unmount(); err != ErrPackageNotMounted("test") {
+		t.Errorf("Unmount() got: %v want: %v", err, ErrPackageNotMounted("test"))
+	}
+}
+
+func TestPackage_Corrupt(t *testing.T) {
+	p, data := newTestPackage(t, "test", map[string]string{
+		"a.txt": "hello world",
+	})
+
+	i := bytes.Index(data, []byte("hello world"))
+	data[i] = 'j'
+
+	if err := p.Mount(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.ReadFile("a.txt"); !isPackageCorrupt(err) {
+		t.Errorf("ReadFile() got: %v want: ErrPackageCorrupt", err)
+	}
+	if err := p.Verify(); !isPackageCorrupt(err) {
+		t.Errorf("Verify() got: %v want: ErrPackageCorrupt", err)
+	}
+
+	bad := NewPackageReader("bad", bytes.NewReader([]byte("not a zip")), 9)
+	if _, ok := bad.Mount().(ErrPackageInvalid); !ok {
+		t.Errorf("Mount() got: %v want: ErrPackageInvalid", bad.Mount())
+	}
+
+	if err := NewPackageFile("missing", "missing.pkg").Mount(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Mount() got: %v want: %v", err, fs.ErrNotExist)
+	}
+}
+
+func isPackageCorrupt(err error) bool {
+	var corrupt ErrPackageCorrupt
+
+	return errors.As(err, &corrupt)
+}
+
+func TestAssetSystem_MountPackageAt(t *testing.T) {
+	a := NewAssetSystem()
+
+	base, _ := newTestPackage(t, "base", map[string]string{"a.txt": "base a", "b.txt": "base b"})
+	patch, _ := newTestPackage(t, "patch", map[string]string{"b.txt": "patch b"})
+	mod, _ := newTestPackage(t, "mod", map[string]string{"a.txt": "mod a", "b.txt": "mod b"})
+
+	mounts := []struct {
+		pkg      *Package
+		priority int
+	}{
+		{base, 0},
+		{mod, 10},
+		{patch, 0},
+	}
+	for _, v := range mounts {
+		if err := a.MountPackageAt(v.pkg, "game", v.priority); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := a.MountPackageAt(patch, "game", 0); err != ErrPackageMounted("patch") {
+		t.Errorf("MountPackageAt() got: %v want: %v", err, ErrPackageMounted("patch"))
+	}
+
+	tests := []struct {
+		unmount string
+		a       string
+		b       string
+	}{
+		{"", "mod a", "mod b"},
+		{"mod", "base a", "patch b"},
+		{"patch", "base a", "base b"},
+	}
+
+	for i, v := range tests {
+		if v.unmount != "" {
+			if err := a.UnmountPackage(v.unmount); err != nil {
+				t.Fatalf("Test %d: %v", i, err)
+			}
+		}
+
+		got := []string{}
+		for _, file := range []string{"game:a.txt", "game:b.txt"} {
+			data, err := a.FS().ReadFile(file)
+			if err != nil {
+				t.Fatalf("Test %d: %v", i, err)
+			}
+			got = append(got, string(data))
+		}
+
+		if want := []string{v.a, v.b}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Test %d: got: %v want: %v", i, got, want)
+		}
+	}
+
+	if got := a.Packages(); !reflect.DeepEqual(got, []string{"base"}) {
+		t.Errorf("Packages() got: %v want: %v", got, []string{"base"})
+	}
+}
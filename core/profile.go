@@ -0,0 +1,434 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var _ System = &ProfilerSystem{}
+
+var profilerInst *ProfilerSystem
+
+const SysNameProfiler = "profiler"
+
+// DefaultProfileFrames is the default number of frames kept by the profiler.
+const DefaultProfileFrames = 300
+
+// ProfileSample is a single timed scope within a frame.
+type ProfileSample struct {
+	// Name is the name of the scope.
+	Name string
+
+	// Depth is the nesting depth of the scope, starting at zero.
+	Depth int
+
+	// Start is the time the scope began, relative to the start of the frame.
+	Start time.Duration
+
+	// Duration is the time spent in the scope.
+	Duration time.Duration
+}
+
+// ProfileFrame holds the scopes recorded during a single frame.
+type ProfileFrame struct {
+	// Index is the number of the frame since profiling began.
+	Index uint64
+
+	// Start is the time the frame began.
+	Start time.Time
+
+	// Duration is the length of the frame.
+	Duration time.Duration
+
+	// Samples are the scopes recorded in the frame, in the order they began.
+	Samples []ProfileSample
+}
+
+// ProfileStat holds timing statistics for a scope over the recorded frames.
+// Durations are the total time spent in the scope per frame.
+type ProfileStat struct {
+	Name   string
+	Calls  int
+	Frames int
+	Min    time.Duration
+	Avg    time.Duration
+	Max    time.Duration
+}
+
+// ProfilerSystem records nested, named timing scopes for the last N frames.
+//
+// Scopes are recorded between FrameStart and FrameEnd, and only from the main
+// thread. All methods may be called on a nil ProfilerSystem, in which case
+// they do nothing, so code may be instrumented regardless of whether the
+// profiler is registered.
+type ProfilerSystem struct {
+	frames   []ProfileFrame
+	current  *ProfileFrame
+	stack    []int
+	head     int
+	count    int
+	index    uint64
+	enabled  bool
+	detailed bool
+	inFrame  bool
+	output   string
+	now      func() time.Time
+}
+
+// Setup sets up the System.
+func (p *ProfilerSystem) Setup() error {
+	if profilerInst != nil {
+		return ErrSystemInit(SysNameProfiler)
+	}
+	profilerInst = p
+
+	return nil
+}
+
+// Teardown tears down the System. If an output file has been set, the
+// recorded frames are written to it as a Chrome trace.
+func (p *ProfilerSystem) Teardown() {
+	if p.output == "" || p.count == 0 {
+		return
+	}
+
+	f, err := os.Create(p.output)
+	if err != nil {
+		logrus.Error("[Profiler] ", err)
+		return
+	}
+	defer f.Close()
+
+	if err := p.WriteChromeTrace(f); err != nil {
+		logrus.Error("[Profiler] ", err)
+		return
+	}
+
+	logrus.Debug("[Profiler] Wrote trace to ", p.output)
+}
+
+// Name returns the name of the System.
+func (p *ProfilerSystem) Name() string {
+	return SysNameProfiler
+}
+
+// Enabled reports if the profiler is recording.
+func (p *ProfilerSystem) Enabled() bool {
+	return p != nil && p.enabled
+}
+
+// SetEnabled starts or stops recording. Disabling the profiler discards the
+// frame in progress, but keeps completed frames.
+func (p *ProfilerSystem) SetEnabled(enabled bool) {
+	if p == nil {
+		return
+	}
+
+	p.enabled = enabled
+	if !enabled {
+		p.inFrame = false
+	}
+}
+
+// Detailed reports if fine grained scopes, such as individual components,
+// should be recorded.
+func (p *ProfilerSystem) Detailed() bool {
+	return p != nil && p.enabled && p.detailed
+}
+
+// SetDetailed sets if fine grained scopes should be recorded.
+func (p *ProfilerSystem) SetDetailed(detailed bool) {
+	if p == nil {
+		return
+	}
+
+	p.detailed = detailed
+}
+
+// SetOutput sets the file the recorded frames are written to on teardown. If
+// empty, nothing is written.
+func (p *ProfilerSystem) SetOutput(filename string) {
+	if p == nil {
+		return
+	}
+
+	p.output = filename
+}
+
+// FrameStart begins recording a new frame.
+func (p *ProfilerSystem) FrameStart() {
+	if p == nil || !p.enabled {
+		return
+	}
+
+	p.current = &p.frames[p.head]
+	p.current.Index = p.index
+	p.current.Start = p.now()
+	p.current.Duration = 0
+	p.current.Samples = p.current.Samples[:0]
+	p.stack = p.stack[:0]
+	p.inFrame = true
+}
+
+// FrameEnd finishes recording the current frame. Scopes which are still open
+// are ended.
+func (p *ProfilerSystem) FrameEnd() {
+	if p == nil || !p.inFrame {
+		return
+	}
+
+	for len(p.stack) > 0 {
+		p.End()
+	}
+
+	p.current.Duration = p.now().Sub(p.current.Start)
+	p.current = nil
+	p.inFrame = false
+
+	p.head = (p.head + 1) % len(p.frames)
+	if p.count < len(p.frames) {
+		p.count++
+	}
+	p.index++
+}
+
+// Begin opens a new scope with the given name, nested within the currently
+// open scope. Every call to Begin must be matched by a call to End.
+func (p *ProfilerSystem) Begin(name string) {
+	if p == nil || !p.inFrame {
+		return
+	}
+
+	p.stack = append(p.stack, len(p.current.Samples))
+	p.current.Samples = append(p.current.Samples, ProfileSample{
+		Name:  name,
+		Depth: len(p.stack) - 1,
+		Start: p.now().Sub(p.current.Start),
+	})
+}
+
+// End closes the most recently opened scope.
+func (p *ProfilerSystem) End() {
+	if p == nil || !p.inFrame || len(p.stack) == 0 {
+		return
+	}
+
+	i := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+
+	s := &p.current.Samples[i]
+	s.Duration = p.now().Sub(p.current.Start) - s.Start
+}
+
+// Scope opens a new scope and returns a function which closes it, for use
+// with defer:
+//
+//	defer profiler.Scope("name")()
+func (p *ProfilerSystem) Scope(name string) func() {
+	p.Begin(name)
+
+	return p.End
+}
+
+// Frames returns copies of the recorded frames, oldest first.
+func (p *ProfilerSystem) Frames() []ProfileFrame {
+	if p == nil {
+		return nil
+	}
+
+	frames := make([]ProfileFrame, 0, p.count)
+	start := (p.head - p.count + len(p.frames)) % len(p.frames)
+
+	for i := 0; i < p.count; i++ {
+		f := p.frames[(start+i)%len(p.frames)]
+		f.Samples = append([]ProfileSample(nil), f.Samples...)
+		frames = append(frames, f)
+	}
+
+	return frames
+}
+
+// Reset discards all recorded frames.
+func (p *ProfilerSystem) Reset() {
+	if p == nil {
+		return
+	}
+
+	p.head = 0
+	p.count = 0
+	p.inFrame = false
+}
+
+// Stats returns statistics for every scope in the recorded frames, sorted by
+// name. The frame itself is reported with the name "Frame".
+func (p *ProfilerSystem) Stats() []ProfileStat {
+	frames := p.Frames()
+	if len(frames) == 0 {
+		return nil
+	}
+
+	stats := make(map[string]*ProfileStat)
+	totals := make(map[string]time.Duration)
+	calls := make(map[string]int)
+
+	record := func(name string, d time.Duration, n int) {
+		s, ok := stats[name]
+		if !ok {
+			s = &ProfileStat{Name: name, Min: d, Max: d}
+			stats[name] = s
+		}
+		if d < s.Min {
+			s.Min = d
+		}
+		if d > s.Max {
+			s.Max = d
+		}
+		s.Avg += d
+		s.Calls += n
+		s.Frames++
+	}
+
+	for i := range frames {
+		for k := range totals {
+			delete(totals, k)
+			delete(calls, k)
+		}
+		for _, s := range frames[i].Samples {
+			totals[s.Name] += s.Duration
+			calls[s.Name]++
+		}
+
+		record("Frame", frames[i].Duration, 1)
+		for name, d := range totals {
+			record(name, d, calls[name])
+		}
+	}
+
+	result := make([]ProfileStat, 0, len(stats))
+	for _, s := range stats {
+		s.Avg /= time.Duration(s.Frames)
+		result = append(result, *s)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// traceEvent is a single event in the Chrome Trace Event format.
+type traceEvent struct {
+	Name      string            `json:"name"`
+	Category  string            `json:"cat,omitempty"`
+	Phase     string            `json:"ph"`
+	Timestamp float64           `json:"ts"`
+	Duration  float64           `json:"dur,omitempty"`
+	PID       int               `json:"pid"`
+	TID       int               `json:"tid"`
+	Args      map[string]string `json:"args,omitempty"`
+}
+
+type traceFile struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// WriteChromeTrace writes the recorded frames to w in the Chrome Trace Event
+// format, which can be opened with chrome://tracing or Perfetto.
+func (p *ProfilerSystem) WriteChromeTrace(w io.Writer) error {
+	frames := p.Frames()
+
+	trace := traceFile{
+		TraceEvents: []traceEvent{{
+			Name:  "thread_name",
+			Phase: "M",
+			PID:   1,
+			TID:   1,
+			Args:  map[string]string{"name": "main"},
+		}},
+		DisplayTimeUnit: "ms",
+	}
+
+	if len(frames) > 0 {
+		epoch := frames[0].Start
+
+		for i := range frames {
+			offset := frames[i].Start.Sub(epoch)
+
+			trace.TraceEvents = append(trace.TraceEvents, traceEvent{
+				Name:      "Frame",
+				Category:  "frame",
+				Phase:     "X",
+				Timestamp: microseconds(offset),
+				Duration:  microseconds(frames[i].Duration),
+				PID:       1,
+				TID:       1,
+			})
+
+			for _, s := range frames[i].Samples {
+				trace.TraceEvents = append(trace.TraceEvents, traceEvent{
+					Name:      s.Name,
+					Category:  "scope",
+					Phase:     "X",
+					Timestamp: microseconds(offset + s.Start),
+					Duration:  microseconds(s.Duration),
+					PID:       1,
+					TID:       1,
+				})
+			}
+		}
+	}
+
+	return json.NewEncoder(w).Encode(&trace)
+}
+
+func microseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
+
+// NewProfilerSystem creates a new profiler system which keeps the given
+// number of frames. If frames is zero or negative, DefaultProfileFrames is
+// used. The profiler is disabled until SetEnabled is called.
+func NewProfilerSystem(frames int) *ProfilerSystem {
+	if frames <= 0 {
+		frames = DefaultProfileFrames
+	}
+
+	return &ProfilerSystem{
+		frames: make([]ProfileFrame, frames),
+		now:    time.Now,
+	}
+}
+
+// GetProfilerSystem gets the profiler system from the current app.
+func GetProfilerSystem() *ProfilerSystem {
+	return profilerInst
+}
@@ -0,0 +1,160 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// fakeNow returns a time source which advances by one millisecond per call.
+func fakeNow() func() time.Time {
+	t := time.Unix(0, 0)
+
+	return func() time.Time {
+		t = t.Add(time.Millisecond)
+		return t
+	}
+}
+
+func TestProfilerSystem_Scopes(t *testing.T) {
+	p := NewProfilerSystem(2)
+	p.now = fakeNow()
+	p.SetEnabled(true)
+
+	for i := 0; i < 3; i++ {
+		p.FrameStart()
+		p.Begin("Update")
+		p.Begin("scene")
+		p.End()
+		p.End()
+		p.Begin("Unclosed")
+		p.FrameEnd()
+	}
+
+	frames := p.Frames()
+	if len(frames) != 2 {
+		t.Fatalf("Frames() got: %d want: 2", len(frames))
+	}
+	if frames[0].Index != 1 || frames[1].Index != 2 {
+		t.Errorf("got: %d, %d want: 1, 2", frames[0].Index, frames[1].Index)
+	}
+
+	tests := []ProfileSample{
+		{Name: "Update", Depth: 0, Start: 1 * time.Millisecond, Duration: 3 * time.Millisecond},
+		{Name: "scene", Depth: 1, Start: 2 * time.Millisecond, Duration: 1 * time.Millisecond},
+		{Name: "Unclosed", Depth: 0, Start: 5 * time.Millisecond, Duration: 1 * time.Millisecond},
+	}
+
+	samples := frames[1].Samples
+	if len(samples) != len(tests) {
+		t.Fatalf("got: %d samples want: %d", len(samples), len(tests))
+	}
+	for i, v := range tests {
+		if samples[i] != v {
+			t.Errorf("Test %d: got: %v want: %v", i, samples[i], v)
+		}
+	}
+}
+
+func TestProfilerSystem_Stats(t *testing.T) {
+	p := NewProfilerSystem(10)
+	p.now = fakeNow()
+	p.SetEnabled(true)
+
+	// Frame 0 has one short call, frame 1 has two calls of one millisecond.
+	p.FrameStart()
+	p.Begin("Update")
+	p.End()
+	p.FrameEnd()
+
+	p.FrameStart()
+	p.Begin("Update")
+	p.End()
+	p.Begin("Update")
+	p.End()
+	p.FrameEnd()
+
+	stats := p.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("Stats() got: %d want: 2", len(stats))
+	}
+
+	s := stats[1]
+	if s.Name != "Update" || s.Calls != 3 || s.Frames != 2 {
+		t.Errorf("got: %v", s)
+	}
+	if s.Min != time.Millisecond || s.Max != 2*time.Millisecond || s.Avg != 1500*time.Microsecond {
+		t.Errorf("got: min %v avg %v max %v want: min 1ms avg 1.5ms max 2ms", s.Min, s.Avg, s.Max)
+	}
+}
+
+func TestProfilerSystem_ChromeTrace(t *testing.T) {
+	p := NewProfilerSystem(4)
+	p.now = fakeNow()
+	p.SetEnabled(true)
+
+	p.FrameStart()
+	p.Begin("Update")
+	p.End()
+	p.FrameEnd()
+
+	var buf bytes.Buffer
+	if err := p.WriteChromeTrace(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var trace struct {
+		TraceEvents []struct {
+			Name string  `json:"name"`
+			Ph   string  `json:"ph"`
+			Ts   float64 `json:"ts"`
+			Dur  float64 `json:"dur"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(trace.TraceEvents) != 3 {
+		t.Fatalf("got: %d events want: 3", len(trace.TraceEvents))
+	}
+	e := trace.TraceEvents[2]
+	if e.Name != "Update" || e.Ph != "X" || e.Ts != 1000 || e.Dur != 1000 {
+		t.Errorf("got: %+v", e)
+	}
+}
+
+func TestProfilerSystem_Nil(t *testing.T) {
+	var p *ProfilerSystem
+
+	p.FrameStart()
+	p.Scope("nothing")()
+	p.FrameEnd()
+
+	if p.Enabled() || p.Stats() != nil {
+		t.Error("nil profiler reported data")
+	}
+}
@@ -39,7 +39,7 @@ type ResourceType int
 
 const (
 	ResourceFile    ResourceType = iota // ResourceFile is a file located on the local filesystem.
-	ResourcePackage                     // ResourcePackage is a file located in a package.
+	ResourcePackage                     // ResourcePackage is a file located in a package or other mounted filesystem.
 	ResourceBindata                     // ResourceBindata is a file built in to the binary.
 )
 
@@ -51,6 +51,7 @@ type Resource struct {
 	buffer    *bytes.Buffer
 	location  string
 	container string
+	meta      *AssetMeta
 }
 
 // NewResource creates a new Resource object for the given filename. The type
@@ -108,6 +109,22 @@ func (r *Resource) Container() string {
 	return r.container
 }
 
+// FSPath returns the path of the resource in the asset system's virtual
+// filesystem, including its mount point.
+func (r *Resource) FSPath() string {
+	if r.resType == ResourceFile {
+		return r.location
+	}
+
+	return r.container + ":" + r.location
+}
+
+// Meta returns the sidecar file of the resource, or nil if it has none. It is
+// read along with the resource by the asset system.
+func (r *Resource) Meta() *AssetMeta {
+	return r.meta
+}
+
 // Type returns the resource type.
 func (r *Resource) Type() ResourceType {
 	return r.resType
@@ -24,6 +24,7 @@ package core
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/sirupsen/logrus"
 )
@@ -54,15 +55,41 @@ type Scene interface {
 	Name() string
 }
 
+// SceneHierarchyWriter is implemented by scenes which can describe their
+// contents, such as for a crash report.
+type SceneHierarchyWriter interface {
+	// WriteHierarchy writes a description of the objects in the scene.
+	WriteHierarchy(w io.Writer) error
+}
+
+// SceneAssetOwner is implemented by scenes which own asset manifests. The
+// manifests are loaded before the scene is loaded, and unloaded once the scene
+// is no longer on the active stack.
+type SceneAssetOwner interface {
+	// OwnedManifests returns the asset manifests owned by the scene.
+	OwnedManifests() []string
+}
+
+// SceneUnloader is implemented by scenes which can release their contents,
+// so that they can be loaded again after their assets have been freed.
+type SceneUnloader interface {
+	// Unload is called when the scene is being released.
+	Unload()
+}
+
 var sceneInst *SceneSystem
 
 const SysNameScene = "scene"
 
 var _ System = &SceneSystem{}
+var _ SystemDependent = &SceneSystem{}
+var _ SystemUpdater = &SceneSystem{}
+var _ SystemFixedUpdater = &SceneSystem{}
 
 type SceneSystem struct {
 	scenes map[string]Scene
 	active []string
+	owned  map[string][]string
 }
 
 // Setup sets up the System.
@@ -77,7 +104,9 @@ func (s *SceneSystem) Setup() error {
 
 // Teardown tears down the System.
 func (s *SceneSystem) Teardown() {
-
+	for name := range s.owned {
+		s.release(name)
+	}
 }
 
 // Name returns the name of the System.
@@ -85,6 +114,21 @@ func (s *SceneSystem) Name() string {
 	return SysNameScene
 }
 
+// Dependencies returns the names of the systems this System depends on.
+func (s *SceneSystem) Dependencies() []string {
+	return []string{SysNameInstance, SysNameTime, SysNameAsset}
+}
+
+// Update is called every frame.
+func (s *SceneSystem) Update() {
+	s.OnUpdate()
+}
+
+// FixedUpdate is called at fixed intervals, after Update.
+func (s *SceneSystem) FixedUpdate() {
+	s.OnFixedUpdate()
+}
+
 func (s *SceneSystem) Register(scene Scene) error {
 	if s.Registered(scene.Name()) {
 		return fmt.Errorf("register scene: '%s' already registered", scene.Name())
@@ -102,6 +146,10 @@ func (s *SceneSystem) Load(name string) error {
 		return fmt.Errorf("load scene: '%s' not registered", name)
 	}
 
+	if err := s.acquire(name); err != nil {
+		return err
+	}
+
 	if !s.scenes[name].Loaded() {
 		return s.scenes[name].Load()
 	}
@@ -109,13 +157,86 @@ func (s *SceneSystem) Load(name string) error {
 	return nil
 }
 
+// acquire loads the manifests owned by a scene, if they are not loaded.
+func (s *SceneSystem) acquire(name string) error {
+	owner, ok := s.scenes[name].(SceneAssetOwner)
+	if !ok {
+		return nil
+	}
+	if _, loaded := s.owned[name]; loaded {
+		return nil
+	}
+
+	manifests := owner.OwnedManifests()
+	if len(manifests) == 0 {
+		return nil
+	}
+
+	assets := GetAssetSystem()
+	if assets == nil {
+		return ErrSystemNotFound(SysNameAsset)
+	}
+
+	if err := assets.LoadManifest(manifests...); err != nil {
+		return err
+	}
+
+	s.owned[name] = manifests
+
+	return nil
+}
+
+// release unloads a scene which owns manifests, then unloads its manifests.
+func (s *SceneSystem) release(name string) {
+	manifests, ok := s.owned[name]
+	if !ok {
+		return
+	}
+
+	delete(s.owned, name)
+
+	if sc, ok := s.scenes[name].(SceneUnloader); ok {
+		sc.Unload()
+	}
+
+	if assets := GetAssetSystem(); assets != nil {
+		if err := assets.UnloadManifest(manifests...); err != nil {
+			logrus.Error(err)
+		}
+	}
+}
+
+// releaseInactive releases scenes which own manifests and are no longer on
+// the active stack.
+func (s *SceneSystem) releaseInactive() {
+	for name := range s.owned {
+		if !s.isActive(name) {
+			s.release(name)
+		}
+	}
+}
+
+func (s *SceneSystem) isActive(name string) bool {
+	for _, v := range s.active {
+		if v == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (s *SceneSystem) PurgePush(name string) error {
 	if !s.Registered(name) {
 		return fmt.Errorf("purge push scene: '%s' not registered", name)
 	}
 
 	s.active = s.active[:0]
-	s.Push(name)
+	if err := s.Push(name); err != nil {
+		s.releaseInactive()
+		return err
+	}
+	s.releaseInactive()
 
 	return nil
 }
@@ -143,6 +264,8 @@ func (s *SceneSystem) Push(name string) error {
 	s.active = append(s.active, name)
 	s.scenes[name].OnActivate()
 
+	publishEvent(EventScenePush{Name: name})
+
 	return nil
 }
 
@@ -153,6 +276,12 @@ func (s *SceneSystem) Pop() string {
 		s.active = s.active[:len(s.active)-1]
 		s.scenes[last].OnDeactivate()
 
+		if !s.isActive(last) {
+			s.release(last)
+		}
+
+		publishEvent(EventScenePop{Name: last})
+
 		return last
 	}
 
@@ -160,6 +289,9 @@ func (s *SceneSystem) Pop() string {
 }
 
 func (s *SceneSystem) RemoveAll() {
+	for name := range s.owned {
+		s.release(name)
+	}
 	for key := range s.scenes {
 		delete(s.scenes, key)
 	}
@@ -171,6 +303,7 @@ func (s *SceneSystem) Unregister(name string) error {
 		return fmt.Errorf("unregister scene: '%s' not registered", name)
 	}
 
+	s.release(name)
 	delete(s.scenes, name)
 
 	return nil
@@ -191,6 +324,22 @@ func (s *SceneSystem) Active() Scene {
 	return nil
 }
 
+// Stack returns the names of the active scenes, with the scene on top of the
+// stack last.
+func (s *SceneSystem) Stack() []string {
+	return append([]string(nil), s.active...)
+}
+
+// Get returns the scene with the given name.
+func (s *SceneSystem) Get(name string) (Scene, error) {
+	sc, ok := s.scenes[name]
+	if !ok {
+		return nil, fmt.Errorf("get scene: '%s' not registered", name)
+	}
+
+	return sc, nil
+}
+
 func (s *SceneSystem) ActiveName() string {
 	if sc := s.Active(); sc != nil {
 		return sc.Name()
@@ -229,6 +378,7 @@ func (s *SceneSystem) OnFixedUpdate() {
 func NewSceneSystem() *SceneSystem {
 	return &SceneSystem{
 		scenes: make(map[string]Scene),
+		owned:  make(map[string][]string),
 	}
 }
 
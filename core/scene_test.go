@@ -0,0 +1,159 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+type testScene struct {
+	name      string
+	manifests []string
+	loaded    bool
+	loads     int
+	unloads   int
+}
+
+func (s *testScene) OnActivate()   {}
+func (s *testScene) OnDeactivate() {}
+func (s *testScene) Display()      {}
+func (s *testScene) FixedUpdate()  {}
+func (s *testScene) Update()       {}
+
+func (s *testScene) Load() error {
+	s.loaded = true
+	s.loads++
+
+	return nil
+}
+
+func (s *testScene) Unload() {
+	s.loaded = false
+	s.unloads++
+}
+
+func (s *testScene) Loaded() bool {
+	return s.loaded
+}
+
+func (s *testScene) Name() string {
+	return s.name
+}
+
+func (s *testScene) OwnedManifests() []string {
+	return s.manifests
+}
+
+func setupSceneAssets() (*AssetSystem, *testAssetHandler, func()) {
+	prevInstance := instanceInst
+	prevAsset := assetInst
+	instanceInst = NewInstanceSystem()
+
+	a := NewAssetSystem()
+	h := newTestAssetHandler()
+	a.RegisterHandler(h)
+	assetInst = a
+
+	a.Mount("test", "mem", fstest.MapFS{
+		"one.json": {Data: []byte(`{"name": "one", "assets": {"test": ["a", "b"]}}`)},
+		"bad.json": {Data: []byte(`{"name": "bad", "assets": {"test": ["a", "missing"]}}`)},
+		"a":        {Data: []byte("a")},
+		"b":        {Data: []byte("b")},
+	}, 0)
+
+	return a, h, func() {
+		instanceInst = prevInstance
+		assetInst = prevAsset
+	}
+}
+
+func TestSceneSystem_OwnedManifests(t *testing.T) {
+	a, h, teardown := setupSceneAssets()
+	defer teardown()
+
+	s := NewSceneSystem()
+	one := &testScene{name: "one", manifests: []string{"test:one.json"}}
+	plain := &testScene{name: "plain"}
+	s.Register(one)
+	s.Register(plain)
+
+	tests := []struct {
+		op      func()
+		loaded  bool
+		names   int
+		loads   int
+		unloads int
+	}{
+		{func() { s.Push("one") }, true, 2, 1, 0},
+		{func() { s.Push("one") }, true, 2, 1, 0},
+		{func() { s.Pop() }, true, 2, 1, 0},
+		{func() { s.Push("plain") }, true, 2, 1, 0},
+		{func() { s.Pop() }, true, 2, 1, 0},
+		{func() { s.Pop() }, false, 0, 1, 1},
+		{func() { s.Push("one") }, true, 2, 2, 1},
+		{func() { s.PurgePush("plain") }, false, 0, 2, 2},
+	}
+
+	for i, v := range tests {
+		v.op()
+		if loaded := a.ManifestLoaded("test:one.json"); loaded != v.loaded {
+			t.Errorf("Test %d: got: %v want: %v", i, loaded, v.loaded)
+		}
+		if n := len(h.Names()); n != v.names {
+			t.Errorf("Test %d: got: %d want: %d", i, n, v.names)
+		}
+		if one.loads != v.loads || one.unloads != v.unloads {
+			t.Errorf("Test %d: got: %d, %d want: %d, %d", i, one.loads, one.unloads, v.loads, v.unloads)
+		}
+	}
+}
+
+func TestSceneSystem_AcquireError(t *testing.T) {
+	a, h, teardown := setupSceneAssets()
+	defer teardown()
+
+	s := NewSceneSystem()
+	bad := &testScene{name: "bad", manifests: []string{"test:one.json", "test:bad.json"}}
+	s.Register(bad)
+
+	if err := s.Push("bad"); err == nil {
+		t.Fatal("Push() got: nil want: error")
+	}
+
+	if n := s.ActiveCount(); n != 0 {
+		t.Errorf("ActiveCount() got: %d want: 0", n)
+	}
+	if bad.loads != 0 {
+		t.Errorf("loads got: %d want: 0", bad.loads)
+	}
+	if a.ManifestLoaded("test:one.json") || a.ManifestLoaded("test:bad.json") {
+		t.Error("ManifestLoaded() got: true want: false")
+	}
+	if n := len(h.Names()); n != 0 {
+		t.Errorf("Names() got: %d want: 0", n)
+	}
+	if n := instanceInst.Count(); n != 0 {
+		t.Errorf("Count() got: %d want: 0", n)
+	}
+}
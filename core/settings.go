@@ -0,0 +1,559 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+
+	"github.com/haakenlabs/arc/pkg/math"
+)
+
+const (
+	SettingBool SettingType = iota
+	SettingInt
+	SettingFloat
+	SettingString
+	SettingIVec2
+)
+
+var settingTypeNames = [...]string{
+	SettingBool:   "bool",
+	SettingInt:    "int",
+	SettingFloat:  "float",
+	SettingString: "string",
+	SettingIVec2:  "ivec2",
+}
+
+// globalSettings is the settings registry backed by the global viper
+// instance and arc.cfg.
+var globalSettings = NewSettings(viper.GetViper())
+
+// SettingType is the type of the value of a setting.
+type SettingType int
+
+func (t SettingType) String() string {
+	if int(t) < len(settingTypeNames) {
+		return settingTypeNames[t]
+	}
+
+	return "unknown"
+}
+
+// ErrSettingNotFound reports that a setting has not been registered.
+type ErrSettingNotFound string
+
+func (e ErrSettingNotFound) Error() string {
+	return "setting " + string(e) + " not found"
+}
+
+// ErrSettingExists reports that a setting has already been registered.
+type ErrSettingExists string
+
+func (e ErrSettingExists) Error() string {
+	return "setting " + string(e) + " already exists"
+}
+
+// ErrSettingInvalid reports that a value is not valid for a setting.
+type ErrSettingInvalid struct {
+	Key    string
+	Value  interface{}
+	Reason string
+}
+
+func (e ErrSettingInvalid) Error() string {
+	return fmt.Sprintf("setting %s: invalid value %v: %s", e.Key, e.Value, e.Reason)
+}
+
+// ErrSettingsInvalid holds the errors of every invalid setting.
+type ErrSettingsInvalid []error
+
+func (e ErrSettingsInvalid) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Setting describes a configuration option.
+type Setting struct {
+	// Key is the name of the setting, such as graphics.vsync.
+	Key string
+
+	// Description is a short, human readable description of the setting.
+	Description string
+
+	// Type is the type of the value of the setting.
+	Type SettingType
+
+	// Default is the value used when the setting has not been configured.
+	Default interface{}
+
+	// Min and Max bound the value of numeric settings, and each component of
+	// vector settings. If both are zero, the value is unbounded.
+	Min float64
+	Max float64
+
+	// Options lists the permitted values of a string setting. If empty, any
+	// value is permitted.
+	Options []string
+}
+
+// EventSettingChanged is sent when a setting has been changed.
+type EventSettingChanged struct {
+	Key   string
+	Value interface{}
+}
+
+// EventType returns the type of the event.
+func (e EventSettingChanged) EventType() EventType {
+	return EventTypeSettingChanged
+}
+
+// SettingListener is called with the new value of a setting when it changes.
+type SettingListener func(key string, value interface{})
+
+// Settings is a registry of typed settings, backed by viper. Values are taken
+// from, in order of priority: values set at runtime or on the command line,
+// environment variables, the configuration file, and finally the defaults.
+//
+// Settings is not safe for concurrent use, and should only be modified from
+// the main thread.
+type Settings struct {
+	v         *viper.Viper
+	settings  map[string]*Setting
+	keys      []string
+	changed   map[string]bool
+	listeners map[string][]*settingWatch
+}
+
+// settingWatch is a registered listener. Listeners are removed by pointer, as
+// functions cannot be compared.
+type settingWatch struct {
+	fn SettingListener
+}
+
+// Register adds a setting to the registry.
+func (s *Settings) Register(setting Setting) error {
+	if _, ok := s.settings[setting.Key]; ok {
+		return ErrSettingExists(setting.Key)
+	}
+
+	value, err := setting.Convert(setting.Default)
+	if err != nil {
+		return err
+	}
+	setting.Default = value
+
+	s.settings[setting.Key] = &setting
+	s.keys = append(s.keys, setting.Key)
+	s.v.SetDefault(setting.Key, value)
+
+	return nil
+}
+
+// MustRegister is like Register, but panics if an error occurs.
+func (s *Settings) MustRegister(setting Setting) {
+	if err := s.Register(setting); err != nil {
+		panic(err)
+	}
+}
+
+// Registered reports if a setting with the given key has been registered.
+func (s *Settings) Registered(key string) bool {
+	_, ok := s.settings[key]
+
+	return ok
+}
+
+// Lookup returns the description of the setting with the given key.
+func (s *Settings) Lookup(key string) (Setting, error) {
+	setting, ok := s.settings[key]
+	if !ok {
+		return Setting{}, ErrSettingNotFound(key)
+	}
+
+	return *setting, nil
+}
+
+// Keys returns the keys of all settings, in the order they were registered.
+func (s *Settings) Keys() []string {
+	return append([]string(nil), s.keys...)
+}
+
+// Get returns the current value of a setting, converted to its type.
+func (s *Settings) Get(key string) (interface{}, error) {
+	setting, ok := s.settings[key]
+	if !ok {
+		return nil, ErrSettingNotFound(key)
+	}
+
+	return setting.Convert(s.v.Get(key))
+}
+
+// get returns the current value of a setting, or its default if the value is
+// invalid. It panics if the setting has not been registered.
+func (s *Settings) get(key string) interface{} {
+	v, err := s.Get(key)
+	if err == nil {
+		return v
+	}
+	if _, ok := err.(ErrSettingNotFound); ok {
+		panic(err)
+	}
+
+	return s.settings[key].Default
+}
+
+// Bool returns the value of a bool setting.
+func (s *Settings) Bool(key string) bool {
+	return s.get(key).(bool)
+}
+
+// Int returns the value of an int setting.
+func (s *Settings) Int(key string) int {
+	return s.get(key).(int)
+}
+
+// Float returns the value of a float setting.
+func (s *Settings) Float(key string) float64 {
+	return s.get(key).(float64)
+}
+
+// String returns the value of a string setting.
+func (s *Settings) String(key string) string {
+	return s.get(key).(string)
+}
+
+// IVec2 returns the value of an ivec2 setting.
+func (s *Settings) IVec2(key string) math.IVec2 {
+	return s.get(key).(math.IVec2)
+}
+
+// Set changes the value of a setting. The change is saved by Save.
+func (s *Settings) Set(key string, value interface{}) error {
+	if err := s.set(key, value); err != nil {
+		return err
+	}
+	s.changed[key] = true
+
+	return nil
+}
+
+// Override changes the value of a setting for this run only. The change is
+// not saved by Save.
+func (s *Settings) Override(key string, value interface{}) error {
+	return s.set(key, value)
+}
+
+// Reset changes a setting back to its default value.
+func (s *Settings) Reset(key string) error {
+	setting, ok := s.settings[key]
+	if !ok {
+		return ErrSettingNotFound(key)
+	}
+
+	return s.Set(key, setting.Default)
+}
+
+func (s *Settings) set(key string, value interface{}) error {
+	setting, ok := s.settings[key]
+	if !ok {
+		return ErrSettingNotFound(key)
+	}
+
+	v, err := setting.Convert(value)
+	if err != nil {
+		return err
+	}
+
+	old, _ := s.Get(key)
+	s.v.Set(key, v)
+
+	if !reflect.DeepEqual(old, v) {
+		s.notify(key, v)
+	}
+
+	return nil
+}
+
+// Changed reports if a setting has been changed with Set since it was loaded.
+func (s *Settings) Changed(key string) bool {
+	return s.changed[key]
+}
+
+// Watch registers fn to be called whenever the value of a setting changes. It
+// returns a function which removes the listener again.
+func (s *Settings) Watch(key string, fn SettingListener) func() {
+	w := &settingWatch{fn: fn}
+	s.listeners[key] = append(s.listeners[key], w)
+
+	return func() {
+		listeners := s.listeners[key]
+		for i := range listeners {
+			if listeners[i] == w {
+				s.listeners[key] = append(listeners[:i:i], listeners[i+1:]...)
+				break
+			}
+		}
+		if len(s.listeners[key]) == 0 {
+			delete(s.listeners, key)
+		}
+	}
+}
+
+func (s *Settings) notify(key string, value interface{}) {
+	for _, w := range s.listeners[key] {
+		w.fn(key, value)
+	}
+
+	publishEvent(EventSettingChanged{Key: key, Value: value})
+}
+
+// Validate checks the current value of every setting, and returns an
+// ErrSettingsInvalid listing those which are invalid.
+func (s *Settings) Validate() error {
+	var errs ErrSettingsInvalid
+
+	for _, key := range s.keys {
+		if _, err := s.Get(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// ApplyArgs overrides settings from command line arguments of the form
+// --key=value, such as --graphics.vsync=false. Arguments which do not name a
+// registered setting are returned in order.
+func (s *Settings) ApplyArgs(args []string) ([]string, error) {
+	var rest []string
+
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--") {
+			rest = append(rest, arg)
+			continue
+		}
+
+		kv := strings.SplitN(strings.TrimPrefix(arg, "--"), "=", 2)
+		if len(kv) != 2 || !s.Registered(kv[0]) {
+			rest = append(rest, arg)
+			continue
+		}
+
+		if err := s.Override(kv[0], kv[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return rest, nil
+}
+
+// Save writes the settings changed with Set to the configuration file. Other
+// values already in the file are preserved.
+func (s *Settings) Save() error {
+	filename := s.v.ConfigFileUsed()
+	if filename == "" {
+		filename = cfgFilename
+	}
+
+	return s.SaveAs(filename)
+}
+
+// SaveAs is like Save, but writes to the given file.
+func (s *Settings) SaveAs(filename string) error {
+	cfg := make(map[string]interface{})
+
+	data, err := ioutil.ReadFile(filename)
+	if err == nil {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, key := range s.keys {
+		if !s.changed[key] {
+			continue
+		}
+
+		v, err := s.Get(key)
+		if err != nil {
+			return err
+		}
+
+		setNested(cfg, strings.Split(key, "."), v)
+	}
+
+	data, err = json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// setNested sets a value in nested maps, creating them where required.
+func setNested(m map[string]interface{}, path []string, value interface{}) {
+	for _, k := range path[:len(path)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[k] = next
+		}
+		m = next
+	}
+
+	m[path[len(path)-1]] = value
+}
+
+// Convert converts value to the type of the setting, and checks that it is
+// permitted.
+func (s *Setting) Convert(value interface{}) (interface{}, error) {
+	invalid := func(reason string) error {
+		return ErrSettingInvalid{Key: s.Key, Value: value, Reason: reason}
+	}
+
+	switch s.Type {
+	case SettingBool:
+		v, err := cast.ToBoolE(value)
+		if err != nil {
+			return nil, invalid("not a bool")
+		}
+		return v, nil
+	case SettingInt:
+		v, err := cast.ToIntE(value)
+		if err != nil {
+			return nil, invalid("not an int")
+		}
+		if !s.inRange(float64(v)) {
+			return nil, invalid(s.rangeReason())
+		}
+		return v, nil
+	case SettingFloat:
+		v, err := cast.ToFloat64E(value)
+		if err != nil {
+			return nil, invalid("not a float")
+		}
+		if !s.inRange(v) {
+			return nil, invalid(s.rangeReason())
+		}
+		return v, nil
+	case SettingString:
+		v, err := cast.ToStringE(value)
+		if err != nil {
+			return nil, invalid("not a string")
+		}
+		if len(s.Options) == 0 {
+			return v, nil
+		}
+		for i := range s.Options {
+			if s.Options[i] == v {
+				return v, nil
+			}
+		}
+		return nil, invalid("must be one of " + strings.Join(s.Options, ", "))
+	case SettingIVec2:
+		v, err := parseIVec2(value)
+		if err != nil {
+			return nil, invalid("not an ivec2")
+		}
+		if !s.inRange(float64(v[0])) || !s.inRange(float64(v[1])) {
+			return nil, invalid(s.rangeReason())
+		}
+		return v, nil
+	}
+
+	return nil, invalid("unknown setting type " + s.Type.String())
+}
+
+func (s *Setting) inRange(v float64) bool {
+	if s.Min == 0 && s.Max == 0 {
+		return true
+	}
+
+	return v >= s.Min && v <= s.Max
+}
+
+func (s *Setting) rangeReason() string {
+	return "must be between " + strconv.FormatFloat(s.Min, 'g', -1, 64) +
+		" and " + strconv.FormatFloat(s.Max, 'g', -1, 64)
+}
+
+// parseIVec2 converts a value to an IVec2. Strings may be of the form
+// 1280x720 or 1280,720.
+func parseIVec2(value interface{}) (math.IVec2, error) {
+	str, ok := value.(string)
+	if !ok {
+		return math.ToIVec2E(value)
+	}
+
+	parts := strings.FieldsFunc(str, func(r rune) bool {
+		return r == 'x' || r == ',' || r == ' '
+	})
+	if len(parts) != 2 {
+		return math.IVec2{}, fmt.Errorf("unable to cast %#v to IVec2", value)
+	}
+
+	var v math.IVec2
+	for i := range parts {
+		n, err := strconv.ParseInt(parts[i], 10, 32)
+		if err != nil {
+			return math.IVec2{}, err
+		}
+		v[i] = int32(n)
+	}
+
+	return v, nil
+}
+
+// NewSettings creates a new settings registry backed by v.
+func NewSettings(v *viper.Viper) *Settings {
+	return &Settings{
+		v:         v,
+		settings:  make(map[string]*Setting),
+		changed:   make(map[string]bool),
+		listeners: make(map[string][]*settingWatch),
+	}
+}
+
+// GlobalSettings returns the settings registry backed by arc.cfg.
+func GlobalSettings() *Settings {
+	return globalSettings
+}
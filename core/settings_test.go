@@ -0,0 +1,210 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/haakenlabs/arc/pkg/math"
+)
+
+func newTestSettings() *Settings {
+	s := NewSettings(viper.New())
+
+	s.MustRegister(Setting{Key: "graphics.vsync", Type: SettingBool, Default: true})
+	s.MustRegister(Setting{Key: "graphics.mode", Type: SettingInt, Default: 0, Min: 0, Max: 2})
+	s.MustRegister(Setting{Key: "graphics.resolution", Type: SettingIVec2, Default: math.IVec2{1280, 720}, Min: 1, Max: 16384})
+	s.MustRegister(Setting{Key: "audio.driver", Type: SettingString, Default: "auto", Options: []string{"auto", "null"}})
+
+	return s
+}
+
+func TestSettings_Convert(t *testing.T) {
+	s := newTestSettings()
+
+	tests := []struct {
+		key   string
+		value interface{}
+		want  interface{}
+		valid bool
+	}{
+		{"graphics.vsync", "false", false, true},
+		{"graphics.vsync", "maybe", nil, false},
+		{"graphics.mode", "2", 2, true},
+		{"graphics.mode", 3, nil, false},
+		{"graphics.resolution", "1920x1080", math.IVec2{1920, 1080}, true},
+		{"graphics.resolution", []interface{}{800.0, 600.0}, math.IVec2{800, 600}, true},
+		{"graphics.resolution", "0x0", nil, false},
+		{"audio.driver", "null", "null", true},
+		{"audio.driver", "alsa", nil, false},
+	}
+
+	for i, v := range tests {
+		err := s.Set(v.key, v.value)
+		if (err == nil) != v.valid {
+			t.Errorf("Test %d: got: %v want valid: %v", i, err, v.valid)
+			continue
+		}
+		if !v.valid {
+			continue
+		}
+		if got, _ := s.Get(v.key); !reflect.DeepEqual(got, v.want) {
+			t.Errorf("Test %d: got: %v want: %v", i, got, v.want)
+		}
+	}
+
+	if err := s.Set("missing", 1); err != ErrSettingNotFound("missing") {
+		t.Errorf("got: %v want: %v", err, ErrSettingNotFound("missing"))
+	}
+}
+
+func TestSettings_Validate(t *testing.T) {
+	s := newTestSettings()
+	s.v.Set("graphics.mode", 7)
+	s.v.Set("audio.driver", "alsa")
+
+	err := s.Validate()
+	errs, ok := err.(ErrSettingsInvalid)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("got: %v want: 2 errors", err)
+	}
+
+	// Typed getters fall back to the default for invalid values.
+	if got := s.Int("graphics.mode"); got != 0 {
+		t.Errorf("got: %v want: 0", got)
+	}
+}
+
+func TestSettings_ApplyArgsAndWatch(t *testing.T) {
+	s := newTestSettings()
+
+	var got []interface{}
+	s.Watch("graphics.vsync", func(key string, value interface{}) {
+		got = append(got, value)
+	})
+
+	rest, err := s.ApplyArgs([]string{"--graphics.vsync=false", "--other=1", "file.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"--other=1", "file.txt"}; !reflect.DeepEqual(rest, want) {
+		t.Errorf("got: %v want: %v", rest, want)
+	}
+	if s.Changed("graphics.vsync") {
+		t.Error("command line override marked as changed")
+	}
+
+	// Setting the same value again does not notify.
+	s.Set("graphics.vsync", false)
+	s.Set("graphics.vsync", true)
+
+	if want := []interface{}{false, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v want: %v", got, want)
+	}
+
+	if _, err := s.ApplyArgs([]string{"--graphics.mode=9"}); err == nil {
+		t.Error("invalid argument got: nil want: error")
+	}
+}
+
+func TestSettings_Unwatch(t *testing.T) {
+	s := newTestSettings()
+
+	var a, b int
+	unwatchA := s.Watch("graphics.vsync", func(key string, value interface{}) { a++ })
+	unwatchB := s.Watch("graphics.vsync", func(key string, value interface{}) { b++ })
+
+	tests := []struct {
+		unwatch func()
+		a, b    int
+	}{
+		{nil, 1, 1},
+		{unwatchA, 1, 2},
+		{unwatchA, 1, 3},
+		{unwatchB, 1, 3},
+	}
+
+	for i, v := range tests {
+		if v.unwatch != nil {
+			v.unwatch()
+		}
+		s.Set("graphics.vsync", !s.Bool("graphics.vsync"))
+
+		if a != v.a || b != v.b {
+			t.Errorf("Test %d: got: %d, %d want: %d, %d", i, a, b, v.a, v.b)
+		}
+	}
+
+	if n := len(s.listeners); n != 0 {
+		t.Errorf("listeners got: %d want: 0", n)
+	}
+}
+
+func TestSettings_Save(t *testing.T) {
+	dir, err := ioutil.TempDir("", "arc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "arc.cfg")
+	if err := ioutil.WriteFile(filename, []byte(`{"user": {"name": "test"}, "graphics": {"mode": 1}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestSettings()
+	s.Override("audio.driver", "null")
+	s.Set("graphics.resolution", "640x480")
+
+	if err := s.SaveAs(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg map[string]interface{}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"user": map[string]interface{}{"name": "test"},
+		"graphics": map[string]interface{}{
+			"mode":       1.0,
+			"resolution": []interface{}{640.0, 480.0},
+		},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("got: %v want: %v", cfg, want)
+	}
+}
@@ -28,6 +28,7 @@ type Sound struct {
 	BaseObject
 
 	streamer beep.Streamer
+	buffer   *beep.Buffer
 	format   beep.Format
 
 	loop bool
@@ -45,6 +46,35 @@ func NewSound(streamer beep.Streamer, format beep.Format) *Sound {
 	return s
 }
 
+// NewBufferedSound creates a sound which plays decoded samples held in
+// memory. Unlike a sound created from a streamer, it can be played more than
+// once.
+func NewBufferedSound(buffer *beep.Buffer) *Sound {
+	s := &Sound{
+		buffer: buffer,
+		format: buffer.Format(),
+	}
+
+	s.SetName("Sound")
+	GetInstanceSystem().MustAssign(s)
+
+	return s
+}
+
+// Streamer returns the streamer which plays the sound.
+func (s *Sound) Streamer() beep.Streamer {
+	if s.buffer != nil {
+		return s.buffer.Streamer(0, s.buffer.Len())
+	}
+
+	return s.streamer
+}
+
+// Buffered reports if the samples of the sound are held in memory.
+func (s *Sound) Buffered() bool {
+	return s.buffer != nil
+}
+
 func (s *Sound) Play() {
 	GetAudioSystem().PlaySound(s)
 }
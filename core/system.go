@@ -22,6 +22,8 @@ SOFTWARE.
 
 package core
 
+import "strings"
+
 type ErrSystemNotFound string
 type ErrSystemExists string
 type ErrSystemInit string
@@ -38,6 +40,25 @@ func (e ErrSystemInit) Error() string {
 	return "system " + string(e) + " already initialized"
 }
 
+// ErrSystemDependency reports that a system depends on a system which has not
+// been registered.
+type ErrSystemDependency struct {
+	System     string
+	Dependency string
+}
+
+func (e ErrSystemDependency) Error() string {
+	return "system " + e.System + " depends on unregistered system " + e.Dependency
+}
+
+// ErrSystemCycle reports that the dependencies of the named systems form a
+// cycle.
+type ErrSystemCycle []string
+
+func (e ErrSystemCycle) Error() string {
+	return "system dependency cycle between: " + strings.Join(e, ", ")
+}
+
 // System is an interface representing a major component of the application.
 type System interface {
 	// Setup sets up the System.
@@ -49,3 +70,139 @@ type System interface {
 	// Name returns the name of the System.
 	Name() string
 }
+
+// SystemDependent is implemented by systems which require other systems to be
+// set up before them.
+type SystemDependent interface {
+	// Dependencies returns the names of the systems this System depends on.
+	Dependencies() []string
+}
+
+// SystemPreUpdater is implemented by systems which need to be called at the
+// start of every frame, before any updates.
+type SystemPreUpdater interface {
+	// PreUpdate is called every frame, before Update.
+	PreUpdate()
+}
+
+// SystemUpdater is implemented by systems which need to be called every frame.
+type SystemUpdater interface {
+	// Update is called every frame.
+	Update()
+}
+
+// SystemFixedUpdater is implemented by systems which need to be called at
+// fixed intervals.
+type SystemFixedUpdater interface {
+	// FixedUpdate is called at fixed intervals, after Update.
+	FixedUpdate()
+}
+
+// SystemPostRenderer is implemented by systems which need to be called at the
+// end of every frame.
+type SystemPostRenderer interface {
+	// PostRender is called every frame after the scene has been displayed and
+	// before the buffers are swapped. In headless mode no rendering takes
+	// place, but PostRender is still called.
+	PostRender()
+}
+
+// SortSystems orders systems so every system comes after its dependencies.
+// Systems without a dependency between them keep their relative order, so
+// the result is deterministic.
+func SortSystems(systems []System) ([]System, error) {
+	index := make(map[string]int, len(systems))
+	for i := range systems {
+		index[systems[i].Name()] = i
+	}
+
+	deps := make([][]string, len(systems))
+	for i := range systems {
+		d, ok := systems[i].(SystemDependent)
+		if !ok {
+			continue
+		}
+
+		for _, name := range d.Dependencies() {
+			if _, ok := index[name]; !ok {
+				return nil, ErrSystemDependency{System: systems[i].Name(), Dependency: name}
+			}
+			deps[i] = append(deps[i], name)
+		}
+	}
+
+	sorted := make([]System, 0, len(systems))
+	placed := make(map[string]bool, len(systems))
+
+	for len(sorted) < len(systems) {
+		progress := false
+
+		for i := range systems {
+			if placed[systems[i].Name()] {
+				continue
+			}
+
+			ready := true
+			for _, name := range deps[i] {
+				if !placed[name] {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+
+			sorted = append(sorted, systems[i])
+			placed[systems[i].Name()] = true
+			progress = true
+			break
+		}
+
+		if !progress {
+			return nil, findCycle(systems, index, deps, placed)
+		}
+	}
+
+	return sorted, nil
+}
+
+// findCycle returns the unplaced systems which depend on themselves through
+// other unplaced systems. Systems which only depend on a cycle are left out.
+func findCycle(systems []System, index map[string]int, deps [][]string, placed map[string]bool) ErrSystemCycle {
+	var cycle ErrSystemCycle
+
+	for i := range systems {
+		if placed[systems[i].Name()] {
+			continue
+		}
+
+		// Search the dependencies of i for a path back to i.
+		seen := make([]bool, len(systems))
+		stack := []int{i}
+		found := false
+
+		for len(stack) > 0 && !found {
+			j := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			for _, name := range deps[j] {
+				k := index[name]
+				if k == i {
+					found = true
+					break
+				}
+				if !seen[k] && !placed[name] {
+					seen[k] = true
+					stack = append(stack, k)
+				}
+			}
+		}
+
+		if found {
+			cycle = append(cycle, systems[i].Name())
+		}
+	}
+
+	return cycle
+}
@@ -0,0 +1,123 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+type dummySystem struct {
+	name string
+	deps []string
+}
+
+func (s *dummySystem) Setup() error { return nil }
+
+func (s *dummySystem) Teardown() {}
+
+func (s *dummySystem) Name() string { return s.name }
+
+func (s *dummySystem) Dependencies() []string { return s.deps }
+
+func systemNames(systems []System) []string {
+	var names []string
+	for i := range systems {
+		names = append(names, systems[i].Name())
+	}
+
+	return names
+}
+
+func TestSortSystems(t *testing.T) {
+	tests := []struct {
+		in   []System
+		want []string
+		err  error
+	}{
+		{
+			in: []System{
+				&dummySystem{name: "a"},
+				&dummySystem{name: "b"},
+				&dummySystem{name: "c"},
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			in: []System{
+				&dummySystem{name: "a", deps: []string{"c"}},
+				&dummySystem{name: "b"},
+				&dummySystem{name: "c", deps: []string{"b"}},
+			},
+			want: []string{"b", "c", "a"},
+		},
+		{
+			in: []System{
+				&dummySystem{name: "a", deps: []string{"d"}},
+				&dummySystem{name: "b"},
+				&dummySystem{name: "c"},
+				&dummySystem{name: "d", deps: []string{"b"}},
+			},
+			want: []string{"b", "c", "d", "a"},
+		},
+		{
+			in: []System{
+				&dummySystem{name: "a", deps: []string{"x"}},
+			},
+			err: ErrSystemDependency{System: "a", Dependency: "x"},
+		},
+		{
+			in: []System{
+				&dummySystem{name: "a"},
+				&dummySystem{name: "b", deps: []string{"c"}},
+				&dummySystem{name: "c", deps: []string{"b"}},
+			},
+			err: ErrSystemCycle{"b", "c"},
+		},
+		{
+			in: []System{
+				&dummySystem{name: "a", deps: []string{"b"}},
+				&dummySystem{name: "b", deps: []string{"c"}},
+				&dummySystem{name: "c", deps: []string{"d"}},
+				&dummySystem{name: "d", deps: []string{"c"}},
+				&dummySystem{name: "e", deps: []string{"e"}},
+			},
+			err: ErrSystemCycle{"c", "d", "e"},
+		},
+	}
+
+	for i, v := range tests {
+		got, err := SortSystems(v.in)
+		if !reflect.DeepEqual(err, v.err) {
+			t.Errorf("Test %d: error got: %v want: %v", i, err, v.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if names := systemNames(got); !reflect.DeepEqual(names, v.want) {
+			t.Errorf("Test %d: got: %v want: %v", i, names, v.want)
+		}
+	}
+}
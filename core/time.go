@@ -23,7 +23,8 @@ SOFTWARE.
 package core
 
 import (
-	"github.com/go-gl/glfw/v3.2/glfw"
+	"errors"
+	"math"
 )
 
 var _ System = &TimeSystem{}
@@ -32,14 +33,37 @@ var timeInst *TimeSystem
 
 const SysNameTime = "time"
 
-const fixedTime = float64(0.05)
+const (
+	// DefaultFixedTime is the default interval between fixed updates.
+	DefaultFixedTime = float64(0.05)
+
+	// maxLogicLag limits the amount of game time which may be waiting on fixed
+	// updates, so a long frame does not cause the logic to fall behind forever.
+	maxLogicLag = float64(0.25)
+)
+
+// ErrInvalidFixedTime reports that a fixed time step is not positive.
+var ErrInvalidFixedTime = errors.New("time: fixed time step must be positive")
 
 // TimeSystem implements a time system.
+//
+// Scaled time (DeltaTime, Time) is affected by the time scale and stops while
+// the system is paused. Unscaled time (UnscaledDeltaTime, UnscaledTime) always
+// follows the clock.
 type TimeSystem struct {
+	clock         Clock
 	frameTime     float64
 	deltaTime     float64
-	nextLogicTick float64
+	unscaledDelta float64
+	time          float64
+	unscaledTime  float64
+	fixedTime     float64
+	logicLag      float64
+	timeScale     float64
+	recording     []float64
 	frame         uint64
+	paused        bool
+	recordEnabled bool
 }
 
 // Setup sets up the System.
@@ -62,24 +86,104 @@ func (t *TimeSystem) Name() string {
 	return SysNameTime
 }
 
+// Clock returns the clock used by this system.
+func (t *TimeSystem) Clock() Clock {
+	return t.clock
+}
+
+// SetClock sets the clock used by this system. The clock should be set before
+// the first frame starts.
+func (t *TimeSystem) SetClock(clock Clock) {
+	t.clock = clock
+}
+
+// FrameTime returns the clock time at which the current frame started.
 func (t *TimeSystem) FrameTime() float64 {
 	return t.frameTime
 }
 
+// DeltaTime returns the scaled duration of the last frame in seconds. While
+// paused, this is zero.
 func (t *TimeSystem) DeltaTime() float64 {
 	return t.deltaTime
 }
 
+// UnscaledDeltaTime returns the duration of the last frame in seconds,
+// ignoring the time scale and pause state.
+func (t *TimeSystem) UnscaledDeltaTime() float64 {
+	return t.unscaledDelta
+}
+
+// Time returns the scaled time in seconds that has elapsed since the first
+// frame.
+func (t *TimeSystem) Time() float64 {
+	return t.time
+}
+
+// UnscaledTime returns the time in seconds that has elapsed since the first
+// frame, ignoring the time scale and pause state.
+func (t *TimeSystem) UnscaledTime() float64 {
+	return t.unscaledTime
+}
+
+// FixedTime returns the interval between fixed updates in seconds.
 func (t *TimeSystem) FixedTime() float64 {
-	return fixedTime
+	return t.fixedTime
+}
+
+// SetFixedTime sets the interval between fixed updates in seconds.
+func (t *TimeSystem) SetFixedTime(fixedTime float64) error {
+	if fixedTime <= 0 || math.IsNaN(fixedTime) || math.IsInf(fixedTime, 0) {
+		return ErrInvalidFixedTime
+	}
+
+	t.fixedTime = fixedTime
+
+	return nil
+}
+
+// TimeScale returns the rate at which scaled time passes. A scale of 1 is real
+// time.
+func (t *TimeSystem) TimeScale() float64 {
+	return t.timeScale
+}
+
+// SetTimeScale sets the rate at which scaled time passes. Negative values are
+// treated as zero.
+func (t *TimeSystem) SetTimeScale(scale float64) {
+	t.timeScale = math.Max(scale, 0)
+}
+
+// Pause stops scaled time. Update is still called every frame, but DeltaTime
+// is zero and no fixed updates take place.
+func (t *TimeSystem) Pause() {
+	t.paused = true
+}
+
+// Resume resumes scaled time after a call to Pause.
+func (t *TimeSystem) Resume() {
+	t.paused = false
+}
+
+// Paused reports if scaled time is paused.
+func (t *TimeSystem) Paused() bool {
+	return t.paused
+}
+
+// Alpha returns how far scaled time has progressed between the last fixed
+// update and the next one, in the range [0, 1]. It can be used to interpolate
+// state which is only updated in fixed updates when rendering.
+func (t *TimeSystem) Alpha() float64 {
+	return math.Min(t.logicLag/t.fixedTime, 1)
 }
 
 func (t *TimeSystem) Delta() float64 {
 	return t.deltaTime
 }
 
+// Now returns the current time of the clock.
 func (t *TimeSystem) Now() float64 {
-	return glfw.GetTime()
+	return t.clock.Now()
 }
 
 func (t *TimeSystem) FrameStart() {
@@ -87,7 +191,26 @@ func (t *TimeSystem) FrameStart() {
 }
 
 func (t *TimeSystem) FrameEnd() {
-	t.deltaTime = t.Now() - t.frameTime
+	if c, ok := t.clock.(FrameClock); ok {
+		c.OnFrame()
+	}
+
+	t.unscaledDelta = t.Now() - t.frameTime
+	t.unscaledTime += t.unscaledDelta
+
+	if t.paused {
+		t.deltaTime = 0
+	} else {
+		t.deltaTime = t.unscaledDelta * t.timeScale
+	}
+
+	t.time += t.deltaTime
+	t.logicLag = math.Min(t.logicLag+t.deltaTime, math.Max(maxLogicLag, t.fixedTime))
+
+	if t.recordEnabled {
+		t.recording = append(t.recording, t.unscaledDelta)
+	}
+
 	t.frame++
 }
 
@@ -95,17 +218,52 @@ func (t *TimeSystem) Frame() uint64 {
 	return t.frame
 }
 
+// LogicTick consumes one fixed interval of scaled time.
 func (t *TimeSystem) LogicTick() {
-	t.nextLogicTick += fixedTime
+	t.logicLag -= t.fixedTime
 }
 
+// LogicUpdate reports if enough scaled time has passed for a fixed update.
+// While paused, this is always false.
 func (t *TimeSystem) LogicUpdate() bool {
-	return t.Now() > t.nextLogicTick
+	return !t.paused && t.logicLag >= t.fixedTime
+}
+
+// StartRecording starts recording unscaled frame deltas. The recording can be
+// replayed with a RecordedClock.
+func (t *TimeSystem) StartRecording() {
+	t.recording = t.recording[:0]
+	t.recordEnabled = true
+}
+
+// StopRecording stops recording frame deltas and returns the recording.
+func (t *TimeSystem) StopRecording() []float64 {
+	t.recordEnabled = false
+
+	recording := make([]float64, len(t.recording))
+	copy(recording, t.recording)
+
+	return recording
 }
 
 // NewTime creates a new time system.
 func NewTimeSystem() *TimeSystem {
-	return &TimeSystem{}
+	return NewTimeSystemWithClock(NewGLFWClock())
+}
+
+// NewHeadlessTimeSystem creates a new time system which does not depend on
+// GLFW. Time is measured from the moment the system is created.
+func NewHeadlessTimeSystem() *TimeSystem {
+	return NewTimeSystemWithClock(NewWallClock())
+}
+
+// NewTimeSystemWithClock creates a new time system using the given clock.
+func NewTimeSystemWithClock(clock Clock) *TimeSystem {
+	return &TimeSystem{
+		clock:     clock,
+		fixedTime: DefaultFixedTime,
+		timeScale: 1,
+	}
 }
 
 // GetTime gets the time system from the current app.
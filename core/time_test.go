@@ -0,0 +1,177 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+const epsilon = 1e-9
+
+func runFrames(t *TimeSystem, n int) (fixed int) {
+	for i := 0; i < n; i++ {
+		t.FrameStart()
+		for t.LogicUpdate() {
+			t.LogicTick()
+			fixed++
+		}
+		t.FrameEnd()
+	}
+
+	return fixed
+}
+
+func TestTimeSystem_ManualClock(t *testing.T) {
+	tests := []struct {
+		step      float64
+		scale     float64
+		frames    int
+		wantTime  float64
+		wantDelta float64
+	}{
+		{step: 0.01, scale: 1, frames: 10, wantTime: 0.1, wantDelta: 0.01},
+		{step: 0.01, scale: 0.5, frames: 10, wantTime: 0.05, wantDelta: 0.005},
+		{step: 0.02, scale: 2, frames: 5, wantTime: 0.2, wantDelta: 0.04},
+		{step: 0.02, scale: 0, frames: 5, wantTime: 0, wantDelta: 0},
+	}
+
+	for i, v := range tests {
+		ts := NewTimeSystemWithClock(NewManualClock(v.step))
+		ts.SetTimeScale(v.scale)
+
+		runFrames(ts, v.frames)
+
+		if math.Abs(ts.Time()-v.wantTime) > epsilon {
+			t.Errorf("Test %d: Time() got: %v want: %v", i, ts.Time(), v.wantTime)
+		}
+		if math.Abs(ts.DeltaTime()-v.wantDelta) > epsilon {
+			t.Errorf("Test %d: DeltaTime() got: %v want: %v", i, ts.DeltaTime(), v.wantDelta)
+		}
+		if math.Abs(ts.UnscaledTime()-v.step*float64(v.frames)) > epsilon {
+			t.Errorf("Test %d: UnscaledTime() got: %v want: %v", i, ts.UnscaledTime(), v.step*float64(v.frames))
+		}
+		if ts.Frame() != uint64(v.frames) {
+			t.Errorf("Test %d: Frame() got: %v want: %v", i, ts.Frame(), v.frames)
+		}
+	}
+}
+
+func TestTimeSystem_FixedUpdate(t *testing.T) {
+	tests := []struct {
+		step      float64
+		fixedTime float64
+		frames    int
+		want      int
+	}{
+		{step: 1.0 / 60.0, fixedTime: 1.0 / 60.0, frames: 60, want: 59},
+		{step: 0.01, fixedTime: 0.05, frames: 100, want: 19},
+		{step: 0.125, fixedTime: 0.0625, frames: 10, want: 18},
+	}
+
+	for i, v := range tests {
+		ts := NewTimeSystemWithClock(NewManualClock(v.step))
+		if err := ts.SetFixedTime(v.fixedTime); err != nil {
+			t.Fatalf("Test %d: unexpected error: %v", i, err)
+		}
+
+		if got := runFrames(ts, v.frames); got != v.want {
+			t.Errorf("Test %d: fixed updates got: %v want: %v", i, got, v.want)
+		}
+		if ts.Alpha() < 0 || ts.Alpha() > 1 {
+			t.Errorf("Test %d: Alpha() out of range: %v", i, ts.Alpha())
+		}
+	}
+}
+
+func TestTimeSystem_SetFixedTime(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want error
+	}{
+		{in: 0.02, want: nil},
+		{in: 0, want: ErrInvalidFixedTime},
+		{in: -1, want: ErrInvalidFixedTime},
+		{in: math.NaN(), want: ErrInvalidFixedTime},
+	}
+
+	for i, v := range tests {
+		ts := NewTimeSystemWithClock(NewManualClock(0))
+
+		if got := ts.SetFixedTime(v.in); got != v.want {
+			t.Errorf("Test %d: got: %v want: %v", i, got, v.want)
+		}
+	}
+}
+
+func TestTimeSystem_Pause(t *testing.T) {
+	ts := NewTimeSystemWithClock(NewManualClock(0.05))
+
+	runFrames(ts, 4)
+	ts.Pause()
+	if got := runFrames(ts, 10); got != 0 {
+		t.Errorf("fixed updates while paused got: %v want: 0", got)
+	}
+	if ts.DeltaTime() != 0 {
+		t.Errorf("DeltaTime() while paused got: %v want: 0", ts.DeltaTime())
+	}
+	if math.Abs(ts.UnscaledDeltaTime()-0.05) > epsilon {
+		t.Errorf("UnscaledDeltaTime() while paused got: %v want: 0.05", ts.UnscaledDeltaTime())
+	}
+	ts.Resume()
+	runFrames(ts, 4)
+
+	if math.Abs(ts.Time()-0.4) > epsilon {
+		t.Errorf("Time() got: %v want: 0.4", ts.Time())
+	}
+	if math.Abs(ts.UnscaledTime()-0.9) > epsilon {
+		t.Errorf("UnscaledTime() got: %v want: 0.9", ts.UnscaledTime())
+	}
+}
+
+func TestTimeSystem_Recording(t *testing.T) {
+	clock := NewManualClock(0)
+	ts := NewTimeSystemWithClock(clock)
+
+	ts.StartRecording()
+	for _, d := range []float64{0.01, 0.02, 0.03} {
+		ts.FrameStart()
+		clock.Advance(d)
+		ts.FrameEnd()
+	}
+	recording := ts.StopRecording()
+
+	replay := NewTimeSystemWithClock(NewRecordedClock(recording))
+	for i, want := range recording {
+		replay.FrameStart()
+		replay.FrameEnd()
+
+		if math.Abs(replay.DeltaTime()-want) > epsilon {
+			t.Errorf("Frame %d: DeltaTime() got: %v want: %v", i, replay.DeltaTime(), want)
+		}
+	}
+
+	if !replay.Clock().(*RecordedClock).Done() {
+		t.Error("RecordedClock not done after replaying all deltas")
+	}
+}
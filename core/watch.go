@@ -0,0 +1,187 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileWatcher polls files for changes to their size or modification time.
+// Polling works on every platform and filesystem, at the cost of a stat call
+// per file each interval. Its methods are safe to call from any goroutine.
+type FileWatcher struct {
+	files map[string]fileState
+	stop  chan struct{}
+	done  chan struct{}
+	mu    *sync.Mutex
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// statFile returns the current state of a file.
+func statFile(file string) fileState {
+	info, err := os.Stat(file)
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+// Add starts watching a file. Files which do not exist yet can be watched,
+// and are reported once they are created.
+func (w *FileWatcher) Add(file string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.files[file]; !ok {
+		w.files[file] = statFile(file)
+	}
+}
+
+// Remove stops watching a file.
+func (w *FileWatcher) Remove(file string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.files, file)
+}
+
+// Watching reports if a file is being watched.
+func (w *FileWatcher) Watching(file string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, ok := w.files[file]
+
+	return ok
+}
+
+// Files returns the watched files, sorted.
+func (w *FileWatcher) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	files := make([]string, 0, len(w.files))
+	for file := range w.files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+// Poll checks the watched files and returns those which have changed since
+// the last poll, sorted. Files which have been removed are not reported until
+// they exist again, so that a file which is replaced by an editor is seen as
+// a single change.
+func (w *FileWatcher) Poll() []string {
+	w.mu.Lock()
+	files := make([]string, 0, len(w.files))
+	for file := range w.files {
+		files = append(files, file)
+	}
+	w.mu.Unlock()
+
+	var changed []string
+
+	for _, file := range files {
+		state := statFile(file)
+
+		w.mu.Lock()
+		prev, ok := w.files[file]
+		if ok {
+			w.files[file] = state
+		}
+		w.mu.Unlock()
+
+		if ok && state.exists && (!prev.exists || state.size != prev.size || !state.modTime.Equal(prev.modTime)) {
+			changed = append(changed, file)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed
+}
+
+// Start polls the watched files every interval on a new goroutine, and calls
+// fn from that goroutine for each file which has changed. Starting a watcher
+// which is already started restarts it.
+func (w *FileWatcher) Start(interval time.Duration, fn func(file string)) {
+	w.Stop()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	w.mu.Lock()
+	w.stop = stop
+	w.done = done
+	w.mu.Unlock()
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				for _, file := range w.Poll() {
+					fn(file)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops polling and waits for the polling goroutine to exit.
+func (w *FileWatcher) Stop() {
+	w.mu.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// NewFileWatcher creates a new file watcher.
+func NewFileWatcher() *FileWatcher {
+	return &FileWatcher{
+		files: make(map[string]fileState),
+		mu:    &sync.Mutex{},
+	}
+}
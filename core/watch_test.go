@@ -0,0 +1,79 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileWatcher_Poll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "arc-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := filepath.Join(dir, "a.glsl")
+	b := filepath.Join(dir, "b.glsl")
+
+	if err := ioutil.WriteFile(a, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewFileWatcher()
+	w.Add(a)
+	w.Add(b)
+
+	base := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		change func() error
+		want   []string
+	}{
+		{func() error { return nil }, nil},
+		{func() error { return os.Chtimes(a, base, base) }, []string{a}},
+		{func() error { return ioutil.WriteFile(b, []byte("b"), 0644) }, []string{b}},
+		{func() error { return os.Remove(a) }, nil},
+		{func() error { return ioutil.WriteFile(a, []byte("aa"), 0644) }, []string{a}},
+		{func() error { w.Remove(a); return os.Chtimes(a, base, base) }, nil},
+	}
+
+	for i, v := range tests {
+		if err := v.change(); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+
+		if got := w.Poll(); !reflect.DeepEqual(got, v.want) {
+			t.Errorf("Test %d: got: %v want: %v", i, got, v.want)
+		}
+	}
+
+	if got := w.Files(); !reflect.DeepEqual(got, []string{b}) {
+		t.Errorf("Files() got: %v want: %v", got, []string{b})
+	}
+}
@@ -29,7 +29,6 @@ import (
 	"github.com/go-gl/glfw/v3.2/glfw"
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 
 	"github.com/haakenlabs/arc/pkg/math"
 )
@@ -72,6 +71,39 @@ type EventJoy struct {
 	event    int
 }
 
+// EventWindowResize is sent when the window has been resized.
+type EventWindowResize struct {
+	Size math.IVec2
+}
+
+// EventWindowClose is sent when the user has requested the window be closed.
+type EventWindowClose struct{}
+
+// EventFileDrop is sent when files have been dropped onto the window.
+type EventFileDrop struct {
+	Names []string
+}
+
+// EventChar is sent when a unicode character has been input.
+type EventChar struct {
+	Char rune
+}
+
+// EventCursorMove is sent when the cursor has moved.
+type EventCursorMove struct {
+	Position mgl32.Vec2
+}
+
+// EventCursorEnter is sent when the cursor enters or leaves the window.
+type EventCursorEnter struct {
+	Entered bool
+}
+
+// EventScroll is sent when the mouse wheel has been scrolled.
+type EventScroll struct {
+	Offset math.DVec2
+}
+
 type DisplayProperties struct {
 	Resolution math.IVec2
 	Mode       DisplayMode
@@ -101,6 +133,8 @@ type WindowSystem struct {
 	windowResized     bool
 	shouldClose       bool
 	hasEvents         bool
+	headless          bool
+	unwatch           []func()
 }
 
 func (w *WindowSystem) Setup() (err error) {
@@ -109,6 +143,10 @@ func (w *WindowSystem) Setup() (err error) {
 	}
 	windowInst = w
 
+	if w.headless {
+		return w.setupHeadless()
+	}
+
 	var monitor *glfw.Monitor
 
 	if err := glfw.Init(); err != nil {
@@ -123,9 +161,9 @@ func (w *WindowSystem) Setup() (err error) {
 	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
 	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
 
-	w.displayMode = DisplayMode(viper.GetInt("graphics.mode"))
-	w.resolution = math.ToIVec2(viper.Get("graphics.resolution"))
-	w.vsync = viper.GetBool("graphics.vsync")
+	w.displayMode = DisplayMode(globalSettings.Int("graphics.mode"))
+	w.resolution = globalSettings.IVec2("graphics.resolution")
+	w.vsync = globalSettings.Bool("graphics.vsync")
 
 	resX := int(w.resolution.X())
 	resY := int(w.resolution.Y())
@@ -177,6 +215,8 @@ func (w *WindowSystem) Setup() (err error) {
 	w.window.SetSizeCallback(w.onWindowResize)
 	glfw.SetJoystickCallback(w.onJoystick)
 
+	w.watchSettings()
+
 	logrus.Debug("[GLFW] Ready")
 
 	return nil
@@ -205,8 +245,51 @@ func (w *WindowSystem) setupGL() error {
 	return nil
 }
 
+// setupHeadless sets up the window system without creating a window or an
+// OpenGL context. The resolution is still read from the configuration so that
+// code depending on it behaves the same as with a real window.
+func (w *WindowSystem) setupHeadless() error {
+	w.displayMode = DisplayModeWindow
+	w.resolution = globalSettings.IVec2("graphics.resolution")
+	w.vsync = false
+
+	w.SetSize(w.resolution)
+
+	logrus.Debug("[Window] Headless mode, GLFW and OpenGL disabled")
+
+	return nil
+}
+
+// watchSettings applies changes to the graphics settings to the window while
+// the app is running.
+func (w *WindowSystem) watchSettings() {
+	w.unwatch = append(w.unwatch,
+		globalSettings.Watch("graphics.vsync", func(key string, value interface{}) {
+			w.EnableVsync(value.(bool))
+		}),
+		globalSettings.Watch("graphics.mode", func(key string, value interface{}) {
+			w.SetDisplayMode(DisplayMode(value.(int)))
+		}),
+		globalSettings.Watch("graphics.resolution", func(key string, value interface{}) {
+			if w.displayMode == DisplayModeWindow {
+				size := value.(math.IVec2)
+				w.window.SetSize(int(size.X()), int(size.Y()))
+			}
+		}),
+	)
+}
+
 // Teardown tears down the System.
 func (w *WindowSystem) Teardown() {
+	for _, unwatch := range w.unwatch {
+		unwatch()
+	}
+	w.unwatch = nil
+
+	if w.headless {
+		return
+	}
+
 	glfw.Terminate()
 }
 
@@ -215,7 +298,16 @@ func (w *WindowSystem) Name() string {
 	return SysNameWindow
 }
 
+// Headless reports if this window system is running without a window.
+func (w *WindowSystem) Headless() bool {
+	return w.headless
+}
+
 func (w *WindowSystem) EnableVsync(enable bool) {
+	if w.headless {
+		return
+	}
+
 	if enable {
 		glfw.SwapInterval(1)
 	} else {
@@ -230,6 +322,10 @@ func (w *WindowSystem) Vsync() bool {
 }
 
 func (w *WindowSystem) CenterWindow() {
+	if w.headless {
+		return
+	}
+
 	monitor := w.window.GetMonitor()
 	if monitor == nil {
 		monitor = glfw.GetPrimaryMonitor()
@@ -245,7 +341,9 @@ func (w *WindowSystem) CenterWindow() {
 func (w *WindowSystem) SetSize(size math.IVec2) {
 	w.resolution = size
 	w.aspectRatio = getRatio(w.resolution)
-	gl.Viewport(0, 0, int32(size.X()), int32(size.Y()))
+	if !w.headless {
+		gl.Viewport(0, 0, int32(size.X()), int32(size.Y()))
+	}
 	w.ortho = mgl32.Ortho2D(0, float32(w.resolution.X()), float32(w.resolution.Y()), 0)
 }
 
@@ -260,11 +358,19 @@ func (w *WindowSystem) Resolution() math.IVec2 {
 }
 
 func (w *WindowSystem) ClearBuffers() {
+	if w.headless {
+		return
+	}
+
 	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 }
 
 // SwapBuffers : Swap front and rear rendering buffers.
 func (w *WindowSystem) SwapBuffers() {
+	if w.headless {
+		return
+	}
+
 	w.window.SwapBuffers()
 }
 
@@ -280,6 +386,10 @@ func (w *WindowSystem) SetDisplayMode(mode DisplayMode) {
 	var monitor *glfw.Monitor
 	var refresh int
 
+	if w.headless {
+		return
+	}
+
 	posX, posY := w.window.GetPos()
 	resX := int(w.resolution.X())
 	resY := int(w.resolution.Y())
@@ -311,11 +421,16 @@ func (w *WindowSystem) SetDisplayMode(mode DisplayMode) {
 	}
 
 	w.window.SetMonitor(monitor, posX, posY, resX, resY, refresh)
+	w.displayMode = mode
 }
 
 func (w *WindowSystem) GetVideoModes() {
 	var modes []*glfw.VidMode
 
+	if w.headless {
+		return
+	}
+
 	monitors := glfw.GetMonitors()
 
 	for i := range monitors {
@@ -413,7 +528,10 @@ func (w *WindowSystem) WindowResized() bool {
 
 func (w *WindowSystem) HandleEvents() {
 	w.clearEvents()
-	glfw.PollEvents()
+
+	if !w.headless {
+		glfw.PollEvents()
+	}
 }
 
 func (w *WindowSystem) HasEvents() bool {
@@ -433,25 +551,30 @@ func (w *WindowSystem) clearEvents() {
 func (w *WindowSystem) keyEvent(key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
 	w.hasEvents = true
 	w.keyEvents = append(w.keyEvents, EventKey{key, scancode, action, mods})
+	enqueueEvent(EventKey{key, scancode, action, mods})
 }
 
 func (w *WindowSystem) mouseButtonEvent(button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
 	w.hasEvents = true
 	w.mouseButtonEvents = append(w.mouseButtonEvents, EventMouseButton{button, action, mod})
+	enqueueEvent(EventMouseButton{button, action, mod})
 }
 
 func (w *WindowSystem) joystickEvent(joy int, event int) {
 	w.hasEvents = true
 	w.joystickEvents = append(w.joystickEvents, EventJoy{joy, event})
+	enqueueEvent(EventJoy{joy, event})
 }
 
 func (w *WindowSystem) onChar(_ *glfw.Window, char rune) {
 	w.hasEvents = true
+	enqueueEvent(EventChar{Char: char})
 }
 
 func (w *WindowSystem) onCursorEnter(_ *glfw.Window, entered bool) {
 	w.hasEvents = true
 	w.cursorEnter = entered
+	enqueueEvent(EventCursorEnter{Entered: entered})
 }
 
 func (w *WindowSystem) onCursorMove(_ *glfw.Window, xPos float64, yPos float64) {
@@ -459,11 +582,12 @@ func (w *WindowSystem) onCursorMove(_ *glfw.Window, xPos float64, yPos float64)
 	w.cursorPosition[0] = float32(xPos)
 	w.cursorPosition[1] = float32(yPos)
 	w.cursorMoved = true
+	enqueueEvent(EventCursorMove{Position: w.cursorPosition})
 }
 
 func (w *WindowSystem) onDrop(_ *glfw.Window, names []string) {
 	w.hasEvents = true
-	fmt.Printf("onDrop: %v\n", names)
+	enqueueEvent(EventFileDrop{Names: names})
 }
 
 func (w *WindowSystem) onJoystick(joy int, event int) {
@@ -486,11 +610,13 @@ func (w *WindowSystem) onScroll(_ *glfw.Window, xOff float64, yOff float64) {
 	w.scrollAxis[0] = xOff
 	w.scrollAxis[1] = yOff
 	w.scrollMoved = true
+	enqueueEvent(EventScroll{Offset: w.scrollAxis})
 }
 
 func (w *WindowSystem) onClose(_ *glfw.Window) {
 	w.hasEvents = true
 	w.shouldClose = true
+	enqueueEvent(EventWindowClose{})
 }
 
 func (w *WindowSystem) onWindowResize(_ *glfw.Window, width int, height int) {
@@ -498,9 +624,105 @@ func (w *WindowSystem) onWindowResize(_ *glfw.Window, width int, height int) {
 		w.hasEvents = true
 		w.SetSize(math.IVec2{int32(width), int32(height)})
 		w.windowResized = true
+		enqueueEvent(EventWindowResize{Size: w.resolution})
 	}
 }
 
+// EventType returns the type of this event.
+func (e EventKey) EventType() EventType {
+	return EventTypeKey
+}
+
+// Key returns the key of this event.
+func (e EventKey) Key() glfw.Key {
+	return e.key
+}
+
+// Scancode returns the system-specific scancode of the key.
+func (e EventKey) Scancode() int {
+	return e.scancode
+}
+
+// Action returns the action of this event.
+func (e EventKey) Action() glfw.Action {
+	return e.action
+}
+
+// Mods returns the modifier keys held during this event.
+func (e EventKey) Mods() glfw.ModifierKey {
+	return e.mods
+}
+
+// EventType returns the type of this event.
+func (e EventMouseButton) EventType() EventType {
+	return EventTypeMouseButton
+}
+
+// Button returns the mouse button of this event.
+func (e EventMouseButton) Button() glfw.MouseButton {
+	return e.button
+}
+
+// Action returns the action of this event.
+func (e EventMouseButton) Action() glfw.Action {
+	return e.action
+}
+
+// Mods returns the modifier keys held during this event.
+func (e EventMouseButton) Mods() glfw.ModifierKey {
+	return e.mod
+}
+
+// EventType returns the type of this event.
+func (e EventJoy) EventType() EventType {
+	return EventTypeJoystick
+}
+
+// Joystick returns the joystick of this event.
+func (e EventJoy) Joystick() int {
+	return e.joystick
+}
+
+// Event returns the joystick event, either connected or disconnected.
+func (e EventJoy) Event() int {
+	return e.event
+}
+
+// EventType returns the type of this event.
+func (e EventWindowResize) EventType() EventType {
+	return EventTypeWindowResize
+}
+
+// EventType returns the type of this event.
+func (e EventWindowClose) EventType() EventType {
+	return EventTypeWindowClose
+}
+
+// EventType returns the type of this event.
+func (e EventFileDrop) EventType() EventType {
+	return EventTypeFileDrop
+}
+
+// EventType returns the type of this event.
+func (e EventChar) EventType() EventType {
+	return EventTypeChar
+}
+
+// EventType returns the type of this event.
+func (e EventCursorMove) EventType() EventType {
+	return EventTypeCursorMove
+}
+
+// EventType returns the type of this event.
+func (e EventCursorEnter) EventType() EventType {
+	return EventTypeCursorEnter
+}
+
+// EventType returns the type of this event.
+func (e EventScroll) EventType() EventType {
+	return EventTypeScroll
+}
+
 // NewWindow creates a new window system.
 func NewWindowSystem(title string) *WindowSystem {
 	return &WindowSystem{
@@ -513,6 +735,16 @@ func NewWindowSystem(title string) *WindowSystem {
 	}
 }
 
+// NewHeadlessWindowSystem creates a new window system which does not open a
+// window or create an OpenGL context. It is intended for dedicated servers and
+// automated tests.
+func NewHeadlessWindowSystem(title string) *WindowSystem {
+	w := NewWindowSystem(title)
+	w.headless = true
+
+	return w
+}
+
 func GetRecommendedVideoMode(monitor *glfw.Monitor) *glfw.VidMode {
 	modes := monitor.GetVideoModes()
 
@@ -29,21 +29,33 @@ import (
 	"github.com/go-gl/mathgl/mgl32"
 
 	"github.com/haakenlabs/arc/core"
+	"github.com/haakenlabs/arc/pkg/meshutil"
 	"github.com/haakenlabs/arc/system/instance"
 )
 
-// Mesh represents a mesh.
+// Mesh represents a mesh. Its vertices may be indexed, and channels other
+// than positions are optional. The vertex attributes which are uploaded are
+// the channels which are present, at the locations of meshutil.Channel.
 type Mesh struct {
 	core.BaseObject
 
 	vertices       []mgl32.Vec3
 	normals        []mgl32.Vec3
 	uvs            []mgl32.Vec2
+	tangents       []mgl32.Vec4
+	colors         []mgl32.Vec4
+	uvs2           []mgl32.Vec2
+	boneIndices    [][4]uint16
+	boneWeights    []mgl32.Vec4
 	triangles      []uint32
 	vao            uint32
 	vbo            uint32
 	ibo            uint32
+	indexType      uint32
+	drawCount      int32
+	attributes     []meshutil.Channel
 	reverseWinding bool
+	material       string
 }
 
 type Vertex struct {
@@ -72,13 +84,6 @@ func (m *Mesh) Alloc() error {
 	gl.BindBuffer(gl.ARRAY_BUFFER, m.vbo)
 	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, m.ibo)
 
-	gl.EnableVertexAttribArray(0)
-	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 32, gl.PtrOffset(0))
-	gl.EnableVertexAttribArray(1)
-	gl.VertexAttribPointer(1, 3, gl.FLOAT, false, 32, gl.PtrOffset(12))
-	gl.EnableVertexAttribArray(2)
-	gl.VertexAttribPointer(2, 2, gl.FLOAT, false, 32, gl.PtrOffset(24))
-
 	return m.Upload()
 }
 
@@ -97,43 +102,120 @@ func (m *Mesh) Unbind() {
 	gl.BindVertexArray(0)
 }
 
+// Draw draws the triangles of the mesh. The mesh must be bound.
 func (m *Mesh) Draw() {
-	if len(m.vertices) == 0 {
+	if m.drawCount == 0 {
 		return
 	}
 
-	gl.DrawArrays(gl.TRIANGLES, 0, int32(len(m.vertices)))
+	if m.indexType != 0 {
+		gl.DrawElements(gl.TRIANGLES, m.drawCount, m.indexType, nil)
+	} else {
+		gl.DrawArrays(gl.TRIANGLES, 0, m.drawCount)
+	}
 }
 
 func (m *Mesh) Clear() {
 	m.vertices = m.vertices[:0]
 	m.normals = m.normals[:0]
 	m.uvs = m.uvs[:0]
+	m.tangents = m.tangents[:0]
+	m.colors = m.colors[:0]
+	m.uvs2 = m.uvs2[:0]
+	m.boneIndices = m.boneIndices[:0]
+	m.boneWeights = m.boneWeights[:0]
 	m.triangles = m.triangles[:0]
+
+	// Nothing is drawn until the mesh is uploaded again.
+	m.indexType = 0
+	m.drawCount = 0
 }
 
+// Upload uploads the vertices and indices of the mesh. Indices are uploaded
+// as 16 bit integers if the mesh has few enough vertices.
 func (m *Mesh) Upload() error {
-	if len(m.vertices) == 0 || len(m.normals) == 0 || len(m.uvs) == 0 {
-		return fmt.Errorf("mesh upload failed: vao %d has invalid geometry definition: empty data", m.vao)
-	}
+	d := m.Data()
 
-	if len(m.vertices) != len(m.normals) || len(m.normals) != len(m.uvs) {
-		return fmt.Errorf("mesh upload failed: vao %d has invalid geometry definition: asymmetric data", m.vao)
+	if err := d.Validate(); err != nil {
+		return fmt.Errorf("mesh upload failed: vao %d has invalid geometry definition: %v", m.vao, err)
 	}
 
-	data := make([]Vertex, len(m.vertices))
-	for idx := range m.vertices {
-		data[idx] = Vertex{m.vertices[idx], m.normals[idx], m.uvs[idx]}
-	}
+	data, layout := d.Interleave()
 
 	m.Bind()
 	gl.BindBuffer(gl.ARRAY_BUFFER, m.vbo)
-	gl.BufferData(gl.ARRAY_BUFFER, len(data)*32, gl.Ptr(data), gl.STATIC_DRAW)
+	gl.BufferData(gl.ARRAY_BUFFER, len(data), gl.Ptr(data), gl.STATIC_DRAW)
+
+	for _, c := range m.attributes {
+		gl.DisableVertexAttribArray(uint32(c))
+	}
+	m.attributes = m.attributes[:0]
+
+	for _, a := range layout.Attributes {
+		loc := uint32(a.Channel)
+		gl.EnableVertexAttribArray(loc)
+
+		if a.Integer {
+			gl.VertexAttribIPointer(loc, int32(a.Components), gl.UNSIGNED_SHORT, int32(layout.Stride), gl.PtrOffset(a.Offset))
+		} else {
+			gl.VertexAttribPointer(loc, int32(a.Components), gl.FLOAT, false, int32(layout.Stride), gl.PtrOffset(a.Offset))
+		}
+
+		m.attributes = append(m.attributes, a.Channel)
+	}
+
+	m.indexType = 0
+	m.drawCount = int32(len(m.vertices))
+
+	if d.Indexed() {
+		gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, m.ibo)
+
+		if idx, ok := d.Indices16(); ok {
+			gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(idx)*2, gl.Ptr(idx), gl.STATIC_DRAW)
+			m.indexType = gl.UNSIGNED_SHORT
+		} else {
+			gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(m.triangles)*4, gl.Ptr(m.triangles), gl.STATIC_DRAW)
+			m.indexType = gl.UNSIGNED_INT
+		}
+
+		m.drawCount = int32(len(m.triangles))
+	}
+
 	m.Unbind()
 
 	return nil
 }
 
+// Data returns the channels and indices of the mesh. The slices are shared
+// with the mesh.
+func (m *Mesh) Data() *meshutil.Data {
+	return &meshutil.Data{
+		Vertices:    m.vertices,
+		Normals:     m.normals,
+		Uvs:         m.uvs,
+		Tangents:    m.tangents,
+		Colors:      m.colors,
+		Uvs2:        m.uvs2,
+		BoneIndices: m.boneIndices,
+		BoneWeights: m.boneWeights,
+		Triangles:   m.triangles,
+	}
+}
+
+// SetData sets the channels and indices of the mesh. The slices are shared
+// with the mesh.
+func (m *Mesh) SetData(d *meshutil.Data) {
+	m.vertices = d.Vertices
+	m.normals = d.Normals
+	m.uvs = d.Uvs
+	m.tangents = d.Tangents
+	m.colors = d.Colors
+	m.uvs2 = d.Uvs2
+	m.boneIndices = d.BoneIndices
+	m.boneWeights = d.BoneWeights
+	m.triangles = d.Triangles
+}
+
 func (m *Mesh) Vertices() []mgl32.Vec3 {
 	return m.vertices
 }
@@ -146,6 +228,31 @@ func (m *Mesh) Uvs() []mgl32.Vec2 {
 	return m.uvs
 }
 
+// Tangents returns the tangents of the mesh, with the handedness of the
+// bitangent in w.
+func (m *Mesh) Tangents() []mgl32.Vec4 {
+	return m.tangents
+}
+
+func (m *Mesh) Colors() []mgl32.Vec4 {
+	return m.colors
+}
+
+// Uvs2 returns the second set of texture coordinates of the mesh.
+func (m *Mesh) Uvs2() []mgl32.Vec2 {
+	return m.uvs2
+}
+
+// BoneIndices returns the indices of the bones which influence each vertex.
+func (m *Mesh) BoneIndices() [][4]uint16 {
+	return m.boneIndices
+}
+
+// BoneWeights returns the weights of the bones which influence each vertex.
+func (m *Mesh) BoneWeights() []mgl32.Vec4 {
+	return m.boneWeights
+}
+
 func (m *Mesh) Triangles() []uint32 {
 	return m.triangles
 }
@@ -158,6 +265,12 @@ func (m *Mesh) ReversedWinding() bool {
 	return m.reverseWinding
 }
 
+// MaterialName returns the name of the material the mesh was imported with,
+// or an empty string if it has none.
+func (m *Mesh) MaterialName() string {
+	return m.material
+}
+
 func (m *Mesh) SetVertices(vertices []mgl32.Vec3) {
 	m.vertices = vertices
 }
@@ -170,10 +283,41 @@ func (m *Mesh) SetUvs(uvs []mgl32.Vec2) {
 	m.uvs = uvs
 }
 
+func (m *Mesh) SetTangents(tangents []mgl32.Vec4) {
+	m.tangents = tangents
+}
+
+func (m *Mesh) SetColors(colors []mgl32.Vec4) {
+	m.colors = colors
+}
+
+func (m *Mesh) SetUvs2(uvs []mgl32.Vec2) {
+	m.uvs2 = uvs
+}
+
+func (m *Mesh) SetBoneIndices(indices [][4]uint16) {
+	m.boneIndices = indices
+}
+
+func (m *Mesh) SetBoneWeights(weights []mgl32.Vec4) {
+	m.boneWeights = weights
+}
+
+// SetTriangles sets the vertex indices of the triangles of the mesh. A mesh
+// without indices draws each three vertices as a triangle.
+func (m *Mesh) SetTriangles(triangles []uint32) {
+	m.triangles = triangles
+}
+
 func (m *Mesh) SetReversedWinding(reverse bool) {
 	m.reverseWinding = reverse
 }
 
+// SetMaterialName sets the name of the material the mesh was imported with.
+func (m *Mesh) SetMaterialName(name string) {
+	m.material = name
+}
+
 func NewMeshQuad() *Mesh {
 	m := NewMesh()
 
@@ -135,6 +135,29 @@ func (s *Shader) Build() error {
 	return err
 }
 
+// Reload builds a new program from data and replaces the current program
+// with it. If the new program fails to build, the current program is kept.
+func (s *Shader) Reload(data []byte) error {
+	next := &Shader{
+		components:      make(map[ShaderComponent]uint32),
+		data:            data,
+		deferredCapable: s.deferredCapable,
+	}
+
+	if err := next.Build(); err != nil {
+		next.Dealloc()
+		return err
+	}
+
+	s.Dealloc()
+
+	s.programId = next.programId
+	s.components = next.components
+	s.data = data
+
+	return nil
+}
+
 func (s *Shader) ProgramId() uint32 {
 	return s.programId
 }
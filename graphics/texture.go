@@ -56,8 +56,13 @@ const (
 	TextureFormatDepth24
 	TextureFormatDepth24Stencil8
 	TextureFormatStencil8
+	TextureFormatSRGBA8
 )
 
+// textureMaxAnisotropy is GL_TEXTURE_MAX_ANISOTROPY, which is core since
+// OpenGL 4.6 and provided by EXT_texture_filter_anisotropic before.
+const textureMaxAnisotropy = 0x84FE
+
 type Texture interface {
 	core.Object
 
@@ -118,6 +123,8 @@ type BaseTexture struct {
 	size           math.IVec2
 	resizable      bool
 	textureType    uint32
+	mipmaps        bool
+	anisotropy     float32
 }
 
 func TextureFormatToInternal(format TextureFormat) int32 {
@@ -166,6 +173,8 @@ func TextureFormatToInternal(format TextureFormat) int32 {
 		return gl.STENCIL_INDEX8
 	case TextureFormatRGBA16UI:
 		return gl.RGBA16UI
+	case TextureFormatSRGBA8:
+		return gl.SRGB8_ALPHA8
 	}
 
 	return 0
@@ -197,6 +206,8 @@ func TextureFormatToFormat(format TextureFormat) uint32 {
 		fallthrough
 	case TextureFormatRGBA8:
 		fallthrough
+	case TextureFormatSRGBA8:
+		fallthrough
 	case TextureFormatDefaultHDRColor:
 		fallthrough
 	case TextureFormatRGBA16:
@@ -234,6 +245,8 @@ func TextureFormatToStorage(format TextureFormat) uint32 {
 		fallthrough
 	case TextureFormatRGBA8:
 		fallthrough
+	case TextureFormatSRGBA8:
+		fallthrough
 	case TextureFormatStencil8:
 		return gl.UNSIGNED_BYTE
 	case TextureFormatR16:
@@ -329,9 +342,16 @@ func (t *BaseTexture) FilterMin() int32 {
 	return t.filterMin
 }
 
-// GenerateMipmaps
+// GenerateMipmaps generates the mipmaps of the texture from its first level.
+// It should be called again after the texture is uploaded.
 func (t *BaseTexture) GenerateMipmaps() {
+	if t.reference == 0 {
+		return
+	}
 
+	t.Bind()
+	gl.GenerateMipmap(t.textureType)
+	t.mipmaps = true
 }
 
 // GLFormat
@@ -368,9 +388,36 @@ func (t *BaseTexture) SetLayers(layers int32) {
 	t.layers = layers
 }
 
-// MipLevels
+// MipLevels returns the number of mipmap levels of the texture, which is 1
+// unless mipmaps have been generated.
 func (t *BaseTexture) MipLevels() uint32 {
-	return 1
+	if !t.mipmaps {
+		return 1
+	}
+
+	size := t.size.X()
+	if t.size.Y() > size {
+		size = t.size.Y()
+	}
+
+	levels := uint32(1)
+	for ; size > 1; size >>= 1 {
+		levels++
+	}
+
+	return levels
+}
+
+// Anisotropy returns the maximum anisotropy of texture filtering.
+func (t *BaseTexture) Anisotropy() float32 {
+	return t.anisotropy
+}
+
+// SetAnisotropy sets the maximum anisotropy of texture filtering. A value of
+// 1 disables anisotropic filtering.
+func (t *BaseTexture) SetAnisotropy(anisotropy float32) {
+	t.anisotropy = anisotropy
+	gl.TexParameterf(t.textureType, textureMaxAnisotropy, t.anisotropy)
 }
 
 // Resizable
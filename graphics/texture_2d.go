@@ -88,6 +88,10 @@ func (t *Texture2D) Upload() {
 	}
 
 	gl.TexImage2D(gl.TEXTURE_2D, 0, t.internalFormat, t.size.X(), t.size.Y(), 0, t.glFormat, t.storageFormat, ptr)
+
+	// The first level was replaced, so the mipmaps are out of date until
+	// they are generated again.
+	t.mipmaps = false
 }
 
 func (t *Texture2D) SetData(data []uint8) {
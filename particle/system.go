@@ -23,16 +23,14 @@ SOFTWARE.
 package particle
 
 import (
-	"fmt"
 	"math"
 
 	"github.com/go-gl/gl/v4.3-core/gl"
-	"github.com/go-gl/glfw/v3.2/glfw"
 	"github.com/go-gl/mathgl/mgl32"
 
+	"github.com/haakenlabs/arc/core"
 	"github.com/haakenlabs/arc/graphics"
 	"github.com/haakenlabs/arc/scene"
-	"github.com/haakenlabs/arc/system/input"
 	"github.com/haakenlabs/arc/system/instance"
 	"github.com/haakenlabs/arc/system/time"
 )
@@ -43,6 +41,18 @@ const (
 	workgroupSize = uint32(128)
 )
 
+// Tuning values shared by all particle systems, set with console cvars.
+var (
+	rateScale     = float32(1)
+	lifetimeScale = float32(1)
+	speedScale    = float32(1)
+	attractors    = true
+)
+
+func init() {
+	core.RegisterCvars(registerCvars)
+}
+
 type System struct {
 	scene.BaseScriptComponent
 
@@ -73,7 +83,7 @@ func (s *System) DrawShader(shader *graphics.Shader, camera *scene.Camera) {
 
 func (s *System) Simulate() {
 
-	deltaTime := float32(time.DeltaTime()) * s.Core.PlaybackSpeed
+	deltaTime := float32(time.DeltaTime()) * s.Core.PlaybackSpeed * speedScale
 
 	// Lifecycle Phase
 	s.Core.lifecycleShader.Bind()
@@ -89,7 +99,7 @@ func (s *System) Simulate() {
 	s.Core.lifecycleShader.SetUniform("u_position", mgl32.Vec3{})
 	s.Core.lifecycleShader.SetUniform("u_random_seed", uint32(time.Frame())^s.Core.RandomSeed)
 	s.Core.lifecycleShader.SetUniform("u_angular_velocity", 0.0)
-	s.Core.lifecycleShader.SetUniform("u_start_lifetime", s.Core.StartLifetime)
+	s.Core.lifecycleShader.SetUniform("u_start_lifetime", s.Core.StartLifetime*lifetimeScale)
 	s.Core.lifecycleShader.SetUniform("u_start_size", s.Core.StartSize)
 	s.Core.lifecycleShader.SetUniform("u_velocity", s.Core.StartSpeed)
 
@@ -106,7 +116,7 @@ func (s *System) Simulate() {
 	}
 
 	// Emit new particles
-	emitNow := uint32(math.Ceil(float64(s.Emission.Rate) * float64(deltaTime)))
+	emitNow := uint32(math.Ceil(float64(s.Emission.Rate*rateScale) * float64(deltaTime)))
 
 	if emitNow > 0 && s.Core.dead > 0 {
 		if emitNow > s.Core.dead {
@@ -130,7 +140,7 @@ func (s *System) Simulate() {
 		s.Core.simulateShader.SetUniform("u_invocations", s.Core.alive)
 		s.Core.simulateShader.SetUniform("u_offset_out", s.outOffset)
 		s.Core.simulateShader.SetUniform("u_delta_time", deltaTime)
-		s.Core.simulateShader.SetUniform("u_attractors", s.Force.EnableAttractors)
+		s.Core.simulateShader.SetUniform("u_attractors", s.Force.EnableAttractors && attractors)
 
 		gl.DispatchCompute((s.Core.alive/workgroupSize)+1, 1, 1)
 		gl.MemoryBarrier(gl.SHADER_STORAGE_BARRIER_BIT)
@@ -141,49 +151,6 @@ func (s *System) Simulate() {
 	s.swapBuffers()
 }
 
-func (s *System) Update() {
-	if input.KeyDown(glfw.Key9) {
-		s.Emission.Rate += 500.0
-	} else if input.KeyDown(glfw.Key8) {
-		s.Emission.Rate -= 500.0
-		if s.Emission.Rate < 0 {
-			s.Emission.Rate = 0
-		}
-	} else if input.KeyDown(glfw.Key0) {
-		s.Emission.Rate = 0.0
-	} else if input.KeyDown(glfw.KeySpace) {
-		s.Emission.Rate = 10000.0
-	}
-
-	if input.KeyUp(glfw.KeySpace) {
-		s.Emission.Rate = 0.0
-	}
-
-	if input.KeyDown(glfw.Key6) {
-		s.Core.StartLifetime -= 1.0
-
-		if s.Core.StartLifetime <= 0 {
-			s.Core.StartLifetime = 1
-		}
-	} else if input.KeyDown(glfw.Key7) {
-		s.Core.StartLifetime += 1.0
-	}
-
-	if input.KeyDown(glfw.Key5) {
-		s.Force.EnableAttractors = !s.Force.EnableAttractors
-		fmt.Printf("EnableAttractors: %v\n", s.Force.EnableAttractors)
-	}
-
-	if input.KeyDown(glfw.Key4) {
-		s.Core.PlaybackSpeed += 0.1
-	} else if input.KeyDown(glfw.Key3) {
-		s.Core.PlaybackSpeed -= 0.1
-		if s.Core.PlaybackSpeed < 0 {
-			s.Core.PlaybackSpeed = 0
-		}
-	}
-}
-
 func (s *System) Draw(camera *scene.Camera) {
 	if s.Renderer != nil {
 		s.Renderer.Draw(camera)
@@ -239,3 +206,49 @@ func NewParticleSystem(maxParticles uint32) *System {
 
 	return s
 }
+
+// registerCvars registers the console variables used to tune all particle
+// systems at runtime with a new console.
+func registerCvars(c *core.ConsoleSystem) {
+	// Values set through an earlier console do not carry over.
+	rateScale, lifetimeScale, speedScale, attractors = 1, 1, 1, true
+
+	c.MustRegisterCvar(core.Setting{
+		Key:         "particle.rate_scale",
+		Description: "Multiplier applied to the emission rate of particle systems.",
+		Type:        core.SettingFloat,
+		Default:     1.0,
+		Min:         0,
+		Max:         100,
+	}).Watch(func(key string, value interface{}) {
+		rateScale = float32(value.(float64))
+	})
+	c.MustRegisterCvar(core.Setting{
+		Key:         "particle.lifetime_scale",
+		Description: "Multiplier applied to the start lifetime of particles.",
+		Type:        core.SettingFloat,
+		Default:     1.0,
+		Min:         0.01,
+		Max:         100,
+	}).Watch(func(key string, value interface{}) {
+		lifetimeScale = float32(value.(float64))
+	})
+	c.MustRegisterCvar(core.Setting{
+		Key:         "particle.speed_scale",
+		Description: "Multiplier applied to the playback speed of particle systems.",
+		Type:        core.SettingFloat,
+		Default:     1.0,
+		Min:         0,
+		Max:         10,
+	}).Watch(func(key string, value interface{}) {
+		speedScale = float32(value.(float64))
+	})
+	c.MustRegisterCvar(core.Setting{
+		Key:         "particle.attractors",
+		Description: "Enable force attractors in particle systems.",
+		Type:        core.SettingBool,
+		Default:     true,
+	}).Watch(func(key string, value interface{}) {
+		attractors = value.(bool)
+	})
+}
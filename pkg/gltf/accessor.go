@@ -0,0 +1,286 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package gltf
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+)
+
+// Component types of accessors.
+const (
+	componentByte          = 5120
+	componentUnsignedByte  = 5121
+	componentShort         = 5122
+	componentUnsignedShort = 5123
+	componentUnsignedInt   = 5125
+	componentFloat         = 5126
+)
+
+var componentSizes = map[int]int{
+	componentByte:          1,
+	componentUnsignedByte:  1,
+	componentShort:         2,
+	componentUnsignedShort: 2,
+	componentUnsignedInt:   4,
+	componentFloat:         4,
+}
+
+var typeSizes = map[string]int{
+	"SCALAR": 1,
+	"VEC2":   2,
+	"VEC3":   3,
+	"VEC4":   4,
+	"MAT2":   4,
+	"MAT3":   9,
+	"MAT4":   16,
+}
+
+type jsonAccessor struct {
+	BufferView    *int        `json:"bufferView"`
+	ByteOffset    int         `json:"byteOffset"`
+	ComponentType int         `json:"componentType"`
+	Normalized    bool        `json:"normalized"`
+	Count         int         `json:"count"`
+	Type          string      `json:"type"`
+	Sparse        interface{} `json:"sparse"`
+}
+
+type jsonBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type jsonBuffer struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
+
+// readBuffers reads the data of all buffers. A buffer without a URI is the
+// binary chunk of a .glb document.
+func (d *decoder) readBuffers() error {
+	d.buffers = make([][]byte, len(d.doc.Buffers))
+
+	for i, b := range d.doc.Buffers {
+		var data []byte
+		var err error
+
+		if b.URI == "" {
+			if i != 0 || d.bin == nil {
+				return fmt.Errorf("gltf: buffer %d: missing uri", i)
+			}
+			data = d.bin
+		} else if data, err = d.readURI(b.URI); err != nil {
+			return fmt.Errorf("gltf: buffer %d: %v", i, err)
+		}
+
+		if len(data) < b.ByteLength {
+			return fmt.Errorf("gltf: buffer %d: got %d bytes want %d", i, len(data), b.ByteLength)
+		}
+
+		d.buffers[i] = data[:b.ByteLength]
+	}
+
+	return nil
+}
+
+// bufferView returns the data of a buffer view.
+func (d *decoder) bufferView(i int) ([]byte, error) {
+	if i < 0 || i >= len(d.doc.BufferViews) {
+		return nil, fmt.Errorf("buffer view %d out of range", i)
+	}
+
+	v := d.doc.BufferViews[i]
+	if v.Buffer < 0 || v.Buffer >= len(d.buffers) {
+		return nil, fmt.Errorf("buffer view %d: buffer %d out of range", i, v.Buffer)
+	}
+
+	b := d.buffers[v.Buffer]
+	if v.ByteOffset < 0 || v.ByteLength < 0 || v.ByteOffset+v.ByteLength > len(b) {
+		return nil, fmt.Errorf("buffer view %d exceeds buffer %d", i, v.Buffer)
+	}
+
+	return b[v.ByteOffset : v.ByteOffset+v.ByteLength], nil
+}
+
+// accessor returns an accessor with the data it reads from, its stride and
+// the size of its elements. The data of an accessor without a buffer view is
+// zero.
+func (d *decoder) accessor(i int) (a *jsonAccessor, data []byte, stride, size int, err error) {
+	if i < 0 || i >= len(d.doc.Accessors) {
+		return nil, nil, 0, 0, fmt.Errorf("accessor %d out of range", i)
+	}
+
+	a = &d.doc.Accessors[i]
+	if a.Sparse != nil {
+		return nil, nil, 0, 0, fmt.Errorf("accessor %d: sparse accessors are not supported", i)
+	}
+
+	n, ok := typeSizes[a.Type]
+	if !ok {
+		return nil, nil, 0, 0, fmt.Errorf("accessor %d: invalid type %q", i, a.Type)
+	}
+	c, ok := componentSizes[a.ComponentType]
+	if !ok {
+		return nil, nil, 0, 0, fmt.Errorf("accessor %d: invalid component type %d", i, a.ComponentType)
+	}
+	if a.Count < 0 {
+		return nil, nil, 0, 0, fmt.Errorf("accessor %d: invalid count %d", i, a.Count)
+	}
+
+	size = n * c
+
+	if a.BufferView == nil {
+		return a, make([]byte, a.Count*size), size, size, nil
+	}
+
+	if data, err = d.bufferView(*a.BufferView); err != nil {
+		return nil, nil, 0, 0, err
+	}
+
+	stride = d.doc.BufferViews[*a.BufferView].ByteStride
+	if stride == 0 {
+		stride = size
+	}
+	if stride < size {
+		return nil, nil, 0, 0, fmt.Errorf("accessor %d: stride %d is less than element size %d", i, stride, size)
+	}
+
+	if a.ByteOffset < 0 || a.ByteOffset > len(data) || (a.Count > 0 && a.ByteOffset+stride*(a.Count-1)+size > len(data)) {
+		return nil, nil, 0, 0, fmt.Errorf("accessor %d exceeds buffer view %d", i, *a.BufferView)
+	}
+
+	return a, data[a.ByteOffset:], stride, size, nil
+}
+
+// readFloats reads the components of an accessor of the given type as
+// floats. Normalized integer components are accepted if ints is true.
+func (d *decoder) readFloats(i int, typ string, ints bool) ([]float32, error) {
+	a, data, stride, _, err := d.accessor(i)
+	if err != nil {
+		return nil, err
+	}
+	if a.Type != typ {
+		return nil, fmt.Errorf("accessor %d: got type %s want %s", i, a.Type, typ)
+	}
+	if a.ComponentType != componentFloat && (!ints || !a.Normalized) {
+		return nil, fmt.Errorf("accessor %d: unsupported component type %d", i, a.ComponentType)
+	}
+
+	n := typeSizes[typ]
+	c := componentSizes[a.ComponentType]
+	out := make([]float32, a.Count*n)
+
+	for e := 0; e < a.Count; e++ {
+		for k := 0; k < n; k++ {
+			b := data[e*stride+k*c:]
+
+			var v float32
+			switch a.ComponentType {
+			case componentFloat:
+				v = math.Float32frombits(binary.LittleEndian.Uint32(b))
+			case componentUnsignedByte:
+				v = float32(b[0]) / 255
+			case componentUnsignedShort:
+				v = float32(binary.LittleEndian.Uint16(b)) / 65535
+			case componentByte:
+				v = float32(math.Max(float64(int8(b[0]))/127, -1))
+			case componentShort:
+				v = float32(math.Max(float64(int16(binary.LittleEndian.Uint16(b)))/32767, -1))
+			default:
+				return nil, fmt.Errorf("accessor %d: unsupported component type %d", i, a.ComponentType)
+			}
+
+			out[e*n+k] = v
+		}
+	}
+
+	return out, nil
+}
+
+// readIndices reads an accessor of unsigned integer scalars.
+func (d *decoder) readIndices(i int) ([]uint32, error) {
+	return d.readUints(i, "SCALAR")
+}
+
+// readUints reads the components of an accessor of unsigned integers of the
+// given type.
+func (d *decoder) readUints(i int, typ string) ([]uint32, error) {
+	a, data, stride, _, err := d.accessor(i)
+	if err != nil {
+		return nil, err
+	}
+	if a.Type != typ {
+		return nil, fmt.Errorf("accessor %d: got type %s want %s", i, a.Type, typ)
+	}
+
+	n := typeSizes[typ]
+	c := componentSizes[a.ComponentType]
+	out := make([]uint32, a.Count*n)
+
+	for e := 0; e < a.Count; e++ {
+		for k := 0; k < n; k++ {
+			b := data[e*stride+k*c:]
+
+			switch a.ComponentType {
+			case componentUnsignedByte:
+				out[e*n+k] = uint32(b[0])
+			case componentUnsignedShort:
+				out[e*n+k] = uint32(binary.LittleEndian.Uint16(b))
+			case componentUnsignedInt:
+				out[e*n+k] = binary.LittleEndian.Uint32(b)
+			default:
+				return nil, fmt.Errorf("accessor %d: unsupported component type %d", i, a.ComponentType)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// decodeDataURI decodes the data of a data URI.
+func decodeDataURI(uri string) ([]byte, error) {
+	i := strings.IndexByte(uri, ',')
+	if i < 0 {
+		return nil, errors.New("invalid data uri")
+	}
+
+	header, data := uri[len("data:"):i], uri[i+1:]
+	if strings.HasSuffix(header, ";base64") {
+		return base64.StdEncoding.DecodeString(data)
+	}
+
+	s, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(s), nil
+}
@@ -0,0 +1,892 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package gltf decodes glTF 2.0 scenes, in .gltf files with external or
+// embedded buffers and in binary .glb files.
+package gltf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/haakenlabs/arc/pkg/meshutil"
+)
+
+// Primitive modes of the glTF format.
+const (
+	ModePoints        = 0
+	ModeLines         = 1
+	ModeLineLoop      = 2
+	ModeLineStrip     = 3
+	ModeTriangles     = 4
+	ModeTriangleStrip = 5
+	ModeTriangleFan   = 6
+)
+
+const (
+	glbMagic     = 0x46546C67 // "glTF"
+	glbChunkJSON = 0x4E4F534A // "JSON"
+	glbChunkBIN  = 0x004E4942 // "BIN\x00"
+)
+
+// OpenFunc reads an external file referenced by a URI, relative to the
+// document.
+type OpenFunc func(uri string) ([]byte, error)
+
+// Document is a decoded glTF document. References between its elements are
+// indices, and -1 for none.
+type Document struct {
+	// Scene is the scene to display, or -1 if the document does not have one.
+	Scene int
+
+	Scenes    []*Scene
+	Nodes     []*Node
+	Meshes    []*Mesh
+	Materials []*Material
+	Images    []*Image
+	Cameras   []*Camera
+}
+
+// Scene is a set of root nodes.
+type Scene struct {
+	Name  string
+	Nodes []int
+}
+
+// Node is a node of the scene hierarchy. Its transform is relative to its
+// parent.
+type Node struct {
+	Name     string
+	Children []int
+	Mesh     int
+	Camera   int
+
+	Translation mgl32.Vec3
+	Rotation    mgl32.Quat
+	Scale       mgl32.Vec3
+}
+
+// Mesh is a set of primitives.
+type Mesh struct {
+	Name       string
+	Primitives []*Primitive
+}
+
+// Primitive is a triangle list with one material. Every vertex has a normal
+// and texture coordinates, and tangents, colors, second texture coordinates,
+// bone indices and bone weights if the document gives them. Normals which
+// are not given by the document are computed per face, in which case the
+// triangles are not indexed. Texture coordinates are converted from the top
+// left origin of glTF to the bottom left origin used by the engine.
+type Primitive struct {
+	meshutil.Data
+
+	Material int
+}
+
+// Material is a PBR metallic-roughness material. Textures are indices of
+// images.
+type Material struct {
+	Name        string
+	BaseColor   mgl32.Vec4
+	Metallic    float32
+	Roughness   float32
+	Emissive    mgl32.Vec3
+	AlphaMode   string
+	DoubleSided bool
+
+	BaseColorTexture         int
+	MetallicRoughnessTexture int
+	NormalTexture            int
+	OcclusionTexture         int
+	EmissiveTexture          int
+}
+
+// Image is an image used by textures. Its data is read from a buffer view, a
+// data URI, or an external file.
+type Image struct {
+	Name     string
+	URI      string
+	MimeType string
+	Data     []byte
+}
+
+// Camera is a perspective or orthographic camera. AspectRatio is 0 if the
+// aspect ratio of the viewport should be used, and Zfar is 0 for an infinite
+// perspective projection.
+type Camera struct {
+	Name         string
+	Orthographic bool
+	Yfov         float32
+	AspectRatio  float32
+	Xmag         float32
+	Ymag         float32
+	Znear        float32
+	Zfar         float32
+}
+
+type jsonDocument struct {
+	Asset struct {
+		Version    string `json:"version"`
+		MinVersion string `json:"minVersion"`
+	} `json:"asset"`
+	ExtensionsRequired []string         `json:"extensionsRequired"`
+	Scene              *int             `json:"scene"`
+	Scenes             []jsonScene      `json:"scenes"`
+	Nodes              []jsonNode       `json:"nodes"`
+	Meshes             []jsonMesh       `json:"meshes"`
+	Materials          []jsonMaterial   `json:"materials"`
+	Textures           []jsonTexture    `json:"textures"`
+	Images             []jsonImage      `json:"images"`
+	Cameras            []jsonCamera     `json:"cameras"`
+	Accessors          []jsonAccessor   `json:"accessors"`
+	BufferViews        []jsonBufferView `json:"bufferViews"`
+	Buffers            []jsonBuffer     `json:"buffers"`
+}
+
+type jsonScene struct {
+	Name  string `json:"name"`
+	Nodes []int  `json:"nodes"`
+}
+
+type jsonNode struct {
+	Name        string       `json:"name"`
+	Children    []int        `json:"children"`
+	Mesh        *int         `json:"mesh"`
+	Camera      *int         `json:"camera"`
+	Matrix      *[16]float32 `json:"matrix"`
+	Translation *[3]float32  `json:"translation"`
+	Rotation    *[4]float32  `json:"rotation"`
+	Scale       *[3]float32  `json:"scale"`
+}
+
+type jsonMesh struct {
+	Name       string          `json:"name"`
+	Primitives []jsonPrimitive `json:"primitives"`
+}
+
+type jsonPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Material   *int           `json:"material"`
+	Mode       *int           `json:"mode"`
+}
+
+type jsonTextureInfo struct {
+	Index    int `json:"index"`
+	TexCoord int `json:"texCoord"`
+}
+
+type jsonMaterial struct {
+	Name                 string `json:"name"`
+	PBRMetallicRoughness struct {
+		BaseColorFactor          *[4]float32      `json:"baseColorFactor"`
+		BaseColorTexture         *jsonTextureInfo `json:"baseColorTexture"`
+		MetallicFactor           *float32         `json:"metallicFactor"`
+		RoughnessFactor          *float32         `json:"roughnessFactor"`
+		MetallicRoughnessTexture *jsonTextureInfo `json:"metallicRoughnessTexture"`
+	} `json:"pbrMetallicRoughness"`
+	NormalTexture    *jsonTextureInfo `json:"normalTexture"`
+	OcclusionTexture *jsonTextureInfo `json:"occlusionTexture"`
+	EmissiveTexture  *jsonTextureInfo `json:"emissiveTexture"`
+	EmissiveFactor   [3]float32       `json:"emissiveFactor"`
+	AlphaMode        string           `json:"alphaMode"`
+	DoubleSided      bool             `json:"doubleSided"`
+}
+
+type jsonTexture struct {
+	Source *int `json:"source"`
+}
+
+type jsonImage struct {
+	Name       string `json:"name"`
+	URI        string `json:"uri"`
+	MimeType   string `json:"mimeType"`
+	BufferView *int   `json:"bufferView"`
+}
+
+type jsonCamera struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Perspective *struct {
+		Yfov        float32 `json:"yfov"`
+		AspectRatio float32 `json:"aspectRatio"`
+		Znear       float32 `json:"znear"`
+		Zfar        float32 `json:"zfar"`
+	} `json:"perspective"`
+	Orthographic *struct {
+		Xmag  float32 `json:"xmag"`
+		Ymag  float32 `json:"ymag"`
+		Znear float32 `json:"znear"`
+		Zfar  float32 `json:"zfar"`
+	} `json:"orthographic"`
+}
+
+// decoder holds a document and its buffers as it is decoded.
+type decoder struct {
+	doc     *jsonDocument
+	open    OpenFunc
+	bin     []byte
+	buffers [][]byte
+}
+
+// Decode reads a .gltf or .glb document. External buffers and images are read
+// with open, which may be nil if the document has none. Primitives of points
+// and lines are skipped.
+func Decode(r io.Reader, open OpenFunc) (*Document, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoder{doc: &jsonDocument{}, open: open}
+
+	if len(data) >= 4 && binary.LittleEndian.Uint32(data) == glbMagic {
+		if data, d.bin, err = splitGLB(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := json.Unmarshal(data, d.doc); err != nil {
+		return nil, fmt.Errorf("gltf: %v", err)
+	}
+
+	if !strings.HasPrefix(d.doc.Asset.Version, "2.") {
+		return nil, fmt.Errorf("gltf: unsupported version %q", d.doc.Asset.Version)
+	}
+	if v := d.doc.Asset.MinVersion; v != "" && v != "2.0" {
+		return nil, fmt.Errorf("gltf: unsupported minimum version %q", v)
+	}
+	if len(d.doc.ExtensionsRequired) != 0 {
+		return nil, fmt.Errorf("gltf: unsupported required extensions: %s", strings.Join(d.doc.ExtensionsRequired, ", "))
+	}
+
+	return d.decode()
+}
+
+// splitGLB splits a binary document into its JSON and binary chunks.
+func splitGLB(data []byte) (js, bin []byte, err error) {
+	if len(data) < 20 {
+		return nil, nil, errors.New("gltf: truncated glb header")
+	}
+	if v := binary.LittleEndian.Uint32(data[4:]); v != 2 {
+		return nil, nil, fmt.Errorf("gltf: unsupported glb version %d", v)
+	}
+	if n := binary.LittleEndian.Uint32(data[8:]); int(n) != len(data) {
+		return nil, nil, fmt.Errorf("gltf: glb length %d, want %d", n, len(data))
+	}
+
+	for chunk := data[12:]; len(chunk) > 0; {
+		if len(chunk) < 8 {
+			return nil, nil, errors.New("gltf: truncated glb chunk")
+		}
+
+		n := binary.LittleEndian.Uint32(chunk)
+		kind := binary.LittleEndian.Uint32(chunk[4:])
+		if uint64(n) > uint64(len(chunk)-8) {
+			return nil, nil, errors.New("gltf: truncated glb chunk")
+		}
+
+		switch body := chunk[8 : 8+n]; {
+		case kind == glbChunkJSON && js == nil:
+			js = body
+		case kind == glbChunkBIN && bin == nil && js != nil:
+			bin = body
+		}
+
+		chunk = chunk[8+n:]
+	}
+
+	if js == nil {
+		return nil, nil, errors.New("gltf: glb has no json chunk")
+	}
+
+	return js, bin, nil
+}
+
+func (d *decoder) decode() (*Document, error) {
+	doc := &Document{Scene: -1}
+
+	if err := d.readBuffers(); err != nil {
+		return nil, err
+	}
+
+	for i, s := range d.doc.Scenes {
+		for _, n := range s.Nodes {
+			if n < 0 || n >= len(d.doc.Nodes) {
+				return nil, fmt.Errorf("gltf: scene %d: node %d out of range", i, n)
+			}
+		}
+		doc.Scenes = append(doc.Scenes, &Scene{Name: s.Name, Nodes: s.Nodes})
+	}
+
+	if d.doc.Scene != nil {
+		if *d.doc.Scene < 0 || *d.doc.Scene >= len(doc.Scenes) {
+			return nil, fmt.Errorf("gltf: scene %d out of range", *d.doc.Scene)
+		}
+		doc.Scene = *d.doc.Scene
+	} else if len(doc.Scenes) > 0 {
+		doc.Scene = 0
+	}
+
+	for i, img := range d.doc.Images {
+		data, err := d.readImage(img)
+		if err != nil {
+			return nil, fmt.Errorf("gltf: image %d: %v", i, err)
+		}
+		doc.Images = append(doc.Images, &Image{Name: img.Name, URI: img.URI, MimeType: img.MimeType, Data: data})
+	}
+
+	for i := range d.doc.Materials {
+		m, err := d.readMaterial(&d.doc.Materials[i])
+		if err != nil {
+			return nil, fmt.Errorf("gltf: material %d: %v", i, err)
+		}
+		doc.Materials = append(doc.Materials, m)
+	}
+
+	for i, c := range d.doc.Cameras {
+		cam, err := readCamera(c)
+		if err != nil {
+			return nil, fmt.Errorf("gltf: camera %d: %v", i, err)
+		}
+		doc.Cameras = append(doc.Cameras, cam)
+	}
+
+	for i, m := range d.doc.Meshes {
+		mesh := &Mesh{Name: m.Name}
+
+		for j, p := range m.Primitives {
+			prim, err := d.readPrimitive(p)
+			if err != nil {
+				return nil, fmt.Errorf("gltf: mesh %d: primitive %d: %v", i, j, err)
+			}
+			if prim != nil {
+				mesh.Primitives = append(mesh.Primitives, prim)
+			}
+		}
+
+		doc.Meshes = append(doc.Meshes, mesh)
+	}
+
+	for i, n := range d.doc.Nodes {
+		node, err := d.readNode(n)
+		if err != nil {
+			return nil, fmt.Errorf("gltf: node %d: %v", i, err)
+		}
+		doc.Nodes = append(doc.Nodes, node)
+	}
+
+	if err := checkHierarchy(doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+func (d *decoder) readMaterial(m *jsonMaterial) (*Material, error) {
+	pbr := &m.PBRMetallicRoughness
+
+	mat := &Material{
+		Name:      m.Name,
+		BaseColor: mgl32.Vec4{1, 1, 1, 1},
+		Metallic:  1,
+		Roughness: 1,
+		Emissive:  m.EmissiveFactor,
+		AlphaMode: m.AlphaMode,
+
+		DoubleSided: m.DoubleSided,
+	}
+
+	if mat.AlphaMode == "" {
+		mat.AlphaMode = "OPAQUE"
+	}
+	if pbr.BaseColorFactor != nil {
+		mat.BaseColor = *pbr.BaseColorFactor
+	}
+	if pbr.MetallicFactor != nil {
+		mat.Metallic = *pbr.MetallicFactor
+	}
+	if pbr.RoughnessFactor != nil {
+		mat.Roughness = *pbr.RoughnessFactor
+	}
+
+	var err error
+
+	textures := []struct {
+		info *jsonTextureInfo
+		dst  *int
+	}{
+		{pbr.BaseColorTexture, &mat.BaseColorTexture},
+		{pbr.MetallicRoughnessTexture, &mat.MetallicRoughnessTexture},
+		{m.NormalTexture, &mat.NormalTexture},
+		{m.OcclusionTexture, &mat.OcclusionTexture},
+		{m.EmissiveTexture, &mat.EmissiveTexture},
+	}
+
+	for _, t := range textures {
+		if *t.dst, err = d.textureImage(t.info); err != nil {
+			return nil, err
+		}
+	}
+
+	return mat, nil
+}
+
+// textureImage returns the index of the image of a texture, or -1 if there is
+// no texture or it has no image.
+func (d *decoder) textureImage(info *jsonTextureInfo) (int, error) {
+	if info == nil {
+		return -1, nil
+	}
+	if info.Index < 0 || info.Index >= len(d.doc.Textures) {
+		return 0, fmt.Errorf("texture %d out of range", info.Index)
+	}
+	if info.TexCoord != 0 {
+		return 0, fmt.Errorf("texture %d uses unsupported texture coordinates %d", info.Index, info.TexCoord)
+	}
+
+	src := d.doc.Textures[info.Index].Source
+	if src == nil {
+		return -1, nil
+	}
+	if *src < 0 || *src >= len(d.doc.Images) {
+		return 0, fmt.Errorf("image %d out of range", *src)
+	}
+
+	return *src, nil
+}
+
+func readCamera(c jsonCamera) (*Camera, error) {
+	cam := &Camera{Name: c.Name}
+
+	switch {
+	case c.Type == "perspective" && c.Perspective != nil:
+		p := c.Perspective
+		cam.Yfov, cam.AspectRatio, cam.Znear, cam.Zfar = p.Yfov, p.AspectRatio, p.Znear, p.Zfar
+		if p.Yfov <= 0 || p.Znear <= 0 || (p.Zfar != 0 && p.Zfar <= p.Znear) {
+			return nil, errors.New("invalid perspective projection")
+		}
+	case c.Type == "orthographic" && c.Orthographic != nil:
+		o := c.Orthographic
+		cam.Orthographic = true
+		cam.Xmag, cam.Ymag, cam.Znear, cam.Zfar = o.Xmag, o.Ymag, o.Znear, o.Zfar
+		if o.Znear < 0 || o.Zfar <= o.Znear {
+			return nil, errors.New("invalid orthographic projection")
+		}
+	default:
+		return nil, fmt.Errorf("invalid camera type %q", c.Type)
+	}
+
+	return cam, nil
+}
+
+func (d *decoder) readNode(n jsonNode) (*Node, error) {
+	node := &Node{
+		Name:     n.Name,
+		Children: n.Children,
+		Mesh:     -1,
+		Camera:   -1,
+		Rotation: mgl32.QuatIdent(),
+		Scale:    mgl32.Vec3{1, 1, 1},
+	}
+
+	if n.Mesh != nil {
+		if *n.Mesh < 0 || *n.Mesh >= len(d.doc.Meshes) {
+			return nil, fmt.Errorf("mesh %d out of range", *n.Mesh)
+		}
+		node.Mesh = *n.Mesh
+	}
+	if n.Camera != nil {
+		if *n.Camera < 0 || *n.Camera >= len(d.doc.Cameras) {
+			return nil, fmt.Errorf("camera %d out of range", *n.Camera)
+		}
+		node.Camera = *n.Camera
+	}
+
+	if n.Matrix != nil {
+		if n.Translation != nil || n.Rotation != nil || n.Scale != nil {
+			return nil, errors.New("node has both a matrix and a transform")
+		}
+		node.Translation, node.Rotation, node.Scale = decompose(mgl32.Mat4(*n.Matrix))
+
+		return node, nil
+	}
+
+	if n.Translation != nil {
+		node.Translation = *n.Translation
+	}
+	if n.Rotation != nil {
+		r := *n.Rotation
+		node.Rotation = mgl32.Quat{W: r[3], V: mgl32.Vec3{r[0], r[1], r[2]}}.Normalize()
+	}
+	if n.Scale != nil {
+		node.Scale = *n.Scale
+	}
+
+	return node, nil
+}
+
+// decompose splits an affine transform into its translation, rotation and
+// scale. Shear is discarded.
+func decompose(m mgl32.Mat4) (mgl32.Vec3, mgl32.Quat, mgl32.Vec3) {
+	t := m.Col(3).Vec3()
+	s := mgl32.Vec3{m.Col(0).Vec3().Len(), m.Col(1).Vec3().Len(), m.Col(2).Vec3().Len()}
+
+	if m.Det() < 0 {
+		s[0] = -s[0]
+	}
+
+	var r mgl32.Mat4
+	for i := 0; i < 3; i++ {
+		if s[i] != 0 {
+			r.SetCol(i, m.Col(i).Mul(1/s[i]))
+		}
+	}
+	r.SetCol(3, mgl32.Vec4{0, 0, 0, 1})
+
+	return t, mgl32.Mat4ToQuat(r).Normalize(), s
+}
+
+// checkHierarchy checks that the nodes form a forest: every child is a valid
+// node with one parent, and no node is its own ancestor.
+func checkHierarchy(doc *Document) error {
+	parents := make([]int, len(doc.Nodes))
+	for i := range parents {
+		parents[i] = -1
+	}
+
+	for i, n := range doc.Nodes {
+		for _, c := range n.Children {
+			if c < 0 || c >= len(doc.Nodes) {
+				return fmt.Errorf("gltf: node %d: child %d out of range", i, c)
+			}
+			if parents[c] >= 0 {
+				return fmt.Errorf("gltf: node %d has more than one parent", c)
+			}
+			parents[c] = i
+		}
+	}
+
+	for i := range doc.Nodes {
+		for p, n := parents[i], 0; p >= 0; p, n = parents[p], n+1 {
+			if p == i || n > len(doc.Nodes) {
+				return fmt.Errorf("gltf: node %d is its own ancestor", i)
+			}
+		}
+	}
+
+	for i, s := range doc.Scenes {
+		for _, n := range s.Nodes {
+			if parents[n] >= 0 {
+				return fmt.Errorf("gltf: scene %d: node %d is not a root node", i, n)
+			}
+		}
+	}
+
+	return nil
+}
+
+// readImage reads the data of an image.
+func (d *decoder) readImage(img jsonImage) ([]byte, error) {
+	if img.BufferView != nil {
+		if img.MimeType == "" {
+			return nil, errors.New("image in a buffer view has no mime type")
+		}
+
+		return d.bufferView(*img.BufferView)
+	}
+
+	return d.readURI(img.URI)
+}
+
+// readURI reads the data of a data URI or an external file.
+func (d *decoder) readURI(uri string) ([]byte, error) {
+	if uri == "" {
+		return nil, errors.New("missing uri")
+	}
+
+	if strings.HasPrefix(uri, "data:") {
+		return decodeDataURI(uri)
+	}
+
+	if d.open == nil {
+		return nil, fmt.Errorf("cannot open external file %s", uri)
+	}
+
+	file, err := url.PathUnescape(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.open(file)
+}
+
+// fan returns the indices of the triangle list of a triangle strip or fan.
+func fan(mode int, indices []uint32) []uint32 {
+	if len(indices) < 3 {
+		return nil
+	}
+
+	tris := make([]uint32, 0, (len(indices)-2)*3)
+	for i := 2; i < len(indices); i++ {
+		switch {
+		case mode == ModeTriangleFan:
+			tris = append(tris, indices[0], indices[i-1], indices[i])
+		case i%2 == 0:
+			tris = append(tris, indices[i-2], indices[i-1], indices[i])
+		default:
+			tris = append(tris, indices[i-1], indices[i-2], indices[i])
+		}
+	}
+
+	return tris
+}
+
+// readPrimitive reads a primitive as a triangle list, or returns nil if it is
+// not made of triangles.
+func (d *decoder) readPrimitive(p jsonPrimitive) (*Primitive, error) {
+	mode := ModeTriangles
+	if p.Mode != nil {
+		mode = *p.Mode
+	}
+	if mode < ModePoints || mode > ModeTriangleFan {
+		return nil, fmt.Errorf("invalid mode %d", mode)
+	}
+	if mode < ModeTriangles {
+		return nil, nil
+	}
+
+	prim := &Primitive{Material: -1}
+
+	if p.Material != nil {
+		if *p.Material < 0 || *p.Material >= len(d.doc.Materials) {
+			return nil, fmt.Errorf("material %d out of range", *p.Material)
+		}
+		prim.Material = *p.Material
+	}
+
+	pos, ok := p.Attributes["POSITION"]
+	if !ok {
+		return nil, errors.New("missing POSITION attribute")
+	}
+
+	v, err := d.readFloats(pos, "VEC3", false)
+	if err != nil {
+		return nil, fmt.Errorf("POSITION: %v", err)
+	}
+	prim.Vertices = toVec3(v)
+
+	if i, ok := p.Attributes["NORMAL"]; ok {
+		v, err := d.readFloats(i, "VEC3", false)
+		if err != nil {
+			return nil, fmt.Errorf("NORMAL: %v", err)
+		}
+		if prim.Normals = toVec3(v); len(prim.Normals) != len(prim.Vertices) {
+			return nil, errors.New("NORMAL count differs from POSITION count")
+		}
+	}
+
+	if i, ok := p.Attributes["TEXCOORD_0"]; ok {
+		v, err := d.readFloats(i, "VEC2", true)
+		if err != nil {
+			return nil, fmt.Errorf("TEXCOORD_0: %v", err)
+		}
+		if prim.Uvs = toUvs(v); len(prim.Uvs) != len(prim.Vertices) {
+			return nil, errors.New("TEXCOORD_0 count differs from POSITION count")
+		}
+	} else {
+		prim.Uvs = make([]mgl32.Vec2, len(prim.Vertices))
+	}
+
+	if err := d.readChannels(p, prim); err != nil {
+		return nil, err
+	}
+
+	if p.Indices != nil {
+		if prim.Triangles, err = d.readIndices(*p.Indices); err != nil {
+			return nil, fmt.Errorf("indices: %v", err)
+		}
+		for _, i := range prim.Triangles {
+			if int(i) >= len(prim.Vertices) {
+				return nil, fmt.Errorf("index %d out of range", i)
+			}
+		}
+	} else {
+		prim.Triangles = make([]uint32, len(prim.Vertices))
+		for i := range prim.Triangles {
+			prim.Triangles[i] = uint32(i)
+		}
+	}
+
+	if mode != ModeTriangles {
+		prim.Triangles = fan(mode, prim.Triangles)
+	}
+	prim.Triangles = prim.Triangles[:len(prim.Triangles)-len(prim.Triangles)%3]
+
+	if prim.Normals == nil {
+		flatten(prim)
+	}
+
+	return prim, nil
+}
+
+// readChannels reads the optional vertex channels of a primitive other than
+// normals and texture coordinates.
+func (d *decoder) readChannels(p jsonPrimitive, prim *Primitive) error {
+	n := len(prim.Vertices)
+
+	if i, ok := p.Attributes["TANGENT"]; ok {
+		v, err := d.readFloats(i, "VEC4", false)
+		if err != nil {
+			return fmt.Errorf("TANGENT: %v", err)
+		}
+		if prim.Tangents = toVec4(v, 4); len(prim.Tangents) != n {
+			return errors.New("TANGENT count differs from POSITION count")
+		}
+
+		// Flipping the texture coordinates mirrors the bitangents.
+		for j := range prim.Tangents {
+			prim.Tangents[j][3] = -prim.Tangents[j][3]
+		}
+	}
+
+	if i, ok := p.Attributes["COLOR_0"]; ok {
+		typ := "VEC4"
+		if i >= 0 && i < len(d.doc.Accessors) && d.doc.Accessors[i].Type == "VEC3" {
+			typ = "VEC3"
+		}
+
+		v, err := d.readFloats(i, typ, true)
+		if err != nil {
+			return fmt.Errorf("COLOR_0: %v", err)
+		}
+		if prim.Colors = toVec4(v, typeSizes[typ]); len(prim.Colors) != n {
+			return errors.New("COLOR_0 count differs from POSITION count")
+		}
+	}
+
+	if i, ok := p.Attributes["TEXCOORD_1"]; ok {
+		v, err := d.readFloats(i, "VEC2", true)
+		if err != nil {
+			return fmt.Errorf("TEXCOORD_1: %v", err)
+		}
+		if prim.Uvs2 = toUvs(v); len(prim.Uvs2) != n {
+			return errors.New("TEXCOORD_1 count differs from POSITION count")
+		}
+	}
+
+	if i, ok := p.Attributes["JOINTS_0"]; ok {
+		v, err := d.readUints(i, "VEC4")
+		if err != nil {
+			return fmt.Errorf("JOINTS_0: %v", err)
+		}
+
+		prim.BoneIndices = make([][4]uint16, len(v)/4)
+		for j := range prim.BoneIndices {
+			for k := 0; k < 4; k++ {
+				prim.BoneIndices[j][k] = uint16(v[j*4+k])
+			}
+		}
+		if len(prim.BoneIndices) != n {
+			return errors.New("JOINTS_0 count differs from POSITION count")
+		}
+	}
+
+	if i, ok := p.Attributes["WEIGHTS_0"]; ok {
+		v, err := d.readFloats(i, "VEC4", true)
+		if err != nil {
+			return fmt.Errorf("WEIGHTS_0: %v", err)
+		}
+		if prim.BoneWeights = toVec4(v, 4); len(prim.BoneWeights) != n {
+			return errors.New("WEIGHTS_0 count differs from POSITION count")
+		}
+	}
+
+	return nil
+}
+
+// flatten gives each triangle of a primitive its own vertices, with the
+// normal of the triangle.
+func flatten(p *Primitive) {
+	p.Data = *p.Data.Unweld()
+	p.Normals = make([]mgl32.Vec3, len(p.Vertices))
+
+	for i := 0; i < len(p.Vertices); i += 3 {
+		a, b, c := p.Vertices[i], p.Vertices[i+1], p.Vertices[i+2]
+
+		normal := b.Sub(a).Cross(c.Sub(a))
+		if normal.Len() > 0 {
+			normal = normal.Normalize()
+		}
+
+		p.Normals[i], p.Normals[i+1], p.Normals[i+2] = normal, normal, normal
+	}
+}
+
+func toVec3(v []float32) []mgl32.Vec3 {
+	out := make([]mgl32.Vec3, len(v)/3)
+	for i := range out {
+		out[i] = mgl32.Vec3{v[i*3], v[i*3+1], v[i*3+2]}
+	}
+
+	return out
+}
+
+// toVec4 converts vectors of n components to 4 components, with w set to 1.
+func toVec4(v []float32, n int) []mgl32.Vec4 {
+	out := make([]mgl32.Vec4, len(v)/n)
+	for i := range out {
+		out[i] = mgl32.Vec4{0, 0, 0, 1}
+		copy(out[i][:], v[i*n:i*n+n])
+	}
+
+	return out
+}
+
+// toUvs converts texture coordinates with a top left origin to texture
+// coordinates with a bottom left origin.
+func toUvs(v []float32) []mgl32.Vec2 {
+	out := toVec2(v)
+	for i := range out {
+		out[i][1] = 1 - out[i][1]
+	}
+
+	return out
+}
+
+func toVec2(v []float32) []mgl32.Vec2 {
+	out := make([]mgl32.Vec2, len(v)/2)
+	for i := range out {
+		out[i] = mgl32.Vec2{v[i*2], v[i*2+1]}
+	}
+
+	return out
+}
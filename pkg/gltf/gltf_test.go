@@ -0,0 +1,257 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// testBuffer returns a buffer with the positions of a quad followed by the
+// indices of its two triangles.
+func testBuffer() []byte {
+	var b bytes.Buffer
+
+	binary.Write(&b, binary.LittleEndian, []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0})
+	binary.Write(&b, binary.LittleEndian, []uint16{0, 1, 2, 0, 2, 3})
+
+	return b.Bytes()
+}
+
+// testDocument returns a document with a quad mesh, and the given buffer URI
+// and nodes.
+func testDocument(uri, nodes string) string {
+	if uri != "" {
+		uri = fmt.Sprintf(`"uri": %q,`, uri)
+	}
+
+	return `{
+	"asset": {"version": "2.0"},
+	"scenes": [{"nodes": [0]}],
+	"nodes": ` + nodes + `,
+	"cameras": [{"type": "perspective", "perspective": {"yfov": 0.8, "znear": 0.1}}],
+	"meshes": [{"name": "quad", "primitives": [{"attributes": {"POSITION": 0}, "indices": 1, "material": 0}]}],
+	"materials": [{"pbrMetallicRoughness": {"baseColorFactor": [1, 0, 0, 1], "metallicFactor": 0.5, "baseColorTexture": {"index": 0}}}],
+	"textures": [{"source": 0}],
+	"images": [{"uri": "data:image/png;base64,AAAA"}],
+	"buffers": [{` + uri + ` "byteLength": 60}],
+	"bufferViews": [{"buffer": 0, "byteLength": 48}, {"buffer": 0, "byteOffset": 48, "byteLength": 12}],
+	"accessors": [
+		{"bufferView": 0, "componentType": 5126, "count": 4, "type": "VEC3"},
+		{"bufferView": 1, "componentType": 5123, "count": 6, "type": "SCALAR"}
+	]
+}`
+}
+
+// testGLB packs a document and a binary chunk into a .glb file.
+func testGLB(js string, bin []byte) []byte {
+	for len(js)%4 != 0 {
+		js += " "
+	}
+
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, []uint32{glbMagic, 2, uint32(12 + 8 + len(js) + 8 + len(bin))})
+	binary.Write(&b, binary.LittleEndian, []uint32{uint32(len(js)), glbChunkJSON})
+	b.WriteString(js)
+	binary.Write(&b, binary.LittleEndian, []uint32{uint32(len(bin)), glbChunkBIN})
+	b.Write(bin)
+
+	return b.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	const nodes = `[{"name": "root", "children": [1], "matrix": [2,0,0,0, 0,2,0,0, 0,0,2,0, 1,2,3,1]}, {"mesh": 0, "camera": 0, "rotation": [0, 0.7071068, 0, 0.7071068]}]`
+
+	buffer := testBuffer()
+	uri := "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(buffer)
+
+	open := func(name string) ([]byte, error) {
+		if name != "quad data.bin" {
+			return nil, fmt.Errorf("unexpected file %s", name)
+		}
+		return buffer, nil
+	}
+
+	inputs := [][]byte{
+		[]byte(testDocument(uri, nodes)),
+		[]byte(testDocument("quad%20data.bin", nodes)),
+		testGLB(testDocument("", nodes), buffer),
+	}
+
+	for i, in := range inputs {
+		doc, err := Decode(bytes.NewReader(in), open)
+		if err != nil {
+			t.Errorf("Test %d: got: %v want: nil", i, err)
+			continue
+		}
+
+		tests := []struct {
+			got  interface{}
+			want interface{}
+		}{
+			{got: doc.Scene, want: 0},
+			{got: len(doc.Nodes), want: 2},
+			{got: doc.Nodes[0].Translation, want: mgl32.Vec3{1, 2, 3}},
+			{got: doc.Nodes[0].Scale, want: mgl32.Vec3{2, 2, 2}},
+			{got: doc.Nodes[0].Mesh, want: -1},
+			{got: doc.Nodes[1].Mesh, want: 0},
+			{got: doc.Nodes[1].Camera, want: 0},
+			{got: doc.Nodes[1].Rotation.ApproxEqualThreshold(mgl32.QuatRotate(mgl32.DegToRad(90), mgl32.Vec3{0, 1, 0}), 1e-5), want: true},
+			{got: doc.Meshes[0].Name, want: "quad"},
+			{got: len(doc.Meshes[0].Primitives[0].Vertices), want: 6},
+			{got: doc.Meshes[0].Primitives[0].Indexed(), want: false},
+			{got: len(doc.Meshes[0].Primitives[0].Normals), want: 6},
+			{got: doc.Meshes[0].Primitives[0].Normals[0], want: mgl32.Vec3{0, 0, 1}},
+			{got: doc.Meshes[0].Primitives[0].Material, want: 0},
+			{got: doc.Materials[0].BaseColor, want: mgl32.Vec4{1, 0, 0, 1}},
+			{got: doc.Materials[0].Metallic, want: float32(0.5)},
+			{got: doc.Materials[0].Roughness, want: float32(1)},
+			{got: doc.Materials[0].BaseColorTexture, want: 0},
+			{got: doc.Materials[0].NormalTexture, want: -1},
+			{got: len(doc.Images[0].Data), want: 3},
+			{got: doc.Cameras[0].Yfov, want: float32(0.8)},
+		}
+
+		for j, v := range tests {
+			if v.got != v.want {
+				t.Errorf("Test %d.%d: got: %v want: %v", i, j, v.got, v.want)
+			}
+		}
+	}
+}
+
+func TestDecode_Channels(t *testing.T) {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, []float32{0, 0, 0, 1, 0, 0, 0, 1, 0})
+	binary.Write(&b, binary.LittleEndian, []float32{0, 0, 1, 0, 0, 1, 0, 0, 1})
+	binary.Write(&b, binary.LittleEndian, []uint8{255, 0, 0, 0, 0, 255, 0, 0, 0, 0, 255, 0})
+	binary.Write(&b, binary.LittleEndian, []uint16{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12})
+	binary.Write(&b, binary.LittleEndian, []float32{0, 0, 1, 0.25, 0.5, 1})
+	binary.Write(&b, binary.LittleEndian, []float32{1, 0, 0, 1, 1, 0, 0, 1, 1, 0, 0, -1})
+
+	uri := "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(b.Bytes())
+
+	in := `{
+	"asset": {"version": "2.0"},
+	"meshes": [{"primitives": [{"attributes": {"POSITION": 0, "NORMAL": 1, "COLOR_0": 2, "JOINTS_0": 3, "TEXCOORD_0": 4, "TEXCOORD_1": 4, "TANGENT": 5}}]}],
+	"buffers": [{"uri": "` + uri + `", "byteLength": 180}],
+	"bufferViews": [
+		{"buffer": 0, "byteLength": 72},
+		{"buffer": 0, "byteOffset": 72, "byteLength": 12, "byteStride": 4},
+		{"buffer": 0, "byteOffset": 84, "byteLength": 24},
+		{"buffer": 0, "byteOffset": 108, "byteLength": 24},
+		{"buffer": 0, "byteOffset": 132, "byteLength": 48}
+	],
+	"accessors": [
+		{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"},
+		{"bufferView": 0, "byteOffset": 36, "componentType": 5126, "count": 3, "type": "VEC3"},
+		{"bufferView": 1, "componentType": 5121, "normalized": true, "count": 3, "type": "VEC3"},
+		{"bufferView": 2, "componentType": 5123, "count": 3, "type": "VEC4"},
+		{"bufferView": 3, "componentType": 5126, "count": 3, "type": "VEC2"},
+		{"bufferView": 4, "componentType": 5126, "count": 3, "type": "VEC4"}
+	]
+}`
+
+	doc, err := Decode(strings.NewReader(in), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := doc.Meshes[0].Primitives[0]
+
+	tests := []struct {
+		got  interface{}
+		want interface{}
+	}{
+		{got: doc.Scene, want: -1},
+		{got: p.Indexed(), want: true},
+		{got: p.Normals[2], want: mgl32.Vec3{0, 0, 1}},
+		{got: p.Colors[0], want: mgl32.Vec4{1, 0, 0, 1}},
+		{got: p.Colors[2], want: mgl32.Vec4{0, 0, 1, 1}},
+		{got: p.BoneIndices[1], want: [4]uint16{5, 6, 7, 8}},
+		{got: len(p.Uvs), want: 3},
+		{got: p.Uvs[0], want: mgl32.Vec2{0, 1}},
+		{got: p.Uvs[1], want: mgl32.Vec2{1, 0.75}},
+		{got: p.Uvs2[2], want: mgl32.Vec2{0.5, 0}},
+		{got: p.Tangents[0], want: mgl32.Vec4{1, 0, 0, -1}},
+		{got: p.Tangents[2], want: mgl32.Vec4{1, 0, 0, 1}},
+		{got: p.Validate(), want: nil},
+	}
+
+	for i, v := range tests {
+		if v.got != v.want {
+			t.Errorf("Test %d: got: %v want: %v", i, v.got, v.want)
+		}
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	uri := "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(testBuffer())
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: `{"asset": {"version": "1.0"}}`, want: "unsupported version"},
+		{in: `{"asset": {"version": "2.0"}, "extensionsRequired": ["KHR_draco_mesh_compression"]}`, want: "KHR_draco_mesh_compression"},
+		{in: testDocument(uri, `[{"children": [1]}, {"children": [0]}]`), want: "own ancestor"},
+		{in: testDocument(uri, `[{"children": [1]}, {"children": [1]}]`), want: "more than one parent"},
+		{in: testDocument(uri, `[{"children": [2]}, {}]`), want: "out of range"},
+		{in: testDocument(uri, `[{}, {"children": [0]}]`), want: "not a root node"},
+		{in: testDocument(uri, `[{"mesh": 1}]`), want: "mesh 1 out of range"},
+		{in: testDocument("quad.bin", `[{}]`), want: "cannot open"},
+		{in: strings.Replace(testDocument(uri, `[{}]`), `"count": 6`, `"count": 7`, 1), want: "exceeds buffer view"},
+		{in: strings.Replace(testDocument(uri, `[{}]`), `"count": 4`, `"count": 2`, 1), want: "index 2 out of range"},
+	}
+
+	for i, v := range tests {
+		_, err := Decode(strings.NewReader(v.in), nil)
+		if err == nil || !strings.Contains(err.Error(), v.want) {
+			t.Errorf("Test %d: got: %v want: %s", i, err, v.want)
+		}
+	}
+}
+
+func TestFan(t *testing.T) {
+	tests := []struct {
+		mode int
+		in   []uint32
+		want []uint32
+	}{
+		{mode: ModeTriangleStrip, in: []uint32{0, 1, 2, 3, 4}, want: []uint32{0, 1, 2, 2, 1, 3, 2, 3, 4}},
+		{mode: ModeTriangleFan, in: []uint32{0, 1, 2, 3}, want: []uint32{0, 1, 2, 0, 2, 3}},
+		{mode: ModeTriangleFan, in: []uint32{0, 1}, want: nil},
+	}
+
+	for i, v := range tests {
+		if got := fan(v.mode, v.in); fmt.Sprint(got) != fmt.Sprint(v.want) {
+			t.Errorf("Test %d: got: %v want: %v", i, got, v.want)
+		}
+	}
+}
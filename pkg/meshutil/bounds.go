@@ -0,0 +1,154 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package meshutil
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// AABB is an axis aligned bounding box.
+type AABB struct {
+	Min mgl32.Vec3
+	Max mgl32.Vec3
+}
+
+// Center returns the center of the box.
+func (b AABB) Center() mgl32.Vec3 {
+	return b.Min.Add(b.Max).Mul(0.5)
+}
+
+// Size returns the size of the box along each axis.
+func (b AABB) Size() mgl32.Vec3 {
+	return b.Max.Sub(b.Min)
+}
+
+// Contains reports whether a point is inside the box or on its surface.
+func (b AABB) Contains(p mgl32.Vec3) bool {
+	for i := 0; i < 3; i++ {
+		if p[i] < b.Min[i] || p[i] > b.Max[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Sphere is a bounding sphere.
+type Sphere struct {
+	Center mgl32.Vec3
+	Radius float32
+}
+
+// Contains reports whether a point is inside the sphere or on its surface,
+// allowing for rounding.
+func (s Sphere) Contains(p mgl32.Vec3) bool {
+	return p.Sub(s.Center).Len() <= s.Radius*(1+1e-5)+1e-6
+}
+
+// Bounds returns the bounding box of the vertices, or a zero box if there are
+// none.
+func (d *Data) Bounds() AABB {
+	if len(d.Vertices) == 0 {
+		return AABB{}
+	}
+
+	b := AABB{Min: d.Vertices[0], Max: d.Vertices[0]}
+	for _, v := range d.Vertices[1:] {
+		for i := 0; i < 3; i++ {
+			if v[i] < b.Min[i] {
+				b.Min[i] = v[i]
+			}
+			if v[i] > b.Max[i] {
+				b.Max[i] = v[i]
+			}
+		}
+	}
+
+	return b
+}
+
+// BoundingSphere returns a sphere which contains the vertices. It is the
+// smaller of the sphere around the center of the bounding box and the sphere
+// from Ritter's algorithm, which starts from the two vertices farthest apart
+// along an axis and grows to include the others. Neither is the smallest
+// sphere, but the first is exact for boxes and the second is close for round
+// shapes.
+func (d *Data) BoundingSphere() Sphere {
+	if len(d.Vertices) == 0 {
+		return Sphere{}
+	}
+
+	// Find the pair of extreme vertices along each axis, and start from the
+	// pair farthest apart.
+	var lo, hi [3]mgl32.Vec3
+	for i := 0; i < 3; i++ {
+		lo[i], hi[i] = d.Vertices[0], d.Vertices[0]
+	}
+	for _, v := range d.Vertices {
+		for i := 0; i < 3; i++ {
+			if v[i] < lo[i][i] {
+				lo[i] = v
+			}
+			if v[i] > hi[i][i] {
+				hi[i] = v
+			}
+		}
+	}
+
+	axis := 0
+	for i := 1; i < 3; i++ {
+		if hi[i].Sub(lo[i]).Len() > hi[axis].Sub(lo[axis]).Len() {
+			axis = i
+		}
+	}
+
+	s := Sphere{
+		Center: lo[axis].Add(hi[axis]).Mul(0.5),
+		Radius: hi[axis].Sub(lo[axis]).Len() / 2,
+	}
+
+	for _, v := range d.Vertices {
+		dist := v.Sub(s.Center).Len()
+		if dist <= s.Radius {
+			continue
+		}
+
+		// Grow the sphere to touch v, keeping the opposite side in place.
+		r := (s.Radius + dist) / 2
+		s.Center = s.Center.Add(v.Sub(s.Center).Mul((r - s.Radius) / dist))
+		s.Radius = r
+	}
+
+	box := Sphere{Center: d.Bounds().Center()}
+	for _, v := range d.Vertices {
+		if dist := v.Sub(box.Center).Len(); dist > box.Radius {
+			box.Radius = dist
+		}
+	}
+
+	if box.Radius < s.Radius {
+		return box
+	}
+
+	return s
+}
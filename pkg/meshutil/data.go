@@ -0,0 +1,251 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package meshutil processes the vertex and index data of meshes without
+// OpenGL.
+package meshutil
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Data holds the vertex channels of a mesh, and the indices of its triangles.
+// Every channel other than Vertices is optional, and is either empty or has
+// an element for each vertex. If Triangles is empty, each three vertices form
+// a triangle.
+type Data struct {
+	Vertices    []mgl32.Vec3
+	Normals     []mgl32.Vec3
+	Uvs         []mgl32.Vec2
+	Tangents    []mgl32.Vec4
+	Colors      []mgl32.Vec4
+	Uvs2        []mgl32.Vec2
+	BoneIndices [][4]uint16
+	BoneWeights []mgl32.Vec4
+	Triangles   []uint32
+}
+
+// Indexed reports whether the data has triangle indices.
+func (d *Data) Indexed() bool {
+	return len(d.Triangles) != 0
+}
+
+// TriangleCount returns the number of triangles.
+func (d *Data) TriangleCount() int {
+	if d.Indexed() {
+		return len(d.Triangles) / 3
+	}
+
+	return len(d.Vertices) / 3
+}
+
+// Triangle returns the vertex indices of a triangle.
+func (d *Data) Triangle(i int) (a, b, c uint32) {
+	if d.Indexed() {
+		return d.Triangles[i*3], d.Triangles[i*3+1], d.Triangles[i*3+2]
+	}
+
+	return uint32(i * 3), uint32(i*3 + 1), uint32(i*3 + 2)
+}
+
+// Validate checks that the data has vertices, that every channel has an
+// element for each vertex, and that the triangles are complete and in range.
+func (d *Data) Validate() error {
+	n := len(d.Vertices)
+	if n == 0 {
+		return fmt.Errorf("meshutil: no vertices")
+	}
+
+	channels := []struct {
+		name string
+		len  int
+	}{
+		{"normals", len(d.Normals)},
+		{"uvs", len(d.Uvs)},
+		{"tangents", len(d.Tangents)},
+		{"colors", len(d.Colors)},
+		{"uvs2", len(d.Uvs2)},
+		{"bone indices", len(d.BoneIndices)},
+		{"bone weights", len(d.BoneWeights)},
+	}
+
+	for _, c := range channels {
+		if c.len != 0 && c.len != n {
+			return fmt.Errorf("meshutil: %d %s for %d vertices", c.len, c.name, n)
+		}
+	}
+
+	if !d.Indexed() {
+		if n%3 != 0 {
+			return fmt.Errorf("meshutil: %d vertices do not form triangles", n)
+		}
+		return nil
+	}
+
+	if len(d.Triangles)%3 != 0 {
+		return fmt.Errorf("meshutil: %d indices do not form triangles", len(d.Triangles))
+	}
+	for _, i := range d.Triangles {
+		if int(i) >= n {
+			return fmt.Errorf("meshutil: index %d out of range of %d vertices", i, n)
+		}
+	}
+
+	return nil
+}
+
+// vertexKey holds every channel of a vertex, so that vertices can be compared.
+type vertexKey struct {
+	v, n        mgl32.Vec3
+	uv, uv2     mgl32.Vec2
+	t, c, w     mgl32.Vec4
+	boneIndices [4]uint16
+}
+
+func (d *Data) key(i uint32) vertexKey {
+	k := vertexKey{v: d.Vertices[i]}
+
+	if len(d.Normals) != 0 {
+		k.n = d.Normals[i]
+	}
+	if len(d.Uvs) != 0 {
+		k.uv = d.Uvs[i]
+	}
+	if len(d.Tangents) != 0 {
+		k.t = d.Tangents[i]
+	}
+	if len(d.Colors) != 0 {
+		k.c = d.Colors[i]
+	}
+	if len(d.Uvs2) != 0 {
+		k.uv2 = d.Uvs2[i]
+	}
+	if len(d.BoneIndices) != 0 {
+		k.boneIndices = d.BoneIndices[i]
+	}
+	if len(d.BoneWeights) != 0 {
+		k.w = d.BoneWeights[i]
+	}
+
+	return k
+}
+
+// Weld returns indexed data in which vertices with identical values in every
+// channel are shared. Vertices which are not used by a triangle are dropped.
+func (d *Data) Weld() *Data {
+	out := d.subset()
+	out.Triangles = make([]uint32, 0, d.TriangleCount()*3)
+
+	index := make(map[vertexKey]uint32)
+
+	for t := 0; t < d.TriangleCount(); t++ {
+		a, b, c := d.Triangle(t)
+
+		for _, i := range [3]uint32{a, b, c} {
+			k := d.key(i)
+
+			j, ok := index[k]
+			if !ok {
+				j = uint32(len(out.Vertices))
+				index[k] = j
+				out.append(d, i)
+			}
+
+			out.Triangles = append(out.Triangles, j)
+		}
+	}
+
+	return out
+}
+
+// Unweld returns data without indices, in which each triangle has its own
+// vertices.
+func (d *Data) Unweld() *Data {
+	out := d.subset()
+
+	for t := 0; t < d.TriangleCount(); t++ {
+		a, b, c := d.Triangle(t)
+		out.append(d, a)
+		out.append(d, b)
+		out.append(d, c)
+	}
+
+	return out
+}
+
+// subset returns empty data with the channels of d.
+func (d *Data) subset() *Data {
+	out := &Data{}
+
+	if len(d.Normals) != 0 {
+		out.Normals = []mgl32.Vec3{}
+	}
+	if len(d.Uvs) != 0 {
+		out.Uvs = []mgl32.Vec2{}
+	}
+	if len(d.Tangents) != 0 {
+		out.Tangents = []mgl32.Vec4{}
+	}
+	if len(d.Colors) != 0 {
+		out.Colors = []mgl32.Vec4{}
+	}
+	if len(d.Uvs2) != 0 {
+		out.Uvs2 = []mgl32.Vec2{}
+	}
+	if len(d.BoneIndices) != 0 {
+		out.BoneIndices = [][4]uint16{}
+	}
+	if len(d.BoneWeights) != 0 {
+		out.BoneWeights = []mgl32.Vec4{}
+	}
+
+	return out
+}
+
+// append appends the vertex i of src to the channels of d which are present.
+func (d *Data) append(src *Data, i uint32) {
+	d.Vertices = append(d.Vertices, src.Vertices[i])
+
+	if d.Normals != nil {
+		d.Normals = append(d.Normals, src.Normals[i])
+	}
+	if d.Uvs != nil {
+		d.Uvs = append(d.Uvs, src.Uvs[i])
+	}
+	if d.Tangents != nil {
+		d.Tangents = append(d.Tangents, src.Tangents[i])
+	}
+	if d.Colors != nil {
+		d.Colors = append(d.Colors, src.Colors[i])
+	}
+	if d.Uvs2 != nil {
+		d.Uvs2 = append(d.Uvs2, src.Uvs2[i])
+	}
+	if d.BoneIndices != nil {
+		d.BoneIndices = append(d.BoneIndices, src.BoneIndices[i])
+	}
+	if d.BoneWeights != nil {
+		d.BoneWeights = append(d.BoneWeights, src.BoneWeights[i])
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package meshutil
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Channel is a vertex channel. Its value is the shader attribute location of
+// the channel.
+type Channel int
+
+const (
+	ChannelPosition    Channel = iota // vec3 vertex
+	ChannelNormal                     // vec3 normal
+	ChannelUv                         // vec2 uv
+	ChannelTangent                    // vec4 tangent, with the handedness in w
+	ChannelColor                      // vec4 color
+	ChannelUv2                        // vec2 uv2
+	ChannelBoneIndices                // uvec4 bone_indices
+	ChannelBoneWeights                // vec4 bone_weights
+)
+
+// Attribute is a vertex attribute of an interleaved vertex buffer.
+type Attribute struct {
+	Channel Channel
+
+	// Components is the number of components of the attribute.
+	Components int
+
+	// Integer reports whether the components are unsigned 16 bit integers.
+	// Otherwise they are 32 bit floats.
+	Integer bool
+
+	// Offset is the offset of the attribute in a vertex, in bytes.
+	Offset int
+}
+
+// Layout is the layout of the vertices of an interleaved vertex buffer.
+type Layout struct {
+	Attributes []Attribute
+	Stride     int
+}
+
+// Layout returns the layout of the channels which are present, in channel
+// order.
+func (d *Data) Layout() Layout {
+	var l Layout
+
+	add := func(c Channel, present bool, components int, integer bool) {
+		if !present {
+			return
+		}
+
+		l.Attributes = append(l.Attributes, Attribute{Channel: c, Components: components, Integer: integer, Offset: l.Stride})
+
+		if integer {
+			l.Stride += components * 2
+		} else {
+			l.Stride += components * 4
+		}
+	}
+
+	add(ChannelPosition, true, 3, false)
+	add(ChannelNormal, len(d.Normals) != 0, 3, false)
+	add(ChannelUv, len(d.Uvs) != 0, 2, false)
+	add(ChannelTangent, len(d.Tangents) != 0, 4, false)
+	add(ChannelColor, len(d.Colors) != 0, 4, false)
+	add(ChannelUv2, len(d.Uvs2) != 0, 2, false)
+	add(ChannelBoneIndices, len(d.BoneIndices) != 0, 4, true)
+	add(ChannelBoneWeights, len(d.BoneWeights) != 0, 4, false)
+
+	return l
+}
+
+// Interleave returns the vertices as an interleaved vertex buffer with the
+// layout of the data, in little endian byte order.
+func (d *Data) Interleave() ([]byte, Layout) {
+	l := d.Layout()
+	buf := make([]byte, len(d.Vertices)*l.Stride)
+
+	for i := range d.Vertices {
+		for _, a := range l.Attributes {
+			b := buf[i*l.Stride+a.Offset:]
+
+			switch a.Channel {
+			case ChannelPosition:
+				putFloats(b, d.Vertices[i][:])
+			case ChannelNormal:
+				putFloats(b, d.Normals[i][:])
+			case ChannelUv:
+				putFloats(b, d.Uvs[i][:])
+			case ChannelTangent:
+				putFloats(b, d.Tangents[i][:])
+			case ChannelColor:
+				putFloats(b, d.Colors[i][:])
+			case ChannelUv2:
+				putFloats(b, d.Uvs2[i][:])
+			case ChannelBoneIndices:
+				for j, v := range d.BoneIndices[i] {
+					binary.LittleEndian.PutUint16(b[j*2:], v)
+				}
+			case ChannelBoneWeights:
+				putFloats(b, d.BoneWeights[i][:])
+			}
+		}
+	}
+
+	return buf, l
+}
+
+// Indices16 returns the triangle indices as 16 bit integers, if every vertex
+// can be indexed by one.
+func (d *Data) Indices16() ([]uint16, bool) {
+	if len(d.Vertices) > math.MaxUint16+1 {
+		return nil, false
+	}
+
+	out := make([]uint16, len(d.Triangles))
+	for i, v := range d.Triangles {
+		out[i] = uint16(v)
+	}
+
+	return out, true
+}
+
+func putFloats(b []byte, v []float32) {
+	for i := range v {
+		binary.LittleEndian.PutUint32(b[i*4:], math.Float32bits(v[i]))
+	}
+}
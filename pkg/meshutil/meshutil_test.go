@@ -0,0 +1,465 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package meshutil
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// quad returns an unindexed quad of two triangles which share an edge.
+func quad() *Data {
+	return &Data{
+		Vertices: []mgl32.Vec3{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 0, 0}, {1, 1, 0}, {0, 1, 0}},
+		Normals:  []mgl32.Vec3{{0, 0, 1}, {0, 0, 1}, {0, 0, 1}, {0, 0, 1}, {0, 0, 1}, {0, 0, 1}},
+		Uvs:      []mgl32.Vec2{{0, 0}, {1, 0}, {1, 1}, {0, 0}, {1, 1}, {0, 1}},
+	}
+}
+
+func TestData_Weld(t *testing.T) {
+	seam := quad()
+	seam.Uvs[3] = mgl32.Vec2{0.5, 0}
+
+	tests := []struct {
+		in        *Data
+		vertices  int
+		triangles []uint32
+	}{
+		{in: quad(), vertices: 4, triangles: []uint32{0, 1, 2, 0, 2, 3}},
+		{in: seam, vertices: 5, triangles: []uint32{0, 1, 2, 3, 2, 4}},
+		{in: quad().Weld(), vertices: 4, triangles: []uint32{0, 1, 2, 0, 2, 3}},
+		{in: &Data{Vertices: []mgl32.Vec3{{1, 0, 0}, {0, 0, 0}, {0, 1, 0}, {0, 0, 1}}, Triangles: []uint32{1, 2, 3}}, vertices: 3, triangles: []uint32{0, 1, 2}},
+	}
+
+	for i, v := range tests {
+		got := v.in.Weld()
+
+		if err := got.Validate(); err != nil {
+			t.Errorf("Test %d: got: %v want: nil", i, err)
+		}
+		if len(got.Vertices) != v.vertices || (v.in.Normals != nil && len(got.Normals) != v.vertices) {
+			t.Errorf("Test %d: got: %d vertices want: %d", i, len(got.Vertices), v.vertices)
+		}
+		if len(got.Triangles) != len(v.triangles) {
+			t.Errorf("Test %d: got: %v want: %v", i, got.Triangles, v.triangles)
+			continue
+		}
+		for j := range got.Triangles {
+			if got.Triangles[j] != v.triangles[j] {
+				t.Errorf("Test %d: got: %v want: %v", i, got.Triangles, v.triangles)
+				break
+			}
+		}
+
+		// Welding must not change the triangles.
+		un := got.Unweld()
+		ref := v.in.Unweld()
+		for j := range ref.Vertices {
+			if un.Vertices[j] != ref.Vertices[j] || (ref.Uvs != nil && un.Uvs[j] != ref.Uvs[j]) {
+				t.Errorf("Test %d: vertex %d: got: %v want: %v", i, j, un.Vertices[j], ref.Vertices[j])
+			}
+		}
+	}
+}
+
+func TestData_Validate(t *testing.T) {
+	tests := []struct {
+		in   *Data
+		want bool
+	}{
+		{in: quad(), want: true},
+		{in: &Data{}, want: false},
+		{in: &Data{Vertices: make([]mgl32.Vec3, 4)}, want: false},
+		{in: &Data{Vertices: make([]mgl32.Vec3, 3), Normals: make([]mgl32.Vec3, 2)}, want: false},
+		{in: &Data{Vertices: make([]mgl32.Vec3, 3), Triangles: []uint32{0, 1, 3}}, want: false},
+		{in: &Data{Vertices: make([]mgl32.Vec3, 3), Triangles: []uint32{0, 1}}, want: false},
+		{in: &Data{Vertices: make([]mgl32.Vec3, 3), BoneIndices: make([][4]uint16, 3), Triangles: []uint32{0, 1, 2}}, want: true},
+	}
+
+	for i, v := range tests {
+		if got := v.in.Validate() == nil; got != v.want {
+			t.Errorf("Test %d: got: %v want: %v", i, got, v.want)
+		}
+	}
+}
+
+func TestData_Layout(t *testing.T) {
+	skinned := quad()
+	skinned.Normals = nil
+	skinned.BoneIndices = make([][4]uint16, 6)
+	skinned.BoneWeights = make([]mgl32.Vec4, 6)
+	skinned.BoneIndices[1] = [4]uint16{7, 0, 0, 0}
+
+	tests := []struct {
+		in      *Data
+		stride  int
+		offsets map[Channel]int
+	}{
+		{in: quad(), stride: 32, offsets: map[Channel]int{ChannelPosition: 0, ChannelNormal: 12, ChannelUv: 24}},
+		{in: &Data{Vertices: quad().Vertices}, stride: 12, offsets: map[Channel]int{ChannelPosition: 0}},
+		{in: skinned, stride: 44, offsets: map[Channel]int{ChannelPosition: 0, ChannelUv: 12, ChannelBoneIndices: 20, ChannelBoneWeights: 28}},
+	}
+
+	for i, v := range tests {
+		buf, l := v.in.Interleave()
+
+		if l.Stride != v.stride || len(buf) != v.stride*len(v.in.Vertices) {
+			t.Errorf("Test %d: got: stride %d want: %d", i, l.Stride, v.stride)
+		}
+		if len(l.Attributes) != len(v.offsets) {
+			t.Errorf("Test %d: got: %d attributes want: %d", i, len(l.Attributes), len(v.offsets))
+		}
+		for _, a := range l.Attributes {
+			if off, ok := v.offsets[a.Channel]; !ok || off != a.Offset {
+				t.Errorf("Test %d: channel %d: got: offset %d want: %d", i, a.Channel, a.Offset, off)
+			}
+		}
+
+		// The second vertex starts with the position (1, 0, 0).
+		if x := math.Float32frombits(binary.LittleEndian.Uint32(buf[l.Stride:])); x != 1 {
+			t.Errorf("Test %d: got: %v want: 1", i, x)
+		}
+	}
+
+	buf, l := skinned.Interleave()
+	if b := binary.LittleEndian.Uint16(buf[l.Stride+20:]); b != 7 {
+		t.Errorf("got: bone index %d want: 7", b)
+	}
+}
+
+func TestData_Indices16(t *testing.T) {
+	tests := []struct {
+		vertices int
+		want     bool
+	}{
+		{vertices: 3, want: true},
+		{vertices: 65536, want: true},
+		{vertices: 65537, want: false},
+	}
+
+	for i, v := range tests {
+		d := &Data{Vertices: make([]mgl32.Vec3, v.vertices), Triangles: []uint32{0, 1, uint32(v.vertices - 1)}}
+
+		idx, ok := d.Indices16()
+		if ok != v.want {
+			t.Errorf("Test %d: got: %v want: %v", i, ok, v.want)
+		}
+		if ok && int(idx[2]) != v.vertices-1 {
+			t.Errorf("Test %d: got: %d want: %d", i, idx[2], v.vertices-1)
+		}
+	}
+}
+
+// cube returns an unindexed unit cube centered on the origin, without
+// normals.
+func cube() *Data {
+	c := [8]mgl32.Vec3{
+		{-0.5, -0.5, -0.5}, {0.5, -0.5, -0.5}, {0.5, 0.5, -0.5}, {-0.5, 0.5, -0.5},
+		{-0.5, -0.5, 0.5}, {0.5, -0.5, 0.5}, {0.5, 0.5, 0.5}, {-0.5, 0.5, 0.5},
+	}
+	faces := [6][4]int{
+		{4, 5, 6, 7}, {1, 0, 3, 2}, {5, 1, 2, 6}, {0, 4, 7, 3}, {7, 6, 2, 3}, {0, 1, 5, 4},
+	}
+
+	d := &Data{}
+	for _, f := range faces {
+		for _, i := range [6]int{0, 1, 2, 0, 2, 3} {
+			d.Vertices = append(d.Vertices, c[f[i]])
+		}
+	}
+
+	return d
+}
+
+// grid returns an indexed square of n by n quads in the xy plane.
+func grid(n int) *Data {
+	d := &Data{}
+	for y := 0; y <= n; y++ {
+		for x := 0; x <= n; x++ {
+			d.Vertices = append(d.Vertices, mgl32.Vec3{float32(x), float32(y), 0})
+			d.Normals = append(d.Normals, mgl32.Vec3{0, 0, 1})
+			d.Uvs = append(d.Uvs, mgl32.Vec2{float32(x) / float32(n), float32(y) / float32(n)})
+		}
+	}
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			i := uint32(y*(n+1) + x)
+			j := i + uint32(n+1)
+			d.Triangles = append(d.Triangles, i, i+1, j+1, i, j+1, j)
+		}
+	}
+
+	return d
+}
+
+func TestData_SmoothNormals(t *testing.T) {
+	tests := []struct {
+		threshold float32
+		vertices  int
+		smooth    bool
+	}{
+		{threshold: 0, vertices: 24},
+		{threshold: math.Pi / 4, vertices: 24},
+		{threshold: math.Pi, vertices: 8, smooth: true},
+	}
+
+	for i, v := range tests {
+		got := cube().SmoothNormals(v.threshold)
+
+		if len(got.Vertices) != v.vertices || len(got.Normals) != v.vertices {
+			t.Errorf("Test %d: got: %d vertices want: %d", i, len(got.Vertices), v.vertices)
+		}
+
+		for j, n := range got.Normals {
+			want := got.Vertices[j].Normalize()
+			if !v.smooth {
+				// Flat normals point along a single axis, outwards.
+				if n.Dot(got.Vertices[j]) != 0.5 || math.Abs(float64(n.Len()-1)) > 1e-6 {
+					t.Errorf("Test %d: got: %v at %v", i, n, got.Vertices[j])
+				}
+				continue
+			}
+			if !n.ApproxEqualThreshold(want, 1e-5) {
+				t.Errorf("Test %d: got: %v want: %v", i, n, want)
+			}
+		}
+	}
+}
+
+func TestData_GenerateTangents(t *testing.T) {
+	mirrored := quad()
+	for i := range mirrored.Uvs {
+		mirrored.Uvs[i][0] = 1 - mirrored.Uvs[i][0]
+	}
+
+	// A quad whose second triangle is mirrored shares no tangent space
+	// across the diagonal.
+	seam := quad()
+	seam.Uvs[4], seam.Uvs[5] = mgl32.Vec2{0, 1}, mgl32.Vec2{1, 1}
+
+	// Two triangles meet at a right angle in the origin. The second is twice
+	// the size but has a hundredth of the texture scale, so its unnormalized
+	// tangent is a hundred times longer. The shared vertex still takes the
+	// mean direction.
+	z := mgl32.Vec3{0, 0, 1}
+	uneven := &Data{
+		Vertices: []mgl32.Vec3{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 0}, {-2, 0, 0}, {0, -2, 0}},
+		Normals:  []mgl32.Vec3{z, z, z, z, z, z},
+		Uvs:      []mgl32.Vec2{{0, 0}, {1, 0}, {0, 1}, {0, 0}, {0, 0.02}, {-0.02, 0}},
+	}
+	diagonal := float32(math.Sqrt2 / 2)
+
+	tests := []struct {
+		in       *Data
+		vertices int
+		want     []mgl32.Vec4
+		err      error
+	}{
+		{in: quad(), vertices: 4, want: []mgl32.Vec4{{1, 0, 0, 1}}},
+		{in: uneven, vertices: 5, want: []mgl32.Vec4{{1, 0, 0, 1}, {0, 1, 0, 1}, {diagonal, diagonal, 0, 1}}},
+		{in: mirrored, vertices: 4, want: []mgl32.Vec4{{-1, 0, 0, -1}}},
+		{in: seam, vertices: 6, want: []mgl32.Vec4{{1, 0, 0, 1}, {-1, 0, 0, -1}}},
+		{in: &Data{Vertices: quad().Vertices}, err: ErrTangentChannels},
+	}
+
+	for i, v := range tests {
+		got, err := v.in.GenerateTangents()
+		if err != v.err {
+			t.Errorf("Test %d: got: %v want: %v", i, err, v.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if len(got.Tangents) != v.vertices {
+			t.Errorf("Test %d: got: %d tangents want: %d", i, len(got.Tangents), v.vertices)
+		}
+		for _, tangent := range got.Tangents {
+			found := false
+			for _, w := range v.want {
+				if tangent.ApproxEqualThreshold(w, 1e-5) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Test %d: got: %v want: %v", i, tangent, v.want)
+			}
+		}
+	}
+}
+
+func TestData_Bounds(t *testing.T) {
+	tests := []struct {
+		in   *Data
+		want AABB
+	}{
+		{in: cube(), want: AABB{Min: mgl32.Vec3{-0.5, -0.5, -0.5}, Max: mgl32.Vec3{0.5, 0.5, 0.5}}},
+		{in: grid(4), want: AABB{Min: mgl32.Vec3{0, 0, 0}, Max: mgl32.Vec3{4, 4, 0}}},
+		{in: &Data{}, want: AABB{}},
+	}
+
+	for i, v := range tests {
+		got := v.in.Bounds()
+		if got != v.want {
+			t.Errorf("Test %d: got: %v want: %v", i, got, v.want)
+		}
+
+		s := v.in.BoundingSphere()
+		for _, p := range v.in.Vertices {
+			if !s.Contains(p) {
+				t.Errorf("Test %d: sphere %v does not contain %v", i, s, p)
+			}
+		}
+
+		// These shapes are bounded best by the sphere around their box.
+		if r := got.Size().Len() / 2; s.Radius > r*(1+1e-5) {
+			t.Errorf("Test %d: got: radius %f want: %f", i, s.Radius, r)
+		}
+	}
+}
+
+func TestData_Simplify(t *testing.T) {
+	tests := []struct {
+		in     *Data
+		target int
+		want   int
+	}{
+		{in: grid(8), target: 32, want: 32},
+		{in: grid(8), target: 2, want: 2},
+		{in: grid(8), target: 200, want: 128},
+		{in: cube(), target: 12, want: 12},
+	}
+
+	for i, v := range tests {
+		got := v.in.Simplify(v.target)
+
+		if err := got.Validate(); err != nil {
+			t.Errorf("Test %d: got: %v want: nil", i, err)
+		}
+		if n := got.TriangleCount(); n > v.want || n < v.want-1 {
+			t.Errorf("Test %d: got: %d triangles want: %d", i, n, v.want)
+		}
+
+		// The simplified surface stays within the original and none of its
+		// triangles turn over.
+		bounds := v.in.Bounds()
+		for _, p := range got.Vertices {
+			if !bounds.Contains(p) {
+				t.Errorf("Test %d: got: %v outside %v", i, p, bounds)
+			}
+		}
+		if len(v.in.Normals) == 0 {
+			continue
+		}
+		for j := 0; j < got.TriangleCount(); j++ {
+			a, b, c := got.Triangle(j)
+			n := FaceNormal(got.Vertices[a], got.Vertices[b], got.Vertices[c])
+			if n[2] <= 0 || n[0] != 0 || n[1] != 0 {
+				t.Errorf("Test %d: got: normal %v want: +z", i, n)
+			}
+		}
+	}
+}
+
+func TestPrimitives(t *testing.T) {
+	tests := []struct {
+		in        *Data
+		triangles int
+		closed    bool
+		bounds    AABB
+	}{
+		{in: Box(mgl32.Vec3{1, 2, 3}, 2), triangles: 48, closed: true, bounds: AABB{Min: mgl32.Vec3{-0.5, -1, -1.5}, Max: mgl32.Vec3{0.5, 1, 1.5}}},
+		{in: Plane(mgl32.Vec2{2, 4}, 2, 3), triangles: 12, bounds: AABB{Min: mgl32.Vec3{-1, 0, -2}, Max: mgl32.Vec3{1, 0, 2}}},
+		{in: UVSphere(1, 8, 4), triangles: 48, closed: true, bounds: AABB{Min: mgl32.Vec3{-1, -1, -1}, Max: mgl32.Vec3{1, 1, 1}}},
+		{in: Icosphere(1, 2), triangles: 320, closed: true},
+		{in: Cylinder(1, 2, 8), triangles: 32, closed: true, bounds: AABB{Min: mgl32.Vec3{-1, -1, -1}, Max: mgl32.Vec3{1, 1, 1}}},
+		{in: Cone(1, 2, 8), triangles: 16, closed: true, bounds: AABB{Min: mgl32.Vec3{-1, -1, -1}, Max: mgl32.Vec3{1, 1, 1}}},
+		{in: Capsule(1, 4, 8, 2), triangles: 64, closed: true, bounds: AABB{Min: mgl32.Vec3{-1, -2, -1}, Max: mgl32.Vec3{1, 2, 1}}},
+		{in: Capsule(1, 1, 8, 2), triangles: 48, closed: true, bounds: AABB{Min: mgl32.Vec3{-1, -1, -1}, Max: mgl32.Vec3{1, 1, 1}}},
+		{in: Torus(1, 0.25, 8, 8), triangles: 128, closed: true, bounds: AABB{Min: mgl32.Vec3{-1.25, -0.25, -1.25}, Max: mgl32.Vec3{1.25, 0.25, 1.25}}},
+	}
+
+	for i, v := range tests {
+		if err := v.in.Validate(); err != nil {
+			t.Errorf("Test %d: got: %v want: nil", i, err)
+			continue
+		}
+		if len(v.in.Normals) != len(v.in.Vertices) || len(v.in.Uvs) != len(v.in.Vertices) {
+			t.Errorf("Test %d: got: %d normals and %d uvs want: %d", i, len(v.in.Normals), len(v.in.Uvs), len(v.in.Vertices))
+		}
+		if n := v.in.TriangleCount(); n != v.triangles {
+			t.Errorf("Test %d: got: %d triangles want: %d", i, n, v.triangles)
+		}
+
+		if v.bounds != (AABB{}) {
+			got := v.in.Bounds()
+			if !got.Min.ApproxEqualThreshold(v.bounds.Min, 1e-5) || !got.Max.ApproxEqualThreshold(v.bounds.Max, 1e-5) {
+				t.Errorf("Test %d: got: %v want: %v", i, got, v.bounds)
+			}
+		}
+
+		for j, n := range v.in.Normals {
+			if math.Abs(float64(n.Len()-1)) > 1e-5 {
+				t.Errorf("Test %d: got: normal %v at %d want: unit length", i, n, j)
+				break
+			}
+		}
+
+		// Triangles wind counterclockwise seen from the side their normals
+		// point to.
+		for j := 0; j < v.in.TriangleCount(); j++ {
+			a, b, c := v.in.Triangle(j)
+			face := FaceNormal(v.in.Vertices[a], v.in.Vertices[b], v.in.Vertices[c])
+			n := v.in.Normals[a].Add(v.in.Normals[b]).Add(v.in.Normals[c])
+
+			if face.Dot(n) <= 0 {
+				t.Errorf("Test %d: got: triangle %d facing %v want: %v", i, j, face, n)
+				break
+			}
+		}
+
+		if !v.closed {
+			continue
+		}
+
+		// Every edge of a closed surface is used once in each direction.
+		edges := make(map[[2]mgl32.Vec3]int)
+		for j := 0; j < v.in.TriangleCount(); j++ {
+			a, b, c := v.in.Triangle(j)
+			p := [3]mgl32.Vec3{v.in.Vertices[a], v.in.Vertices[b], v.in.Vertices[c]}
+			for k := 0; k < 3; k++ {
+				edges[[2]mgl32.Vec3{p[k], p[(k+1)%3]}]++
+			}
+		}
+		for e, n := range edges {
+			if n != 1 || edges[[2]mgl32.Vec3{e[1], e[0]}] != 1 {
+				t.Errorf("Test %d: got: edge %v used %d times want: 1", i, e, n)
+				break
+			}
+		}
+	}
+}
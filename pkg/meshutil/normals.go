@@ -0,0 +1,109 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package meshutil
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// FaceNormal returns the normal of a triangle scaled by twice its area.
+func FaceNormal(a, b, c mgl32.Vec3) mgl32.Vec3 {
+	return b.Sub(a).Cross(c.Sub(a))
+}
+
+// cornerAngle returns the angle of the corner a of a triangle.
+func cornerAngle(a, b, c mgl32.Vec3) float32 {
+	u, v := b.Sub(a), c.Sub(a)
+	if u.Len() == 0 || v.Len() == 0 {
+		return 0
+	}
+
+	cos := float64(u.Normalize().Dot(v.Normalize()))
+
+	return float32(math.Acos(math.Max(-1, math.Min(1, cos))))
+}
+
+// FlatNormals returns indexed data with the normal of each triangle at its
+// corners. Vertices are only shared by triangles with the same normal.
+func (d *Data) FlatNormals() *Data {
+	return d.SmoothNormals(0)
+}
+
+// SmoothNormals returns indexed data with normals computed from the
+// triangles. The normal of a corner of a triangle is the average of the
+// normals of the triangles around its position whose normals differ from its
+// triangle's by no more than threshold radians, weighted by the angles of
+// their corners. A threshold of zero gives flat normals, and of pi smooth
+// normals everywhere. Vertices are split where their normals differ.
+func (d *Data) SmoothNormals(threshold float32) *Data {
+	out := d.Unweld()
+	out.Normals = make([]mgl32.Vec3, len(out.Vertices))
+
+	faces := make([]mgl32.Vec3, len(out.Vertices)/3)
+	for i := range faces {
+		n := FaceNormal(out.Vertices[i*3], out.Vertices[i*3+1], out.Vertices[i*3+2])
+		if n.Len() > 0 {
+			n = n.Normalize()
+		}
+		faces[i] = n
+	}
+
+	// Group the corners of the triangles by position.
+	corners := make(map[mgl32.Vec3][]int)
+	for i, v := range out.Vertices {
+		corners[v] = append(corners[v], i)
+	}
+
+	cos := float32(math.Cos(float64(threshold)))
+
+	for i := range out.Vertices {
+		f := i / 3
+
+		if threshold <= 0 {
+			out.Normals[i] = faces[f]
+			continue
+		}
+
+		var n mgl32.Vec3
+		for _, j := range corners[out.Vertices[i]] {
+			g := j / 3
+			if g != f && faces[f].Dot(faces[g]) < cos {
+				continue
+			}
+
+			a, b, c := out.Vertices[j], out.Vertices[g*3+(j+1)%3], out.Vertices[g*3+(j+2)%3]
+			n = n.Add(faces[g].Mul(cornerAngle(a, b, c)))
+		}
+
+		if n.Len() > 0 {
+			n = n.Normalize()
+		} else {
+			n = faces[f]
+		}
+		out.Normals[i] = n
+	}
+
+	return out.Weld()
+}
@@ -0,0 +1,468 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package meshutil
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// vertex is a vertex of a generated surface.
+type vertex struct {
+	p, n mgl32.Vec3
+	uv   mgl32.Vec2
+}
+
+// builder accumulates the vertices and triangles of a generated mesh.
+type builder struct {
+	d Data
+}
+
+func newBuilder() *builder {
+	return &builder{d: Data{
+		Normals:   []mgl32.Vec3{},
+		Uvs:       []mgl32.Vec2{},
+		Triangles: []uint32{},
+	}}
+}
+
+func (b *builder) vertex(v vertex) uint32 {
+	b.d.Vertices = append(b.d.Vertices, v.p)
+	b.d.Normals = append(b.d.Normals, v.n)
+	b.d.Uvs = append(b.d.Uvs, v.uv)
+
+	return uint32(len(b.d.Vertices) - 1)
+}
+
+// triangle adds a triangle, unless two of its corners are at the same
+// position, as they are at the poles of a sphere.
+func (b *builder) triangle(i, j, k uint32) {
+	v := b.d.Vertices
+	if v[i] == v[j] || v[j] == v[k] || v[i] == v[k] {
+		return
+	}
+
+	b.d.Triangles = append(b.d.Triangles, i, j, k)
+}
+
+// surface adds a grid of cols by rows quads, with the vertex at column i and
+// row j given by f. The triangles wind counterclockwise around the cross
+// product of the directions of increasing columns and rows.
+func (b *builder) surface(cols, rows int, f func(i, j int) vertex) {
+	base := uint32(len(b.d.Vertices))
+
+	for j := 0; j <= rows; j++ {
+		for i := 0; i <= cols; i++ {
+			b.vertex(f(i, j))
+		}
+	}
+
+	for j := 0; j < rows; j++ {
+		for i := 0; i < cols; i++ {
+			a := base + uint32(j*(cols+1)+i)
+			c := a + uint32(cols+1)
+
+			b.triangle(a, a+1, c+1)
+			b.triangle(a, c+1, c)
+		}
+	}
+}
+
+// disc adds a flat disc of the given radius at height y, facing up or down.
+func (b *builder) disc(radius, y float32, segments int, up bool) {
+	n := mgl32.Vec3{0, -1, 0}
+	if up {
+		n = mgl32.Vec3{0, 1, 0}
+	}
+
+	center := b.vertex(vertex{p: mgl32.Vec3{0, y, 0}, n: n, uv: mgl32.Vec2{0.5, 0.5}})
+
+	ring := make([]uint32, segments+1)
+	for i := range ring {
+		cos, sin := angle(i, segments)
+
+		// Texture coordinates are not mirrored when seen from the front.
+		v := 0.5 - 0.5*sin
+		if up {
+			v = 0.5 + 0.5*sin
+		}
+
+		ring[i] = b.vertex(vertex{
+			p:  mgl32.Vec3{radius * cos, y, -radius * sin},
+			n:  n,
+			uv: mgl32.Vec2{0.5 + 0.5*cos, v},
+		})
+	}
+
+	for i := 0; i < segments; i++ {
+		if up {
+			b.triangle(center, ring[i], ring[i+1])
+		} else {
+			b.triangle(center, ring[i+1], ring[i])
+		}
+	}
+}
+
+// data returns the generated mesh, welded.
+func (b *builder) data() *Data {
+	return b.d.Weld()
+}
+
+// angle returns the cosine and sine of the angle of step i of n around a
+// circle. The last step is exactly the first, so seams close.
+func angle(i, n int) (cos, sin float32) {
+	if i == n || i == 0 {
+		return 1, 0
+	}
+
+	s, c := math.Sincos(2 * math.Pi * float64(i) / float64(n))
+
+	return float32(c), float32(s)
+}
+
+// revolve returns the vertex of a surface of revolution around the y axis at
+// step i of segments, for a profile point at radius r and height y with the
+// normal (nr, ny) in the plane of the profile. Vertices at the first step
+// face +x, and the steps go counterclockwise seen from above.
+func revolve(i, segments int, r, y, nr, ny, v float32) vertex {
+	cos, sin := angle(i, segments)
+
+	return vertex{
+		p:  mgl32.Vec3{r * cos, y, -r * sin},
+		n:  mgl32.Vec3{nr * cos, ny, -nr * sin},
+		uv: mgl32.Vec2{float32(i) / float32(segments), v},
+	}
+}
+
+// pole returns the vertex of a surface of revolution at a pole, with the
+// texture coordinate of the middle of the triangle which uses it.
+func pole(i, segments int, y, ny float32, top bool) vertex {
+	u := (float32(i) + 0.5) / float32(segments)
+	v := float32(0)
+	if top {
+		u = (float32(i) - 0.5) / float32(segments)
+		v = 1
+	}
+
+	return vertex{p: mgl32.Vec3{0, y, 0}, n: mgl32.Vec3{0, ny, 0}, uv: mgl32.Vec2{u, v}}
+}
+
+// atLeast returns n, or min if n is smaller.
+func atLeast(n, min int) int {
+	if n < min {
+		return min
+	}
+
+	return n
+}
+
+// Box returns a box of the given size centered on the origin, with each face
+// divided into segments by segments quads. Each face has the whole texture.
+func Box(size mgl32.Vec3, segments int) *Data {
+	segments = atLeast(segments, 1)
+	half := size.Mul(0.5)
+
+	// The axes of the texture on each face, whose cross product is the
+	// normal of the face.
+	faces := [6][2]mgl32.Vec3{
+		{{0, 0, -1}, {0, 1, 0}},
+		{{0, 0, 1}, {0, 1, 0}},
+		{{1, 0, 0}, {0, 0, -1}},
+		{{1, 0, 0}, {0, 0, 1}},
+		{{1, 0, 0}, {0, 1, 0}},
+		{{-1, 0, 0}, {0, 1, 0}},
+	}
+
+	b := newBuilder()
+	for _, f := range faces {
+		u, v := f[0], f[1]
+		n := u.Cross(v)
+
+		b.surface(segments, segments, func(i, j int) vertex {
+			s := float32(i) / float32(segments)
+			t := float32(j) / float32(segments)
+
+			p := n.Add(u.Mul(2*s - 1)).Add(v.Mul(2*t - 1))
+
+			return vertex{
+				p:  mgl32.Vec3{p[0] * half[0], p[1] * half[1], p[2] * half[2]},
+				n:  n,
+				uv: mgl32.Vec2{s, t},
+			}
+		})
+	}
+
+	return b.data()
+}
+
+// Plane returns a plane of the given size in x and z, facing +y and centered
+// on the origin, divided into segmentsX by segmentsZ quads.
+func Plane(size mgl32.Vec2, segmentsX, segmentsZ int) *Data {
+	segmentsX = atLeast(segmentsX, 1)
+	segmentsZ = atLeast(segmentsZ, 1)
+
+	b := newBuilder()
+	b.surface(segmentsX, segmentsZ, func(i, j int) vertex {
+		s := float32(i) / float32(segmentsX)
+		t := float32(j) / float32(segmentsZ)
+
+		return vertex{
+			p:  mgl32.Vec3{(s - 0.5) * size[0], 0, (0.5 - t) * size[1]},
+			n:  mgl32.Vec3{0, 1, 0},
+			uv: mgl32.Vec2{s, t},
+		}
+	})
+
+	return b.data()
+}
+
+// UVSphere returns a sphere centered on the origin, divided into segments
+// around its axis and rings from pole to pole. The texture wraps around the
+// sphere once, with its seam facing +x.
+func UVSphere(radius float32, segments, rings int) *Data {
+	segments = atLeast(segments, 3)
+	rings = atLeast(rings, 2)
+
+	b := newBuilder()
+	b.surface(segments, rings, func(i, j int) vertex {
+		switch j {
+		case 0:
+			return pole(i, segments, -radius, -1, false)
+		case rings:
+			return pole(i, segments, radius, 1, true)
+		}
+
+		s, c := math.Sincos(math.Pi * float64(j) / float64(rings))
+		sin, cos := float32(s), float32(c)
+
+		return revolve(i, segments, radius*sin, -radius*cos, sin, -cos, float32(j)/float32(rings))
+	})
+
+	return b.data()
+}
+
+// Icosphere returns a sphere centered on the origin, made by dividing each
+// triangle of an icosahedron into four the given number of times, so its
+// triangles are nearly equal in size. The texture is mapped as on a UV
+// sphere.
+func Icosphere(radius float32, subdivisions int) *Data {
+	subdivisions = atLeast(subdivisions, 0)
+
+	const t = 1.618033988749895
+
+	points := []mgl32.Vec3{
+		{-1, t, 0}, {1, t, 0}, {-1, -t, 0}, {1, -t, 0},
+		{0, -1, t}, {0, 1, t}, {0, -1, -t}, {0, 1, -t},
+		{t, 0, -1}, {t, 0, 1}, {-t, 0, -1}, {-t, 0, 1},
+	}
+	for i := range points {
+		points[i] = points[i].Normalize()
+	}
+
+	faces := [][3]int{
+		{0, 11, 5}, {0, 5, 1}, {0, 1, 7}, {0, 7, 10}, {0, 10, 11},
+		{1, 5, 9}, {5, 11, 4}, {11, 10, 2}, {10, 7, 6}, {7, 1, 8},
+		{3, 9, 4}, {3, 4, 2}, {3, 2, 6}, {3, 6, 8}, {3, 8, 9},
+		{4, 9, 5}, {2, 4, 11}, {6, 2, 10}, {8, 6, 7}, {9, 8, 1},
+	}
+
+	for n := 0; n < subdivisions; n++ {
+		midpoints := make(map[[2]int]int)
+		midpoint := func(a, b int) int {
+			key := [2]int{a, b}
+			if a > b {
+				key = [2]int{b, a}
+			}
+
+			m, ok := midpoints[key]
+			if !ok {
+				m = len(points)
+				midpoints[key] = m
+				points = append(points, points[a].Add(points[b]).Normalize())
+			}
+
+			return m
+		}
+
+		next := make([][3]int, 0, len(faces)*4)
+		for _, f := range faces {
+			ab, bc, ca := midpoint(f[0], f[1]), midpoint(f[1], f[2]), midpoint(f[2], f[0])
+			next = append(next,
+				[3]int{f[0], ab, ca},
+				[3]int{f[1], bc, ab},
+				[3]int{f[2], ca, bc},
+				[3]int{ab, bc, ca},
+			)
+		}
+		faces = next
+	}
+
+	b := newBuilder()
+	for _, f := range faces {
+		var corners [3]vertex
+		for k, i := range f {
+			p := points[i]
+			u := float32(math.Atan2(float64(-p[2]), float64(p[0])) / (2 * math.Pi))
+			if u < 0 {
+				u++
+			}
+			v := float32(math.Acos(math.Max(-1, math.Min(1, float64(-p[1])))) / math.Pi)
+
+			corners[k] = vertex{p: p.Mul(radius), n: p, uv: mgl32.Vec2{u, v}}
+		}
+
+		// Triangles across the seam wrap past 1 rather than spanning the
+		// whole texture.
+		lo, hi := corners[0].uv[0], corners[0].uv[0]
+		for _, c := range corners[1:] {
+			lo = float32(math.Min(float64(lo), float64(c.uv[0])))
+			hi = float32(math.Max(float64(hi), float64(c.uv[0])))
+		}
+		if hi-lo > 0.5 {
+			for k := range corners {
+				if corners[k].uv[0] < 0.5 {
+					corners[k].uv[0]++
+				}
+			}
+		}
+
+		// The pole has no longitude, so use the middle of the other corners.
+		for k := range corners {
+			if n := corners[k].n; n[0] == 0 && n[2] == 0 {
+				other := corners[(k+1)%3].uv[0] + corners[(k+2)%3].uv[0]
+				corners[k].uv[0] = other / 2
+			}
+		}
+
+		b.triangle(b.vertex(corners[0]), b.vertex(corners[1]), b.vertex(corners[2]))
+	}
+
+	return b.data()
+}
+
+// Cylinder returns a closed cylinder of the given radius and height around
+// the y axis, centered on the origin and divided into segments around its
+// axis.
+func Cylinder(radius, height float32, segments int) *Data {
+	segments = atLeast(segments, 3)
+	half := height / 2
+
+	b := newBuilder()
+	b.surface(segments, 1, func(i, j int) vertex {
+		return revolve(i, segments, radius, float32(2*j-1)*half, 1, 0, float32(j))
+	})
+	b.disc(radius, half, segments, true)
+	b.disc(radius, -half, segments, false)
+
+	return b.data()
+}
+
+// Cone returns a closed cone of the given radius and height around the y
+// axis, centered on the origin with its apex at the top, divided into
+// segments around its axis.
+func Cone(radius, height float32, segments int) *Data {
+	segments = atLeast(segments, 3)
+	half := height / 2
+
+	// The normal of the side leans up by the slope of the cone.
+	slope := mgl32.Vec2{height, radius}.Normalize()
+
+	b := newBuilder()
+	b.surface(segments, 1, func(i, j int) vertex {
+		if j == 0 {
+			return revolve(i, segments, radius, -half, slope[0], slope[1], 0)
+		}
+
+		// Each vertex at the apex has the normal of the middle of its
+		// triangle.
+		v := pole(i, segments, half, 1, true)
+		cos, sin := float32(math.Cos(2*math.Pi*float64(v.uv[0]))), float32(math.Sin(2*math.Pi*float64(v.uv[0])))
+		v.n = mgl32.Vec3{slope[0] * cos, slope[1], -slope[0] * sin}
+
+		return v
+	})
+	b.disc(radius, -half, segments, false)
+
+	return b.data()
+}
+
+// Capsule returns a capsule around the y axis, centered on the origin: a
+// cylinder of the given radius with hemispherical ends, whose total height is
+// at least twice the radius. It is divided into segments around its axis and
+// rings from the side to each pole. The texture is stretched over the whole
+// capsule in proportion to the length of its profile.
+func Capsule(radius, height float32, segments, rings int) *Data {
+	segments = atLeast(segments, 3)
+	rings = atLeast(rings, 1)
+
+	half := float32(math.Max(0, float64(height/2-radius)))
+	length := math.Pi*radius + 2*half
+
+	// The profile goes from the bottom pole around the bottom hemisphere,
+	// up the side and around the top hemisphere.
+	b := newBuilder()
+	b.surface(segments, 2*rings+1, func(i, j int) vertex {
+		switch j {
+		case 0:
+			return pole(i, segments, -half-radius, -1, false)
+		case 2*rings + 1:
+			return pole(i, segments, half+radius, 1, true)
+		}
+
+		k, y := j, -half
+		if j > rings {
+			k, y = j-1, half
+		}
+
+		theta := math.Pi / 2 * float64(k) / float64(rings)
+		s, c := math.Sincos(theta)
+		sin, cos := float32(s), float32(c)
+
+		arc := radius * float32(theta)
+		if j > rings {
+			arc += 2 * half
+		}
+
+		return revolve(i, segments, radius*sin, y-radius*cos, sin, -cos, arc/length)
+	})
+
+	return b.data()
+}
+
+// Torus returns a torus around the y axis, centered on the origin. Its tube
+// of radius tube is swept around a circle of radius radius, divided into
+// segments around the axis and sides around the tube.
+func Torus(radius, tube float32, segments, sides int) *Data {
+	segments = atLeast(segments, 3)
+	sides = atLeast(sides, 3)
+
+	b := newBuilder()
+	b.surface(segments, sides, func(i, j int) vertex {
+		cos, sin := angle(j, sides)
+
+		return revolve(i, segments, radius+tube*cos, tube*sin, cos, sin, float32(j)/float32(sides))
+	})
+
+	return b.data()
+}
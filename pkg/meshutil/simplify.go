@@ -0,0 +1,383 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package meshutil
+
+import (
+	"container/heap"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// boundaryWeight scales the planes which keep boundary edges in place,
+// relative to the planes of the triangles.
+const boundaryWeight = 10
+
+// quadric is the sum of the squared distances to a set of planes, as the
+// upper triangle of a symmetric 4x4 matrix.
+type quadric [10]float64
+
+// planeQuadric returns the quadric of the plane n·p + d = 0, scaled by w.
+func planeQuadric(n mgl32.Vec3, d float64, w float64) quadric {
+	a, b, c := float64(n[0]), float64(n[1]), float64(n[2])
+
+	return quadric{
+		a * a * w, a * b * w, a * c * w, a * d * w,
+		b * b * w, b * c * w, b * d * w,
+		c * c * w, c * d * w,
+		d * d * w,
+	}
+}
+
+func (q *quadric) add(r quadric) {
+	for i := range q {
+		q[i] += r[i]
+	}
+}
+
+// error returns the weighted sum of the squared distances of p to the planes.
+func (q *quadric) error(p mgl32.Vec3) float64 {
+	x, y, z := float64(p[0]), float64(p[1]), float64(p[2])
+
+	return q[0]*x*x + 2*q[1]*x*y + 2*q[2]*x*z + 2*q[3]*x +
+		q[4]*y*y + 2*q[5]*y*z + 2*q[6]*y +
+		q[7]*z*z + 2*q[8]*z +
+		q[9]
+}
+
+// collapse is a candidate collapse of the position from into the position to.
+// The stamps are the stamps of the positions when the cost was computed.
+type collapse struct {
+	cost     float64
+	from, to int
+	stamps   [2]int
+}
+
+type collapseHeap []collapse
+
+func (h collapseHeap) Len() int            { return len(h) }
+func (h collapseHeap) Less(i, j int) bool  { return h[i].cost < h[j].cost }
+func (h collapseHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *collapseHeap) Push(x interface{}) { *h = append(*h, x.(collapse)) }
+func (h *collapseHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return c
+}
+
+// simplifier holds the state of a simplification. Vertices are grouped by
+// position, and edges are collapsed between positions, so the vertices of a
+// position with different normals or texture coordinates move together.
+type simplifier struct {
+	data      *Data
+	tris      [][3]uint32
+	alive     []bool
+	live      int
+	pos       []int
+	positions []mgl32.Vec3
+	wedges    [][]uint32
+	adjacent  [][]int
+	quadrics  []quadric
+	removed   []bool
+	stamps    []int
+	queue     collapseHeap
+}
+
+// Simplify returns indexed data with at most target triangles, made by
+// collapsing the edges whose removal changes the surface least, measured by
+// quadric error. Collapses move a position onto one of its neighbours, so
+// the remaining vertices keep their channels. Boundary edges are kept in
+// place where possible, and collapses which would flip a triangle are
+// skipped, so the result may have more than target triangles.
+func (d *Data) Simplify(target int) *Data {
+	s := newSimplifier(d.Weld())
+
+	for s.live > target && s.queue.Len() > 0 {
+		c := heap.Pop(&s.queue).(collapse)
+
+		if s.removed[c.from] || s.removed[c.to] {
+			continue
+		}
+		if s.stamps[c.from] != c.stamps[0] || s.stamps[c.to] != c.stamps[1] {
+			continue
+		}
+		if s.flips(c.from, c.to) {
+			continue
+		}
+
+		s.collapse(c.from, c.to)
+	}
+
+	return s.result()
+}
+
+func newSimplifier(d *Data) *simplifier {
+	s := &simplifier{
+		data: d,
+		pos:  make([]int, len(d.Vertices)),
+	}
+
+	index := make(map[mgl32.Vec3]int)
+	for i, v := range d.Vertices {
+		p, ok := index[v]
+		if !ok {
+			p = len(s.positions)
+			index[v] = p
+			s.positions = append(s.positions, v)
+			s.wedges = append(s.wedges, nil)
+		}
+
+		s.pos[i] = p
+		s.wedges[p] = append(s.wedges[p], uint32(i))
+	}
+
+	n := len(s.positions)
+	s.adjacent = make([][]int, n)
+	s.quadrics = make([]quadric, n)
+	s.removed = make([]bool, n)
+	s.stamps = make([]int, n)
+
+	edges := make(map[[2]int]int)
+	normals := make([]mgl32.Vec3, 0, d.TriangleCount())
+
+	for t := 0; t < d.TriangleCount(); t++ {
+		a, b, c := d.Triangle(t)
+		tri := [3]uint32{a, b, c}
+
+		pa, pb, pc := s.pos[a], s.pos[b], s.pos[c]
+		if pa == pb || pb == pc || pa == pc {
+			continue
+		}
+
+		i := len(s.tris)
+		s.tris = append(s.tris, tri)
+		s.alive = append(s.alive, true)
+		s.live++
+
+		normal := FaceNormal(s.positions[pa], s.positions[pb], s.positions[pc])
+		area := float64(normal.Len()) / 2
+		if area > 0 {
+			normal = normal.Normalize()
+		}
+		normals = append(normals, normal)
+
+		q := planeQuadric(normal, -float64(normal.Dot(s.positions[pa])), area)
+
+		for k, p := range [3]int{pa, pb, pc} {
+			s.quadrics[p].add(q)
+			s.adjacent[p] = append(s.adjacent[p], i)
+
+			edges[edgeKey(p, s.pos[tri[(k+1)%3]])]++
+		}
+	}
+
+	// Add planes perpendicular to the triangles along boundary edges, which
+	// are used by only one triangle.
+	for i, tri := range s.tris {
+		for k := 0; k < 3; k++ {
+			p, q := s.pos[tri[k]], s.pos[tri[(k+1)%3]]
+			if edges[edgeKey(p, q)] != 1 {
+				continue
+			}
+
+			e := s.positions[q].Sub(s.positions[p])
+			n := e.Cross(normals[i])
+			if n.Len() == 0 {
+				continue
+			}
+			n = n.Normalize()
+
+			b := planeQuadric(n, -float64(n.Dot(s.positions[p])), boundaryWeight*float64(e.Dot(e)))
+			s.quadrics[p].add(b)
+			s.quadrics[q].add(b)
+		}
+	}
+
+	for e := range edges {
+		s.push(e[0], e[1])
+		s.push(e[1], e[0])
+	}
+
+	return s
+}
+
+func edgeKey(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+
+	return [2]int{a, b}
+}
+
+// push adds the collapse of from into to to the queue.
+func (s *simplifier) push(from, to int) {
+	q := s.quadrics[from]
+	q.add(s.quadrics[to])
+
+	heap.Push(&s.queue, collapse{
+		cost:   q.error(s.positions[to]),
+		from:   from,
+		to:     to,
+		stamps: [2]int{s.stamps[from], s.stamps[to]},
+	})
+}
+
+// flips reports whether collapsing from into to would turn a triangle which
+// remains over, or make it degenerate.
+func (s *simplifier) flips(from, to int) bool {
+	for _, t := range s.adjacent[from] {
+		if !s.alive[t] {
+			continue
+		}
+
+		var p [3]mgl32.Vec3
+		removed := false
+
+		for k, v := range s.tris[t] {
+			switch s.pos[v] {
+			case to:
+				removed = true
+			case from:
+				p[k] = s.positions[to]
+				continue
+			}
+			p[k] = s.positions[s.pos[v]]
+		}
+
+		if removed {
+			continue
+		}
+
+		a, b, c := s.tris[t][0], s.tris[t][1], s.tris[t][2]
+		before := FaceNormal(s.positions[s.pos[a]], s.positions[s.pos[b]], s.positions[s.pos[c]])
+		after := FaceNormal(p[0], p[1], p[2])
+
+		if after.Len() <= before.Len()*1e-6 || before.Dot(after) <= 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collapse moves the position from onto to. Each vertex of from is replaced
+// by the vertex of to across a removed triangle, so that texture coordinates
+// and normals stay continuous.
+func (s *simplifier) collapse(from, to int) {
+	remap := make(map[uint32]uint32)
+
+	for _, t := range s.adjacent[from] {
+		if !s.alive[t] {
+			continue
+		}
+
+		var a, b uint32
+		var hasFrom, hasTo bool
+		for _, v := range s.tris[t] {
+			switch s.pos[v] {
+			case from:
+				a, hasFrom = v, true
+			case to:
+				b, hasTo = v, true
+			}
+		}
+
+		if hasFrom && hasTo {
+			if _, ok := remap[a]; !ok {
+				remap[a] = b
+			}
+			s.alive[t] = false
+			s.live--
+		}
+	}
+
+	for _, t := range s.adjacent[from] {
+		if !s.alive[t] {
+			continue
+		}
+
+		for k, v := range s.tris[t] {
+			if s.pos[v] != from {
+				continue
+			}
+
+			w, ok := remap[v]
+			if !ok {
+				w = s.wedges[to][0]
+			}
+			s.tris[t][k] = w
+		}
+
+		s.adjacent[to] = append(s.adjacent[to], t)
+	}
+
+	s.quadrics[to].add(s.quadrics[from])
+	s.removed[from] = true
+	s.adjacent[from] = nil
+	s.stamps[to]++
+
+	neighbours := make(map[int]bool)
+	for _, t := range s.adjacent[to] {
+		if !s.alive[t] {
+			continue
+		}
+		for _, v := range s.tris[t] {
+			if p := s.pos[v]; p != to {
+				neighbours[p] = true
+			}
+		}
+	}
+
+	for n := range neighbours {
+		s.push(to, n)
+		s.push(n, to)
+	}
+}
+
+// result returns the remaining triangles, without unused vertices.
+func (s *simplifier) result() *Data {
+	out := s.data.subset()
+	out.Triangles = make([]uint32, 0, s.live*3)
+
+	index := make(map[uint32]uint32)
+
+	for t, tri := range s.tris {
+		if !s.alive[t] {
+			continue
+		}
+
+		for _, v := range tri {
+			j, ok := index[v]
+			if !ok {
+				j = uint32(len(out.Vertices))
+				index[v] = j
+				out.append(s.data, v)
+			}
+
+			out.Triangles = append(out.Triangles, j)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,165 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package meshutil
+
+import (
+	"errors"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// ErrTangentChannels is returned when tangents are generated for data without
+// normals or texture coordinates.
+var ErrTangentChannels = errors.New("meshutil: tangents require normals and uvs")
+
+// tangentKey identifies the tangent space of a vertex on triangles of one
+// texture orientation.
+type tangentKey struct {
+	vertex uint32
+	flip   bool
+}
+
+type tangentSum struct {
+	t, b mgl32.Vec3
+}
+
+// GenerateTangents returns indexed data with tangents, following the
+// conventions of MikkTSpace: the tangent of each triangle is projected onto
+// the tangent plane of each of its vertices, normalized and weighted by the
+// angle of the corner, the result is orthogonalized against the normal, and
+// the bitangent is w × cross(normal, tangent). Vertices which are shared by
+// triangles with mirrored texture coordinates are split so each side has its
+// own tangent. Triangles with degenerate texture coordinates contribute no
+// tangent, and vertices without any tangent get an arbitrary one perpendicular
+// to the normal.
+func (d *Data) GenerateTangents() (*Data, error) {
+	if len(d.Normals) == 0 || len(d.Uvs) == 0 {
+		return nil, ErrTangentChannels
+	}
+
+	w := d.Weld()
+
+	sums := make(map[tangentKey]*tangentSum)
+	keys := make([]tangentKey, len(w.Triangles))
+
+	for t := 0; t < len(w.Triangles); t += 3 {
+		idx := w.Triangles[t : t+3]
+		p0, p1, p2 := w.Vertices[idx[0]], w.Vertices[idx[1]], w.Vertices[idx[2]]
+		uv0, uv1, uv2 := w.Uvs[idx[0]], w.Uvs[idx[1]], w.Uvs[idx[2]]
+
+		e1, e2 := p1.Sub(p0), p2.Sub(p0)
+		du1, dv1 := uv1[0]-uv0[0], uv1[1]-uv0[1]
+		du2, dv2 := uv2[0]-uv0[0], uv2[1]-uv0[1]
+
+		r := du1*dv2 - du2*dv1
+		flip := r < 0
+
+		var tan, bit mgl32.Vec3
+		if float32(math.Abs(float64(r))) > 1e-12 {
+			tan = e1.Mul(dv2).Sub(e2.Mul(dv1)).Mul(1 / r)
+			bit = e2.Mul(du1).Sub(e1.Mul(du2)).Mul(1 / r)
+		}
+
+		for k := 0; k < 3; k++ {
+			v := idx[k]
+			key := tangentKey{vertex: v, flip: flip}
+			keys[t+k] = key
+
+			s, ok := sums[key]
+			if !ok {
+				s = &tangentSum{}
+				sums[key] = s
+			}
+
+			n := w.Normals[v]
+			angle := cornerAngle(w.Vertices[v], w.Vertices[idx[(k+1)%3]], w.Vertices[idx[(k+2)%3]])
+
+			// The tangents are normalized before they are weighted, as their
+			// length scales with the inverse of the area of the texture
+			// coordinates and would let small triangles dominate.
+			s.t = s.t.Add(unit(orthogonalize(tan, n)).Mul(angle))
+			s.b = s.b.Add(unit(bit).Mul(angle))
+		}
+	}
+
+	// Existing tangents are replaced, so they are not copied.
+	out := w.subset()
+	out.Tangents = nil
+	out.Triangles = make([]uint32, len(w.Triangles))
+
+	index := make(map[tangentKey]uint32)
+	tangents := []mgl32.Vec4{}
+
+	for i, key := range keys {
+		j, ok := index[key]
+		if !ok {
+			j = uint32(len(out.Vertices))
+			index[key] = j
+
+			out.append(w, key.vertex)
+			tangents = append(tangents, tangent(w.Normals[key.vertex], sums[key]))
+		}
+
+		out.Triangles[i] = j
+	}
+	out.Tangents = tangents
+
+	return out, nil
+}
+
+// orthogonalize returns the component of v perpendicular to the unit vector
+// n.
+func orthogonalize(v, n mgl32.Vec3) mgl32.Vec3 {
+	return v.Sub(n.Mul(n.Dot(v)))
+}
+
+// unit returns v normalized, or the zero vector if v has no length.
+func unit(v mgl32.Vec3) mgl32.Vec3 {
+	if v.Len() < 1e-12 {
+		return mgl32.Vec3{}
+	}
+
+	return v.Normalize()
+}
+
+// tangent returns the unit tangent and handedness of a vertex with normal n.
+func tangent(n mgl32.Vec3, s *tangentSum) mgl32.Vec4 {
+	t := orthogonalize(s.t, n)
+
+	if t.Len() < 1e-12 {
+		// Use any direction in the tangent plane.
+		t = orthogonalize(mgl32.Vec3{1, 0, 0}, n)
+		if t.Len() < 1e-6 {
+			t = orthogonalize(mgl32.Vec3{0, 1, 0}, n)
+		}
+	}
+	t = t.Normalize()
+
+	w := float32(1)
+	if n.Cross(t).Dot(s.b) < 0 {
+		w = -1
+	}
+
+	return t.Vec4(w)
+}
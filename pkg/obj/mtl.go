@@ -0,0 +1,218 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package obj
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Map is a texture map of a material.
+type Map struct {
+	// File is the file of the texture, relative to the material library.
+	File string
+
+	// Line is the line of the material library which gave the map.
+	Line int
+}
+
+// Material is a material of an MTL material library.
+type Material struct {
+	Name string
+
+	Ambient  mgl32.Vec3
+	Diffuse  mgl32.Vec3
+	Specular mgl32.Vec3
+	Emissive mgl32.Vec3
+
+	// Shininess is the specular exponent (Ns).
+	Shininess float32
+
+	// Opacity is the dissolve factor (d), where 1 is opaque.
+	Opacity float32
+
+	// Illum is the illumination model.
+	Illum int
+
+	// Roughness and Metallic are the PBR extension parameters (Pr and Pm).
+	// Roughness is derived from Shininess if it is not given.
+	Roughness float32
+	Metallic  float32
+
+	// Line is the line of the material library which declared the material.
+	Line int
+
+	DiffuseMap   *Map
+	SpecularMap  *Map
+	EmissiveMap  *Map
+	NormalMap    *Map
+	OpacityMap   *Map
+	RoughnessMap *Map
+	MetallicMap  *Map
+}
+
+// newMaterial returns a material with the default values of the MTL format.
+func newMaterial(name string, line int) *Material {
+	return &Material{
+		Name:      name,
+		Ambient:   mgl32.Vec3{0.2, 0.2, 0.2},
+		Diffuse:   mgl32.Vec3{0.8, 0.8, 0.8},
+		Specular:  mgl32.Vec3{1, 1, 1},
+		Opacity:   1,
+		Roughness: -1,
+		Line:      line,
+	}
+}
+
+// DecodeMaterials reads the materials of an MTL material library.
+// Unsupported statements are ignored. Map options are skipped, and the last
+// argument of a map statement is used as its file.
+func DecodeMaterials(r io.Reader) ([]*Material, error) {
+	var materials []*Material
+	var m *Material
+
+	seen := make(map[string]bool)
+
+	err := scanLines(r, func(line int, keyword string, args []string) error {
+		if keyword == "newmtl" {
+			if len(args) == 0 {
+				return errors.New("newmtl without a name")
+			}
+
+			// Names are joined as they are by usemtl, so that names with
+			// spaces match.
+			name := strings.Join(args, " ")
+			if seen[name] {
+				return fmt.Errorf("duplicate material: %s", name)
+			}
+			seen[name] = true
+
+			m = newMaterial(name, line)
+			materials = append(materials, m)
+
+			return nil
+		}
+
+		if m == nil {
+			return fmt.Errorf("%s before newmtl", keyword)
+		}
+
+		var err error
+
+		switch keyword {
+		case "Ka":
+			m.Ambient, err = parseColor(args)
+		case "Kd":
+			m.Diffuse, err = parseColor(args)
+		case "Ks":
+			m.Specular, err = parseColor(args)
+		case "Ke":
+			m.Emissive, err = parseColor(args)
+		case "Ns":
+			m.Shininess, err = parseFloat(args)
+		case "d":
+			m.Opacity, err = parseFloat(args)
+		case "Tr":
+			var tr float32
+			tr, err = parseFloat(args)
+			m.Opacity = 1 - tr
+		case "Pr":
+			m.Roughness, err = parseFloat(args)
+		case "Pm":
+			m.Metallic, err = parseFloat(args)
+		case "illum":
+			if len(args) != 1 {
+				return errors.New("illum takes one value")
+			}
+			if m.Illum, err = strconv.Atoi(args[0]); err != nil {
+				return fmt.Errorf("invalid illumination model: %s", args[0])
+			}
+		case "map_Kd":
+			m.DiffuseMap, err = parseMap(args, line)
+		case "map_Ks":
+			m.SpecularMap, err = parseMap(args, line)
+		case "map_Ke":
+			m.EmissiveMap, err = parseMap(args, line)
+		case "map_Bump", "map_bump", "bump", "norm":
+			m.NormalMap, err = parseMap(args, line)
+		case "map_d":
+			m.OpacityMap, err = parseMap(args, line)
+		case "map_Pr":
+			m.RoughnessMap, err = parseMap(args, line)
+		case "map_Pm":
+			m.MetallicMap, err = parseMap(args, line)
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range materials {
+		if m.Roughness < 0 {
+			m.Roughness = float32(math.Sqrt(2 / (float64(m.Shininess) + 2)))
+		}
+	}
+
+	return materials, nil
+}
+
+// parseColor parses an RGB color. A single value is used for all channels.
+func parseColor(args []string) (mgl32.Vec3, error) {
+	v, err := parseFloats(args, 1, 3)
+	if err != nil {
+		return mgl32.Vec3{}, err
+	}
+	if len(v) == 1 {
+		return mgl32.Vec3{v[0], v[0], v[0]}, nil
+	}
+	if len(v) != 3 {
+		return mgl32.Vec3{}, fmt.Errorf("got %d values, want 1 or 3", len(v))
+	}
+
+	return mgl32.Vec3{v[0], v[1], v[2]}, nil
+}
+
+func parseFloat(args []string) (float32, error) {
+	v, err := parseFloats(args, 1, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	return v[0], nil
+}
+
+func parseMap(args []string, line int) (*Map, error) {
+	if len(args) == 0 {
+		return nil, errors.New("map without a file")
+	}
+
+	return &Map{File: args[len(args)-1], Line: line}, nil
+}
@@ -0,0 +1,426 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package obj decodes Wavefront OBJ models and MTL material libraries.
+package obj
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Error is an error in a line of an OBJ or MTL file.
+type Error struct {
+	Line int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("obj: line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the error in the line.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Model is a decoded OBJ model.
+type Model struct {
+	// Meshes are the parts of the model, one for each object or group and
+	// material, in the order they are first used.
+	Meshes []*Mesh
+
+	// MaterialLibs are the files of the material libraries used by the model,
+	// relative to the model.
+	MaterialLibs []string
+}
+
+// Mesh is a part of a model. Its vertices are not indexed, so each three
+// vertices form a triangle.
+type Mesh struct {
+	// Name is the name of the object or group, joined by a slash if both are
+	// given, or empty if neither is.
+	Name string
+
+	// Material is the material used by the mesh, or empty if none is.
+	Material string
+
+	Vertices []mgl32.Vec3
+	Normals  []mgl32.Vec3
+	Uvs      []mgl32.Vec2
+}
+
+// corner is a corner of a face, given by indices of positions, texture
+// coordinates and normals. Missing indices are -1.
+type corner struct {
+	v, t, n int
+}
+
+// face is a polygon of a mesh.
+type face struct {
+	corners []corner
+	smooth  int
+	line    int
+}
+
+// meshBuilder collects the faces of a mesh.
+type meshBuilder struct {
+	name     string
+	material string
+	faces    []face
+}
+
+// decoder holds the state of an OBJ file as it is read.
+type decoder struct {
+	model    *Model
+	v        []mgl32.Vec3
+	vt       []mgl32.Vec2
+	vn       []mgl32.Vec3
+	builders []*meshBuilder
+	current  *meshBuilder
+	object   string
+	group    string
+	material string
+	smooth   int
+	line     int
+}
+
+// Decode reads an OBJ model. Polygons are triangulated, negative indices are
+// resolved relative to the last element, and normals which are not given are
+// computed from the smoothing groups of the faces. Faces without area are
+// skipped, and unsupported statements are ignored.
+func Decode(r io.Reader) (*Model, error) {
+	d := &decoder{model: &Model{}}
+
+	err := scanLines(r, func(line int, keyword string, args []string) error {
+		d.line = line
+		return d.statement(keyword, args)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range d.builders {
+		if len(b.faces) == 0 {
+			continue
+		}
+
+		m, err := d.build(b)
+		if err != nil {
+			return nil, err
+		}
+		if len(m.Vertices) == 0 {
+			continue
+		}
+		d.model.Meshes = append(d.model.Meshes, m)
+	}
+
+	return d.model, nil
+}
+
+func (d *decoder) statement(keyword string, args []string) error {
+	switch keyword {
+	case "v":
+		v, err := parseFloats(args, 3, 7)
+		if err != nil {
+			return err
+		}
+		d.v = append(d.v, mgl32.Vec3{v[0], v[1], v[2]})
+	case "vt":
+		v, err := parseFloats(args, 1, 3)
+		if err != nil {
+			return err
+		}
+		v = append(v, 0)
+		d.vt = append(d.vt, mgl32.Vec2{v[0], v[1]})
+	case "vn":
+		v, err := parseFloats(args, 3, 3)
+		if err != nil {
+			return err
+		}
+		d.vn = append(d.vn, mgl32.Vec3{v[0], v[1], v[2]})
+	case "f":
+		return d.face(args)
+	case "o":
+		d.object = strings.Join(args, " ")
+		d.group = ""
+		d.current = nil
+	case "g":
+		d.group = strings.Join(args, " ")
+		d.current = nil
+	case "usemtl":
+		if len(args) == 0 {
+			return errors.New("usemtl without a material")
+		}
+		d.material = strings.Join(args, " ")
+		d.current = nil
+	case "mtllib":
+		d.model.MaterialLibs = append(d.model.MaterialLibs, args...)
+	case "s":
+		if len(args) != 1 {
+			return errors.New("smoothing group takes one argument")
+		}
+		if args[0] == "off" {
+			d.smooth = 0
+			return nil
+		}
+		s, err := strconv.Atoi(args[0])
+		if err != nil || s < 0 {
+			return fmt.Errorf("invalid smoothing group: %s", args[0])
+		}
+		d.smooth = s
+	}
+
+	return nil
+}
+
+// face adds a face to the current mesh.
+func (d *decoder) face(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("face has %d vertices, want at least 3", len(args))
+	}
+
+	f := face{corners: make([]corner, len(args)), smooth: d.smooth, line: d.line}
+
+	for i, arg := range args {
+		parts := strings.Split(arg, "/")
+		if len(parts) > 3 {
+			return fmt.Errorf("invalid face vertex: %s", arg)
+		}
+
+		c := corner{v: -1, t: -1, n: -1}
+
+		var err error
+		if c.v, err = resolveIndex(parts[0], len(d.v)); err != nil {
+			return err
+		}
+		if len(parts) > 1 && parts[1] != "" {
+			if c.t, err = resolveIndex(parts[1], len(d.vt)); err != nil {
+				return err
+			}
+		}
+		if len(parts) > 2 && parts[2] != "" {
+			if c.n, err = resolveIndex(parts[2], len(d.vn)); err != nil {
+				return err
+			}
+		}
+
+		f.corners[i] = c
+	}
+
+	d.builder().faces = append(d.builder().faces, f)
+
+	return nil
+}
+
+// builder returns the builder of the current object or group and material.
+// Faces of an object or group which is used again are added to its mesh.
+func (d *decoder) builder() *meshBuilder {
+	if d.current != nil {
+		return d.current
+	}
+
+	name := d.object
+	if d.group != "" {
+		if name != "" {
+			name += "/"
+		}
+		name += d.group
+	}
+
+	for _, b := range d.builders {
+		if b.name == name && b.material == d.material {
+			d.current = b
+			return b
+		}
+	}
+
+	d.current = &meshBuilder{name: name, material: d.material}
+	d.builders = append(d.builders, d.current)
+
+	return d.current
+}
+
+// build triangulates the faces of a mesh, and computes the normals which are
+// not given. Faces in a smoothing group share normals at their positions, and
+// other faces are flat.
+func (d *decoder) build(b *meshBuilder) (*Mesh, error) {
+	type smoothKey struct {
+		v, smooth int
+	}
+	smoothed := make(map[smoothKey]mgl32.Vec3)
+
+	normals := make([]mgl32.Vec3, len(b.faces))
+	for i, f := range b.faces {
+		points := make([]mgl32.Vec3, len(f.corners))
+		for j, c := range f.corners {
+			points[j] = d.v[c.v]
+		}
+
+		normals[i] = newellNormal(points)
+		if f.smooth != 0 {
+			for _, c := range f.corners {
+				k := smoothKey{c.v, f.smooth}
+				smoothed[k] = smoothed[k].Add(normals[i])
+			}
+		}
+	}
+
+	m := &Mesh{Name: b.name, Material: b.material}
+
+	for i, f := range b.faces {
+		points := make([]mgl32.Vec3, len(f.corners))
+		for j, c := range f.corners {
+			points[j] = d.v[c.v]
+		}
+
+		// Exported models often have slivers without area, which are
+		// skipped whatever their number of corners.
+		tris := triangulate(points)
+		if len(tris) == 0 {
+			continue
+		}
+
+		for _, tri := range tris {
+			for _, j := range tri {
+				c := f.corners[j]
+
+				var n mgl32.Vec3
+				switch {
+				case c.n >= 0:
+					n = d.vn[c.n]
+				case f.smooth != 0:
+					n = smoothed[smoothKey{c.v, f.smooth}]
+				default:
+					n = normals[i]
+				}
+				if n.Len() > 0 {
+					n = n.Normalize()
+				}
+
+				var t mgl32.Vec2
+				if c.t >= 0 {
+					t = d.vt[c.t]
+				}
+
+				m.Vertices = append(m.Vertices, d.v[c.v])
+				m.Normals = append(m.Normals, n)
+				m.Uvs = append(m.Uvs, t)
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// resolveIndex converts an index of an OBJ file to an index of a slice of
+// length n. Positive indices start at 1, and negative indices count back from
+// the last element.
+func resolveIndex(s string, n int) (int, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid index: %s", s)
+	}
+
+	switch {
+	case i > 0 && i <= n:
+		return i - 1, nil
+	case i < 0 && -i <= n:
+		return n + i, nil
+	}
+
+	return 0, fmt.Errorf("index %d out of range of %d elements", i, n)
+}
+
+// parseFloats parses between min and max float arguments.
+func parseFloats(args []string, min, max int) ([]float32, error) {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return nil, fmt.Errorf("got %d values, want %d", len(args), min)
+		}
+		return nil, fmt.Errorf("got %d values, want %d to %d", len(args), min, max)
+	}
+
+	v := make([]float32, len(args))
+	for i, arg := range args {
+		f, err := strconv.ParseFloat(arg, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number: %s", arg)
+		}
+		v[i] = float32(f)
+	}
+
+	return v, nil
+}
+
+// scanLines calls fn with the keyword and arguments of each statement of an
+// OBJ or MTL file. Comments and blank lines are skipped, and lines ending
+// with a backslash are joined with the next line. Errors returned by fn are
+// given the line number of the statement.
+func scanLines(r io.Reader, fn func(line int, keyword string, args []string) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	line, start := 0, 0
+	var text string
+
+	for s.Scan() {
+		line++
+
+		if text == "" {
+			start = line
+		}
+		text += s.Text()
+
+		if strings.HasSuffix(text, "\\") {
+			text = strings.TrimSuffix(text, "\\") + " "
+			continue
+		}
+
+		statement := text
+		text = ""
+
+		if i := strings.IndexByte(statement, '#'); i >= 0 {
+			statement = statement[:i]
+		}
+
+		fields := strings.Fields(statement)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if err := fn(start, fields[0], fields[1:]); err != nil {
+			if _, ok := err.(*Error); ok {
+				return err
+			}
+			return &Error{Line: start, Err: err}
+		}
+	}
+
+	return s.Err()
+}
@@ -0,0 +1,287 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package obj
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		in        string
+		meshes    []string
+		materials []string
+		vertices  []int
+	}{
+		{
+			in:       "v 0 0 0\nv 1 0 0\nv 1 1 0\nf 1 2 3\n",
+			meshes:   []string{""},
+			vertices: []int{3},
+		},
+		{
+			in:       "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nf -4 -3 -2 -1\n",
+			meshes:   []string{""},
+			vertices: []int{6},
+		},
+		{
+			in:        "mtllib a.mtl b.mtl\nv 0 0 0\nv 1 0 0\nv 1 1 0\no box\nf 1 2 3\ng top\nusemtl red\nf 1 2 3\nusemtl blue\nf 1 2 3\nusemtl red\nf 3 2 1\n",
+			meshes:    []string{"box", "box/top", "box/top"},
+			materials: []string{"", "red", "blue"},
+			vertices:  []int{3, 6, 3},
+		},
+		{
+			in:       "v 0 0 0 \\\n\nv 1 0 0 # comment\nv 1 1 0\nvt 0 0\nvn 0 0 1\nf 1/1/1 2/1/1 3//1\n",
+			meshes:   []string{""},
+			vertices: []int{3},
+		},
+		{
+			in:       "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 2 0 0\nv 3 0 0\nf 1 2 3\nf 1 2 4\nf 1 2 4 5\nf 1 1 1 1\n",
+			meshes:   []string{""},
+			vertices: []int{3},
+		},
+		{
+			in:     "v 0 0 0\nv 1 0 0\nv 2 0 0\no sliver\nf 1 2 3\n",
+			meshes: []string{},
+		},
+	}
+
+	for i, v := range tests {
+		m, err := Decode(strings.NewReader(v.in))
+		if err != nil {
+			t.Errorf("Test %d: got: %v want: nil", i, err)
+			continue
+		}
+		if len(m.Meshes) != len(v.meshes) {
+			t.Errorf("Test %d: got: %d meshes want: %d", i, len(m.Meshes), len(v.meshes))
+			continue
+		}
+
+		for j, mesh := range m.Meshes {
+			if mesh.Name != v.meshes[j] {
+				t.Errorf("Test %d: mesh %d: got: %q want: %q", i, j, mesh.Name, v.meshes[j])
+			}
+			if v.materials != nil && mesh.Material != v.materials[j] {
+				t.Errorf("Test %d: mesh %d: got: %q want: %q", i, j, mesh.Material, v.materials[j])
+			}
+			if len(mesh.Vertices) != v.vertices[j] || len(mesh.Normals) != v.vertices[j] || len(mesh.Uvs) != v.vertices[j] {
+				t.Errorf("Test %d: mesh %d: got: %d vertices want: %d", i, j, len(mesh.Vertices), v.vertices[j])
+			}
+		}
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		in   string
+		line int
+	}{
+		{in: "v 0 0 0\nv 1 0 0\nf 1 2\n", line: 3},
+		{in: "v 0 0 0\nv 1 0 0\nv 1 1 0\n\nf 1 2 4\n", line: 5},
+		{in: "v 0 0 0\nv 1 0 0\nv 1 1 0\nf 0 1 2\n", line: 4},
+		{in: "v 0 0\n", line: 1},
+		{in: "v 0 0 0\nv 1 0 0\nv 1 1 0\nf 1/1 2 3\n", line: 4},
+		{in: "# comment\ns on\n", line: 2},
+	}
+
+	for i, v := range tests {
+		_, err := Decode(strings.NewReader(v.in))
+
+		var e *Error
+		if !errors.As(err, &e) {
+			t.Errorf("Test %d: got: %v want: *Error", i, err)
+			continue
+		}
+		if e.Line != v.line {
+			t.Errorf("Test %d: got: %d want: %d", i, e.Line, v.line)
+		}
+	}
+}
+
+func TestDecode_Normals(t *testing.T) {
+	// Two faces folded along the edge 2-3, first flat and then smoothed.
+	const faces = "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 1 0 -1\n"
+
+	flat, err := Decode(strings.NewReader(faces + "f 1 2 3\nf 2 4 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	smooth, err := Decode(strings.NewReader(faces + "s 1\nf 1 2 3\nf 2 4 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		got  mgl32.Vec3
+		want mgl32.Vec3
+	}{
+		{got: flat.Meshes[0].Normals[1], want: mgl32.Vec3{0, 0, 1}},
+		{got: flat.Meshes[0].Normals[3], want: mgl32.Vec3{1, 0, 0}},
+		{got: smooth.Meshes[0].Normals[0], want: mgl32.Vec3{0, 0, 1}},
+		{got: smooth.Meshes[0].Normals[1], want: mgl32.Vec3{1, 0, 1}.Normalize()},
+		{got: smooth.Meshes[0].Normals[3], want: mgl32.Vec3{1, 0, 1}.Normalize()},
+	}
+
+	for i, v := range tests {
+		if !v.got.ApproxEqual(v.want) {
+			t.Errorf("Test %d: got: %v want: %v", i, v.got, v.want)
+		}
+	}
+}
+
+func TestTriangulate(t *testing.T) {
+	tests := []struct {
+		in   []mgl32.Vec3
+		want int
+	}{
+		{in: []mgl32.Vec3{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}}, want: 1},
+		{in: []mgl32.Vec3{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}}, want: 2},
+		{in: []mgl32.Vec3{{0, 0, 0}, {0, 1, 0}, {1, 1, 0}, {1, 0, 0}}, want: 2},
+		{in: []mgl32.Vec3{{0, 0, 0}, {2, 0, 0}, {2, 2, 0}, {1, 0.5, 0}, {0, 2, 0}}, want: 3},
+		{in: []mgl32.Vec3{{0, 0, 0}, {0, 0, 2}, {0, 2, 2}, {0, 1, 1.5}, {0, 2, 0}}, want: 3},
+		{in: []mgl32.Vec3{{0, 0, 0}, {1, 0, 0}, {2, 0, 0}, {3, 0, 0}}, want: 0},
+		{in: []mgl32.Vec3{{0, 0, 0}, {1, 0, 0}, {2, 0, 0}}, want: 0},
+	}
+
+	for i, v := range tests {
+		tris := triangulate(v.in)
+		if len(tris) != v.want {
+			t.Errorf("Test %d: got: %d want: %d", i, len(tris), v.want)
+			continue
+		}
+
+		// The triangles must cover the polygon and keep its winding.
+		var area float32
+		n := newellNormal(v.in)
+		for _, tri := range tris {
+			tn := newellNormal([]mgl32.Vec3{v.in[tri[0]], v.in[tri[1]], v.in[tri[2]]})
+			if tn.Dot(n) < 0 {
+				t.Errorf("Test %d: triangle %v is flipped", i, tri)
+			}
+			area += tn.Len()
+		}
+		if !mgl32.FloatEqual(area, n.Len()) {
+			t.Errorf("Test %d: got: area %v want: %v", i, area, n.Len())
+		}
+	}
+}
+
+func TestDecodeMaterials(t *testing.T) {
+	const in = `# library
+newmtl red
+Kd 1 0 0
+Ns 198
+d 0.5
+map_Kd -s 1 1 1 textures/red.png
+
+newmtl metal
+Pr 0.25
+Pm 1
+illum 2
+map_Bump -bm 0.5 normal.png
+`
+
+	m, err := DecodeMaterials(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 {
+		t.Fatalf("got: %d materials want: 2", len(m))
+	}
+
+	tests := []struct {
+		got  interface{}
+		want interface{}
+	}{
+		{got: m[0].Name, want: "red"},
+		{got: m[0].Diffuse, want: mgl32.Vec3{1, 0, 0}},
+		{got: m[0].Opacity, want: float32(0.5)},
+		{got: m[0].Roughness, want: float32(0.1)},
+		{got: *m[0].DiffuseMap, want: Map{File: "textures/red.png", Line: 6}},
+		{got: m[1].Line, want: 8},
+		{got: m[1].Diffuse, want: mgl32.Vec3{0.8, 0.8, 0.8}},
+		{got: m[1].Roughness, want: float32(0.25)},
+		{got: m[1].Metallic, want: float32(1)},
+		{got: m[1].Illum, want: 2},
+		{got: *m[1].NormalMap, want: Map{File: "normal.png", Line: 12}},
+	}
+
+	for i, v := range tests {
+		if v.got != v.want {
+			t.Errorf("Test %d: got: %v want: %v", i, v.got, v.want)
+		}
+	}
+}
+
+func TestDecodeMaterials_Names(t *testing.T) {
+	lib, err := DecodeMaterials(strings.NewReader("newmtl red\nnewmtl My Metal\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := Decode(strings.NewReader("v 0 0 0\nv 1 0 0\nv 1 1 0\nusemtl red\nf 1 2 3\nusemtl My  Metal\nf 1 2 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(model.Meshes) != len(lib) {
+		t.Fatalf("got: %d meshes want: %d", len(model.Meshes), len(lib))
+	}
+
+	// Each material used by the model is found by name in the library.
+	for i, mesh := range model.Meshes {
+		if mesh.Material != lib[i].Name {
+			t.Errorf("Test %d: got: %q want: a material of the library", i, mesh.Material)
+		}
+	}
+}
+
+func TestDecodeMaterials_Errors(t *testing.T) {
+	tests := []struct {
+		in   string
+		line int
+	}{
+		{in: "Kd 1 1 1\n", line: 1},
+		{in: "newmtl a\nKd 1 x 1\n", line: 2},
+		{in: "newmtl a\nKd 1 1\n", line: 2},
+		{in: "newmtl a\n\nnewmtl a\n", line: 3},
+		{in: "newmtl a\nmap_Kd\n", line: 2},
+		{in: "newmtl\n", line: 1},
+		{in: "newmtl My  Metal\nnewmtl My Metal\n", line: 2},
+	}
+
+	for i, v := range tests {
+		_, err := DecodeMaterials(strings.NewReader(v.in))
+
+		var e *Error
+		if !errors.As(err, &e) {
+			t.Errorf("Test %d: got: %v want: *Error", i, err)
+			continue
+		}
+		if e.Line != v.line {
+			t.Errorf("Test %d: got: %d want: %d", i, e.Line, v.line)
+		}
+	}
+}
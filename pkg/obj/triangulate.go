@@ -0,0 +1,144 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package obj
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// newellNormal returns the normal of a polygon scaled by its area, computed
+// with Newell's method so that concave and non-planar polygons are handled.
+func newellNormal(points []mgl32.Vec3) mgl32.Vec3 {
+	var n mgl32.Vec3
+
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		n[0] += (p[1] - q[1]) * (p[2] + q[2])
+		n[1] += (p[2] - q[2]) * (p[0] + q[0])
+		n[2] += (p[0] - q[0]) * (p[1] + q[1])
+	}
+
+	return n.Mul(0.5)
+}
+
+// triangulate splits a polygon into triangles by ear clipping, and returns the
+// indices of their points in the winding order of the polygon. The polygon is
+// projected onto the plane of the largest component of its normal. If no ear
+// can be found the remaining points are split as a fan. Degenerate polygons
+// return nil.
+func triangulate(points []mgl32.Vec3) [][3]int {
+	if len(points) < 3 {
+		return nil
+	}
+
+	n := newellNormal(points)
+	if n.Len() == 0 {
+		return nil
+	}
+	if len(points) == 3 {
+		return [][3]int{{0, 1, 2}}
+	}
+
+	// Project onto the two axes other than the dominant axis of the normal,
+	// swapping them if needed so the polygon winds counterclockwise.
+	u, v := 1, 2
+	switch ax, ay, az := abs32(n[0]), abs32(n[1]), abs32(n[2]); {
+	case ay >= ax && ay >= az:
+		u, v = 2, 0
+	case az >= ax && az >= ay:
+		u, v = 0, 1
+	}
+	if n[3-u-v] < 0 {
+		u, v = v, u
+	}
+
+	p := make([]mgl32.Vec2, len(points))
+	for i := range points {
+		p[i] = mgl32.Vec2{points[i][u], points[i][v]}
+	}
+
+	remaining := make([]int, len(points))
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	tris := make([][3]int, 0, len(points)-2)
+
+	for len(remaining) > 3 {
+		ear := -1
+		for i := range remaining {
+			a := remaining[(i+len(remaining)-1)%len(remaining)]
+			b := remaining[i]
+			c := remaining[(i+1)%len(remaining)]
+
+			if isEar(p, remaining, a, b, c) {
+				ear = i
+				break
+			}
+		}
+
+		if ear < 0 {
+			for i := 1; i < len(remaining)-1; i++ {
+				tris = append(tris, [3]int{remaining[0], remaining[i], remaining[i+1]})
+			}
+			return tris
+		}
+
+		a := remaining[(ear+len(remaining)-1)%len(remaining)]
+		c := remaining[(ear+1)%len(remaining)]
+		tris = append(tris, [3]int{a, remaining[ear], c})
+		remaining = append(remaining[:ear], remaining[ear+1:]...)
+	}
+
+	return append(tris, [3]int{remaining[0], remaining[1], remaining[2]})
+}
+
+// isEar reports whether the corner b of the polygon is convex and the
+// triangle abc contains no other remaining point.
+func isEar(p []mgl32.Vec2, remaining []int, a, b, c int) bool {
+	if cross2(p[a], p[b], p[c]) <= 0 {
+		return false
+	}
+
+	for _, i := range remaining {
+		if i == a || i == b || i == c {
+			continue
+		}
+		if cross2(p[a], p[b], p[i]) >= 0 && cross2(p[b], p[c], p[i]) >= 0 && cross2(p[c], p[a], p[i]) >= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cross2 returns the z component of the cross product of b-a and c-a.
+func cross2(a, b, c mgl32.Vec2) float32 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+func abs32(x float32) float32 {
+	return float32(math.Abs(float64(x)))
+}
@@ -37,6 +37,9 @@ import (
 var _ GraphListener = &Camera{}
 var _ ScriptComponent = &Camera{}
 
+// DefaultCameraFov is the vertical field of view of new cameras, in radians.
+const DefaultCameraFov = 1.309
+
 type RenderPath int
 
 const (
@@ -114,6 +117,10 @@ func (c *Camera) SetClearMode(mode ClearMode) {
 }
 
 func (c *Camera) Render() {
+	if c.framebuffer == nil {
+		return
+	}
+
 	c.startRender()
 
 	c.renderDeferred()
@@ -233,6 +240,14 @@ func (c *Camera) SetFov(fov float32) {
 	c.fov = fov
 }
 
+func (c *Camera) SetNearClip(near float32) {
+	c.nearClip = near
+}
+
+func (c *Camera) SetFarClip(far float32) {
+	c.farClip = far
+}
+
 func (c *Camera) CameraPosition() mgl32.Vec3 {
 	return c.GetTransform().Position()
 }
@@ -532,7 +547,7 @@ func NewCamera(renderPath RenderPath, hdr bool) *Camera {
 		effects:       []Effect{},
 		deferredCache: []Drawable{},
 		forwardCache:  []Drawable{},
-		fov:           1.309,
+		fov:           DefaultCameraFov,
 		nearClip:      0.01,
 		farClip:       100000.0,
 		aspectRatio:   window.AspectRatio(),
@@ -542,7 +557,10 @@ func NewCamera(renderPath RenderPath, hdr bool) *Camera {
 	c.SetName("Camera")
 	instance.MustAssign(c)
 
-	c.setupPipeline()
+	// Headless cameras have no render targets, and never render.
+	if !window.Headless() {
+		c.setupPipeline()
+	}
 	c.UpdateMatrices()
 
 	return c
@@ -571,8 +589,10 @@ func (c *Camera) Update() {
 
 func (c *Camera) Resize() {
 	c.aspectRatio = window.AspectRatio()
-	c.framebuffer.SetSize(window.Resolution())
-	if c.renderPath == RenderPathDeferred {
+	if c.framebuffer != nil {
+		c.framebuffer.SetSize(window.Resolution())
+	}
+	if c.gbuffer != nil {
 		c.gbuffer.SetSize(window.Resolution())
 	}
 	c.UpdateMatrices()
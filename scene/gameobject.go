@@ -23,9 +23,12 @@ SOFTWARE.
 package scene
 
 import (
+	"reflect"
+
 	"github.com/haakenlabs/arc/core"
 	"github.com/haakenlabs/arc/internal/sg"
 	"github.com/haakenlabs/arc/system/instance"
+	"github.com/haakenlabs/arc/system/profile"
 )
 
 type Message uint8
@@ -41,6 +44,50 @@ const (
 	MessageSGUpdate
 )
 
+var messageNames = [...]string{
+	MessageActivate:    "Activate",
+	MessageStart:       "Start",
+	MessageAwake:       "Awake",
+	MessageUpdate:      "Update",
+	MessageLateUpdate:  "LateUpdate",
+	MessageFixedUpdate: "FixedUpdate",
+	MessageGUIRender:   "GUIRender",
+	MessageSGUpdate:    "SGUpdate",
+}
+
+func (m Message) String() string {
+	if int(m) < len(messageNames) {
+		return messageNames[m]
+	}
+
+	return "Unknown"
+}
+
+// scopeKey identifies the profiler scope of a message sent to a component.
+type scopeKey struct {
+	t   reflect.Type
+	msg Message
+}
+
+// scopeNames caches profiler scope names, such as "ui.Controller.GUIRender".
+var scopeNames = make(map[scopeKey]string)
+
+func scopeName(c Component, msg Message) string {
+	key := scopeKey{reflect.TypeOf(c), msg}
+
+	name, ok := scopeNames[key]
+	if !ok {
+		t := key.t
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		name = t.String() + "." + msg.String()
+		scopeNames[key] = name
+	}
+
+	return name
+}
+
 var _ sg.Node = &GameObject{}
 
 type GameObject struct {
@@ -176,7 +223,13 @@ func (g *GameObject) SendMessage(msg Message) {
 		return
 	}
 
+	detailed := profile.Detailed()
+
 	for i := range g.components {
+		if detailed {
+			profile.Begin(scopeName(g.components[i], msg))
+		}
+
 		switch msg {
 		case MessageStart:
 			if c, ok := g.components[i].(ScriptComponent); ok {
@@ -207,6 +260,10 @@ func (g *GameObject) SendMessage(msg Message) {
 				c.OnSceneGraphUpdate()
 			}
 		}
+
+		if detailed {
+			profile.End()
+		}
 	}
 }
 
@@ -25,6 +25,7 @@ package scene
 import (
 	"github.com/haakenlabs/arc/internal/sg"
 	"github.com/haakenlabs/arc/system/instance"
+	"github.com/haakenlabs/arc/system/profile"
 )
 
 type GraphListener interface {
@@ -206,7 +207,9 @@ func (s *Graph) Components() []Component {
 }
 
 func (s *Graph) SendMessage(message Message) {
+	profile.Begin(message.String())
 	for _, v := range s.aCache {
 		v.SendMessage(message)
 	}
+	profile.End()
 }
@@ -120,13 +120,7 @@ func (m *MeshRenderer) DrawShader(shader *graphics.Shader, camera *Camera) {
 
 	for i := range meshes {
 		meshes[i].Bind()
-
-		if meshes[i].Indexed() {
-			gl.DrawElements(gl.TRIANGLES, int32(len(meshes[i].Triangles())), gl.UNSIGNED_INT, nil)
-		} else {
-			gl.DrawArrays(gl.TRIANGLES, 0, int32(len(meshes[i].Vertices())))
-		}
-
+		meshes[i].Draw()
 		meshes[i].Unbind()
 
 	}
@@ -0,0 +1,165 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/haakenlabs/arc/core"
+	"github.com/haakenlabs/arc/graphics"
+	"github.com/haakenlabs/arc/system/instance"
+)
+
+// Prefab is a template of a hierarchy of game objects, such as an imported
+// scene. Each call to Instantiate creates a new hierarchy which shares the
+// meshes and materials of the prefab.
+type Prefab struct {
+	core.BaseObject
+
+	nodes []*PrefabNode
+}
+
+// PrefabNode describes a game object of a prefab.
+type PrefabNode struct {
+	Name     string
+	Position mgl32.Vec3
+	Rotation mgl32.Quat
+	Scale    mgl32.Vec3
+
+	// Parts are the meshes drawn by the object. A single part is drawn by the
+	// object itself, and several parts by a child object for each part.
+	Parts []PrefabPart
+
+	// Camera is the camera of the object, or nil if it has none.
+	Camera *PrefabCamera
+
+	Children []*PrefabNode
+}
+
+// PrefabPart is a mesh drawn with a material.
+type PrefabPart struct {
+	Mesh     *graphics.Mesh
+	Material *Material
+}
+
+// PrefabCamera describes a perspective camera, which is created with the
+// deferred render path and HDR. A far clip of zero keeps the default far clip
+// of the camera.
+type PrefabCamera struct {
+	Fov      float32
+	NearClip float32
+	FarClip  float32
+}
+
+// NewPrefab creates a new prefab with the given root nodes.
+func NewPrefab(name string, nodes []*PrefabNode) *Prefab {
+	p := &Prefab{
+		nodes: nodes,
+	}
+
+	p.SetName(name)
+	instance.MustAssign(p)
+
+	return p
+}
+
+// Nodes returns the root nodes of the prefab.
+func (p *Prefab) Nodes() []*PrefabNode {
+	return p.nodes
+}
+
+// Instantiate creates a hierarchy of game objects from the prefab. The root
+// object is named after the prefab, and has an object for each root node as
+// a child. Add the root object to a scene to display it. The view of each
+// camera is set from its node as if the root object is at the origin.
+func (p *Prefab) Instantiate() *GameObject {
+	root := NewGameObject(p.Name())
+
+	for _, n := range p.nodes {
+		root.AddChild(n.instantiate(mgl32.Ident4(), mgl32.QuatIdent()))
+	}
+
+	return root
+}
+
+// instantiate creates the game object of a node. The parent matrix and
+// rotation place the node in the prefab, and are used to set the view of its
+// camera.
+func (n *PrefabNode) instantiate(parent mgl32.Mat4, rotation mgl32.Quat) *GameObject {
+	g := NewGameObject(n.Name)
+
+	matrix := parent.Mul4(mgl32.Translate3D(n.Position.X(), n.Position.Y(), n.Position.Z()))
+	matrix = matrix.Mul4(n.Rotation.Mat4())
+	matrix = matrix.Mul4(mgl32.Scale3D(n.Scale.X(), n.Scale.Y(), n.Scale.Z()))
+	rotation = rotation.Mul(n.Rotation)
+
+	t := g.Transform()
+	t.SetPosition(n.Position)
+	t.SetRotation(n.Rotation)
+	t.SetScale(n.Scale)
+
+	if len(n.Parts) == 1 {
+		addPart(g, n.Parts[0])
+	} else {
+		for _, part := range n.Parts {
+			c := NewGameObject(part.Mesh.Name())
+			addPart(c, part)
+			g.AddChild(c)
+		}
+	}
+
+	if n.Camera != nil {
+		c := NewCamera(RenderPathDeferred, true)
+		c.SetFov(n.Camera.Fov)
+		c.SetNearClip(n.Camera.NearClip)
+		if n.Camera.FarClip > 0 {
+			c.SetFarClip(n.Camera.FarClip)
+		}
+		c.UpdateMatrices()
+		c.SetViewMatrix(cameraView(matrix, rotation))
+
+		g.AddComponent(c)
+	}
+
+	for _, child := range n.Children {
+		g.AddChild(child.instantiate(matrix, rotation))
+	}
+
+	return g
+}
+
+// cameraView returns the view matrix of a camera placed by a matrix. Only
+// the position and rotation are used, as the scale must not distort the view.
+func cameraView(matrix mgl32.Mat4, rotation mgl32.Quat) mgl32.Mat4 {
+	eye := matrix.Col(3)
+
+	return mgl32.Translate3D(eye.X(), eye.Y(), eye.Z()).Mul4(rotation.Normalize().Mat4()).Inv()
+}
+
+func addPart(g *GameObject, part PrefabPart) {
+	r := NewMeshRenderer()
+	r.SetMaterial(part.Material)
+
+	g.AddComponent(NewMeshFilter(part.Mesh))
+	g.AddComponent(r)
+}
@@ -0,0 +1,67 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package scene
+
+import (
+	"github.com/haakenlabs/arc/core"
+)
+
+// GameObjectRef is a weak reference to a game object. Once the object has
+// been released, Get returns nil.
+type GameObjectRef struct {
+	core.WeakRef
+}
+
+// NewGameObjectRef creates a weak reference to a game object.
+func NewGameObjectRef(object *GameObject) GameObjectRef {
+	if object == nil {
+		return GameObjectRef{}
+	}
+
+	return GameObjectRef{core.NewWeakRef(object)}
+}
+
+// Get returns the game object, or nil if it has been released.
+func (r GameObjectRef) Get() *GameObject {
+	object, _ := r.WeakRef.Get().(*GameObject)
+
+	return object
+}
+
+// ComponentRef is a weak reference to a component. Once the component has
+// been released, Get returns nil.
+type ComponentRef struct {
+	core.WeakRef
+}
+
+// NewComponentRef creates a weak reference to a component.
+func NewComponentRef(component Component) ComponentRef {
+	return ComponentRef{core.NewWeakRef(component)}
+}
+
+// Get returns the component, or nil if it has been released.
+func (r ComponentRef) Get() Component {
+	component, _ := r.WeakRef.Get().(Component)
+
+	return component
+}
@@ -22,15 +22,32 @@ SOFTWARE.
 
 package scene
 
-import "github.com/haakenlabs/arc/core"
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/haakenlabs/arc/core"
+	"github.com/haakenlabs/arc/system/instance"
+	"github.com/haakenlabs/arc/system/profile"
+)
 
 var _ core.Scene = &Scene{}
+var _ core.SceneHierarchyWriter = &Scene{}
+var _ core.SceneAssetOwner = &Scene{}
+var _ core.SceneUnloader = &Scene{}
 
 type Scene struct {
 	LoadFunc         func() error
+	UnloadFunc       func()
 	OnActivateFunc   func()
 	OnDeacticateFunc func()
 
+	// Manifests are the asset manifests owned by this scene. They are loaded
+	// before LoadFunc is called, and unloaded along with the scene when it is
+	// popped off the active stack.
+	Manifests []string
+
 	environment *Environment
 	graph       *Graph
 	cameras     []*Camera
@@ -76,6 +93,46 @@ func (s *Scene) Load() error {
 	return nil
 }
 
+// Unload releases the objects and components in the scene graph, so that the
+// scene can be loaded again.
+func (s *Scene) Unload() {
+	if !s.loaded {
+		return
+	}
+
+	if s.UnloadFunc != nil {
+		s.UnloadFunc()
+	}
+
+	if s.graph.Dirty() {
+		s.graph.Update()
+	}
+
+	components := s.graph.Components()
+	objects := s.graph.Objects()
+
+	ids := make([]int32, 0, len(components)+len(objects))
+	for i := len(components) - 1; i >= 0; i-- {
+		ids = append(ids, components[i].ID())
+	}
+	for i := len(objects) - 1; i >= 0; i-- {
+		ids = append(ids, objects[i].ID())
+	}
+
+	instance.Release(ids...)
+
+	s.graph = nil
+	s.environment = nil
+	s.cameras = nil
+	s.loaded = false
+	s.started = false
+}
+
+// OwnedManifests returns the asset manifests owned by this scene.
+func (s *Scene) OwnedManifests() []string {
+	return s.Manifests
+}
+
 // Loaded reports if the scene has been loaded.
 func (s *Scene) Loaded() bool {
 	return s.loaded
@@ -112,7 +169,9 @@ func (s *Scene) Display() {
 
 	cameras := s.cameras
 	for i := range cameras {
+		profile.Begin("Camera.Render")
 		cameras[i].Render()
+		profile.End()
 	}
 
 	s.graph.SendMessage(MessageGUIRender)
@@ -140,6 +199,34 @@ func (s *Scene) Update() {
 	s.graph.SendMessage(MessageLateUpdate)
 }
 
+// WriteHierarchy writes the objects in the scene graph as an indented tree,
+// with the components of each object.
+func (s *Scene) WriteHierarchy(w io.Writer) error {
+	for _, o := range s.graph.Objects() {
+		if o == s.graph.root {
+			continue
+		}
+
+		depth := len(o.Ancestors()) - 1
+		if depth < 0 {
+			depth = 0
+		}
+
+		types := make([]string, len(o.Components()))
+		for i, c := range o.Components() {
+			types[i] = fmt.Sprintf("%T", c)
+		}
+
+		_, err := fmt.Fprintf(w, "%s%s [%08X] active=%v {%s}\n",
+			strings.Repeat("  ", depth), o.Name(), o.ID(), o.Active(), strings.Join(types, ", "))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s *Scene) Environment() *Environment {
 	return s.environment
 }
@@ -1,6 +1,9 @@
 package asset
 
 import (
+	"io/fs"
+	"time"
+
 	"github.com/haakenlabs/arc/core"
 )
 
@@ -28,6 +31,22 @@ func MountPackage(name string) error {
 	return core.GetAssetSystem().MountPackage(name)
 }
 
+// MountPackageFile mounts the package file at the given path by name, at a
+// mount point with the given priority.
+func MountPackageFile(name, file, point string, priority int) error {
+	return core.GetAssetSystem().MountPackageFile(name, file, point, priority)
+}
+
+// MountPackageAt mounts a package at a mount point with the given priority.
+func MountPackageAt(p *core.Package, point string, priority int) error {
+	return core.GetAssetSystem().MountPackageAt(p, point, priority)
+}
+
+// SetPackageVerifier sets how packages mounted from now on are verified.
+func SetPackageVerifier(v *core.PackageVerifier) {
+	core.GetAssetSystem().SetPackageVerifier(v)
+}
+
 // UnmountPackage unmounts a mounted package given by name.
 func UnmountPackage(name string) error {
 	return core.GetAssetSystem().UnmountPackage(name)
@@ -43,6 +62,84 @@ func LoadManifest(files ...string) error {
 	return core.GetAssetSystem().LoadManifest(files...)
 }
 
+// LoadManifestAsync loads manifests of assets without blocking, and returns
+// a ManifestLoad to track its progress.
+func LoadManifestAsync(files ...string) *core.ManifestLoad {
+	return core.GetAssetSystem().LoadManifestAsync(files...)
+}
+
+// UnloadManifest reverses a LoadManifest, deallocating assets which are no
+// longer referenced.
+func UnloadManifest(files ...string) error {
+	return core.GetAssetSystem().UnloadManifest(files...)
+}
+
+// Acquire gets an asset by name from a handler by kind, and adds a reference
+// to it.
+func Acquire(kind, name string) (core.Object, error) {
+	return core.GetAssetSystem().Acquire(kind, name)
+}
+
+// Release removes a reference to an asset, and deallocates it if it is no
+// longer referenced.
+func Release(kind, name string) error {
+	return core.GetAssetSystem().Release(kind, name)
+}
+
+// Dependencies returns the loaded assets which an asset depends on.
+func Dependencies(kind, name string) []core.AssetRef {
+	return core.GetAssetSystem().AssetDependencies(kind, name)
+}
+
+// Dependents returns the loaded assets which depend on an asset.
+func Dependents(kind, name string) []core.AssetRef {
+	return core.GetAssetSystem().AssetDependents(kind, name)
+}
+
+// ByGUID returns the loaded asset with the GUID given by its sidecar file.
+func ByGUID(guid string) (core.AssetRef, bool) {
+	return core.GetAssetSystem().AssetByGUID(guid)
+}
+
+// LoadOrder returns the files of the assets which LoadManifest would load for
+// the given manifests, in the order they would be loaded.
+func LoadOrder(files ...string) ([]string, error) {
+	return core.GetAssetSystem().LoadOrder(files...)
+}
+
+// EnableHotReload watches the files of assets loaded from now on, and reloads
+// the assets when their files change.
+func EnableHotReload(interval time.Duration, builtinDir string) error {
+	return core.GetAssetSystem().EnableHotReload(interval, builtinDir)
+}
+
+// DisableHotReload stops watching asset files.
+func DisableHotReload() {
+	core.GetAssetSystem().DisableHotReload()
+}
+
+// Reload reloads an asset from its files.
+func Reload(kind, name string) error {
+	return core.GetAssetSystem().Reload(kind, name)
+}
+
+// FS returns the virtual filesystem which resources are read from.
+func FS() *core.FS {
+	return core.GetAssetSystem().FS()
+}
+
+// Mount mounts a filesystem as a layer named name at a mount point of the
+// virtual filesystem.
+func Mount(point, name string, fsys fs.FS, priority int) error {
+	return core.GetAssetSystem().Mount(point, name, fsys, priority)
+}
+
+// Unmount removes the layer named name from a mount point of the virtual
+// filesystem.
+func Unmount(point, name string) error {
+	return core.GetAssetSystem().Unmount(point, name)
+}
+
 func ReadResource(r *core.Resource) error {
 	return core.GetAssetSystem().ReadResource(r)
 }
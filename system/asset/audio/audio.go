@@ -24,6 +24,7 @@ package audio
 
 import (
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/faiface/beep"
@@ -45,24 +46,46 @@ type Handler struct {
 	core.BaseAssetHandler
 }
 
+// ImportSettings are the import settings of a sound, read from the import
+// section of its .meta sidecar file.
+type ImportSettings struct {
+	// Stream decodes the sound as it is played. Otherwise the sound is
+	// decoded when it is loaded, and held in memory so that it can be played
+	// more than once.
+	Stream bool `json:"stream"`
+}
+
+// DefaultImportSettings returns the import settings of sounds without a
+// sidecar file.
+func DefaultImportSettings() ImportSettings {
+	return ImportSettings{
+		Stream: true,
+	}
+}
+
 func (h *Handler) Load(r *core.Resource) error {
-	var streamer beep.Streamer
+	var streamer beep.StreamSeekCloser
 	var format beep.Format
 	var err error
 
 	name := r.Base()
-	ext := filepath.Ext(name)
+	ext := strings.ToLower(filepath.Ext(name))
 
 	if _, dup := h.Items[name]; dup {
 		return core.ErrAssetExists(name)
 	}
 
+	settings := DefaultImportSettings()
+	if err := r.Meta().Decode(&settings); err != nil {
+		return err
+	}
+
 	switch ext {
-	case "mp3":
+	case ".mp3":
 		streamer, format, err = mp3.Decode(r.ReadCloser())
-	case "wav":
+	case ".wav":
 		streamer, format, err = wav.Decode(r.ReadCloser())
-	case "flac":
+	case ".flac":
 		streamer, format, err = flac.Decode(r.ReadCloser())
 	default:
 		return fmt.Errorf("unknown audio type: %s", ext)
@@ -72,7 +95,16 @@ func (h *Handler) Load(r *core.Resource) error {
 		return err
 	}
 
-	s := core.NewSound(streamer, format)
+	var s *core.Sound
+	if settings.Stream {
+		s = core.NewSound(streamer, format)
+	} else {
+		buffer := beep.NewBuffer(format)
+		buffer.Append(streamer)
+		streamer.Close()
+
+		s = core.NewBufferedSound(buffer)
+	}
 	s.SetName(name)
 
 	return h.Add(name, s)
@@ -0,0 +1,244 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package material
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/haakenlabs/arc/core"
+	"github.com/haakenlabs/arc/pkg/obj"
+	"github.com/haakenlabs/arc/scene"
+	"github.com/haakenlabs/arc/system/asset"
+	"github.com/haakenlabs/arc/system/asset/texture"
+	"github.com/haakenlabs/arc/system/instance"
+)
+
+const (
+	AssetNameMaterial = "material"
+)
+
+var _ core.AssetHandler = &Handler{}
+
+// Handler loads the materials of Wavefront MTL material libraries. Each
+// material is named by the library followed by a slash and its name in the
+// library, which is the name given to meshes which use it.
+type Handler struct {
+	core.BaseAssetHandler
+}
+
+// Load will load data from the reader. The texture maps of the materials
+// refer to texture assets by file name, so the textures must be loaded
+// first, for example by listing them as dependencies of the library in its
+// manifest.
+func (h *Handler) Load(r *core.Resource) error {
+	lib, err := obj.DecodeMaterials(r.Reader())
+	if err != nil {
+		return err
+	}
+	if len(lib) == 0 {
+		return fmt.Errorf("material: library %s has no materials", r.Location())
+	}
+
+	names := make([]string, len(lib))
+	materials := make([]*scene.Material, 0, len(lib))
+	for i, m := range lib {
+		names[i] = Name(r.Base(), m.Name)
+
+		mat, err := newMaterial(names[i], m)
+		if err != nil {
+			release(materials)
+			return err
+		}
+		materials = append(materials, mat)
+	}
+
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+
+	// Every name is checked first, so that a library is added whole or not
+	// at all.
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, dup := h.Items[name]; dup || seen[name] {
+			release(materials)
+			return core.ErrAssetExists(name)
+		}
+		seen[name] = true
+	}
+
+	for i := range materials {
+		if err := h.add(names[i], materials[i]); err != nil {
+			for _, name := range names[:i] {
+				delete(h.Items, name)
+			}
+			release(materials)
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// release releases the instances of materials which were not added.
+func release(materials []*scene.Material) {
+	ids := make([]int32, len(materials))
+	for i, m := range materials {
+		ids[i] = m.ID()
+	}
+
+	instance.Release(ids...)
+}
+
+// Name returns the asset name of a material of a library.
+func Name(lib, material string) string {
+	return lib + "/" + material
+}
+
+// newMaterial creates a PBR material from a material of a library.
+func newMaterial(name string, m *obj.Material) (*scene.Material, error) {
+	mat := scene.NewMaterialPBR()
+	mat.SetName(name)
+
+	mat.SetProperty("f_albedo", m.Diffuse)
+	mat.SetProperty("f_metallic", m.Metallic)
+	mat.SetProperty("f_roughness", m.Roughness)
+
+	maps := []struct {
+		id  scene.MaterialTexture
+		ref *obj.Map
+	}{
+		{scene.MaterialTextureAlbedo, m.DiffuseMap},
+		{scene.MaterialTextureNormal, m.NormalMap},
+		{scene.MaterialTextureMetallic, m.MetallicMap},
+	}
+
+	for _, v := range maps {
+		if v.ref == nil {
+			continue
+		}
+
+		// Libraries written on Windows may separate directories with
+		// backslashes.
+		name := path.Base(strings.Replace(v.ref.File, "\\", "/", -1))
+
+		t, err := texture.Get(name)
+		if err != nil {
+			instance.Release(mat.ID())
+			return nil, &obj.Error{Line: v.ref.Line, Err: fmt.Errorf("material %s: texture %s: %v", m.Name, name, err)}
+		}
+
+		mat.SetTexture(v.id, t)
+	}
+
+	if m.DiffuseMap != nil {
+		// The texture gives the albedo, so it is not tinted.
+		mat.SetProperty("f_albedo", mgl32.Vec3{1, 1, 1})
+	}
+
+	return mat, nil
+}
+
+func (h *Handler) Add(name string, material *scene.Material) error {
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+
+	return h.add(name, material)
+}
+
+// add adds a material. The lock must be held.
+func (h *Handler) add(name string, material *scene.Material) error {
+	if _, dup := h.Items[name]; dup {
+		return core.ErrAssetExists(name)
+	}
+
+	if err := material.Alloc(); err != nil {
+		return err
+	}
+
+	h.Items[name] = material.ID()
+
+	return nil
+}
+
+// Get gets an asset by name.
+func (h *Handler) Get(name string) (*scene.Material, error) {
+	h.Mu.RLock()
+	defer h.Mu.RUnlock()
+
+	a, err := h.GetAsset(name)
+	if err != nil {
+		return nil, err
+	}
+
+	a2, ok := a.(*scene.Material)
+	if !ok {
+		return nil, core.ErrAssetType(name)
+	}
+
+	return a2, nil
+}
+
+// MustGet is like GetAsset, but panics if an error occurs.
+func (h *Handler) MustGet(name string) *scene.Material {
+	a, err := h.Get(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return a
+}
+
+func (h *Handler) Name() string {
+	return AssetNameMaterial
+}
+
+func NewHandler() *Handler {
+	h := &Handler{}
+	h.Items = make(map[string]int32)
+	h.Mu = &sync.RWMutex{}
+
+	return h
+}
+
+func Get(name string) (*scene.Material, error) {
+	return mustHandler().Get(name)
+}
+
+func MustGet(name string) *scene.Material {
+	return mustHandler().MustGet(name)
+}
+
+func mustHandler() *Handler {
+	h, err := asset.GetHandler(AssetNameMaterial)
+	if err != nil {
+		panic(err)
+	}
+
+	return h.(*Handler)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package mesh
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/haakenlabs/arc/graphics"
+	"github.com/haakenlabs/arc/pkg/meshutil"
+)
+
+// Names of the builtin meshes. Each fits in a unit cube centered on the
+// origin, except the plane, which is 10 by 10 units.
+const (
+	BuiltinBox       = "builtin/box"
+	BuiltinPlane     = "builtin/plane"
+	BuiltinSphere    = "builtin/sphere"
+	BuiltinIcosphere = "builtin/icosphere"
+	BuiltinCylinder  = "builtin/cylinder"
+	BuiltinCone      = "builtin/cone"
+	BuiltinCapsule   = "builtin/capsule"
+	BuiltinTorus     = "builtin/torus"
+)
+
+// builtins returns the geometry of the builtin meshes.
+func builtins() map[string]*meshutil.Data {
+	return map[string]*meshutil.Data{
+		BuiltinBox:       meshutil.Box(mgl32.Vec3{1, 1, 1}, 1),
+		BuiltinPlane:     meshutil.Plane(mgl32.Vec2{10, 10}, 10, 10),
+		BuiltinSphere:    meshutil.UVSphere(0.5, 32, 16),
+		BuiltinIcosphere: meshutil.Icosphere(0.5, 3),
+		BuiltinCylinder:  meshutil.Cylinder(0.5, 1, 32),
+		BuiltinCone:      meshutil.Cone(0.5, 1, 32),
+		BuiltinCapsule:   meshutil.Capsule(0.25, 1, 32, 8),
+		BuiltinTorus:     meshutil.Torus(0.35, 0.15, 32, 16),
+	}
+}
+
+// AddBuiltins adds the builtin primitive meshes. It requires an OpenGL
+// context.
+func (h *Handler) AddBuiltins() error {
+	for name, d := range builtins() {
+		m := graphics.NewMesh()
+		m.SetData(d)
+
+		if err := h.Add(name, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddBuiltins adds the builtin primitive meshes to the mesh handler.
+func AddBuiltins() error {
+	return mustHandler().AddBuiltins()
+}
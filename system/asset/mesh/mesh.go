@@ -24,6 +24,10 @@ package mesh
 
 import (
 	"encoding/gob"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/go-gl/mathgl/mgl32"
@@ -32,7 +36,11 @@ import (
 	"github.com/haakenlabs/arc/core"
 	"github.com/haakenlabs/arc/graphics"
 	"github.com/haakenlabs/arc/pkg/math"
+	"github.com/haakenlabs/arc/pkg/meshutil"
+	"github.com/haakenlabs/arc/pkg/obj"
 	"github.com/haakenlabs/arc/system/asset"
+	"github.com/haakenlabs/arc/system/asset/material"
+	"github.com/haakenlabs/arc/system/instance"
 )
 
 const (
@@ -72,30 +80,196 @@ type Metadata struct {
 }
 
 var _ core.AssetHandler = &Handler{}
+var _ core.AssetReloader = &Handler{}
 
 type Handler struct {
 	core.BaseAssetHandler
 }
 
-// Load will load data from the reader.
+// decodedMesh holds the geometry of a decoded mesh.
+type decodedMesh struct {
+	name     string
+	material string
+	data     *meshutil.Data
+}
+
+// Load will load data from the reader. Wavefront OBJ files are loaded as one
+// mesh for each of their objects, groups and materials, named by the file
+// followed by a slash and the name of the part. Parts without a name are
+// named by the file. The material of a part is named as it is by the material
+// handler, by its library followed by a slash and its name.
 func (h *Handler) Load(r *core.Resource) error {
-	metadata := &Metadata{}
-	m := graphics.NewMesh()
+	meshes, err := decode(r)
+	if err != nil {
+		return err
+	}
 
-	dec := gob.NewDecoder(r.Reader())
-	err := dec.Decode(&metadata)
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+
+	// Every name is checked first, so that a file is added whole or not at
+	// all.
+	seen := make(map[string]bool, len(meshes))
+	for _, d := range meshes {
+		if _, dup := h.Items[d.name]; dup || seen[d.name] {
+			return core.ErrAssetExists(d.name)
+		}
+		seen[d.name] = true
+	}
+
+	ids := make([]int32, 0, len(meshes))
+	for i, d := range meshes {
+		m := graphics.NewMesh()
+		ids = append(ids, m.ID())
+
+		m.SetData(d.data)
+		m.SetMaterialName(d.material)
+
+		if err := h.add(d.name, m); err != nil {
+			for _, added := range meshes[:i] {
+				delete(h.Items, added.name)
+			}
+			instance.Release(ids...)
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reload decodes the mesh in the resource and uploads it to an existing mesh.
+// If the new geometry is invalid, the mesh keeps its previous geometry.
+func (h *Handler) Reload(name string, r *core.Resource) error {
+	meshes, err := decode(r)
 	if err != nil {
 		return err
 	}
 
-	name := metadata.Name
+	var d *decodedMesh
+	for i := range meshes {
+		if meshes[i].name == name {
+			d = meshes[i]
+			break
+		}
+	}
+	if d == nil {
+		return errors.Errorf("reloaded resource %s has no mesh %s", r.Location(), name)
+	}
 
-	if _, dup := h.Items[name]; dup {
-		return core.ErrAssetExists(name)
+	m, err := h.Get(name)
+	if err != nil {
+		return err
+	}
+
+	prev := m.Data()
+
+	m.SetData(d.data)
+
+	if err := m.Upload(); err != nil {
+		// The failed upload may have replaced some of the buffers, so the
+		// previous data is uploaded again as well as restored.
+		m.SetData(prev)
+		if rerr := m.Upload(); rerr != nil {
+			return errors.Annotatef(err, "restore mesh %s: %v", name, rerr)
+		}
+
+		return err
+	}
+
+	m.SetMaterialName(d.material)
+
+	return nil
+}
+
+// decode decodes the meshes in a resource, and applies its import settings.
+func decode(r *core.Resource) ([]*decodedMesh, error) {
+	settings, err := readSettings(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var meshes []*decodedMesh
+
+	if strings.ToLower(filepath.Ext(r.Base())) == ".obj" {
+		meshes, err = decodeOBJ(r)
+	} else {
+		var d *decodedMesh
+		d, err = decodeMetadata(r)
+		meshes = []*decodedMesh{d}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range meshes {
+		settings.process(d)
+	}
+
+	return meshes, nil
+}
+
+// decodeOBJ decodes the meshes of a Wavefront OBJ file.
+func decodeOBJ(r *core.Resource) ([]*decodedMesh, error) {
+	model, err := obj.Decode(r.Reader())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(model.Meshes) == 0 {
+		return nil, ErrMeshMissingFaces
+	}
+
+	meshes := make([]*decodedMesh, len(model.Meshes))
+	names := make(map[string]int)
+
+	for i, m := range model.Meshes {
+		name := r.Base()
+		if m.Name != "" {
+			name += "/" + m.Name
+		}
+
+		// Parts of one group with several materials share its name, so all
+		// but the first are numbered.
+		if names[name]++; names[name] > 1 {
+			name = fmt.Sprintf("%s.%d", name, names[name]-1)
+		}
+
+		meshes[i] = &decodedMesh{
+			name:     name,
+			material: materialName(model, m),
+			data:     &meshutil.Data{Vertices: m.Vertices, Normals: m.Normals, Uvs: m.Uvs},
+		}
+	}
+
+	return meshes, nil
+}
+
+// materialName returns the asset name of the material used by a part of a
+// model. Materials are looked up in the first material library of the model.
+func materialName(model *obj.Model, m *obj.Mesh) string {
+	if m.Material == "" || len(model.MaterialLibs) == 0 {
+		return m.Material
+	}
+
+	// Libraries written on Windows may separate directories with
+	// backslashes.
+	lib := path.Base(strings.Replace(model.MaterialLibs[0], "\\", "/", -1))
+
+	return material.Name(lib, m.Material)
+}
+
+// decodeMetadata decodes a gob encoded mesh.
+func decodeMetadata(r *core.Resource) (*decodedMesh, error) {
+	metadata := &Metadata{}
+
+	dec := gob.NewDecoder(r.Reader())
+	if err := dec.Decode(&metadata); err != nil {
+		return nil, err
 	}
 
 	if len(metadata.F) == 0 {
-		return ErrMeshMissingFaces
+		return nil, ErrMeshMissingFaces
 	}
 
 	v := make([]mgl32.Vec3, len(metadata.F)*3)
@@ -118,22 +292,23 @@ func (h *Handler) Load(r *core.Resource) error {
 				t[i*3+j] = metadata.T[metadata.F[i][j][FaceTexture]]
 				n[i*3+j] = metadata.N[metadata.F[i][j][FaceNormal]]
 			default:
-				return ErrMeshInvalidFaceType
+				return nil, ErrMeshInvalidFaceType
 			}
 		}
 	}
 
-	m.SetVertices(v)
-	m.SetNormals(n)
-	m.SetUvs(t)
-
-	return h.Add(name, m)
+	return &decodedMesh{name: metadata.Name, data: &meshutil.Data{Vertices: v, Normals: n, Uvs: t}}, nil
 }
 
 func (h *Handler) Add(name string, mesh *graphics.Mesh) error {
 	h.Mu.Lock()
 	defer h.Mu.Unlock()
 
+	return h.add(name, mesh)
+}
+
+// add adds a mesh. The lock must be held.
+func (h *Handler) add(name string, mesh *graphics.Mesh) error {
 	if _, dup := h.Items[name]; dup {
 		return core.ErrAssetExists(name)
 	}
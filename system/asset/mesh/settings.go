@@ -0,0 +1,143 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package mesh
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/haakenlabs/arc/core"
+)
+
+// ImportSettings are the import settings of a mesh, read from the import
+// section of its .meta sidecar file.
+type ImportSettings struct {
+	// Scale scales the vertices of the mesh.
+	Scale float32 `json:"scale"`
+
+	// UpAxis is the up axis of the mesh: y, or z for meshes which are
+	// converted from Z up to Y up.
+	UpAxis string `json:"up_axis"`
+
+	// Weld shares identical vertices between triangles, and uploads the mesh
+	// with indices.
+	Weld bool `json:"weld"`
+
+	// Normals selects the normals of the mesh: import keeps the normals of
+	// the file, and flat or smooth replaces them with generated normals.
+	Normals string `json:"normals"`
+
+	// SmoothingAngle is the largest angle in degrees between triangles whose
+	// normals are averaged when generating smooth normals.
+	SmoothingAngle float32 `json:"smoothing_angle"`
+
+	// Tangents generates tangents for normal mapping, for meshes with
+	// normals and texture coordinates.
+	Tangents bool `json:"tangents"`
+}
+
+// DefaultImportSettings returns the import settings of meshes without a
+// sidecar file.
+func DefaultImportSettings() ImportSettings {
+	return ImportSettings{
+		Scale:          1,
+		UpAxis:         "y",
+		Weld:           true,
+		Normals:        "import",
+		SmoothingAngle: 60,
+	}
+}
+
+// readSettings reads the import settings of a resource.
+func readSettings(r *core.Resource) (ImportSettings, error) {
+	s := DefaultImportSettings()
+
+	meta := r.Meta()
+	if err := meta.Decode(&s); err != nil {
+		return s, err
+	}
+
+	if s.Scale <= 0 {
+		return s, meta.Invalid("scale %v is not positive", s.Scale)
+	}
+	if s.UpAxis != "y" && s.UpAxis != "z" {
+		return s, meta.Invalid("unknown up_axis %q, want y or z", s.UpAxis)
+	}
+	if s.Normals != "import" && s.Normals != "flat" && s.Normals != "smooth" {
+		return s, meta.Invalid("unknown normals %q, want import, flat or smooth", s.Normals)
+	}
+	if s.SmoothingAngle < 0 || s.SmoothingAngle > 180 {
+		return s, meta.Invalid("smoothing_angle %v is not between 0 and 180", s.SmoothingAngle)
+	}
+
+	return s, nil
+}
+
+// process applies the settings to the geometry of a decoded mesh.
+func (s ImportSettings) process(d *decodedMesh) {
+	v := d.data.Vertices
+	for i := range v {
+		if s.UpAxis == "z" {
+			v[i] = zUpToYUp(v[i])
+		}
+		v[i] = v[i].Mul(s.Scale)
+	}
+
+	if s.UpAxis == "z" {
+		n := d.data.Normals
+		for i := range n {
+			n[i] = zUpToYUp(n[i])
+		}
+
+		t := d.data.Tangents
+		for i := range t {
+			t[i] = zUpToYUp(t[i].Vec3()).Vec4(t[i].W())
+		}
+	}
+
+	indexed := d.data.Indexed()
+
+	switch s.Normals {
+	case "flat":
+		d.data = d.data.FlatNormals()
+	case "smooth":
+		d.data = d.data.SmoothNormals(mgl32.DegToRad(s.SmoothingAngle))
+	}
+
+	if s.Tangents && len(d.data.Normals) != 0 && len(d.data.Uvs) != 0 {
+		// The channels are checked above, so this cannot fail.
+		d.data, _ = d.data.GenerateTangents()
+	}
+
+	// Generating normals and tangents welds the data, so unweld it again
+	// unless it was indexed to begin with.
+	if s.Weld {
+		d.data = d.data.Weld()
+	} else if !indexed && d.data.Indexed() {
+		d.data = d.data.Unweld()
+	}
+}
+
+// zUpToYUp rotates a vector from a Z up to a Y up coordinate system.
+func zUpToYUp(v mgl32.Vec3) mgl32.Vec3 {
+	return mgl32.Vec3{v[0], v[2], -v[1]}
+}
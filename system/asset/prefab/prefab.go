@@ -0,0 +1,527 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package prefab
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/haakenlabs/arc/core"
+	"github.com/haakenlabs/arc/graphics"
+	"github.com/haakenlabs/arc/pkg/gltf"
+	"github.com/haakenlabs/arc/scene"
+	"github.com/haakenlabs/arc/system/asset"
+	"github.com/haakenlabs/arc/system/asset/material"
+	"github.com/haakenlabs/arc/system/asset/mesh"
+	"github.com/haakenlabs/arc/system/asset/texture"
+)
+
+const (
+	AssetNamePrefab = "prefab"
+)
+
+var _ core.AssetHandler = &Handler{}
+
+// Handler loads glTF 2.0 scenes (.gltf and .glb) as prefabs. The meshes,
+// materials and images of a scene are added as mesh, material and texture
+// assets named by the file followed by a slash and their name, and are
+// released along with the prefab.
+type Handler struct {
+	core.BaseAssetHandler
+
+	owned map[string][]core.AssetRef
+}
+
+// Load will load data from the reader.
+func (h *Handler) Load(r *core.Resource) error {
+	name := r.Base()
+
+	h.Mu.RLock()
+	_, dup := h.Items[name]
+	h.Mu.RUnlock()
+
+	if dup {
+		return core.ErrAssetExists(name)
+	}
+
+	doc, err := gltf.Decode(r.Reader(), func(uri string) ([]byte, error) {
+		res, err := core.NewResource(filepath.Join(r.DirPrefix(), uri))
+		if err != nil {
+			return nil, err
+		}
+		if err := asset.ReadResource(res); err != nil {
+			return nil, err
+		}
+
+		return res.Bytes(), nil
+	})
+	if err != nil {
+		return errors.Annotate(err, r.Location())
+	}
+
+	imp, err := newImporter(name, doc)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := imp.nodes(rootNodes(doc))
+	if err != nil {
+		imp.release()
+		return errors.Annotate(err, r.Location())
+	}
+
+	p := scene.NewPrefab(name, nodes)
+
+	h.Mu.Lock()
+	h.Items[name] = p.ID()
+	h.owned[name] = imp.owned
+	h.Mu.Unlock()
+
+	return nil
+}
+
+// Release removes a reference to a prefab. Once the prefab is removed, the
+// assets which were created for it are released.
+func (h *Handler) Release(name string) (bool, error) {
+	removed, err := h.BaseAssetHandler.Release(name)
+	if removed {
+		h.releaseOwned(name)
+	}
+
+	return removed, err
+}
+
+// Remove removes a prefab regardless of its references, and releases the
+// assets which were created for it.
+func (h *Handler) Remove(name string) error {
+	if err := h.BaseAssetHandler.Remove(name); err != nil {
+		return err
+	}
+
+	h.releaseOwned(name)
+
+	return nil
+}
+
+// Unload removes all prefabs, and releases the assets which were created for
+// them.
+func (h *Handler) Unload() {
+	for _, name := range h.Names() {
+		if err := h.Remove(name); err != nil {
+			logrus.Error(err)
+		}
+	}
+}
+
+func (h *Handler) releaseOwned(name string) {
+	h.Mu.Lock()
+	owned := h.owned[name]
+	delete(h.owned, name)
+	h.Mu.Unlock()
+
+	for _, ref := range owned {
+		if err := asset.Release(ref.Kind, ref.Name); err != nil {
+			logrus.Debug(err)
+		}
+	}
+}
+
+// Get gets an asset by name.
+func (h *Handler) Get(name string) (*scene.Prefab, error) {
+	a, err := h.GetAsset(name)
+	if err != nil {
+		return nil, err
+	}
+
+	a2, ok := a.(*scene.Prefab)
+	if !ok {
+		return nil, core.ErrAssetType(name)
+	}
+
+	return a2, nil
+}
+
+// MustGet is like GetAsset, but panics if an error occurs.
+func (h *Handler) MustGet(name string) *scene.Prefab {
+	a, err := h.Get(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return a
+}
+
+func (h *Handler) Name() string {
+	return AssetNamePrefab
+}
+
+func NewHandler() *Handler {
+	h := &Handler{}
+	h.Items = make(map[string]int32)
+	h.Mu = &sync.RWMutex{}
+	h.owned = make(map[string][]core.AssetRef)
+
+	return h
+}
+
+func Get(name string) (*scene.Prefab, error) {
+	return mustHandler().Get(name)
+}
+
+func MustGet(name string) *scene.Prefab {
+	return mustHandler().MustGet(name)
+}
+
+// Instantiate creates a hierarchy of game objects from a prefab by name.
+func Instantiate(name string) (*scene.GameObject, error) {
+	p, err := Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Instantiate(), nil
+}
+
+func mustHandler() *Handler {
+	h, err := asset.GetHandler(AssetNamePrefab)
+	if err != nil {
+		panic(err)
+	}
+
+	return h.(*Handler)
+}
+
+// rootNodes returns the root nodes of the scene of a document. If the
+// document has no scene, all nodes without a parent are roots.
+func rootNodes(doc *gltf.Document) []int {
+	if doc.Scene >= 0 {
+		return doc.Scenes[doc.Scene].Nodes
+	}
+
+	child := make([]bool, len(doc.Nodes))
+	for _, n := range doc.Nodes {
+		for _, c := range n.Children {
+			child[c] = true
+		}
+	}
+
+	var roots []int
+	for i := range doc.Nodes {
+		if !child[i] {
+			roots = append(roots, i)
+		}
+	}
+
+	return roots
+}
+
+// importer creates the assets of a document as they are used by its nodes.
+type importer struct {
+	name string
+	doc  *gltf.Document
+
+	meshes    *mesh.Handler
+	materials *material.Handler
+	textures  *texture.Handler
+
+	names        map[string]bool
+	parts        map[int][]scene.PrefabPart
+	materialObjs map[int]*scene.Material
+	textureObjs  map[int]*graphics.Texture2D
+	owned        []core.AssetRef
+}
+
+func newImporter(name string, doc *gltf.Document) (*importer, error) {
+	imp := &importer{
+		name:         name,
+		doc:          doc,
+		names:        make(map[string]bool),
+		parts:        make(map[int][]scene.PrefabPart),
+		materialObjs: make(map[int]*scene.Material),
+		textureObjs:  make(map[int]*graphics.Texture2D),
+	}
+
+	handlers := []struct {
+		kind string
+		dst  interface{}
+	}{
+		{mesh.AssetNameMesh, &imp.meshes},
+		{material.AssetNameMaterial, &imp.materials},
+		{texture.AssetNameTexture, &imp.textures},
+	}
+
+	for _, v := range handlers {
+		h, err := asset.GetHandler(v.kind)
+		if err != nil {
+			return nil, err
+		}
+
+		var ok bool
+		switch dst := v.dst.(type) {
+		case **mesh.Handler:
+			*dst, ok = h.(*mesh.Handler)
+		case **material.Handler:
+			*dst, ok = h.(*material.Handler)
+		case **texture.Handler:
+			*dst, ok = h.(*texture.Handler)
+		}
+		if !ok {
+			return nil, errors.Errorf("prefab: unexpected %s handler %T", v.kind, h)
+		}
+	}
+
+	return imp, nil
+}
+
+// assetName returns a unique name for an asset of the prefab, from the name of
+// an element of the document or its kind and index.
+func (imp *importer) assetName(name, kind string, index int) string {
+	if name == "" {
+		name = fmt.Sprintf("%s%d", kind, index)
+	}
+
+	full := imp.name + "/" + name
+	for i := 1; imp.names[full]; i++ {
+		full = fmt.Sprintf("%s/%s.%d", imp.name, name, i)
+	}
+	imp.names[full] = true
+
+	return full
+}
+
+// own acquires an asset which was created for the prefab.
+func (imp *importer) own(kind, name string) error {
+	if _, err := asset.Acquire(kind, name); err != nil {
+		return err
+	}
+
+	imp.owned = append(imp.owned, core.AssetRef{Kind: kind, Name: name})
+
+	return nil
+}
+
+// release releases the assets which were created, after an error.
+func (imp *importer) release() {
+	for _, ref := range imp.owned {
+		if err := asset.Release(ref.Kind, ref.Name); err != nil {
+			logrus.Debug(err)
+		}
+	}
+	imp.owned = nil
+}
+
+func (imp *importer) nodes(indices []int) ([]*scene.PrefabNode, error) {
+	nodes := make([]*scene.PrefabNode, len(indices))
+
+	for i, index := range indices {
+		n := imp.doc.Nodes[index]
+
+		node := &scene.PrefabNode{
+			Name:     n.Name,
+			Position: n.Translation,
+			Rotation: n.Rotation,
+			Scale:    n.Scale,
+		}
+		if node.Name == "" {
+			node.Name = fmt.Sprintf("node%d", index)
+		}
+
+		if n.Mesh >= 0 {
+			parts, err := imp.mesh(n.Mesh)
+			if err != nil {
+				return nil, err
+			}
+			node.Parts = parts
+		}
+
+		if n.Camera >= 0 {
+			c := imp.doc.Cameras[n.Camera]
+
+			// Cameras only support screen space orthographic projections,
+			// so orthographic cameras keep the default field of view.
+			node.Camera = &scene.PrefabCamera{Fov: c.Yfov, NearClip: c.Znear, FarClip: c.Zfar}
+			if c.Orthographic {
+				node.Camera.Fov = scene.DefaultCameraFov
+			}
+		}
+
+		children, err := imp.nodes(n.Children)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = children
+
+		nodes[i] = node
+	}
+
+	return nodes, nil
+}
+
+// mesh returns the parts of a mesh, and adds a mesh asset for each of its
+// primitives the first time it is used.
+func (imp *importer) mesh(index int) ([]scene.PrefabPart, error) {
+	if parts, ok := imp.parts[index]; ok {
+		return parts, nil
+	}
+
+	m := imp.doc.Meshes[index]
+	parts := make([]scene.PrefabPart, len(m.Primitives))
+
+	base := m.Name
+	if base == "" {
+		base = fmt.Sprintf("mesh%d", index)
+	}
+
+	for i, p := range m.Primitives {
+		name := base
+		if len(m.Primitives) > 1 {
+			name = fmt.Sprintf("%s/%d", base, i)
+		}
+		name = imp.assetName(name, "", 0)
+
+		// Primitives with computed normals are not indexed, but vertices of
+		// coplanar triangles can still be shared.
+		data := &p.Data
+		if !data.Indexed() {
+			data = data.Weld()
+		}
+
+		gm := graphics.NewMesh()
+		gm.SetName(name)
+		gm.SetData(data)
+
+		mat, err := imp.material(p.Material)
+		if err != nil {
+			return nil, err
+		}
+		gm.SetMaterialName(mat.Name())
+
+		if err := imp.meshes.Add(name, gm); err != nil {
+			return nil, err
+		}
+		if err := imp.own(mesh.AssetNameMesh, name); err != nil {
+			return nil, err
+		}
+
+		parts[i] = scene.PrefabPart{Mesh: gm, Material: mat}
+	}
+
+	imp.parts[index] = parts
+
+	return parts, nil
+}
+
+// material returns a material, and adds it as a material asset the first
+// time it is used. Primitives without a material use a default material.
+func (imp *importer) material(index int) (*scene.Material, error) {
+	if m, ok := imp.materialObjs[index]; ok {
+		return m, nil
+	}
+
+	mat := scene.NewMaterialPBR()
+
+	var name string
+	if index < 0 {
+		name = imp.assetName("default", "", 0)
+		mat.SetProperty("f_albedo", mgl32.Vec3{1, 1, 1})
+		mat.SetProperty("f_metallic", float32(0))
+		mat.SetProperty("f_roughness", float32(1))
+	} else {
+		m := imp.doc.Materials[index]
+		name = imp.assetName(m.Name, "material", index)
+
+		mat.SetProperty("f_albedo", m.BaseColor.Vec3())
+		mat.SetProperty("f_metallic", m.Metallic)
+		mat.SetProperty("f_roughness", m.Roughness)
+
+		textures := []struct {
+			id    scene.MaterialTexture
+			image int
+			srgb  bool
+		}{
+			{scene.MaterialTextureAlbedo, m.BaseColorTexture, true},
+			{scene.MaterialTextureNormal, m.NormalTexture, false},
+			{scene.MaterialTextureMetallic, m.MetallicRoughnessTexture, false},
+		}
+
+		for _, v := range textures {
+			if v.image < 0 {
+				continue
+			}
+
+			t, err := imp.texture(v.image, v.srgb)
+			if err != nil {
+				return nil, err
+			}
+			mat.SetTexture(v.id, t)
+		}
+	}
+
+	mat.SetName(name)
+
+	if err := imp.materials.Add(name, mat); err != nil {
+		return nil, err
+	}
+	if err := imp.own(material.AssetNameMaterial, name); err != nil {
+		return nil, err
+	}
+
+	imp.materialObjs[index] = mat
+
+	return mat, nil
+}
+
+// texture returns the texture of an image, and adds it as a texture asset
+// the first time it is used. Images are uploaded as other textures are, since
+// the decoder converts the texture coordinates to the engine's origin.
+func (imp *importer) texture(index int, srgb bool) (*graphics.Texture2D, error) {
+	if t, ok := imp.textureObjs[index]; ok {
+		return t, nil
+	}
+
+	img := imp.doc.Images[index]
+
+	name := img.Name
+	if name == "" && img.URI != "" && !strings.HasPrefix(img.URI, "data:") {
+		name = filepath.Base(img.URI)
+	}
+	name = imp.assetName(name, "image", index)
+
+	t, err := imp.textures.Embed(name, img.Data, srgb)
+	if err != nil {
+		return nil, errors.Annotatef(err, "image %d", index)
+	}
+	if err := imp.own(texture.AssetNameTexture, name); err != nil {
+		return nil, err
+	}
+
+	imp.textureObjs[index] = t
+
+	return t, nil
+}
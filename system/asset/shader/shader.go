@@ -24,6 +24,7 @@ package shader
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"sync"
@@ -31,6 +32,7 @@ import (
 	"github.com/haakenlabs/arc/core"
 	"github.com/haakenlabs/arc/graphics"
 	"github.com/haakenlabs/arc/system/asset"
+	"github.com/haakenlabs/arc/system/window"
 )
 
 const (
@@ -38,9 +40,13 @@ const (
 )
 
 var _ core.AssetHandler = &Handler{}
+var _ core.AssetReloader = &Handler{}
+var _ core.AssetSourcer = &Handler{}
 
 type Handler struct {
 	core.BaseAssetHandler
+
+	sources map[string][]string
 }
 
 type Metadata struct {
@@ -51,16 +57,11 @@ type Metadata struct {
 
 // Load will load data from the reader.
 func (h *Handler) Load(r *core.Resource) error {
-	m := &Metadata{}
-
-	data, err := ioutil.ReadAll(r.Reader())
+	m, data, sources, err := h.read(r)
 	if err != nil {
 		return err
 	}
 
-	if err := json.Unmarshal(data, m); err != nil {
-		return err
-	}
 	name := m.Name
 	if _, dup := h.Items[name]; dup {
 		return core.ErrAssetExists(name)
@@ -69,21 +70,90 @@ func (h *Handler) Load(r *core.Resource) error {
 	s := graphics.NewShader(m.Deferred)
 
 	s.SetName(m.Name)
+	s.AddData(data)
+
+	if err := h.Add(name, s); err != nil {
+		return err
+	}
+
+	h.setSources(name, sources)
+
+	return nil
+}
+
+// Reload rebuilds an existing shader from the resource. If the shader fails to
+// compile, it keeps its previous program.
+func (h *Handler) Reload(name string, r *core.Resource) error {
+	m, data, sources, err := h.read(r)
+	if err != nil {
+		return err
+	}
+
+	if m.Name != name {
+		return fmt.Errorf("shader: reloaded shader %s is named %s", name, m.Name)
+	}
+
+	s, err := h.Get(name)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Reload(data); err != nil {
+		return err
+	}
+
+	h.setSources(name, sources)
+
+	return nil
+}
+
+// Sources returns the locations of the shader source files of a shader.
+func (h *Handler) Sources(name string) []string {
+	h.Mu.RLock()
+	defer h.Mu.RUnlock()
+
+	return h.sources[name]
+}
+
+// read reads the metadata of a shader, and the shader source files it lists.
+func (h *Handler) read(r *core.Resource) (*Metadata, []byte, []string, error) {
+	m := &Metadata{}
+
+	data, err := ioutil.ReadAll(r.Reader())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var src []byte
+	sources := make([]string, len(m.Files))
 
 	// Populate shader data.
 	for i := range m.Files {
-		r, err := core.NewResource(filepath.Join(r.DirPrefix(), m.Files[i]))
+		sources[i] = filepath.Join(r.DirPrefix(), m.Files[i])
+
+		r, err := core.NewResource(sources[i])
 		if err != nil {
-			return err
+			return nil, nil, nil, err
 		}
 		if err := asset.ReadResource(r); err != nil {
-			return err
+			return nil, nil, nil, err
 		}
 
-		s.AddData(r.Bytes())
+		src = append(src, r.Bytes()...)
 	}
 
-	return h.Add(name, s)
+	return m, src, sources, nil
+}
+
+func (h *Handler) setSources(name string, sources []string) {
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+
+	h.sources[name] = sources
 }
 
 func (h *Handler) Add(name string, shader *graphics.Shader) error {
@@ -130,7 +200,9 @@ func (h *Handler) Name() string {
 }
 
 func NewHandler() *Handler {
-	h := &Handler{}
+	h := &Handler{
+		sources: make(map[string][]string),
+	}
 	h.Items = make(map[string]int32)
 	h.Mu = &sync.RWMutex{}
 
@@ -145,7 +217,13 @@ func NewShaderUtilsSkybox() *graphics.Shader {
 	return MustGet("utils/skybox")
 }
 
+// DefaultShader returns the standard shader. Headless apps have no OpenGL
+// context and load no shaders, so it returns nil for them.
 func DefaultShader() *graphics.Shader {
+	if window.Headless() {
+		return nil
+	}
+
 	return MustGet("standard")
 }
 
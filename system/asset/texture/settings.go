@@ -0,0 +1,224 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package texture
+
+import (
+	"github.com/go-gl/gl/v4.3-core/gl"
+
+	"github.com/haakenlabs/arc/core"
+	"github.com/haakenlabs/arc/graphics"
+	"github.com/haakenlabs/arc/pkg/math"
+)
+
+// ImportSettings are the import settings of a texture, read from the import
+// section of its .meta sidecar file.
+type ImportSettings struct {
+	// Filter is the filtering of the texture: nearest or linear.
+	Filter string `json:"filter"`
+
+	// Wrap is how texture coordinates outside of the texture are wrapped:
+	// clamp, repeat or mirror.
+	Wrap string `json:"wrap"`
+
+	// Mipmaps generates the mipmaps of the texture.
+	Mipmaps bool `json:"mipmaps"`
+
+	// SRGB stores the texture in sRGB color space, so that it is converted
+	// to linear color space when sampled. It requires an 8 bit RGBA image.
+	SRGB bool `json:"srgb"`
+
+	// Anisotropy is the maximum anisotropy of filtering, from 1 to 16. Zero
+	// leaves the default of the driver.
+	Anisotropy float32 `json:"anisotropy"`
+
+	// MaxSize limits the width and height of the texture. Larger images are
+	// halved in size until they fit. Zero does not limit the size.
+	MaxSize int32 `json:"max_size"`
+
+	// FlipY flips the image vertically.
+	FlipY bool `json:"flip_y"`
+}
+
+var filters = map[string][2]int32{
+	"nearest": {gl.NEAREST, gl.NEAREST_MIPMAP_NEAREST},
+	"linear":  {gl.LINEAR, gl.LINEAR_MIPMAP_LINEAR},
+}
+
+var wraps = map[string]int32{
+	"clamp":  gl.CLAMP_TO_EDGE,
+	"repeat": gl.REPEAT,
+	"mirror": gl.MIRRORED_REPEAT,
+}
+
+// DefaultImportSettings returns the import settings of textures without a
+// sidecar file.
+func DefaultImportSettings() ImportSettings {
+	return ImportSettings{
+		Filter: "linear",
+		Wrap:   "clamp",
+	}
+}
+
+// readSettings reads the import settings of a resource.
+func readSettings(r *core.Resource) (ImportSettings, error) {
+	s := DefaultImportSettings()
+
+	meta := r.Meta()
+	if err := meta.Decode(&s); err != nil {
+		return s, err
+	}
+
+	if _, ok := filters[s.Filter]; !ok {
+		return s, meta.Invalid("unknown filter %q, want nearest or linear", s.Filter)
+	}
+	if _, ok := wraps[s.Wrap]; !ok {
+		return s, meta.Invalid("unknown wrap %q, want clamp, repeat or mirror", s.Wrap)
+	}
+	if s.Anisotropy != 0 && (s.Anisotropy < 1 || s.Anisotropy > 16) {
+		return s, meta.Invalid("anisotropy %v is not between 1 and 16", s.Anisotropy)
+	}
+	if s.MaxSize < 0 {
+		return s, meta.Invalid("max_size %d is negative", s.MaxSize)
+	}
+
+	return s, nil
+}
+
+// process applies the settings which change the pixels of a decoded texture.
+func (s ImportSettings) process(d *decodedTexture, meta *core.AssetMeta) error {
+	if s.SRGB {
+		if d.format != graphics.TextureFormatRGBA8 {
+			return meta.Invalid("srgb requires an 8 bit RGBA image")
+		}
+		d.format = graphics.TextureFormatSRGBA8
+	}
+
+	if s.FlipY {
+		flipY(d.pix, d.size.Y())
+	}
+
+	if s.MaxSize > 0 {
+		depth := 1
+		switch d.format {
+		case graphics.TextureFormatR16, graphics.TextureFormatRG16, graphics.TextureFormatRGBA16:
+			depth = 2
+		}
+
+		for d.size.X() > s.MaxSize || d.size.Y() > s.MaxSize {
+			d.pix, d.size = halve(d.pix, d.size, depth)
+		}
+	}
+
+	return nil
+}
+
+// apply applies the settings which change the sampling of a texture. The
+// texture must be allocated.
+func (s ImportSettings) apply(t *graphics.Texture2D) {
+	t.Bind()
+
+	filter := filters[s.Filter]
+	if s.Mipmaps {
+		t.SetFilter(filter[0], filter[1])
+	} else {
+		t.SetFilter(filter[0], filter[0])
+	}
+
+	t.SetWrapST(wraps[s.Wrap], wraps[s.Wrap])
+
+	if s.Anisotropy != 0 {
+		t.SetAnisotropy(s.Anisotropy)
+	}
+	if s.Mipmaps {
+		t.GenerateMipmaps()
+	}
+}
+
+// flipY reverses the rows of an image.
+func flipY(pix []uint8, height int32) {
+	if height < 2 {
+		return
+	}
+
+	stride := len(pix) / int(height)
+	row := make([]uint8, stride)
+
+	for top, bottom := 0, int(height)-1; top < bottom; top, bottom = top+1, bottom-1 {
+		a := pix[top*stride : (top+1)*stride]
+		b := pix[bottom*stride : (bottom+1)*stride]
+
+		copy(row, a)
+		copy(a, b)
+		copy(b, row)
+	}
+}
+
+// halve halves the size of an image by averaging each 2x2 block of pixels.
+// Channels are depth bytes each, big endian as in the image package.
+func halve(pix []uint8, size math.IVec2, depth int) ([]uint8, math.IVec2) {
+	w, h := int(size.X()), int(size.Y())
+	bpp := len(pix) / (w * h)
+
+	nw, nh := w/2, h/2
+	if nw < 1 {
+		nw = 1
+	}
+	if nh < 1 {
+		nh = 1
+	}
+
+	out := make([]uint8, nw*nh*bpp)
+
+	sample := func(x, y, c int) int {
+		if x >= w {
+			x = w - 1
+		}
+		if y >= h {
+			y = h - 1
+		}
+
+		i := (y*w+x)*bpp + c
+		if depth == 2 {
+			return int(pix[i])<<8 | int(pix[i+1])
+		}
+
+		return int(pix[i])
+	}
+
+	for y := 0; y < nh; y++ {
+		for x := 0; x < nw; x++ {
+			for c := 0; c < bpp; c += depth {
+				v := (sample(2*x, 2*y, c) + sample(2*x+1, 2*y, c) + sample(2*x, 2*y+1, c) + sample(2*x+1, 2*y+1, c) + 2) / 4
+
+				i := (y*nw+x)*bpp + c
+				if depth == 2 {
+					out[i], out[i+1] = uint8(v>>8), uint8(v)
+				} else {
+					out[i] = uint8(v)
+				}
+			}
+		}
+	}
+
+	return out, math.IVec2{int32(nw), int32(nh)}
+}
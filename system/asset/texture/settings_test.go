@@ -0,0 +1,179 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package texture
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/haakenlabs/arc/core"
+	"github.com/haakenlabs/arc/graphics"
+	"github.com/haakenlabs/arc/pkg/math"
+)
+
+func TestFlipY(t *testing.T) {
+	tests := []struct {
+		pix    []uint8
+		height int32
+		want   []uint8
+	}{
+		{[]uint8{1, 2}, 1, []uint8{1, 2}},
+		{[]uint8{1, 2, 3, 4}, 2, []uint8{3, 4, 1, 2}},
+		{[]uint8{1, 2, 3}, 3, []uint8{3, 2, 1}},
+		{[]uint8{1, 1, 2, 2, 3, 3}, 3, []uint8{3, 3, 2, 2, 1, 1}},
+	}
+
+	for i, v := range tests {
+		flipY(v.pix, v.height)
+
+		if !reflect.DeepEqual(v.pix, v.want) {
+			t.Errorf("Test %d: got: %v want: %v", i, v.pix, v.want)
+		}
+	}
+}
+
+func TestHalve(t *testing.T) {
+	tests := []struct {
+		pix   []uint8
+		size  math.IVec2
+		depth int
+		want  []uint8
+		wsize math.IVec2
+	}{
+		{[]uint8{0, 4, 8, 12}, math.IVec2{2, 2}, 1, []uint8{6}, math.IVec2{1, 1}},
+		// Odd sizes drop the last row and column.
+		{[]uint8{0, 4, 99, 8, 12, 99, 99, 99, 99}, math.IVec2{3, 3}, 1, []uint8{6}, math.IVec2{1, 1}},
+		// A side of one pixel stays one pixel.
+		{[]uint8{0, 2, 4, 6}, math.IVec2{1, 4}, 1, []uint8{1, 5}, math.IVec2{1, 2}},
+		// Two channels of one byte are averaged separately.
+		{[]uint8{0, 10, 2, 20}, math.IVec2{2, 1}, 1, []uint8{1, 15}, math.IVec2{1, 1}},
+		// 16 bit channels are averaged as big endian values.
+		{[]uint8{0x01, 0x00, 0x03, 0x00}, math.IVec2{2, 1}, 2, []uint8{0x02, 0x00}, math.IVec2{1, 1}},
+		{[]uint8{0x00, 0xff, 0x00, 0x01}, math.IVec2{2, 1}, 2, []uint8{0x00, 0x80}, math.IVec2{1, 1}},
+	}
+
+	for i, v := range tests {
+		pix, size := halve(v.pix, v.size, v.depth)
+
+		if !reflect.DeepEqual(pix, v.want) || size != v.wsize {
+			t.Errorf("Test %d: got: %v %v want: %v %v", i, pix, size, v.want, v.wsize)
+		}
+	}
+}
+
+func TestImportSettings_Process(t *testing.T) {
+	tests := []struct {
+		size    math.IVec2
+		format  graphics.TextureFormat
+		bpp     int
+		maxSize int32
+		want    math.IVec2
+	}{
+		{math.IVec2{4, 4}, graphics.TextureFormatRGBA8, 4, 0, math.IVec2{4, 4}},
+		{math.IVec2{4, 4}, graphics.TextureFormatRGBA8, 4, 4, math.IVec2{4, 4}},
+		{math.IVec2{5, 3}, graphics.TextureFormatRGBA8, 4, 2, math.IVec2{2, 1}},
+		{math.IVec2{8, 2}, graphics.TextureFormatRGBA8, 4, 3, math.IVec2{2, 1}},
+		{math.IVec2{4, 4}, graphics.TextureFormatRGBA16, 8, 2, math.IVec2{2, 2}},
+		{math.IVec2{4, 1}, graphics.TextureFormatR16, 2, 1, math.IVec2{1, 1}},
+	}
+
+	for i, v := range tests {
+		d := &decodedTexture{
+			size:   v.size,
+			format: v.format,
+			pix:    make([]uint8, int(v.size.X()*v.size.Y())*v.bpp),
+		}
+
+		s := DefaultImportSettings()
+		s.MaxSize = v.maxSize
+
+		if err := s.process(d, nil); err != nil {
+			t.Errorf("Test %d: got: %v want: nil", i, err)
+			continue
+		}
+		if d.size != v.want || len(d.pix) != int(v.want.X()*v.want.Y())*v.bpp {
+			t.Errorf("Test %d: got: %v, %d bytes want: %v", i, d.size, len(d.pix), v.want)
+		}
+	}
+
+	d := &decodedTexture{size: math.IVec2{1, 1}, format: graphics.TextureFormatRGBA16, pix: make([]uint8, 8)}
+	if err := (ImportSettings{SRGB: true}).process(d, nil); err == nil {
+		t.Error("srgb 16 bit got: nil want: error")
+	}
+}
+
+func TestReadSettings(t *testing.T) {
+	const guid = `"guid": "0f8fad5b-d9cb-469f-a165-70867728950e"`
+
+	tests := []struct {
+		meta string
+		want ImportSettings
+		err  bool
+	}{
+		{"", DefaultImportSettings(), false},
+		{`{` + guid + `}`, DefaultImportSettings(), false},
+		{
+			`{` + guid + `, "import": {"filter": "nearest", "wrap": "repeat", "mipmaps": true, "anisotropy": 16, "max_size": 512, "flip_y": true}}`,
+			ImportSettings{Filter: "nearest", Wrap: "repeat", Mipmaps: true, Anisotropy: 16, MaxSize: 512, FlipY: true},
+			false,
+		},
+		{`{` + guid + `, "import": {"mipmap": true}}`, ImportSettings{}, true},
+		{`{` + guid + `, "import": {"max_size": "large"}}`, ImportSettings{}, true},
+		{`{` + guid + `, "import": {"filter": "cubic"}}`, ImportSettings{}, true},
+		{`{` + guid + `, "import": {"wrap": "border"}}`, ImportSettings{}, true},
+		{`{` + guid + `, "import": {"anisotropy": 0.5}}`, ImportSettings{}, true},
+		{`{` + guid + `, "import": {"anisotropy": 17}}`, ImportSettings{}, true},
+		{`{` + guid + `, "import": {"max_size": -1}}`, ImportSettings{}, true},
+	}
+
+	for i, v := range tests {
+		files := fstest.MapFS{"a.png": {Data: []byte("png")}}
+		if v.meta != "" {
+			files["a.png"+core.AssetMetaExtension] = &fstest.MapFile{Data: []byte(v.meta)}
+		}
+
+		a := core.NewAssetSystem()
+		a.Mount("test", "mem", files, 0)
+
+		r, _ := core.NewResource("test:a.png")
+		if err := a.ReadResource(r); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+
+		s, err := readSettings(r)
+		if (err != nil) != v.err {
+			t.Errorf("Test %d: got: %v want error: %v", i, err, v.err)
+			continue
+		}
+
+		var metaErr core.ErrAssetMeta
+		if err != nil && !errors.As(err, &metaErr) {
+			t.Errorf("Test %d: got: %T want: core.ErrAssetMeta", i, err)
+		}
+		if err == nil && s != v.want {
+			t.Errorf("Test %d: got: %+v want: %+v", i, s, v.want)
+		}
+	}
+}
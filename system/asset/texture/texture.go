@@ -23,10 +23,12 @@ SOFTWARE.
 package texture
 
 import (
+	"bytes"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
+	"io"
 	"sync"
 
 	"github.com/haakenlabs/arc/core"
@@ -45,87 +47,211 @@ const (
 )
 
 var _ core.AssetHandler = &Handler{}
+var _ core.AssetDecoder = &Handler{}
+var _ core.AssetReloader = &Handler{}
 
 type Handler struct {
 	core.BaseAssetHandler
 }
 
+// decodedTexture holds the pixels of a decoded image, and its import
+// settings.
+type decodedTexture struct {
+	size     math.IVec2
+	format   graphics.TextureFormat
+	pix      []uint8
+	settings ImportSettings
+}
+
 // Load will load data from the reader.
 func (h *Handler) Load(r *core.Resource) error {
-	var texture *graphics.Texture2D
-	var img image.Image
+	v, err := h.Decode(r)
+	if err != nil {
+		return err
+	}
+
+	return h.Upload(r, v)
+}
 
+// Decode decodes the image in the resource. It may be called from any
+// goroutine.
+func (h *Handler) Decode(r *core.Resource) (interface{}, error) {
 	name := r.Base()
 
-	if _, dup := h.Items[r.Base()]; dup {
-		return core.ErrAssetExists(name)
+	h.Mu.RLock()
+	_, dup := h.Items[name]
+	h.Mu.RUnlock()
+
+	if dup {
+		return nil, core.ErrAssetExists(name)
+	}
+
+	return decode(r)
+}
+
+// Reload decodes the image in the resource and uploads it to an existing
+// texture, and applies the import settings of the resource.
+func (h *Handler) Reload(name string, r *core.Resource) error {
+	d, err := decode(r)
+	if err != nil {
+		return err
 	}
 
-	img, _, err := image.Decode(r.Reader())
+	t, err := h.Get(name)
 	if err != nil {
 		return err
 	}
 
-	x := int32(img.Bounds().Dx())
-	y := int32(img.Bounds().Dy())
+	if d.size != t.Size() && !t.Resizable() {
+		return fmt.Errorf("texture: cannot resize texture %s to %s", name, d.size)
+	}
+
+	t.SetTexFormat(d.format)
+	t.SetData(d.pix)
+
+	if d.size != t.Size() {
+		if err := t.SetSize(d.size); err != nil {
+			return err
+		}
+	} else {
+		t.Upload()
+	}
+
+	d.settings.apply(t)
+
+	return nil
+}
+
+// decode decodes the image in a resource, and applies its import settings.
+func decode(r *core.Resource) (*decodedTexture, error) {
+	settings, err := readSettings(r)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := decodeImage(r.Reader())
+	if err != nil {
+		return nil, err
+	}
+	d.settings = settings
+
+	if err := settings.process(d, r.Meta()); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// decodeImage decodes an image into the pixels of a texture.
+func decodeImage(rd io.Reader) (*decodedTexture, error) {
+	img, _, err := image.Decode(rd)
+	if err != nil {
+		return nil, err
+	}
 
-	texture = graphics.NewTexture2D(math.IVec2{x, y}, graphics.TextureFormatDefaultColor)
+	d := &decodedTexture{
+		size: math.IVec2{int32(img.Bounds().Dx()), int32(img.Bounds().Dy())},
+	}
 
 	switch img.ColorModel() {
 	// 4 channels, 16 bits per channel
 	case color.RGBA64Model:
 		rgba := image.NewRGBA64(img.Bounds())
 		draw.Draw(rgba, rgba.Bounds(), img, image.Point{}, draw.Src)
-		texture.SetTexFormat(graphics.TextureFormatRGBA16)
-		texture.SetData(rgba.Pix)
+		d.format, d.pix = graphics.TextureFormatRGBA16, rgba.Pix
 		// 4 channels, 8 bits per channel
 	case color.RGBAModel:
 		rgba := image.NewRGBA(img.Bounds())
 		draw.Draw(rgba, rgba.Bounds(), img, image.Point{}, draw.Src)
-		texture.SetTexFormat(graphics.TextureFormatRGBA8)
-		texture.SetData(rgba.Pix)
+		d.format, d.pix = graphics.TextureFormatRGBA8, rgba.Pix
 		// 2 channels, 16 bits per channel
 	case color.Alpha16Model:
 		alpha := image.NewAlpha16(img.Bounds())
 		draw.Draw(alpha, alpha.Bounds(), img, image.Point{}, draw.Src)
-		texture.SetTexFormat(graphics.TextureFormatRG16)
-		texture.SetData(alpha.Pix)
+		d.format, d.pix = graphics.TextureFormatRG16, alpha.Pix
 		// 2 channels, 8 bits per channel
 	case color.AlphaModel:
 		alpha := image.NewAlpha(img.Bounds())
 		draw.Draw(alpha, alpha.Bounds(), img, image.Point{}, draw.Src)
-		texture.SetTexFormat(graphics.TextureFormatRG8)
-		texture.SetData(alpha.Pix)
+		d.format, d.pix = graphics.TextureFormatRG8, alpha.Pix
 		// 1 channel, 16 bits per channel
 	case color.Gray16Model:
 		gray := image.NewGray16(img.Bounds())
 		draw.Draw(gray, gray.Bounds(), img, image.Point{}, draw.Src)
-		texture.SetTexFormat(graphics.TextureFormatR16)
-		texture.SetData(gray.Pix)
+		d.format, d.pix = graphics.TextureFormatR16, gray.Pix
 		// 1 channel, 16 bits per channel
 	case color.GrayModel:
 		gray := image.NewGray(img.Bounds())
 		draw.Draw(gray, gray.Bounds(), img, image.Point{}, draw.Src)
-		texture.SetTexFormat(graphics.TextureFormatR8)
-		texture.SetData(gray.Pix)
+		d.format, d.pix = graphics.TextureFormatR8, gray.Pix
 	case color.NRGBA64Model:
 		rgba := image.NewNRGBA64(img.Bounds())
 		draw.Draw(rgba, rgba.Bounds(), img, image.Point{}, draw.Src)
-		texture.SetTexFormat(graphics.TextureFormatRGBA16)
-		texture.SetData(rgba.Pix)
+		d.format, d.pix = graphics.TextureFormatRGBA16, rgba.Pix
 	case color.NRGBAModel:
 		rgba := image.NewNRGBA(img.Bounds())
 		draw.Draw(rgba, rgba.Bounds(), img, image.Point{}, draw.Src)
-		texture.SetTexFormat(graphics.TextureFormatRGBA8)
-		texture.SetData(rgba.Pix)
+		d.format, d.pix = graphics.TextureFormatRGBA8, rgba.Pix
+		// JPEG images, converted to 4 channels, 8 bits per channel
+	case color.YCbCrModel, color.CMYKModel:
+		rgba := image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), img, image.Point{}, draw.Src)
+		d.format, d.pix = graphics.TextureFormatRGBA8, rgba.Pix
 	default:
-		return fmt.Errorf("invalid color format: %v", img.ColorModel())
+		return nil, fmt.Errorf("invalid color format: %v", img.ColorModel())
+	}
+
+	return d, nil
+}
+
+// Upload creates and allocates a texture decoded by Decode. It must be called
+// from the main thread.
+func (h *Handler) Upload(r *core.Resource, v interface{}) error {
+	_, err := h.upload(r.Base(), v.(*decodedTexture))
+
+	return err
+}
+
+// Embed decodes an image embedded in another asset, such as a model, and adds
+// it as a texture with mipmaps. If srgb is true and the image is 8 bit RGBA,
+// the texture is sampled as sRGB. It must be called from the main thread.
+func (h *Handler) Embed(name string, data []byte, srgb bool) (*graphics.Texture2D, error) {
+	d, err := decodeImage(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	d.settings = DefaultImportSettings()
+	d.settings.Wrap = "repeat"
+	d.settings.Mipmaps = true
+	d.settings.SRGB = srgb && d.format == graphics.TextureFormatRGBA8
+
+	if err := d.settings.process(d, nil); err != nil {
+		return nil, err
 	}
 
-	return h.Add(name, texture)
+	return h.upload(name, d)
+}
+
+// upload creates and allocates a decoded texture, and adds it by name.
+func (h *Handler) upload(name string, d *decodedTexture) (*graphics.Texture2D, error) {
+	texture := graphics.NewTexture2D(d.size, graphics.TextureFormatDefaultColor)
+	texture.SetTexFormat(d.format)
+	texture.SetData(d.pix)
+
+	if err := h.Add(name, texture); err != nil {
+		return nil, err
+	}
+
+	d.settings.apply(texture)
+
+	return texture, nil
 }
 
 func (h *Handler) Add(name string, texture *graphics.Texture2D) error {
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+
 	if _, dup := h.Items[name]; dup {
 		return core.ErrAssetExists(name)
 	}
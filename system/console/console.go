@@ -0,0 +1,69 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package console
+
+import (
+	"github.com/haakenlabs/arc/core"
+)
+
+// Exec runs a line and adds it to the history.
+func Exec(line string) error {
+	return core.GetConsoleSystem().Exec(line)
+}
+
+// ExecScript runs a script.
+func ExecScript(filename string) error {
+	return core.GetConsoleSystem().ExecScript(filename)
+}
+
+// Enqueue queues a line to be run at the start of the next frame. It is safe
+// to call from any goroutine.
+func Enqueue(line string) {
+	core.GetConsoleSystem().Enqueue(line)
+}
+
+// RegisterCommand adds a command.
+func RegisterCommand(cmd core.Command) error {
+	return core.GetConsoleSystem().RegisterCommand(cmd)
+}
+
+// RegisterCvar adds a console variable.
+func RegisterCvar(s core.Setting) (*core.Cvar, error) {
+	return core.GetConsoleSystem().RegisterCvar(s)
+}
+
+// MustRegisterCvar is like RegisterCvar, but panics if an error occurs.
+func MustRegisterCvar(s core.Setting) *core.Cvar {
+	return core.GetConsoleSystem().MustRegisterCvar(s)
+}
+
+// Cvar returns the console variable with the given name.
+func Cvar(name string) (*core.Cvar, error) {
+	return core.GetConsoleSystem().Cvar(name)
+}
+
+// Complete returns the possible completions of the last word of a partial
+// line.
+func Complete(line string) []string {
+	return core.GetConsoleSystem().Complete(line)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package event
+
+import (
+	"github.com/haakenlabs/arc/core"
+)
+
+// Subscribe registers a handler for events of the given type.
+func Subscribe(t core.EventType, handler core.EventHandler) core.EventHandle {
+	return core.GetEventSystem().Subscribe(t, handler)
+}
+
+// Unsubscribe removes the subscription with the given handle.
+func Unsubscribe(h core.EventHandle) bool {
+	return core.GetEventSystem().Unsubscribe(h)
+}
+
+// Publish sends an event to all of its subscribers immediately.
+func Publish(e core.Event) {
+	core.GetEventSystem().Publish(e)
+}
+
+// Enqueue adds an event to the queue, which is dispatched at the start of the
+// next frame. It is safe to call from any goroutine.
+func Enqueue(e core.Event) {
+	core.GetEventSystem().Enqueue(e)
+}
@@ -51,3 +51,25 @@ func ReleaseAll() {
 func Get(id int32) (core.Object, error) {
 	return core.GetInstanceSystem().Get(id)
 }
+
+// HandleOf returns a handle to an assigned object. Unlike its ID, the handle
+// of an object is never reused after the object is released.
+func HandleOf(o core.Object) core.Handle {
+	return core.GetInstanceSystem().HandleOf(o)
+}
+
+// Resolve returns the object a handle refers to. It returns false if the
+// object has been released.
+func Resolve(h core.Handle) (core.Object, bool) {
+	return core.GetInstanceSystem().Resolve(h)
+}
+
+// Valid reports if a handle refers to an object which has not been released.
+func Valid(h core.Handle) bool {
+	return core.GetInstanceSystem().Valid(h)
+}
+
+// Census takes a snapshot of the live objects, grouped by type and name.
+func Census() core.Census {
+	return core.GetInstanceSystem().Census()
+}
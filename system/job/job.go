@@ -0,0 +1,51 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package job
+
+import (
+	"time"
+
+	"github.com/haakenlabs/arc/core"
+)
+
+// Go runs fn on a worker goroutine.
+func Go(fn core.JobFunc) *core.Future {
+	return core.GetJobSystem().Go(fn)
+}
+
+// Main runs fn on the main thread during the next frame.
+func Main(fn core.JobFunc) *core.Future {
+	return core.GetJobSystem().Main(fn)
+}
+
+// RunOnMain queues fn to run on the main thread. It is safe to call from any
+// goroutine.
+func RunOnMain(fn func()) {
+	core.GetJobSystem().RunOnMain(fn)
+}
+
+// DrainMain runs queued main thread jobs until the queue is empty or budget
+// has elapsed.
+func DrainMain(budget time.Duration) int {
+	return core.GetJobSystem().DrainMain(budget)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright (c) 2018 HaakenLabs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package profile
+
+import (
+	"io"
+
+	"github.com/haakenlabs/arc/core"
+)
+
+// Begin opens a new profiler scope with the given name.
+func Begin(name string) {
+	core.GetProfilerSystem().Begin(name)
+}
+
+// End closes the most recently opened profiler scope.
+func End() {
+	core.GetProfilerSystem().End()
+}
+
+// Scope opens a new profiler scope and returns a function which closes it.
+func Scope(name string) func() {
+	return core.GetProfilerSystem().Scope(name)
+}
+
+// Enabled reports if the profiler is recording.
+func Enabled() bool {
+	return core.GetProfilerSystem().Enabled()
+}
+
+// SetEnabled starts or stops recording.
+func SetEnabled(enabled bool) {
+	core.GetProfilerSystem().SetEnabled(enabled)
+}
+
+// Detailed reports if fine grained scopes should be recorded.
+func Detailed() bool {
+	return core.GetProfilerSystem().Detailed()
+}
+
+// Stats returns statistics for every scope in the recorded frames.
+func Stats() []core.ProfileStat {
+	return core.GetProfilerSystem().Stats()
+}
+
+// WriteChromeTrace writes the recorded frames to w in the Chrome Trace Event
+// format.
+func WriteChromeTrace(w io.Writer) error {
+	return core.GetProfilerSystem().WriteChromeTrace(w)
+}
@@ -27,7 +27,7 @@ import (
 )
 
 func FrameTime() float64 {
-	return core.GetTimeSystem().FixedTime()
+	return core.GetTimeSystem().FrameTime()
 }
 
 func DeltaTime() float64 {
@@ -38,6 +38,46 @@ func FixedTime() float64 {
 	return core.GetTimeSystem().FixedTime()
 }
 
+func SetFixedTime(fixedTime float64) error {
+	return core.GetTimeSystem().SetFixedTime(fixedTime)
+}
+
+func UnscaledDeltaTime() float64 {
+	return core.GetTimeSystem().UnscaledDeltaTime()
+}
+
+func Time() float64 {
+	return core.GetTimeSystem().Time()
+}
+
+func UnscaledTime() float64 {
+	return core.GetTimeSystem().UnscaledTime()
+}
+
+func TimeScale() float64 {
+	return core.GetTimeSystem().TimeScale()
+}
+
+func SetTimeScale(scale float64) {
+	core.GetTimeSystem().SetTimeScale(scale)
+}
+
+func Pause() {
+	core.GetTimeSystem().Pause()
+}
+
+func Resume() {
+	core.GetTimeSystem().Resume()
+}
+
+func Paused() bool {
+	return core.GetTimeSystem().Paused()
+}
+
+func Alpha() float64 {
+	return core.GetTimeSystem().Alpha()
+}
+
 func Delta() float64 {
 	return core.GetTimeSystem().Delta()
 }
@@ -69,3 +69,9 @@ func GLFWWindow() *glfw.Window {
 func OrthoMatrix() mgl32.Mat4 {
 	return core.GetWindowSystem().OrthoMatrix()
 }
+
+// Headless reports if the app is running without a window or an OpenGL
+// context.
+func Headless() bool {
+	return core.GetWindowSystem().Headless()
+}
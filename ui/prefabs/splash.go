@@ -23,13 +23,59 @@ SOFTWARE.
 package prefabs
 
 import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/haakenlabs/arc/core"
 	"github.com/haakenlabs/arc/scene"
 	"github.com/haakenlabs/arc/system/asset/texture"
+	"github.com/haakenlabs/arc/system/instance"
 	"github.com/haakenlabs/arc/system/window"
 	"github.com/haakenlabs/arc/ui"
 	"github.com/haakenlabs/arc/ui/widget"
 )
 
+// LoadProgress updates a progress widget with the progress of an
+// asynchronous manifest load.
+type LoadProgress struct {
+	scene.BaseScriptComponent
+
+	// OnFinishFunc is called once when the load has finished.
+	OnFinishFunc func(*core.ManifestLoad)
+
+	load     *core.ManifestLoad
+	progress *widget.Progress
+	finished bool
+}
+
+func (p *LoadProgress) Update() {
+	if p.finished {
+		return
+	}
+
+	p.progress.SetProgress(p.load.Fraction())
+
+	if p.load.Finished() {
+		p.finished = true
+		if p.OnFinishFunc != nil {
+			p.OnFinishFunc(p.load)
+		}
+	}
+}
+
+// NewLoadProgress creates a component which displays the progress of load
+// with progress.
+func NewLoadProgress(load *core.ManifestLoad, progress *widget.Progress) *LoadProgress {
+	p := &LoadProgress{
+		load:     load,
+		progress: progress,
+	}
+
+	p.SetName("LoadProgress")
+	instance.MustAssign(p)
+
+	return p
+}
+
 func CreateSplash() *scene.GameObject {
 	c := ui.CreateController("splash")
 
@@ -47,3 +93,20 @@ func CreateSplash() *scene.GameObject {
 
 	return c
 }
+
+// CreateLoadingSplash creates a splash screen with a progress bar showing the
+// progress of load. The LoadProgress component of the returned object may be
+// used to act when the load has finished.
+func CreateLoadingSplash(load *core.ManifestLoad) *scene.GameObject {
+	c := CreateSplash()
+
+	bar := widget.CreateProgress("splash-progress")
+	ui.RectTransformComponent(bar).SetPresets(ui.AnchorBottomCenter, ui.PivotBottomCenter)
+	ui.RectTransformComponent(bar).SetSize(mgl32.Vec2{320, 10})
+	ui.RectTransformComponent(bar).SetPosition2D(mgl32.Vec2{0, -32})
+
+	c.AddChild(bar)
+	c.AddComponent(NewLoadProgress(load, widget.ProgressComponent(bar)))
+
+	return c
+}